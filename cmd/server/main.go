@@ -4,36 +4,60 @@ import (
 	"context"
 	//"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
+	"sync"
 	"syscall"
 	"time"
+	_ "time/tzdata" // The ?tz= filter must not depend on the host having a zoneinfo database
 
 	"expense_tracker/internal/config"
 	"expense_tracker/internal/handler"
+	"expense_tracker/internal/logger"
 	"expense_tracker/internal/middleware"
 	"expense_tracker/internal/repository"
 	"expense_tracker/internal/service"
+	"expense_tracker/internal/storage"
+	"expense_tracker/internal/tracing"
 	"expense_tracker/internal/utils"
+	"expense_tracker/internal/webhook"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
+// version is the build version, set at build time:
+//
+//	go build -ldflags "-X main.version=1.2.3" ./cmd/server
+var version = "dev"
+
 func main() {
 	// Load .env file
 	if err := godotenv.Load(); err != nil {
 		log.Println("No .env file found or error loading, relying on environment variables")
 	}
 
+	// Structured JSON logging; the standard log package is routed through it as well
+	appLogger := logger.New(os.Getenv("LOG_LEVEL"))
+	slog.SetDefault(appLogger)
+
 	// --- Configuration ---
 	dbCfg, err := config.LoadDBConfig()
 	if err != nil {
 		log.Fatalf("Failed to load DB config: %v", err)
 	}
 
+	repository.SetQueryTimeout(dbCfg.QueryTimeout)
+
+	txCfg, err := config.LoadTransactionConfig()
+	if err != nil {
+		log.Fatalf("Failed to load transaction config: %v", err)
+	}
+
 	jwtSecret := os.Getenv("JWT_SECRET_KEY")
 	if jwtSecret == "" {
 		log.Fatalf("JWT_SECRET_KEY not set in environment")
@@ -45,6 +69,28 @@ func main() {
 		jwtExpHours = 24
 	}
 
+	// Opt-in DB check that the token's user still exists (cached to limit per-request cost)
+	verifyTokenUser, _ := strconv.ParseBool(os.Getenv("VERIFY_TOKEN_USER"))
+	userStatusTTLSeconds, err := strconv.Atoi(os.Getenv("USER_STATUS_CACHE_TTL_SECONDS"))
+	if err != nil || userStatusTTLSeconds < 0 {
+		userStatusTTLSeconds = 30
+	}
+
+	refreshExpHours, err := strconv.ParseInt(os.Getenv("REFRESH_TOKEN_EXPIRATION_HOURS"), 10, 64)
+	if err != nil || refreshExpHours <= 0 {
+		refreshExpHours = utils.DefaultRefreshExpirationHours
+	}
+
+	// Per-IP limit on the auth endpoints, to slow down password guessing
+	authRateLimit, err := strconv.Atoi(os.Getenv("AUTH_RATE_LIMIT"))
+	if err != nil || authRateLimit <= 0 {
+		authRateLimit = 10
+	}
+	authRateWindowSeconds, err := strconv.Atoi(os.Getenv("AUTH_RATE_LIMIT_WINDOW_SECONDS"))
+	if err != nil || authRateWindowSeconds <= 0 {
+		authRateWindowSeconds = 60
+	}
+
 	serverPort := os.Getenv("SERVER_PORT")
 	if serverPort == "" {
 		serverPort = "8080" // Default port
@@ -54,18 +100,51 @@ func main() {
 	if uploadsDir == "" {
 		uploadsDir = "uploads" // Default uploads directory
 	}
-	// Ensure uploads directory exists
-	if err := os.MkdirAll(uploadsDir, os.ModePerm); err != nil {
-		log.Fatalf("Failed to create uploads directory %s: %v", uploadsDir, err)
+	// Wrap responses in {"success": ..., "data"/"error": ...}; raw objects by default
+	responseEnvelope, _ := strconv.ParseBool(os.Getenv("RESPONSE_ENVELOPE"))
+	// Compression is on unless a reverse proxy in front already does it (GZIP_COMPRESSION=false)
+	gzipCompression, err := strconv.ParseBool(os.Getenv("GZIP_COMPRESSION"))
+	if err != nil {
+		gzipCompression = true
+	}
+	// How long shutdown waits for in-flight requests (large receipt uploads included) to finish
+	shutdownTimeoutSeconds, err := strconv.Atoi(os.Getenv("SHUTDOWN_TIMEOUT_SECONDS"))
+	if err != nil || shutdownTimeoutSeconds <= 0 {
+		shutdownTimeoutSeconds = 30
+	}
+	shutdownTimeout := time.Duration(shutdownTimeoutSeconds) * time.Second
+	// Prometheus metrics go on their own port when METRICS_PORT is set, else on /metrics of the API
+	// port behind admin authentication
+	metricsPort := os.Getenv("METRICS_PORT")
+
+	s3Cfg, err := storage.LoadS3Config()
+	if err != nil {
+		log.Fatalf("Failed to load S3 config: %v", err)
+	}
+
+	// Transaction webhooks are off unless WEBHOOK_URL is set
+	webhookCfg, err := webhook.LoadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load webhook config: %v", err)
+	}
+
+	// Tracing is a no-op unless an OTLP endpoint is configured
+	shutdownTracing := func(context.Context) error { return nil }
+	if tracingCfg := tracing.LoadConfig(); tracingCfg != nil {
+		if shutdownTracing, err = tracing.Setup(context.Background(), tracingCfg); err != nil {
+			log.Fatalf("Failed to set up tracing: %v", err)
+		}
+		dbCfg.Tracer = tracing.QueryTracer{}
+		log.Printf("Traces will be exported to: %s", tracingCfg.Endpoint)
 	}
-	log.Printf("Uploads will be stored in: %s", uploadsDir)
 
 	// --- Database Connection ---
-	dbPool, err := config.ConnectDB(dbCfg)
+	// replicaPool is nil unless DATABASE_REPLICA_URL is set
+	dbPool, replicaPool, err := config.ConnectDB(dbCfg)
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
-	defer dbPool.Close()
+	// Closed explicitly once the HTTP server has drained, see Graceful Shutdown below
 
 	// --- Auto Migration ---
 	if err := config.AutoMigrate(dbPool); err != nil {
@@ -73,57 +152,140 @@ func main() {
 	}
 
 	// --- Initialize Utilities ---
-	jwtUtil := utils.NewJWTUtil(jwtSecret, jwtExpHours)
+	jwtUtil := utils.NewJWTUtil(jwtSecret, jwtExpHours).WithRefreshExpiration(refreshExpHours)
+
+	// --- Receipt Storage ---
+	var receiptStorage storage.ReceiptStorage
+	if s3Cfg != nil {
+		receiptStorage, err = storage.NewS3Storage(context.Background(), s3Cfg)
+		if err != nil {
+			log.Fatalf("Failed to initialize S3 storage: %v", err)
+		}
+		log.Printf("Uploads will be stored in S3 bucket: %s", s3Cfg.Bucket)
+	} else {
+		receiptStorage, err = storage.NewLocalStorage(uploadsDir)
+		if err != nil {
+			log.Fatalf("Failed to initialize local storage: %v", err)
+		}
+		log.Printf("Uploads will be stored in: %s", uploadsDir)
+	}
 
 	// --- Initialize Repositories ---
 	userRepo := repository.NewUserRepository(dbPool)
-	transactionRepo := repository.NewTransactionRepository(dbPool)
+	transactionRepo := repository.NewTransactionRepository(dbPool, replicaPool)
+	refreshTokenRepo := repository.NewRefreshTokenRepository(dbPool)
+	tokenBlacklistRepo := repository.NewTokenBlacklistRepository(dbPool)
+	categoryRepo := repository.NewCategoryRepository(dbPool)
+	budgetRepo := repository.NewBudgetRepository(dbPool)
+	recurringRepo := repository.NewRecurringRepository(dbPool)
+	accountRepo := repository.NewAccountRepository(dbPool)
 
 	// --- Initialize Services ---
-	authService := service.NewAuthService(userRepo, jwtUtil)
-	transactionService := service.NewTransactionService(transactionRepo, uploadsDir)
+	var transactionNotifier service.TransactionNotifier
+	webhookNotifier := webhook.NewNotifier(webhookCfg) // Nil, and safe to drain, without a config
+	if webhookNotifier != nil {
+		transactionNotifier = webhookNotifier
+		log.Printf("Transaction webhooks will be sent to: %s", webhookCfg.URL)
+	}
+	// Nil unless VERIFY_TOKEN_USER is set; services invalidate it when an account changes
+	var userStatusCache *service.UserStatusCache
+	if verifyTokenUser {
+		userStatusCache = service.NewUserStatusCache(userRepo, time.Duration(userStatusTTLSeconds)*time.Second)
+	}
+	transactionService := service.NewTransactionService(transactionRepo, userRepo, categoryRepo, receiptStorage, txCfg, transactionNotifier)
+	authService := service.NewAuthService(userRepo, transactionRepo, refreshTokenRepo, tokenBlacklistRepo, jwtUtil, receiptStorage, userStatusCache, transactionService)
+	categoryService := service.NewCategoryService(categoryRepo)
+	budgetService := service.NewBudgetService(budgetRepo, categoryRepo, userRepo)
+	recurringService := service.NewRecurringService(recurringRepo, categoryRepo, transactionService)
+	adminService := service.NewAdminService(userRepo, transactionRepo, refreshTokenRepo, userStatusCache)
+	accountService := service.NewAccountService(accountRepo)
+
+	// --- Background Jobs ---
+	bgCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	var backgroundJobs sync.WaitGroup // Waited for on shutdown, before the pools close
+	service.StartTokenBlacklistCleanup(bgCtx, &backgroundJobs, tokenBlacklistRepo, time.Hour)
+	service.StartRecurringScheduler(bgCtx, &backgroundJobs, recurringService, time.Minute)
+	service.StartIdempotencyKeyCleanup(bgCtx, &backgroundJobs, transactionRepo, txCfg.IdempotencyKeyTTL, time.Hour)
+	// Only local uploads can be walked; for S3 use a bucket lifecycle rule instead
+	if walkable, ok := receiptStorage.(storage.WalkableStorage); ok && txCfg.ReceiptPurgeInterval > 0 {
+		service.StartReceiptPurge(bgCtx, &backgroundJobs, transactionRepo, walkable, txCfg.ReceiptPurgeGrace, txCfg.ReceiptPurgeInterval)
+	}
 
 	// --- Initialize Handlers ---
 	authHandler := handler.NewAuthHandler(authService)
-	transactionHandler := handler.NewTransactionHandler(transactionService, uploadsDir)
+	transactionHandler := handler.NewTransactionHandler(transactionService, txCfg.MaxReceiptSize)
+	categoryHandler := handler.NewCategoryHandler(categoryService)
+	budgetHandler := handler.NewBudgetHandler(budgetService)
+	recurringHandler := handler.NewRecurringHandler(recurringService)
+	adminHandler := handler.NewAdminHandler(adminService)
+	accountHandler := handler.NewAccountHandler(accountService)
+	healthHandler := handler.NewHealthHandler(dbPool, version)
 
 	// --- Setup Gin Router ---
 	// gin.SetMode(gin.ReleaseMode) // Uncomment for production
-	router := gin.Default()
-
-	// Simple CORS middleware (allow all for development)
-	// For production, configure specific origins, methods, headers
-	router.Use(func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(http.StatusNoContent)
-			return
-		}
-		c.Next()
-	})
+	// gin.New instead of gin.Default: panics are recovered by RecoveryMiddleware below, which answers in JSON
+	router := gin.New()
+	router.Use(gin.Logger())
+	// Uploads are capped per route; this only bounds how much of a form is held in memory
+	router.MaxMultipartMemory = txCfg.MaxReceiptSize
+
+	// CORS allowlist from CORS_ALLOWED_ORIGINS; empty allows any origin without credentials
+	router.Use(middleware.CORS(config.LoadCORSConfig()))
+
+	router.Use(middleware.RequestIDMiddleware())
+	router.Use(middleware.RequestLoggerMiddleware(appLogger))
+	router.Use(middleware.MetricsMiddleware())
+	router.Use(middleware.TracingMiddleware())
+
+	// Must be registered before any middleware that can abort with an error
+	router.Use(middleware.ResponseEnvelopeMiddleware(responseEnvelope))
+
+	// After the middlewares above so the 500 carries the request ID and is counted in metrics
+	router.Use(middleware.RecoveryMiddleware())
+
+	if gzipCompression {
+		router.Use(middleware.GzipMiddleware(middleware.DefaultGzipMinSize))
+	}
 
 	// --- Initialize Middlewares ---
-	jwtAuthMW := middleware.JWTAuthMiddleware(jwtUtil)
+	var userStatusChecker middleware.UserStatusChecker
+	if userStatusCache != nil {
+		userStatusChecker = userStatusCache
+	}
+	jwtAuthMW := middleware.JWTAuthMiddleware(jwtUtil, tokenBlacklistRepo, userStatusChecker)
 	adminRoleMW := middleware.AdminMiddleware()
+	authRateLimitMW := middleware.RateLimitMiddleware(authRateLimit, time.Duration(authRateWindowSeconds)*time.Second)
 	// userRoleMW := middleware.UserMiddleware() // Not strictly needed if JWTAuthMW is enough for "logged in"
 
 	// --- Register Routes ---
 	apiGroup := router.Group("/api/v1") // Base path for API
-	authHandler.RegisterAuthRoutes(apiGroup)
+	authHandler.RegisterAuthRoutes(apiGroup, jwtAuthMW, authRateLimitMW)
 	transactionHandler.RegisterTransactionRoutes(apiGroup, jwtAuthMW, nil /*userRoleMW*/, adminRoleMW)
+	categoryHandler.RegisterCategoryRoutes(apiGroup, jwtAuthMW)
+	budgetHandler.RegisterBudgetRoutes(apiGroup, jwtAuthMW)
+	recurringHandler.RegisterRecurringRoutes(apiGroup, jwtAuthMW)
+	adminHandler.RegisterAdminRoutes(apiGroup, jwtAuthMW, adminRoleMW)
+	accountHandler.RegisterAccountRoutes(apiGroup, jwtAuthMW)
 
-	// Health check endpoint (not in TZ, but good practice)
-	router.GET("/health", func(c *gin.Context) {
-		// Check DB connection
-		if err := dbPool.Ping(context.Background()); err != nil {
-			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "error", "db": "unhealthy"})
-			return
-		}
-		c.JSON(http.StatusOK, gin.H{"status": "ok", "db": "healthy"})
-	})
+	// Health check endpoints (not in TZ, but good practice)
+	healthHandler.RegisterHealthRoutes(router)
+
+	var metricsSrv *http.Server
+	if metricsPort != "" {
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("/metrics", promhttp.Handler())
+		metricsSrv = &http.Server{Addr: ":" + metricsPort, Handler: metricsMux}
+		go func() {
+			log.Printf("Metrics server starting on port %s", metricsPort)
+			if err := metricsSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Fatalf("metrics listen: %s\n", err)
+			}
+		}()
+	} else {
+		// The API port is public, so only admins may read the metrics there
+		router.GET("/metrics", jwtAuthMW, adminRoleMW, gin.WrapH(promhttp.Handler()))
+	}
 
 	// --- Start Server ---
 	srv := &http.Server{
@@ -143,11 +305,36 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	log.Println("Shutting down server...")
+	stopBackground()
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	// Shutdown stops accepting connections and returns once every in-flight request has finished
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
 	defer cancel()
 	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatal("Server forced to shutdown:", err)
+		log.Printf("Server did not drain within %s, closing remaining connections: %v", shutdownTimeout, err)
+		srv.Close()
+	}
+	if metricsSrv != nil {
+		if err := metricsSrv.Shutdown(ctx); err != nil {
+			log.Printf("Metrics server forced to shutdown: %v", err)
+		}
+	}
+	// The jobs were cancelled above, and their queries use bgCtx, so this returns promptly
+	backgroundJobs.Wait()
+	// Requests and the recurring scheduler are done, so no new webhooks can start
+	if !webhookNotifier.Drain(ctx) {
+		log.Printf("Webhook deliveries did not finish within %s and were abandoned", shutdownTimeout)
+	}
+
+	// Only after the server is drained, so no request starts a query on a closed pool. Close
+	// itself waits for queries that still hold a connection (bounded by DB_QUERY_TIMEOUT_SECONDS).
+	dbPool.Close()
+	if replicaPool != nil {
+		replicaPool.Close()
+	}
+	// Last, so spans of the final requests and queries are flushed to the collector
+	if err := shutdownTracing(ctx); err != nil {
+		log.Printf("Failed to flush traces: %v", err)
 	}
 
 	log.Println("Server exiting")
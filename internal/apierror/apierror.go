@@ -0,0 +1,107 @@
+// Package apierror defines the error body every API error response carries:
+//
+//	{"error": {"code": "TRANSACTION_NOT_FOUND", "message": "transaction not found"}}
+//
+// Codes are stable and meant for programs; messages are for people and may change.
+package apierror
+
+import "net/http"
+
+// Code is a stable, machine-readable error identifier
+type Code string
+
+// Generic codes, used when no more specific code applies; see ForStatus
+const (
+	CodeBadRequest         Code = "BAD_REQUEST"
+	CodeValidationFailed   Code = "VALIDATION_FAILED" // Comes with per-field messages
+	CodeUnauthorized       Code = "UNAUTHORIZED"
+	CodeForbidden          Code = "FORBIDDEN"
+	CodeNotFound           Code = "NOT_FOUND"
+	CodeConflict           Code = "CONFLICT"
+	CodePayloadTooLarge    Code = "PAYLOAD_TOO_LARGE"
+	CodeRateLimited        Code = "RATE_LIMITED"
+	CodeInternal           Code = "INTERNAL_ERROR"
+	CodeServiceUnavailable Code = "SERVICE_UNAVAILABLE"
+)
+
+// Domain codes, mapped from the service layer's errors
+const (
+	CodeTransactionNotFound   Code = "TRANSACTION_NOT_FOUND"
+	CodeReceiptNotFound       Code = "RECEIPT_NOT_FOUND"
+	CodeNoThumbnail           Code = "NO_THUMBNAIL"
+	CodeInvalidFileFormat     Code = "INVALID_FILE_FORMAT"
+	CodeFileTooLarge          Code = "FILE_TOO_LARGE"
+	CodeInvalidAmount         Code = "INVALID_AMOUNT"
+	CodeAmountBelowMinimum    Code = "AMOUNT_BELOW_MINIMUM"
+	CodeVersionConflict       Code = "VERSION_CONFLICT"
+	CodeInvalidTransfer       Code = "INVALID_TRANSFER"
+	CodeTransferNotEditable   Code = "TRANSFER_NOT_EDITABLE"
+	CodeUnsupportedCurrency   Code = "UNSUPPORTED_CURRENCY"
+	CodeFutureTransactionDate Code = "FUTURE_TRANSACTION_DATE"
+	CodeDescriptionTooLong    Code = "DESCRIPTION_TOO_LONG"
+	CodeInvalidTag            Code = "INVALID_TAG"
+	CodeInvalidIdempotencyKey Code = "INVALID_IDEMPOTENCY_KEY"
+	CodeInvalidDateRange      Code = "INVALID_DATE_RANGE"
+	CodeInvalidGranularity    Code = "INVALID_GRANULARITY"
+	CodeRangeTooLarge         Code = "RANGE_TOO_LARGE"
+	CodeInvalidBreakdownType  Code = "INVALID_BREAKDOWN_TYPE"
+	CodeInvalidCSV            Code = "INVALID_CSV"
+	CodeTooManyImportRows     Code = "TOO_MANY_IMPORT_ROWS"
+	CodeAccountNotFound       Code = "ACCOUNT_NOT_FOUND"
+	CodeAccountExists         Code = "ACCOUNT_EXISTS"
+	CodeBlankAccountName      Code = "BLANK_ACCOUNT_NAME"
+	CodeCategoryNotFound      Code = "CATEGORY_NOT_FOUND"
+	CodeCategoryExists        Code = "CATEGORY_EXISTS"
+	CodeUnknownCategory       Code = "UNKNOWN_CATEGORY"
+	CodeBlankCategory         Code = "BLANK_CATEGORY"
+	CodeCategoryTypeMismatch  Code = "CATEGORY_TYPE_MISMATCH"
+	CodeInvalidMonth          Code = "INVALID_MONTH"
+	CodeRecurringNotFound     Code = "RECURRING_NOT_FOUND"
+	CodeUserNotFound          Code = "USER_NOT_FOUND"
+	CodeUserAlreadyExists     Code = "USER_ALREADY_EXISTS"
+	CodeInvalidCredentials    Code = "INVALID_CREDENTIALS"
+	CodeInvalidRefreshToken   Code = "INVALID_REFRESH_TOKEN"
+	CodeIncorrectPassword     Code = "INCORRECT_PASSWORD"
+	CodePasswordTooShort      Code = "PASSWORD_TOO_SHORT"
+	CodeInvalidPhone          Code = "INVALID_PHONE"
+	CodeInvalidRole           Code = "INVALID_ROLE"
+	CodeLastAdmin             Code = "LAST_ADMIN"
+	CodeAdminNoteTooLong      Code = "ADMIN_NOTE_TOO_LONG"
+	CodeInvalidThreshold      Code = "INVALID_ALERT_THRESHOLD"
+)
+
+// Error is the value of the "error" key in an error response
+type Error struct {
+	Code    Code   `json:"code"`
+	Message string `json:"message"`
+	// Fields maps request fields to what is wrong with them, for VALIDATION_FAILED
+	Fields map[string]string `json:"fields,omitempty"`
+	// RequestID is set on server errors so users can quote it in support tickets
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// ForStatus returns the generic code for an HTTP error status
+func ForStatus(status int) Code {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeBadRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusRequestEntityTooLarge:
+		return CodePayloadTooLarge
+	case http.StatusTooManyRequests:
+		return CodeRateLimited
+	case http.StatusServiceUnavailable:
+		return CodeServiceUnavailable
+	}
+	if status >= 500 {
+		return CodeInternal
+	}
+	return CodeBadRequest
+}
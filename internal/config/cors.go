@@ -0,0 +1,25 @@
+package config
+
+import (
+	"os"
+	"strings"
+)
+
+// CORSConfig holds the browser origins allowed to call the API
+type CORSConfig struct {
+	// AllowedOrigins are exact origins such as "https://app.example.com"; empty allows any
+	// origin without credentials, for local development
+	AllowedOrigins []string
+}
+
+// LoadCORSConfig loads the CORS allowlist from CORS_ALLOWED_ORIGINS (comma-separated)
+func LoadCORSConfig() *CORSConfig {
+	cfg := &CORSConfig{}
+	for _, origin := range strings.Split(os.Getenv("CORS_ALLOWED_ORIGINS"), ",") {
+		origin = strings.TrimRight(strings.TrimSpace(origin), "/")
+		if origin != "" {
+			cfg.AllowedOrigins = append(cfg.AllowedOrigins, origin)
+		}
+	}
+	return cfg
+}
@@ -5,18 +5,117 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
 	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 // DBConfig holds database connection parameters
 type DBConfig struct {
-	DSN string
+	DSN          string
+	ReplicaDSN   string        // Optional read-only replica for heavy reads; empty means reads use the primary
+	QueryTimeout time.Duration // Deadline for each repository call; 0 keeps the repository default
+	// Pool sizing; zero values keep the pgxpool defaults
+	MaxConns        int32
+	MinConns        int32
+	MaxConnLifetime time.Duration
+	MaxConnIdleTime time.Duration
+	// Tracer, when set, observes every query on both pools, e.g. to give each its own trace span
+	Tracer pgx.QueryTracer
 }
 
-// LoadDBConfig loads database configuration from environment variables
+// sslModes are the libpq sslmode values accepted in DB_SSLMODE
+var sslModes = map[string]bool{
+	"disable":     true,
+	"allow":       true,
+	"prefer":      true,
+	"require":     true,
+	"verify-ca":   true,
+	"verify-full": true,
+}
+
+// LoadDBConfig loads database configuration from environment variables. DATABASE_URL, when set,
+// is used verbatim; otherwise the DSN is built from DB_HOST, DB_PORT, DB_USER, DB_PASSWORD,
+// DB_NAME and DB_SSLMODE. DATABASE_REPLICA_URL optionally names a read replica.
 func LoadDBConfig() (*DBConfig, error) {
+	dsn, err := loadDSN()
+	if err != nil {
+		return nil, err
+	}
+
+	var queryTimeout time.Duration
+	if v := os.Getenv("DB_QUERY_TIMEOUT_SECONDS"); v != "" {
+		seconds, err := strconv.Atoi(v)
+		if err != nil || seconds <= 0 {
+			return nil, fmt.Errorf("invalid DB_QUERY_TIMEOUT_SECONDS %q: must be a positive integer", v)
+		}
+		queryTimeout = time.Duration(seconds) * time.Second
+	}
+
+	cfg := &DBConfig{DSN: dsn, QueryTimeout: queryTimeout}
+	if replicaURL := os.Getenv("DATABASE_REPLICA_URL"); replicaURL != "" {
+		if _, err := pgxpool.ParseConfig(replicaURL); err != nil {
+			return nil, fmt.Errorf("invalid DATABASE_REPLICA_URL: %w", err)
+		}
+		cfg.ReplicaDSN = replicaURL
+	}
+	if cfg.MaxConns, err = envInt32("DB_MAX_CONNS"); err != nil {
+		return nil, err
+	}
+	if cfg.MinConns, err = envInt32("DB_MIN_CONNS"); err != nil {
+		return nil, err
+	}
+	if cfg.MaxConns > 0 && cfg.MinConns > cfg.MaxConns {
+		return nil, fmt.Errorf("DB_MIN_CONNS (%d) must not exceed DB_MAX_CONNS (%d)", cfg.MinConns, cfg.MaxConns)
+	}
+	if cfg.MaxConnLifetime, err = envMinutes("DB_MAX_CONN_LIFETIME_MINUTES"); err != nil {
+		return nil, err
+	}
+	if cfg.MaxConnIdleTime, err = envMinutes("DB_MAX_CONN_IDLE_MINUTES"); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// envInt32 reads an optional non-negative integer variable, 0 when unset
+func envInt32(name string) (int32, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(v, 10, 32)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid %s %q: must be a non-negative integer", name, v)
+	}
+	return int32(n), nil
+}
+
+// envMinutes reads an optional duration given in whole minutes, 0 when unset
+func envMinutes(name string) (time.Duration, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return 0, nil
+	}
+	minutes, err := strconv.Atoi(v)
+	if err != nil || minutes <= 0 {
+		return 0, fmt.Errorf("invalid %s %q: must be a positive integer", name, v)
+	}
+	return time.Duration(minutes) * time.Minute, nil
+}
+
+// loadDSN returns DATABASE_URL if present, or builds a key/value DSN from the DB_* variables
+func loadDSN() (string, error) {
+	if databaseURL := os.Getenv("DATABASE_URL"); databaseURL != "" {
+		if _, err := pgxpool.ParseConfig(databaseURL); err != nil {
+			return "", fmt.Errorf("invalid DATABASE_URL: %w", err)
+		}
+		return databaseURL, nil
+	}
+
 	dbHost := os.Getenv("DB_HOST")
 	dbPort := os.Getenv("DB_PORT")
 	dbUser := os.Getenv("DB_USER")
@@ -24,17 +123,52 @@ func LoadDBConfig() (*DBConfig, error) {
 	dbName := os.Getenv("DB_NAME")
 
 	if dbHost == "" || dbPort == "" || dbUser == "" || dbName == "" {
-		return nil, fmt.Errorf("database environment variables not set (DB_HOST, DB_PORT, DB_USER, DB_PASSWORD, DB_NAME)")
+		return "", fmt.Errorf("database environment variables not set: set DATABASE_URL, or DB_HOST, DB_PORT, DB_USER, DB_PASSWORD and DB_NAME")
+	}
+
+	sslMode := os.Getenv("DB_SSLMODE")
+	if sslMode == "" {
+		sslMode = "disable" // Local development; managed Postgres usually needs require or stricter
+	}
+	if !sslModes[sslMode] {
+		return "", fmt.Errorf("invalid DB_SSLMODE %q: must be one of disable, allow, prefer, require, verify-ca, verify-full", sslMode)
 	}
 
-	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		dbHost, dbPort, dbUser, dbPassword, dbName)
+	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		dbHost, dbPort, dbUser, dbPassword, dbName, sslMode), nil
+}
+
+// scanTimestampsInUTC makes TIMESTAMPTZ columns scan as UTC instead of the server's local
+// zone, so API responses carry the same timestamps whatever TZ the process runs in
+func scanTimestampsInUTC(ctx context.Context, conn *pgx.Conn) error {
+	conn.TypeMap().RegisterType(&pgtype.Type{
+		Name:  "timestamptz",
+		OID:   pgtype.TimestamptzOID,
+		Codec: &pgtype.TimestamptzCodec{ScanLocation: time.UTC},
+	})
+	return nil
+}
 
-	return &DBConfig{DSN: dsn}, nil
+// ConnectDB establishes the connection pool to the primary database and, when a replica is
+// configured, a second pool to it; replica is nil otherwise. Both pools share the sizing settings.
+func ConnectDB(cfg *DBConfig) (primary, replica *pgxpool.Pool, err error) {
+	primary, err = connectPool(cfg, cfg.DSN, "primary")
+	if err != nil {
+		return nil, nil, err
+	}
+	if cfg.ReplicaDSN == "" {
+		return primary, nil, nil
+	}
+	replica, err = connectPool(cfg, cfg.ReplicaDSN, "replica")
+	if err != nil {
+		primary.Close()
+		return nil, nil, fmt.Errorf("replica: %w", err)
+	}
+	return primary, replica, nil
 }
 
-// ConnectDB establishes a connection to the PostgreSQL database
-func ConnectDB(cfg *DBConfig) (*pgxpool.Pool, error) {
+// connectPool opens a pool to dsn with cfg's pool settings, retrying while the database comes up
+func connectPool(cfg *DBConfig, dsn, label string) (*pgxpool.Pool, error) {
 	var pool *pgxpool.Pool
 	var err error
 
@@ -42,83 +176,45 @@ func ConnectDB(cfg *DBConfig) (*pgxpool.Pool, error) {
 	maxRetries := 5
 	retryInterval := 5 * time.Second
 
+	poolCfg, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid database DSN: %w", err)
+	}
+	poolCfg.AfterConnect = scanTimestampsInUTC
+	if cfg.Tracer != nil {
+		poolCfg.ConnConfig.Tracer = cfg.Tracer
+	}
+	if cfg.MaxConns > 0 {
+		poolCfg.MaxConns = cfg.MaxConns
+	}
+	if cfg.MinConns > 0 {
+		poolCfg.MinConns = cfg.MinConns
+	}
+	if cfg.MaxConnLifetime > 0 {
+		poolCfg.MaxConnLifetime = cfg.MaxConnLifetime
+	}
+	if cfg.MaxConnIdleTime > 0 {
+		poolCfg.MaxConnIdleTime = cfg.MaxConnIdleTime
+	}
+	if poolCfg.MinConns > poolCfg.MaxConns {
+		return nil, fmt.Errorf("DB_MIN_CONNS (%d) must not exceed the pool size (%d)", poolCfg.MinConns, poolCfg.MaxConns)
+	}
+	log.Printf("Database pool (%s): max_conns=%d min_conns=%d max_conn_lifetime=%v max_conn_idle_time=%v",
+		label, poolCfg.MaxConns, poolCfg.MinConns, poolCfg.MaxConnLifetime, poolCfg.MaxConnIdleTime)
+
 	for i := 0; i < maxRetries; i++ {
-		pool, err = pgxpool.New(context.Background(), cfg.DSN)
+		pool, err = pgxpool.NewWithConfig(context.Background(), poolCfg)
 		if err == nil {
 			// Try to ping the database
 			err = pool.Ping(context.Background())
 			if err == nil {
-				log.Println("Successfully connected to PostgreSQL!")
+				log.Printf("Successfully connected to PostgreSQL (%s)!", label)
 				return pool, nil
 			}
+			pool.Close()
 		}
-		log.Printf("Failed to connect to database (attempt %d/%d): %v. Retrying in %v...", i+1, maxRetries, err, retryInterval)
+		log.Printf("Failed to connect to database (%s, attempt %d/%d): %v. Retrying in %v...", label, i+1, maxRetries, err, retryInterval)
 		time.Sleep(retryInterval)
 	}
 	return nil, fmt.Errorf("unable to connect to database after %d attempts: %w", maxRetries, err)
 }
-
-// AutoMigrate creates tables if they don't exist
-func AutoMigrate(db *pgxpool.Pool) error {
-	sql := `
-	CREATE TABLE IF NOT EXISTS users (
-		id SERIAL PRIMARY KEY,
-		phone TEXT UNIQUE NOT NULL,
-		password_hash TEXT NOT NULL,
-		role TEXT NOT NULL CHECK (role IN ('user', 'admin')) DEFAULT 'user',
-		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
-	);
-
-	CREATE TABLE IF NOT EXISTS transactions (
-		id BIGSERIAL PRIMARY KEY,
-		user_id BIGINT NOT NULL,
-		amount BIGINT NOT NULL, -- in smallest currency unit (e.g., cents)
-		type VARCHAR(50) NOT NULL CHECK (type IN ('income', 'expense')),
-		category VARCHAR(100) NOT NULL,
-		description TEXT,
-		transaction_date TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
-		receipt_path TEXT, -- stores relative path to the uploaded file
-		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
-	);
-
-	-- Indexes for performance
-	CREATE INDEX IF NOT EXISTS idx_transactions_user_id ON transactions(user_id);
-	CREATE INDEX IF NOT EXISTS idx_transactions_type ON transactions(type);
-	CREATE INDEX IF NOT EXISTS idx_transactions_category ON transactions(category);
-	CREATE INDEX IF NOT EXISTS idx_transactions_transaction_date ON transactions(transaction_date);
-
-    -- Function to update updated_at column
-    CREATE OR REPLACE FUNCTION update_updated_at_column()
-    RETURNS TRIGGER AS $$
-    BEGIN
-       NEW.updated_at = NOW();
-       RETURN NEW;
-    END;
-    $$ language 'plpgsql';
-
-    -- Trigger for transactions table
-    DO $$
-    BEGIN
-        IF NOT EXISTS (
-            SELECT 1
-            FROM pg_trigger
-            WHERE tgname = 'set_transactions_updated_at' AND tgrelid = 'transactions'::regclass
-        ) THEN
-            CREATE TRIGGER set_transactions_updated_at
-            BEFORE UPDATE ON transactions
-            FOR EACH ROW
-            EXECUTE FUNCTION update_updated_at_column();
-        END IF;
-    END
-    $$;
-	`
-	_, err := db.Exec(context.Background(), sql)
-	if err != nil {
-		return fmt.Errorf("unable to apply migrations: %w", err)
-	}
-
-	log.Println("AutoMigrate applied successfully")
-	return nil
-}
\ No newline at end of file
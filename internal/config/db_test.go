@@ -0,0 +1,96 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setDBEnv sets the DB_* connection variables and clears the overrides
+func setDBEnv(t *testing.T) {
+	t.Setenv("DATABASE_URL", "")
+	t.Setenv("DATABASE_REPLICA_URL", "")
+	t.Setenv("DB_HOST", "localhost")
+	t.Setenv("DB_PORT", "5432")
+	t.Setenv("DB_USER", "expense")
+	t.Setenv("DB_PASSWORD", "secret")
+	t.Setenv("DB_NAME", "expense_tracker")
+	t.Setenv("DB_SSLMODE", "")
+}
+
+func TestLoadDSN(t *testing.T) {
+	setDBEnv(t)
+	dsn, err := loadDSN()
+	assert.NoError(t, err)
+	assert.Equal(t, "host=localhost port=5432 user=expense password=secret dbname=expense_tracker sslmode=disable", dsn)
+
+	t.Setenv("DB_SSLMODE", "verify-full")
+	dsn, err = loadDSN()
+	assert.NoError(t, err)
+	assert.Contains(t, dsn, "sslmode=verify-full")
+
+	t.Setenv("DB_SSLMODE", "on")
+	_, err = loadDSN()
+	assert.ErrorContains(t, err, "invalid DB_SSLMODE")
+
+	// DATABASE_URL wins over the DB_* variables, even an invalid DB_SSLMODE
+	t.Setenv("DATABASE_URL", "postgres://app:pw@db.example.com:5432/expenses?sslmode=require")
+	dsn, err = loadDSN()
+	assert.NoError(t, err)
+	assert.Equal(t, "postgres://app:pw@db.example.com:5432/expenses?sslmode=require", dsn)
+
+	t.Setenv("DATABASE_URL", "postgres://app@db.example.com:notaport/expenses")
+	_, err = loadDSN()
+	assert.ErrorContains(t, err, "invalid DATABASE_URL")
+
+	t.Setenv("DATABASE_URL", "")
+	t.Setenv("DB_HOST", "")
+	_, err = loadDSN()
+	assert.ErrorContains(t, err, "database environment variables not set")
+}
+
+func TestLoadDBConfig_PoolSettings(t *testing.T) {
+	setDBEnv(t)
+	t.Setenv("DB_QUERY_TIMEOUT_SECONDS", "")
+	for _, name := range []string{"DB_MAX_CONNS", "DB_MIN_CONNS", "DB_MAX_CONN_LIFETIME_MINUTES", "DB_MAX_CONN_IDLE_MINUTES"} {
+		t.Setenv(name, "")
+	}
+
+	// Unset keeps the pgxpool defaults
+	cfg, err := LoadDBConfig()
+	assert.NoError(t, err)
+	assert.Zero(t, cfg.MaxConns)
+	assert.Zero(t, cfg.MinConns)
+	assert.Zero(t, cfg.MaxConnLifetime)
+	assert.Zero(t, cfg.MaxConnIdleTime)
+
+	t.Setenv("DB_MAX_CONNS", "20")
+	t.Setenv("DB_MIN_CONNS", "2")
+	t.Setenv("DB_MAX_CONN_LIFETIME_MINUTES", "60")
+	t.Setenv("DB_MAX_CONN_IDLE_MINUTES", "15")
+	cfg, err = LoadDBConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, int32(20), cfg.MaxConns)
+	assert.Equal(t, int32(2), cfg.MinConns)
+	assert.Equal(t, time.Hour, cfg.MaxConnLifetime)
+	assert.Equal(t, 15*time.Minute, cfg.MaxConnIdleTime)
+
+	t.Setenv("DB_MIN_CONNS", "30")
+	_, err = LoadDBConfig()
+	assert.ErrorContains(t, err, "must not exceed DB_MAX_CONNS")
+	t.Setenv("DB_MIN_CONNS", "2")
+
+	for name, value := range map[string]string{
+		"DB_MAX_CONNS":                 "-1",
+		"DB_MIN_CONNS":                 "many",
+		"DB_MAX_CONN_LIFETIME_MINUTES": "0",
+		"DB_MAX_CONN_IDLE_MINUTES":     "1.5",
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(name, value)
+			_, err := LoadDBConfig()
+			assert.ErrorContains(t, err, "invalid "+name)
+		})
+	}
+}
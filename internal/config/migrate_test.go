@@ -0,0 +1,44 @@
+package config
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadMigrations_OrdersByVersion(t *testing.T) {
+	files := fstest.MapFS{
+		"migrations/0010_add_tags.sql":       {Data: []byte("ALTER TABLE t ADD COLUMN tags TEXT;")},
+		"migrations/0002_add_currency.sql":   {Data: []byte("ALTER TABLE t ADD COLUMN currency TEXT;")},
+		"migrations/0001_initial_schema.sql": {Data: []byte("CREATE TABLE t (id INT);")},
+	}
+	migrations, err := loadMigrations(files)
+	assert.NoError(t, err)
+	if assert.Len(t, migrations, 3) {
+		assert.Equal(t, 1, migrations[0].Version)
+		assert.Equal(t, "initial_schema", migrations[0].Name)
+		assert.Equal(t, 2, migrations[1].Version)
+		assert.Equal(t, 10, migrations[2].Version)
+		assert.Equal(t, "ALTER TABLE t ADD COLUMN tags TEXT;", migrations[2].SQL)
+	}
+}
+
+func TestLoadMigrations_Invalid(t *testing.T) {
+	_, err := loadMigrations(fstest.MapFS{"migrations/initial.sql": {}})
+	assert.Error(t, err)
+
+	_, err = loadMigrations(fstest.MapFS{
+		"migrations/0001_a.sql": {},
+		"migrations/1_b.sql":    {},
+	})
+	assert.ErrorContains(t, err, "share version 1")
+}
+
+func TestLoadMigrations_Embedded(t *testing.T) {
+	migrations, err := loadMigrations(migrationFiles)
+	assert.NoError(t, err)
+	if assert.NotEmpty(t, migrations) {
+		assert.Equal(t, 1, migrations[0].Version)
+	}
+}
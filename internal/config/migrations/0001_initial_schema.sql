@@ -0,0 +1,173 @@
+-- Schema as it stood before versioned migrations; every statement is idempotent so
+-- databases created by the old AutoMigrate can record it as applied
+CREATE TABLE IF NOT EXISTS users (
+	id SERIAL PRIMARY KEY,
+	phone TEXT UNIQUE NOT NULL,
+	password_hash TEXT NOT NULL,
+	role TEXT NOT NULL CHECK (role IN ('user', 'admin')) DEFAULT 'user',
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS transactions (
+	id BIGSERIAL PRIMARY KEY,
+	user_id BIGINT NOT NULL,
+	amount BIGINT NOT NULL, -- in smallest currency unit (e.g., cents)
+	type VARCHAR(50) NOT NULL CHECK (type IN ('income', 'expense')),
+	category VARCHAR(100) NOT NULL,
+	description TEXT,
+	transaction_date TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	receipt_path TEXT, -- stores relative path to the uploaded file
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Indexes for performance
+CREATE INDEX IF NOT EXISTS idx_transactions_user_id ON transactions(user_id);
+CREATE INDEX IF NOT EXISTS idx_transactions_type ON transactions(type);
+CREATE INDEX IF NOT EXISTS idx_transactions_category ON transactions(category);
+CREATE INDEX IF NOT EXISTS idx_transactions_user_category ON transactions(user_id, category);
+CREATE INDEX IF NOT EXISTS idx_transactions_transaction_date ON transactions(transaction_date);
+
+-- Merchant/payee, separate from the free-text description
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS merchant VARCHAR(255);
+-- Bumped on every update, for optimistic concurrency control
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;
+CREATE INDEX IF NOT EXISTS idx_transactions_merchant ON transactions(merchant);
+-- Receipts are stored under generated names; this keeps the uploader's filename for downloads
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS receipt_name TEXT;
+
+-- ISO 4217 currency codes; a user's currency is the default for their new transactions
+ALTER TABLE users ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'UZS';
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'UZS';
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+	id BIGSERIAL PRIMARY KEY,
+	user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	token_hash TEXT UNIQUE NOT NULL, -- sha256 of the opaque token, never the token itself
+	expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	revoked BOOLEAN NOT NULL DEFAULT FALSE,
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens(user_id);
+
+-- Categories: user_id NULL marks a global default available to everyone
+CREATE TABLE IF NOT EXISTS categories (
+	id SERIAL PRIMARY KEY,
+	user_id INT REFERENCES users(id) ON DELETE CASCADE,
+	name VARCHAR(100) NOT NULL,
+	type TEXT NOT NULL CHECK (type IN ('income', 'expense')),
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_categories_owner_name_type ON categories (COALESCE(user_id, 0), LOWER(name), type);
+
+INSERT INTO categories (user_id, name, type) VALUES
+	(NULL, 'Food', 'expense'),
+	(NULL, 'Transport', 'expense'),
+	(NULL, 'Housing', 'expense'),
+	(NULL, 'Utilities', 'expense'),
+	(NULL, 'Health', 'expense'),
+	(NULL, 'Entertainment', 'expense'),
+	(NULL, 'Shopping', 'expense'),
+	(NULL, 'Other', 'expense'),
+	(NULL, 'Salary', 'income'),
+	(NULL, 'Freelance', 'income'),
+	(NULL, 'Gifts', 'income'),
+	(NULL, 'Other', 'income')
+ON CONFLICT (COALESCE(user_id, 0), LOWER(name), type) DO NOTHING;
+
+-- Monthly spending caps; month is the first day of the budgeted month
+CREATE TABLE IF NOT EXISTS budgets (
+	id SERIAL PRIMARY KEY,
+	user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	category VARCHAR(100) NOT NULL,
+	month DATE NOT NULL,
+	limit_amount BIGINT NOT NULL CHECK (limit_amount > 0), -- In tiyns
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (user_id, category, month)
+);
+
+CREATE TABLE IF NOT EXISTS recurring_transactions (
+	id SERIAL PRIMARY KEY,
+	user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	amount BIGINT NOT NULL CHECK (amount > 0), -- In tiyns
+	type VARCHAR(50) NOT NULL CHECK (type IN ('income', 'expense')),
+	category VARCHAR(100) NOT NULL,
+	description TEXT,
+	interval VARCHAR(10) NOT NULL CHECK (interval IN ('daily', 'weekly', 'monthly')),
+	next_run_date DATE NOT NULL,
+	active BOOLEAN NOT NULL DEFAULT TRUE,
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_recurring_transactions_user_id ON recurring_transactions(user_id);
+CREATE INDEX IF NOT EXISTS idx_recurring_transactions_due ON recurring_transactions(next_run_date) WHERE active;
+
+-- Accounts (wallets) that transfers move money between
+CREATE TABLE IF NOT EXISTS accounts (
+	id SERIAL PRIMARY KEY,
+	user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	name VARCHAR(100) NOT NULL,
+	balance BIGINT NOT NULL DEFAULT 0, -- In tiyns
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_accounts_user_name ON accounts (user_id, LOWER(name));
+
+-- Transfers are transactions of type 'transfer' between two of the user's accounts
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS from_account_id INT REFERENCES accounts(id) ON DELETE SET NULL;
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS to_account_id INT REFERENCES accounts(id) ON DELETE SET NULL;
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'transactions_type_check'
+			AND pg_get_constraintdef(oid) LIKE '%transfer%') THEN
+		ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_type_check;
+		ALTER TABLE transactions ADD CONSTRAINT transactions_type_check CHECK (type IN ('income', 'expense', 'transfer'));
+	END IF;
+END $$;
+
+-- Idempotency-Key of each create request, so client retries return the original transaction
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+	user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	key VARCHAR(255) NOT NULL,
+	transaction_id BIGINT NOT NULL REFERENCES transactions(id) ON DELETE CASCADE,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (user_id, key)
+);
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_created_at ON idempotency_keys(created_at);
+
+-- Trigram index so description search (ILIKE '%...%') doesn't scan the whole table
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE INDEX IF NOT EXISTS idx_transactions_description_trgm ON transactions USING GIN (description gin_trgm_ops);
+
+CREATE TABLE IF NOT EXISTS token_blacklist (
+	jti TEXT PRIMARY KEY, -- JWT ID of a revoked access token
+	expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_token_blacklist_expires_at ON token_blacklist(expires_at);
+
+-- Function to update updated_at column
+CREATE OR REPLACE FUNCTION update_updated_at_column()
+RETURNS TRIGGER AS $$
+BEGIN
+   NEW.updated_at = NOW();
+   RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+-- Trigger for transactions table
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1
+        FROM pg_trigger
+        WHERE tgname = 'set_transactions_updated_at' AND tgrelid = 'transactions'::regclass
+    ) THEN
+        CREATE TRIGGER set_transactions_updated_at
+        BEFORE UPDATE ON transactions
+        FOR EACH ROW
+        EXECUTE FUNCTION update_updated_at_column();
+    END IF;
+END
+$$;
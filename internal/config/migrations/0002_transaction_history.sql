@@ -0,0 +1,13 @@
+-- Change log of transactions: the values a transaction had before each update or deletion.
+-- transaction_id has no foreign key so the log outlives deleted transactions; it goes away
+-- with the owning user.
+CREATE TABLE IF NOT EXISTS transaction_history (
+	id BIGSERIAL PRIMARY KEY,
+	transaction_id BIGINT NOT NULL,
+	user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE, -- Owner of the transaction
+	action TEXT NOT NULL CHECK (action IN ('update', 'delete')),
+	old_values JSONB NOT NULL,
+	changed_by INT REFERENCES users(id) ON DELETE SET NULL,
+	changed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_transaction_history_transaction_id ON transaction_history(transaction_id, changed_at);
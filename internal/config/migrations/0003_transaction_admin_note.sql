@@ -0,0 +1,3 @@
+-- Admins' own annotation of a transaction, kept apart from the user's description and never
+-- shown to the user.
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS admin_note TEXT;
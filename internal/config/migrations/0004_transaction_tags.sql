@@ -0,0 +1,2 @@
+-- Free-form labels such as "work" or "reimbursable"; a transaction can carry several, unlike its category.
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
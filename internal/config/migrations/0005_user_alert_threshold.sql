@@ -0,0 +1,2 @@
+-- Monthly expense total, in the user's currency, above which the user is warned; NULL means no alert.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS monthly_alert_threshold BIGINT CHECK (monthly_alert_threshold >= 0);
@@ -0,0 +1,2 @@
+-- Backs the keyset on (transaction_date, id) that walks a user's listing page by page.
+CREATE INDEX IF NOT EXISTS idx_transactions_user_date_id ON transactions (user_id, transaction_date DESC, id DESC);
@@ -0,0 +1,6 @@
+-- Day of month a monthly rule falls on. next_run_date alone loses it once a short month clamps
+-- the date (Jan 31 -> Feb 29), which would keep every later run on the 29th.
+ALTER TABLE recurring_transactions ADD COLUMN IF NOT EXISTS anchor_day SMALLINT;
+UPDATE recurring_transactions SET anchor_day = EXTRACT(DAY FROM next_run_date) WHERE anchor_day IS NULL;
+ALTER TABLE recurring_transactions ALTER COLUMN anchor_day SET NOT NULL;
+ALTER TABLE recurring_transactions ADD CONSTRAINT recurring_transactions_anchor_day_check CHECK (anchor_day BETWEEN 1 AND 31);
@@ -0,0 +1,8 @@
+-- The admin note is invisible to the owner, so setting it must not bump updated_at (and with it
+-- the ETag the owner sees). The trigger now fires only when a column other than admin_note changes.
+DROP TRIGGER IF EXISTS set_transactions_updated_at ON transactions;
+CREATE TRIGGER set_transactions_updated_at
+BEFORE UPDATE ON transactions
+FOR EACH ROW
+WHEN ((to_jsonb(OLD) - 'admin_note') IS DISTINCT FROM (to_jsonb(NEW) - 'admin_note'))
+EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,4 @@
+-- Attaching and removing a receipt are recorded in the transaction's history too
+ALTER TABLE transaction_history DROP CONSTRAINT IF EXISTS transaction_history_action_check;
+ALTER TABLE transaction_history ADD CONSTRAINT transaction_history_action_check
+	CHECK (action IN ('update', 'delete', 'receipt_upload', 'receipt_delete'));
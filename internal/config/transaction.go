@@ -0,0 +1,258 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// End date interpretation modes for user transaction filters
+const (
+	EndDateInclusive = "inclusive" // EndDate includes its whole calendar day
+	EndDateExclusive = "exclusive" // EndDate is an exact, exclusive bound
+)
+
+// Receipt upload defaults used when the corresponding env vars are absent
+const DefaultMaxReceiptSizeMB = 5
+
+// DefaultIdempotencyKeyTTLHours is how long an Idempotency-Key is remembered by default
+const DefaultIdempotencyKeyTTLHours = 24
+
+// Orphaned receipt purge defaults; a zero interval disables the purge
+const (
+	DefaultReceiptPurgeIntervalHours = 24
+	DefaultReceiptPurgeGraceMinutes  = 24 * 60
+)
+
+// MinReceiptPurgeGrace is the shortest accepted grace period. An upload is written to disk before
+// its path is stored on the transaction, so younger files may belong to a request still in flight.
+const MinReceiptPurgeGrace = 10 * time.Minute
+
+// DefaultMaxFutureDateHours is how far ahead of now a transaction_date may be by default
+const DefaultMaxFutureDateHours = 24
+
+// MaxDescriptionLength is the default and largest description length, in characters;
+// MAX_DESCRIPTION_LENGTH can only lower it
+const MaxDescriptionLength = 500
+
+// MaxTransactionAmount is the largest accepted amount, in the currency minor unit. At a trillion
+// units of any currency it is far beyond real transactions, while leaving int64 sums over
+// millions of rows clear of overflow.
+const MaxTransactionAmount = 100_000_000_000_000
+
+// DefaultStatsCacheTTLSeconds is how long admin statistics are cached by default; zero disables the cache
+const DefaultStatsCacheTTLSeconds = 60
+
+// DefaultCSVAmountDivisor turns amounts in tiyns into sums in CSV exports; CSV_AMOUNT_DIVISOR
+// overrides it with another power of ten, 1 keeping raw minor units
+const DefaultCSVAmountDivisor = 100
+
+// DefaultCSVDateLayout is the time layout of dates in CSV exports unless CSV_DATE_FORMAT sets another
+const DefaultCSVDateLayout = time.RFC3339
+
+// DefaultReceiptExtensions lists the receipt extensions accepted by default
+var DefaultReceiptExtensions = []string{".jpg", ".jpeg", ".png", ".pdf"}
+
+// TransactionConfig holds tunable rules applied to transactions
+type TransactionConfig struct {
+	// MinAmount is the smallest accepted amount, in the currency minor unit (e.g., tiyns)
+	MinAmount int64
+	// EndDateMode controls how a user-supplied end date bounds a query
+	EndDateMode string
+	// MaxReceiptSize is the largest accepted receipt upload, in bytes
+	MaxReceiptSize int64
+	// AllowedReceiptExtensions are lower-case extensions with a leading dot, e.g. ".pdf"
+	AllowedReceiptExtensions []string
+	// IdempotencyKeyTTL is how long a create request's Idempotency-Key is honoured
+	IdempotencyKeyTTL time.Duration
+	// MaxFutureDate is how far ahead of now a transaction_date may be; it absorbs clock and
+	// time zone differences while catching typos like the year 2099
+	MaxFutureDate time.Duration
+	// MaxDescriptionLength is the longest description accepted, in characters, after trimming
+	MaxDescriptionLength int
+	// ReceiptPurgeInterval is how often unreferenced receipt files are purged; zero disables it
+	ReceiptPurgeInterval time.Duration
+	// ReceiptPurgeGrace is how old an unreferenced receipt file must be before it is purged
+	ReceiptPurgeGrace time.Duration
+	// StatsCacheTTL is how long admin statistics for one filter set are reused; zero disables caching
+	StatsCacheTTL time.Duration
+	// CSVAmountDivisor is how many minor units make one unit of the amounts in CSV exports
+	CSVAmountDivisor int64
+	// CSVDateLayout is the Go time layout of dates in CSV exports, e.g. "02.01.2006 15:04"
+	CSVDateLayout string
+}
+
+// LoadTransactionConfig loads transaction settings from environment variables
+func LoadTransactionConfig() (*TransactionConfig, error) {
+	cfg := &TransactionConfig{
+		MinAmount:                1, // Same floor as the gt=0 binding on amount
+		EndDateMode:              EndDateInclusive,
+		MaxReceiptSize:           DefaultMaxReceiptSizeMB * 1024 * 1024,
+		AllowedReceiptExtensions: DefaultReceiptExtensions,
+		IdempotencyKeyTTL:        DefaultIdempotencyKeyTTLHours * time.Hour,
+		MaxFutureDate:            DefaultMaxFutureDateHours * time.Hour,
+		MaxDescriptionLength:     MaxDescriptionLength,
+		ReceiptPurgeInterval:     DefaultReceiptPurgeIntervalHours * time.Hour,
+		ReceiptPurgeGrace:        DefaultReceiptPurgeGraceMinutes * time.Minute,
+		StatsCacheTTL:            DefaultStatsCacheTTLSeconds * time.Second,
+		CSVAmountDivisor:         DefaultCSVAmountDivisor,
+		CSVDateLayout:            DefaultCSVDateLayout,
+	}
+
+	if minAmountStr := os.Getenv("MIN_TRANSACTION_AMOUNT"); minAmountStr != "" {
+		minAmount, err := strconv.ParseInt(minAmountStr, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MIN_TRANSACTION_AMOUNT: %w", err)
+		}
+		if minAmount < 1 {
+			return nil, fmt.Errorf("MIN_TRANSACTION_AMOUNT must be at least 1, got %d", minAmount)
+		}
+		cfg.MinAmount = minAmount
+	}
+
+	if endDateMode := os.Getenv("END_DATE_MODE"); endDateMode != "" {
+		if endDateMode != EndDateInclusive && endDateMode != EndDateExclusive {
+			return nil, fmt.Errorf("END_DATE_MODE must be %q or %q, got %q", EndDateInclusive, EndDateExclusive, endDateMode)
+		}
+		cfg.EndDateMode = endDateMode
+	}
+
+	if maxSizeStr := os.Getenv("MAX_RECEIPT_SIZE_MB"); maxSizeStr != "" {
+		maxSizeMB, err := strconv.ParseInt(maxSizeStr, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MAX_RECEIPT_SIZE_MB: %w", err)
+		}
+		if maxSizeMB <= 0 {
+			return nil, fmt.Errorf("MAX_RECEIPT_SIZE_MB must be positive, got %d", maxSizeMB)
+		}
+		cfg.MaxReceiptSize = maxSizeMB * 1024 * 1024
+	}
+
+	if extsStr := os.Getenv("ALLOWED_RECEIPT_EXTENSIONS"); extsStr != "" {
+		exts, err := parseExtensions(extsStr)
+		if err != nil {
+			return nil, err
+		}
+		cfg.AllowedReceiptExtensions = exts
+	}
+
+	if ttlStr := os.Getenv("IDEMPOTENCY_KEY_TTL_HOURS"); ttlStr != "" {
+		ttlHours, err := strconv.Atoi(ttlStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IDEMPOTENCY_KEY_TTL_HOURS: %w", err)
+		}
+		if ttlHours <= 0 {
+			return nil, fmt.Errorf("IDEMPOTENCY_KEY_TTL_HOURS must be positive, got %d", ttlHours)
+		}
+		cfg.IdempotencyKeyTTL = time.Duration(ttlHours) * time.Hour
+	}
+
+	if intervalStr := os.Getenv("RECEIPT_PURGE_INTERVAL_HOURS"); intervalStr != "" {
+		intervalHours, err := strconv.Atoi(intervalStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RECEIPT_PURGE_INTERVAL_HOURS: %w", err)
+		}
+		if intervalHours < 0 {
+			return nil, fmt.Errorf("RECEIPT_PURGE_INTERVAL_HOURS must not be negative, got %d", intervalHours)
+		}
+		cfg.ReceiptPurgeInterval = time.Duration(intervalHours) * time.Hour
+	}
+
+	if graceStr := os.Getenv("RECEIPT_PURGE_GRACE_MINUTES"); graceStr != "" {
+		graceMinutes, err := strconv.Atoi(graceStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RECEIPT_PURGE_GRACE_MINUTES: %w", err)
+		}
+		if grace := time.Duration(graceMinutes) * time.Minute; grace < MinReceiptPurgeGrace {
+			return nil, fmt.Errorf("RECEIPT_PURGE_GRACE_MINUTES must be at least %d, got %d", int(MinReceiptPurgeGrace.Minutes()), graceMinutes)
+		}
+		cfg.ReceiptPurgeGrace = time.Duration(graceMinutes) * time.Minute
+	}
+
+	if futureStr := os.Getenv("MAX_FUTURE_DATE_HOURS"); futureStr != "" {
+		futureHours, err := strconv.Atoi(futureStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MAX_FUTURE_DATE_HOURS: %w", err)
+		}
+		if futureHours < 0 {
+			return nil, fmt.Errorf("MAX_FUTURE_DATE_HOURS must not be negative, got %d", futureHours)
+		}
+		cfg.MaxFutureDate = time.Duration(futureHours) * time.Hour
+	}
+
+	if descStr := os.Getenv("MAX_DESCRIPTION_LENGTH"); descStr != "" {
+		maxLength, err := strconv.Atoi(descStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MAX_DESCRIPTION_LENGTH: %w", err)
+		}
+		if maxLength < 1 || maxLength > MaxDescriptionLength {
+			return nil, fmt.Errorf("MAX_DESCRIPTION_LENGTH must be between 1 and %d, got %d", MaxDescriptionLength, maxLength)
+		}
+		cfg.MaxDescriptionLength = maxLength
+	}
+
+	if ttlStr := os.Getenv("STATS_CACHE_TTL_SECONDS"); ttlStr != "" {
+		ttlSeconds, err := strconv.Atoi(ttlStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid STATS_CACHE_TTL_SECONDS: %w", err)
+		}
+		if ttlSeconds < 0 {
+			return nil, fmt.Errorf("STATS_CACHE_TTL_SECONDS must not be negative, got %d", ttlSeconds)
+		}
+		cfg.StatsCacheTTL = time.Duration(ttlSeconds) * time.Second
+	}
+
+	if divisorStr := os.Getenv("CSV_AMOUNT_DIVISOR"); divisorStr != "" {
+		divisor, err := strconv.ParseInt(divisorStr, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV_AMOUNT_DIVISOR: %w", err)
+		}
+		if !isPowerOfTen(divisor) {
+			return nil, fmt.Errorf("CSV_AMOUNT_DIVISOR must be a power of ten such as 1, 100 or 1000, got %d", divisor)
+		}
+		cfg.CSVAmountDivisor = divisor
+	}
+
+	if layout := os.Getenv("CSV_DATE_FORMAT"); layout != "" {
+		// A layout without any reference-time element would print the same text for every date
+		reference := time.Date(2006, time.January, 2, 15, 4, 5, 0, time.UTC)
+		if reference.Format(layout) == layout {
+			return nil, fmt.Errorf("CSV_DATE_FORMAT must be a Go time layout such as 02.01.2006, got %q", layout)
+		}
+		cfg.CSVDateLayout = layout
+	}
+
+	return cfg, nil
+}
+
+// isPowerOfTen reports whether n is 1, 10, 100, ...
+func isPowerOfTen(n int64) bool {
+	if n < 1 {
+		return false
+	}
+	for n%10 == 0 {
+		n /= 10
+	}
+	return n == 1
+}
+
+// parseExtensions normalizes a comma-separated list such as "jpg, .PNG,pdf" to [".jpg", ".png", ".pdf"]
+func parseExtensions(list string) ([]string, error) {
+	var exts []string
+	for _, ext := range strings.Split(list, ",") {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		exts = append(exts, ext)
+	}
+	if len(exts) == 0 {
+		return nil, fmt.Errorf("ALLOWED_RECEIPT_EXTENSIONS must list at least one extension")
+	}
+	return exts, nil
+}
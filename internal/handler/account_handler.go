@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"expense_tracker/internal/model"
+	"expense_tracker/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AccountHandler handles account (wallet) requests
+type AccountHandler struct {
+	service service.AccountService
+}
+
+// NewAccountHandler creates a new AccountHandler
+func NewAccountHandler(s service.AccountService) *AccountHandler {
+	return &AccountHandler{service: s}
+}
+
+func (h *AccountHandler) CreateAccount(c *gin.Context) {
+	userID, err := getAuthUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var req model.CreateAccountRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	account, err := h.service.CreateAccount(c.Request.Context(), userID, req)
+	if err != nil {
+		if errors.Is(err, service.ErrAccountExists) {
+			respondServiceError(c, http.StatusConflict, err)
+		} else if errors.Is(err, service.ErrBlankAccountName) {
+			respondServiceError(c, http.StatusBadRequest, err)
+		} else {
+			log.Printf("Error creating account: %v", err)
+			respondError(c, http.StatusInternalServerError, "Failed to create account")
+		}
+		return
+	}
+	respondJSON(c, http.StatusCreated, account)
+}
+
+func (h *AccountHandler) ListAccounts(c *gin.Context) {
+	userID, err := getAuthUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	accounts, err := h.service.ListAccounts(c.Request.Context(), userID)
+	if err != nil {
+		log.Printf("Error listing accounts: %v", err)
+		respondError(c, http.StatusInternalServerError, "Failed to retrieve accounts")
+		return
+	}
+	respondJSON(c, http.StatusOK, accounts)
+}
+
+func (h *AccountHandler) GetAccount(c *gin.Context) {
+	userID, err := getAuthUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid account ID")
+		return
+	}
+
+	account, err := h.service.GetAccount(c.Request.Context(), id, userID)
+	if err != nil {
+		if errors.Is(err, service.ErrAccountNotFound) {
+			respondServiceError(c, http.StatusNotFound, err)
+		} else {
+			log.Printf("Error getting account: %v", err)
+			respondError(c, http.StatusInternalServerError, "Failed to retrieve account")
+		}
+		return
+	}
+	respondJSON(c, http.StatusOK, account)
+}
+
+func (h *AccountHandler) UpdateAccount(c *gin.Context) {
+	userID, err := getAuthUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid account ID")
+		return
+	}
+
+	var req model.UpdateAccountRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	account, err := h.service.RenameAccount(c.Request.Context(), id, userID, req)
+	if err != nil {
+		if errors.Is(err, service.ErrAccountNotFound) {
+			respondServiceError(c, http.StatusNotFound, err)
+		} else if errors.Is(err, service.ErrAccountExists) {
+			respondServiceError(c, http.StatusConflict, err)
+		} else if errors.Is(err, service.ErrBlankAccountName) {
+			respondServiceError(c, http.StatusBadRequest, err)
+		} else {
+			log.Printf("Error updating account: %v", err)
+			respondError(c, http.StatusInternalServerError, "Failed to update account")
+		}
+		return
+	}
+	respondJSON(c, http.StatusOK, account)
+}
+
+func (h *AccountHandler) DeleteAccount(c *gin.Context) {
+	userID, err := getAuthUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid account ID")
+		return
+	}
+
+	if err := h.service.DeleteAccount(c.Request.Context(), id, userID); err != nil {
+		if errors.Is(err, service.ErrAccountNotFound) {
+			respondServiceError(c, http.StatusNotFound, err)
+		} else {
+			log.Printf("Error deleting account: %v", err)
+			respondError(c, http.StatusInternalServerError, "Failed to delete account")
+		}
+		return
+	}
+	respondJSON(c, http.StatusOK, gin.H{"message": "Account deleted successfully"})
+}
+
+// RegisterAccountRoutes registers account routes
+func (h *AccountHandler) RegisterAccountRoutes(rg *gin.RouterGroup, authMW gin.HandlerFunc) {
+	accountRoutes := rg.Group("/accounts")
+	accountRoutes.Use(authMW)
+	{
+		accountRoutes.POST("", h.CreateAccount)
+		accountRoutes.GET("", h.ListAccounts)
+		accountRoutes.GET("/:id", h.GetAccount)
+		accountRoutes.PUT("/:id", h.UpdateAccount)
+		accountRoutes.DELETE("/:id", h.DeleteAccount)
+	}
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"expense_tracker/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminHandler handles admin user management requests
+type AdminHandler struct {
+	service service.AdminService
+}
+
+// NewAdminHandler creates a new AdminHandler
+func NewAdminHandler(s service.AdminService) *AdminHandler {
+	return &AdminHandler{service: s}
+}
+
+func (h *AdminHandler) SetUserRole(c *gin.Context) {
+	actorID, err := getAuthUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var req struct {
+		Role string `json:"role" binding:"required"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	user, err := h.service.SetUserRole(c.Request.Context(), actorID, userID, req.Role)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidRole) {
+			respondServiceError(c, http.StatusBadRequest, err)
+		} else if errors.Is(err, service.ErrUserNotFound) {
+			respondServiceError(c, http.StatusNotFound, err)
+		} else if errors.Is(err, service.ErrLastAdmin) {
+			respondServiceError(c, http.StatusConflict, err)
+		} else {
+			log.Printf("Error setting user role: %v", err)
+			respondError(c, http.StatusInternalServerError, "Failed to update user role")
+		}
+		return
+	}
+	respondJSON(c, http.StatusOK, user)
+}
+
+// SetTransactionNote sets or, with a blank note, clears the admin note on a transaction
+func (h *AdminHandler) SetTransactionNote(c *gin.Context) {
+	transactionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid transaction ID")
+		return
+	}
+
+	var req struct {
+		Note *string `json:"note" binding:"required"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	note, err := h.service.SetTransactionNote(c.Request.Context(), transactionID, *req.Note)
+	if err != nil {
+		if errors.Is(err, service.ErrAdminNoteTooLong) {
+			respondServiceError(c, http.StatusBadRequest, err)
+		} else if errors.Is(err, service.ErrTransactionNotFound) {
+			respondServiceError(c, http.StatusNotFound, err)
+		} else {
+			log.Printf("Error setting admin note on transaction %d: %v", transactionID, err)
+			respondError(c, http.StatusInternalServerError, "Failed to set transaction note")
+		}
+		return
+	}
+	respondJSON(c, http.StatusOK, gin.H{"id": transactionID, "admin_note": note})
+}
+
+// RegisterAdminRoutes registers admin user management and transaction review routes
+func (h *AdminHandler) RegisterAdminRoutes(rg *gin.RouterGroup, authMW, adminMW gin.HandlerFunc) {
+	adminRoutes := rg.Group("/admin/users")
+	adminRoutes.Use(authMW)
+	adminRoutes.Use(adminMW)
+	{
+		adminRoutes.PUT("/:id/role", h.SetUserRole)
+	}
+
+	adminTxRoutes := rg.Group("/admin/transactions")
+	adminTxRoutes.Use(authMW)
+	adminTxRoutes.Use(adminMW)
+	{
+		adminTxRoutes.PUT("/:id/note", h.SetTransactionNote)
+	}
+}
@@ -2,9 +2,12 @@ package handler
 
 import (
 	"errors"
+	"log"
 	"net/http"
+	"time"
 
 	//"expense_tracker/internal/model"
+	"expense_tracker/internal/middleware"
 	"expense_tracker/internal/service"
 
 	"github.com/gin-gonic/gin"
@@ -20,35 +23,52 @@ func NewAuthHandler(s service.AuthService) *AuthHandler {
 	return &AuthHandler{service: s}
 }
 
+// expiresIn is the number of whole seconds until expiresAt, never negative
+func expiresIn(expiresAt time.Time) int64 {
+	seconds := int64(time.Until(expiresAt) / time.Second)
+	if seconds < 0 {
+		return 0
+	}
+	return seconds
+}
+
 func (h *AuthHandler) Register(c *gin.Context) {
 	var req struct {
 		Phone    string `json:"phone" binding:"required"`
 		Password string `json:"password" binding:"required,min=6"` // Basic validation
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
-	user, token, err := h.service.Register(c.Request.Context(), req.Phone, req.Password)
+	// invite is only consulted while no admin exists yet, to bootstrap the first one
+	user, tokens, err := h.service.Register(c.Request.Context(), req.Phone, req.Password, c.Query("invite"))
 	if err != nil {
 		if errors.Is(err, service.ErrUserAlreadyExists) {
-			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			respondServiceError(c, http.StatusConflict, err)
+			return
+		}
+		if errors.Is(err, service.ErrInvalidPhone) {
+			respondServiceError(c, http.StatusBadRequest, err)
 			return
 		}
 		// Log the detailed error for server admins
 		// log.Printf("Error during registration: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register user"})
+		respondError(c, http.StatusInternalServerError, "Failed to register user")
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
-		"message": "User registered successfully",
-		"user_id": user.ID,
-		"phone":   user.Phone,
-		"role":    user.Role,
-		"token":   token,
+	respondJSON(c, http.StatusCreated, gin.H{
+		"message":       "User registered successfully",
+		"user_id":       user.ID,
+		"phone":         user.Phone,
+		"role":          user.Role,
+		"currency":      user.Currency,
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_at":    tokens.ExpiresAt.UTC().Format(time.RFC3339),
+		"expires_in":    expiresIn(tokens.ExpiresAt),
 	})
 }
 
@@ -58,36 +78,269 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		Password string `json:"password" binding:"required"`
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
-	user, token, err := h.service.Login(c.Request.Context(), req.Phone, req.Password)
+	user, tokens, err := h.service.Login(c.Request.Context(), req.Phone, req.Password)
 	if err != nil {
 		if errors.Is(err, service.ErrInvalidCredentials) || errors.Is(err, service.ErrUserNotFound) {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": service.ErrInvalidCredentials.Error()})
+			respondServiceError(c, http.StatusUnauthorized, service.ErrInvalidCredentials)
 			return
 		}
 		// log.Printf("Error during login: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to login"})
+		respondError(c, http.StatusInternalServerError, "Failed to login")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Login successful",
-		"user_id": user.ID,
-		"phone":   user.Phone,
-		"role":    user.Role,
-		"token":   token,
+	respondJSON(c, http.StatusOK, gin.H{
+		"message":       "Login successful",
+		"user_id":       user.ID,
+		"phone":         user.Phone,
+		"role":          user.Role,
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_at":    tokens.ExpiresAt.UTC().Format(time.RFC3339),
+		"expires_in":    expiresIn(tokens.ExpiresAt),
 	})
 }
 
-// RegisterAuthRoutes registers auth routes
-func (h *AuthHandler) RegisterAuthRoutes(rg *gin.RouterGroup) {
-	authGroup := rg.Group("/auth")
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	user, tokens, err := h.service.Refresh(c.Request.Context(), req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidRefreshToken) {
+			respondServiceError(c, http.StatusUnauthorized, err)
+			return
+		}
+		log.Printf("Error during token refresh: %v", err)
+		respondError(c, http.StatusInternalServerError, "Failed to refresh token")
+		return
+	}
+
+	respondJSON(c, http.StatusOK, gin.H{
+		"message":       "Token refreshed successfully",
+		"user_id":       user.ID,
+		"role":          user.Role,
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_at":    tokens.ExpiresAt.UTC().Format(time.RFC3339),
+		"expires_in":    expiresIn(tokens.ExpiresAt),
+	})
+}
+
+func (h *AuthHandler) Logout(c *gin.Context) {
+	userID, err := getAuthUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, "Unauthorized: "+err.Error())
+		return
+	}
+
+	// The body is optional; a refresh token in it is revoked along with the access token
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if c.Request.ContentLength > 0 {
+		if !bindJSON(c, &req) {
+			return
+		}
+	}
+
+	jti := c.GetString(middleware.AuthTokenIDKey)
+	expiresAt := c.GetTime(middleware.AuthTokenExpiryKey)
+	if err := h.service.Logout(c.Request.Context(), userID, jti, expiresAt, req.RefreshToken); err != nil {
+		log.Printf("Error during logout: %v", err)
+		respondError(c, http.StatusInternalServerError, "Failed to log out")
+		return
+	}
+
+	respondJSON(c, http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	userID, err := getAuthUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, "Unauthorized: "+err.Error())
+		return
+	}
+
+	var req struct {
+		OldPassword string `json:"old_password" binding:"required"`
+		NewPassword string `json:"new_password" binding:"required"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if err := h.service.ChangePassword(c.Request.Context(), userID, req.OldPassword, req.NewPassword); err != nil {
+		if errors.Is(err, service.ErrIncorrectPassword) {
+			respondServiceError(c, http.StatusUnauthorized, err)
+		} else if errors.Is(err, service.ErrPasswordTooShort) {
+			respondServiceError(c, http.StatusBadRequest, err)
+		} else {
+			log.Printf("Error changing password: %v", err)
+			respondError(c, http.StatusInternalServerError, "Failed to change password")
+		}
+		return
+	}
+
+	respondJSON(c, http.StatusOK, gin.H{"message": "Password changed successfully"})
+}
+
+func (h *AuthHandler) Me(c *gin.Context) {
+	userID, err := getAuthUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, "Unauthorized: "+err.Error())
+		return
+	}
+
+	user, err := h.service.GetProfile(c.Request.Context(), userID)
+	if err != nil {
+		if errors.Is(err, service.ErrUserNotFound) {
+			respondServiceError(c, http.StatusNotFound, err)
+			return
+		}
+		log.Printf("Error getting profile: %v", err)
+		respondError(c, http.StatusInternalServerError, "Failed to retrieve profile")
+		return
+	}
+
+	alert, err := h.service.GetSpendingAlert(c.Request.Context(), user)
+	if err != nil {
+		log.Printf("Error getting spending alert: %v", err)
+		respondError(c, http.StatusInternalServerError, "Failed to retrieve profile")
+		return
+	}
+
+	respondJSON(c, http.StatusOK, gin.H{
+		"id":                      user.ID,
+		"phone":                   user.Phone,
+		"role":                    user.Role,
+		"currency":                user.Currency,
+		"created_at":              user.CreatedAt,
+		"monthly_alert_threshold": alert.Threshold,
+		"month_spent":             alert.MonthSpent,
+		"over_threshold":          alert.OverThreshold,
+	})
+}
+
+// SetCurrency changes the currency the user's new transactions default to
+func (h *AuthHandler) SetCurrency(c *gin.Context) {
+	userID, err := getAuthUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, "Unauthorized: "+err.Error())
+		return
+	}
+
+	var req struct {
+		Currency string `json:"currency" binding:"required"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	user, err := h.service.SetCurrency(c.Request.Context(), userID, req.Currency)
+	if err != nil {
+		if errors.Is(err, service.ErrUnsupportedCurrency) {
+			respondServiceError(c, http.StatusBadRequest, err)
+		} else if errors.Is(err, service.ErrUserNotFound) {
+			respondServiceError(c, http.StatusNotFound, err)
+		} else {
+			log.Printf("Error changing currency: %v", err)
+			respondError(c, http.StatusInternalServerError, "Failed to change currency")
+		}
+		return
+	}
+
+	respondJSON(c, http.StatusOK, gin.H{"currency": user.Currency})
+}
+
+// SetAlertThreshold sets the monthly expense total the caller is warned above in /auth/me;
+// null removes the alert
+func (h *AuthHandler) SetAlertThreshold(c *gin.Context) {
+	userID, err := getAuthUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, "Unauthorized: "+err.Error())
+		return
+	}
+
+	var req struct {
+		MonthlyAlertThreshold *int64 `json:"monthly_alert_threshold" binding:"omitempty,gte=0"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	user, err := h.service.SetAlertThreshold(c.Request.Context(), userID, req.MonthlyAlertThreshold)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidThreshold) {
+			respondServiceError(c, http.StatusBadRequest, err)
+		} else if errors.Is(err, service.ErrUserNotFound) {
+			respondServiceError(c, http.StatusNotFound, err)
+		} else {
+			log.Printf("Error changing alert threshold: %v", err)
+			respondError(c, http.StatusInternalServerError, "Failed to change alert threshold")
+		}
+		return
+	}
+
+	respondJSON(c, http.StatusOK, gin.H{"monthly_alert_threshold": user.MonthlyAlertThreshold})
+}
+
+// DeleteAccount deletes the caller's account and all their data; the password is asked
+// again so a stray authenticated request can't do it
+func (h *AuthHandler) DeleteAccount(c *gin.Context) {
+	userID, err := getAuthUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, "Unauthorized: "+err.Error())
+		return
+	}
+
+	var req struct {
+		Password string `json:"password" binding:"required"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	jti := c.GetString(middleware.AuthTokenIDKey)
+	expiresAt := c.GetTime(middleware.AuthTokenExpiryKey)
+	if err := h.service.DeleteAccount(c.Request.Context(), userID, req.Password, jti, expiresAt); err != nil {
+		if errors.Is(err, service.ErrIncorrectPassword) {
+			respondServiceError(c, http.StatusUnauthorized, err)
+		} else if errors.Is(err, service.ErrUserNotFound) {
+			respondServiceError(c, http.StatusNotFound, err)
+		} else if errors.Is(err, service.ErrLastAdminAccount) {
+			respondServiceError(c, http.StatusConflict, err)
+		} else {
+			log.Printf("Error deleting account: %v", err)
+			respondError(c, http.StatusInternalServerError, "Failed to delete account")
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RegisterAuthRoutes registers auth routes; rateLimitMW guards the whole group against brute force
+func (h *AuthHandler) RegisterAuthRoutes(rg *gin.RouterGroup, authMW, rateLimitMW gin.HandlerFunc) {
+	authGroup := rg.Group("/auth", rateLimitMW)
 	{
 		authGroup.POST("/register", h.Register)
 		authGroup.POST("/login", h.Login)
+		authGroup.POST("/refresh", h.Refresh)
+		authGroup.POST("/logout", authMW, h.Logout)
+		authGroup.PUT("/password", authMW, h.ChangePassword)
+		authGroup.GET("/me", authMW, h.Me)
+		authGroup.PUT("/currency", authMW, h.SetCurrency)
+		authGroup.PUT("/alert-threshold", authMW, h.SetAlertThreshold)
+		authGroup.DELETE("/account", authMW, h.DeleteAccount)
 	}
 }
@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"expense_tracker/internal/apierror"
+	"expense_tracker/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// maxJSONBodySize caps JSON request bodies; none of the API's payloads come close
+const maxJSONBodySize = 1 << 20
+
+func init() {
+	// Report validation errors under the JSON names clients send, not the Go field names
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(f reflect.StructField) string {
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" {
+				return ""
+			}
+			if name == "" {
+				return f.Name
+			}
+			return name
+		})
+	}
+}
+
+// bindJSON decodes and validates the request body into obj. On failure it writes the
+// response itself (400 with per-field messages, or 413) and returns false.
+func bindJSON(c *gin.Context, obj interface{}) bool {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxJSONBodySize)
+	if err := c.ShouldBindJSON(obj); err != nil {
+		respondBindingError(c, err)
+		return false
+	}
+	return true
+}
+
+// respondBindingError turns a binding error into a response clients can act on:
+// {"error": {"code": "VALIDATION_FAILED", "message": "Invalid request", "fields": {"amount": "must be greater than 0"}}}
+func respondBindingError(c *gin.Context, err error) {
+	if isBodyTooLarge(err) {
+		respondError(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("Request body too large (max %d bytes)", maxJSONBodySize))
+		return
+	}
+
+	var validationErrs validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.As(err, &validationErrs):
+		fields := make(map[string]string, len(validationErrs))
+		for _, fe := range validationErrs {
+			fields[fe.Field()] = validationMessage(fe)
+		}
+		respondFieldErrors(c, fields)
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		respondFieldErrors(c, map[string]string{typeErr.Field: "must be " + jsonTypeName(typeErr.Type)})
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		respondError(c, http.StatusBadRequest, "Invalid request: malformed JSON")
+	case errors.Is(err, io.EOF):
+		respondError(c, http.StatusBadRequest, "Invalid request: body is required")
+	default:
+		respondError(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+	}
+}
+
+// respondFieldErrors writes a 400 listing what is wrong with each field
+func respondFieldErrors(c *gin.Context, fields map[string]string) {
+	c.JSON(http.StatusBadRequest, middleware.ErrorBody(c, http.StatusBadRequest,
+		apierror.Error{Code: apierror.CodeValidationFailed, Message: "Invalid request", Fields: fields}))
+}
+
+// validationMessage phrases a failed binding rule for API consumers
+func validationMessage(fe validator.FieldError) string {
+	isString := fe.Kind() == reflect.String
+	switch fe.Tag() {
+	case "required", "required_unless", "required_if", "required_with":
+		return "is required"
+	case "gt":
+		return "must be greater than " + fe.Param()
+	case "gte":
+		return "must be at least " + fe.Param()
+	case "lt":
+		return "must be less than " + fe.Param()
+	case "lte":
+		return "must be at most " + fe.Param()
+	case "min":
+		if isString {
+			return "must be at least " + fe.Param() + " characters long"
+		}
+		return "must be at least " + fe.Param()
+	case "max":
+		if isString {
+			return "must be at most " + fe.Param() + " characters long"
+		}
+		return "must be at most " + fe.Param()
+	case "len":
+		if isString {
+			return "must be exactly " + fe.Param() + " characters long"
+		}
+		return "must have exactly " + fe.Param() + " items"
+	case "oneof":
+		return "must be one of: " + strings.Join(strings.Fields(fe.Param()), ", ")
+	default:
+		return "is invalid"
+	}
+}
+
+// jsonTypeName names the JSON type a Go type is decoded from
+func jsonTypeName(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
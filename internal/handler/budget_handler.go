@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"expense_tracker/internal/model"
+	"expense_tracker/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BudgetHandler handles budget requests
+type BudgetHandler struct {
+	service service.BudgetService
+}
+
+// NewBudgetHandler creates a new BudgetHandler
+func NewBudgetHandler(s service.BudgetService) *BudgetHandler {
+	return &BudgetHandler{service: s}
+}
+
+func (h *BudgetHandler) SetBudget(c *gin.Context) {
+	userID, err := getAuthUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var req model.SetBudgetRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	budget, err := h.service.SetBudget(c.Request.Context(), userID, req)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidMonth) || errors.Is(err, service.ErrUnknownCategory) {
+			respondServiceError(c, http.StatusBadRequest, err)
+		} else {
+			log.Printf("Error setting budget: %v", err)
+			respondError(c, http.StatusInternalServerError, "Failed to set budget")
+		}
+		return
+	}
+	respondJSON(c, http.StatusOK, budget)
+}
+
+func (h *BudgetHandler) GetBudgetStatus(c *gin.Context) {
+	userID, err := getAuthUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	month := c.DefaultQuery("month", time.Now().UTC().Format("2006-01"))
+	statuses, err := h.service.GetBudgetStatus(c.Request.Context(), userID, month)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidMonth) {
+			respondServiceError(c, http.StatusBadRequest, err)
+		} else {
+			log.Printf("Error getting budget status: %v", err)
+			respondError(c, http.StatusInternalServerError, "Failed to retrieve budgets")
+		}
+		return
+	}
+	respondJSON(c, http.StatusOK, statuses)
+}
+
+// RegisterBudgetRoutes registers budget routes
+func (h *BudgetHandler) RegisterBudgetRoutes(rg *gin.RouterGroup, authMW gin.HandlerFunc) {
+	budgetRoutes := rg.Group("/budgets")
+	budgetRoutes.Use(authMW)
+	{
+		budgetRoutes.PUT("", h.SetBudget)
+		budgetRoutes.GET("", h.GetBudgetStatus)
+	}
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"expense_tracker/internal/model"
+	"expense_tracker/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CategoryHandler handles category requests
+type CategoryHandler struct {
+	service service.CategoryService
+}
+
+// NewCategoryHandler creates a new CategoryHandler
+func NewCategoryHandler(s service.CategoryService) *CategoryHandler {
+	return &CategoryHandler{service: s}
+}
+
+func (h *CategoryHandler) ListCategories(c *gin.Context) {
+	userID, err := getAuthUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	categories, err := h.service.ListCategories(c.Request.Context(), userID)
+	if err != nil {
+		log.Printf("Error listing categories: %v", err)
+		respondError(c, http.StatusInternalServerError, "Failed to retrieve categories")
+		return
+	}
+	respondJSON(c, http.StatusOK, categories)
+}
+
+func (h *CategoryHandler) CreateCategory(c *gin.Context) {
+	userID, err := getAuthUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var req model.CreateCategoryRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	category, err := h.service.CreateCategory(c.Request.Context(), userID, req)
+	if err != nil {
+		if errors.Is(err, service.ErrCategoryExists) {
+			respondServiceError(c, http.StatusConflict, err)
+		} else if errors.Is(err, service.ErrBlankCategory) {
+			respondServiceError(c, http.StatusBadRequest, err)
+		} else {
+			log.Printf("Error creating category: %v", err)
+			respondError(c, http.StatusInternalServerError, "Failed to create category")
+		}
+		return
+	}
+	respondJSON(c, http.StatusCreated, category)
+}
+
+func (h *CategoryHandler) DeleteCategory(c *gin.Context) {
+	userID, err := getAuthUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	categoryID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid category ID")
+		return
+	}
+
+	if err := h.service.DeleteCategory(c.Request.Context(), categoryID, userID); err != nil {
+		if errors.Is(err, service.ErrCategoryNotFound) {
+			respondServiceError(c, http.StatusNotFound, err)
+		} else {
+			log.Printf("Error deleting category: %v", err)
+			respondError(c, http.StatusInternalServerError, "Failed to delete category")
+		}
+		return
+	}
+	respondJSON(c, http.StatusOK, gin.H{"message": "Category deleted successfully"})
+}
+
+// RegisterCategoryRoutes registers category routes
+func (h *CategoryHandler) RegisterCategoryRoutes(rg *gin.RouterGroup, authMW gin.HandlerFunc) {
+	categoryRoutes := rg.Group("/categories")
+	categoryRoutes.Use(authMW)
+	{
+		categoryRoutes.GET("", h.ListCategories)
+		categoryRoutes.POST("", h.CreateCategory)
+		categoryRoutes.DELETE("/:id", h.DeleteCategory)
+	}
+}
@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// healthPingTimeout bounds the DB ping so a hung database fails the check instead of stalling it
+const healthPingTimeout = 2 * time.Second
+
+// HealthHandler serves liveness and diagnostics endpoints
+type HealthHandler struct {
+	db        *pgxpool.Pool
+	version   string
+	startedAt time.Time
+}
+
+// NewHealthHandler creates a new HealthHandler; version is the build version reported by /health/detailed
+func NewHealthHandler(db *pgxpool.Pool, version string) *HealthHandler {
+	return &HealthHandler{db: db, version: version, startedAt: time.Now()}
+}
+
+func (h *HealthHandler) ping(c *gin.Context) error {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), healthPingTimeout)
+	defer cancel()
+	return h.db.Ping(ctx)
+}
+
+// Health is the cheap check for load balancers
+func (h *HealthHandler) Health(c *gin.Context) {
+	if err := h.ping(c); err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "error", "db": "unhealthy"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "db": "healthy"})
+}
+
+// HealthDetailed adds pool statistics, build version and uptime for ops dashboards.
+// Only a failed ping makes it 503; an exhausted pool is reported but still healthy.
+func (h *HealthHandler) HealthDetailed(c *gin.Context) {
+	status, dbStatus, code := "ok", "healthy", http.StatusOK
+	if err := h.ping(c); err != nil {
+		status, dbStatus, code = "error", "unhealthy", http.StatusServiceUnavailable
+	}
+
+	stat := h.db.Stat()
+	c.JSON(code, gin.H{
+		"status":         status,
+		"db":             dbStatus,
+		"version":        h.version,
+		"uptime_seconds": int64(time.Since(h.startedAt).Seconds()),
+		"pool": gin.H{
+			"acquired_conns":     stat.AcquiredConns(),
+			"idle_conns":         stat.IdleConns(),
+			"total_conns":        stat.TotalConns(),
+			"max_conns":          stat.MaxConns(),
+			"constructing_conns": stat.ConstructingConns(),
+		},
+	})
+}
+
+// RegisterHealthRoutes registers the health endpoints at the router root, outside /api/v1
+func (h *HealthHandler) RegisterHealthRoutes(router *gin.Engine) {
+	router.GET("/health", h.Health)
+	router.GET("/health/detailed", h.HealthDetailed)
+}
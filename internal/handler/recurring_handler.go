@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"expense_tracker/internal/model"
+	"expense_tracker/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RecurringHandler handles recurring transaction requests
+type RecurringHandler struct {
+	service service.RecurringService
+}
+
+// NewRecurringHandler creates a new RecurringHandler
+func NewRecurringHandler(s service.RecurringService) *RecurringHandler {
+	return &RecurringHandler{service: s}
+}
+
+func (h *RecurringHandler) CreateRecurring(c *gin.Context) {
+	userID, err := getAuthUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var req model.CreateRecurringRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	rule, err := h.service.CreateRecurring(c.Request.Context(), userID, req)
+	if err != nil {
+		if errors.Is(err, service.ErrUnknownCategory) || errors.Is(err, service.ErrCategoryTypeMismatch) {
+			respondServiceError(c, http.StatusBadRequest, err)
+		} else {
+			log.Printf("Error creating recurring transaction: %v", err)
+			respondError(c, http.StatusInternalServerError, "Failed to create recurring transaction")
+		}
+		return
+	}
+	respondJSON(c, http.StatusCreated, rule)
+}
+
+func (h *RecurringHandler) ListRecurring(c *gin.Context) {
+	userID, err := getAuthUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	rules, err := h.service.ListRecurring(c.Request.Context(), userID)
+	if err != nil {
+		log.Printf("Error listing recurring transactions: %v", err)
+		respondError(c, http.StatusInternalServerError, "Failed to retrieve recurring transactions")
+		return
+	}
+	respondJSON(c, http.StatusOK, rules)
+}
+
+func (h *RecurringHandler) GetRecurring(c *gin.Context) {
+	userID, err := getAuthUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid recurring transaction ID")
+		return
+	}
+
+	rule, err := h.service.GetRecurring(c.Request.Context(), id, userID)
+	if err != nil {
+		if errors.Is(err, service.ErrRecurringNotFound) {
+			respondServiceError(c, http.StatusNotFound, err)
+		} else {
+			log.Printf("Error getting recurring transaction: %v", err)
+			respondError(c, http.StatusInternalServerError, "Failed to retrieve recurring transaction")
+		}
+		return
+	}
+	respondJSON(c, http.StatusOK, rule)
+}
+
+func (h *RecurringHandler) UpdateRecurring(c *gin.Context) {
+	userID, err := getAuthUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid recurring transaction ID")
+		return
+	}
+
+	var req model.UpdateRecurringRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	rule, err := h.service.UpdateRecurring(c.Request.Context(), id, userID, req)
+	if err != nil {
+		if errors.Is(err, service.ErrRecurringNotFound) {
+			respondServiceError(c, http.StatusNotFound, err)
+		} else if errors.Is(err, service.ErrUnknownCategory) || errors.Is(err, service.ErrCategoryTypeMismatch) {
+			respondServiceError(c, http.StatusBadRequest, err)
+		} else {
+			log.Printf("Error updating recurring transaction: %v", err)
+			respondError(c, http.StatusInternalServerError, "Failed to update recurring transaction")
+		}
+		return
+	}
+	respondJSON(c, http.StatusOK, rule)
+}
+
+func (h *RecurringHandler) DeleteRecurring(c *gin.Context) {
+	userID, err := getAuthUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid recurring transaction ID")
+		return
+	}
+
+	if err := h.service.DeleteRecurring(c.Request.Context(), id, userID); err != nil {
+		if errors.Is(err, service.ErrRecurringNotFound) {
+			respondServiceError(c, http.StatusNotFound, err)
+		} else {
+			log.Printf("Error deleting recurring transaction: %v", err)
+			respondError(c, http.StatusInternalServerError, "Failed to delete recurring transaction")
+		}
+		return
+	}
+	respondJSON(c, http.StatusOK, gin.H{"message": "Recurring transaction deleted successfully"})
+}
+
+// RegisterRecurringRoutes registers recurring transaction routes
+func (h *RecurringHandler) RegisterRecurringRoutes(rg *gin.RouterGroup, authMW gin.HandlerFunc) {
+	recurringRoutes := rg.Group("/recurring")
+	recurringRoutes.Use(authMW)
+	{
+		recurringRoutes.POST("", h.CreateRecurring)
+		recurringRoutes.GET("", h.ListRecurring)
+		recurringRoutes.GET("/:id", h.GetRecurring)
+		recurringRoutes.PUT("/:id", h.UpdateRecurring)
+		recurringRoutes.DELETE("/:id", h.DeleteRecurring)
+	}
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"errors"
+
+	"expense_tracker/internal/apierror"
+	"expense_tracker/internal/middleware"
+	"expense_tracker/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondJSON writes a successful response, wrapped when the envelope mode is active
+func respondJSON(c *gin.Context, status int, data interface{}) {
+	if c.GetBool(middleware.ResponseEnvelopeKey) {
+		c.JSON(status, gin.H{"success": true, "data": data})
+		return
+	}
+	c.JSON(status, data)
+}
+
+// respondError writes an error response with the generic code for status, wrapped when the
+// envelope mode is active; 5xx responses include the request ID
+func respondError(c *gin.Context, status int, message string) {
+	c.JSON(status, middleware.ErrorBody(c, status, apierror.Error{Code: apierror.ForStatus(status), Message: message}))
+}
+
+// respondServiceError is respondError for an error from the service layer: the code names
+// the sentinel err wraps, falling back to the generic code for status
+func respondServiceError(c *gin.Context, status int, err error) {
+	c.JSON(status, middleware.ErrorBody(c, status, apierror.Error{Code: errorCode(status, err), Message: err.Error()}))
+}
+
+// serviceErrorCodes maps service sentinels to their API error codes. Codes are part of the
+// API: add new ones freely, but never change an existing mapping.
+var serviceErrorCodes = []struct {
+	err  error
+	code apierror.Code
+}{
+	{service.ErrTransactionNotFound, apierror.CodeTransactionNotFound},
+	{service.ErrForbidden, apierror.CodeForbidden},
+	{service.ErrReceiptNotFound, apierror.CodeReceiptNotFound},
+	{service.ErrNoThumbnail, apierror.CodeNoThumbnail},
+	{service.ErrInvalidFileFormat, apierror.CodeInvalidFileFormat},
+	{service.ErrFileSizeExceeded, apierror.CodeFileTooLarge},
+	{service.ErrInvalidAmount, apierror.CodeInvalidAmount},
+	{service.ErrAmountBelowMinimum, apierror.CodeAmountBelowMinimum},
+	{service.ErrVersionConflict, apierror.CodeVersionConflict},
+	{service.ErrInvalidTransfer, apierror.CodeInvalidTransfer},
+	{service.ErrTransferNotEditable, apierror.CodeTransferNotEditable},
+	{service.ErrUnsupportedCurrency, apierror.CodeUnsupportedCurrency},
+	{service.ErrFutureTransactionDate, apierror.CodeFutureTransactionDate},
+	{service.ErrDescriptionTooLong, apierror.CodeDescriptionTooLong},
+	{service.ErrInvalidTag, apierror.CodeInvalidTag},
+	{service.ErrInvalidIdempotencyKey, apierror.CodeInvalidIdempotencyKey},
+	{service.ErrInvalidDateRange, apierror.CodeInvalidDateRange},
+	{service.ErrInvalidGranularity, apierror.CodeInvalidGranularity},
+	{service.ErrTimeSeriesRangeTooLarge, apierror.CodeRangeTooLarge},
+	{service.ErrInvalidBreakdownType, apierror.CodeInvalidBreakdownType},
+	{service.ErrInvalidCSV, apierror.CodeInvalidCSV},
+	{service.ErrTooManyImportRows, apierror.CodeTooManyImportRows},
+	{service.ErrAccountNotFound, apierror.CodeAccountNotFound},
+	{service.ErrAccountExists, apierror.CodeAccountExists},
+	{service.ErrBlankAccountName, apierror.CodeBlankAccountName},
+	{service.ErrCategoryNotFound, apierror.CodeCategoryNotFound},
+	{service.ErrCategoryExists, apierror.CodeCategoryExists},
+	{service.ErrUnknownCategory, apierror.CodeUnknownCategory},
+	{service.ErrBlankCategory, apierror.CodeBlankCategory},
+	{service.ErrCategoryTypeMismatch, apierror.CodeCategoryTypeMismatch},
+	{service.ErrInvalidMonth, apierror.CodeInvalidMonth},
+	{service.ErrRecurringNotFound, apierror.CodeRecurringNotFound},
+	{service.ErrUserNotFound, apierror.CodeUserNotFound},
+	{service.ErrUserAlreadyExists, apierror.CodeUserAlreadyExists},
+	{service.ErrInvalidCredentials, apierror.CodeInvalidCredentials},
+	{service.ErrInvalidRefreshToken, apierror.CodeInvalidRefreshToken},
+	{service.ErrIncorrectPassword, apierror.CodeIncorrectPassword},
+	{service.ErrPasswordTooShort, apierror.CodePasswordTooShort},
+	{service.ErrInvalidPhone, apierror.CodeInvalidPhone},
+	{service.ErrInvalidRole, apierror.CodeInvalidRole},
+	{service.ErrLastAdmin, apierror.CodeLastAdmin},
+	{service.ErrLastAdminAccount, apierror.CodeLastAdmin},
+	{service.ErrAdminNoteTooLong, apierror.CodeAdminNoteTooLong},
+	{service.ErrInvalidThreshold, apierror.CodeInvalidThreshold},
+}
+
+// errorCode returns the code of the first sentinel err wraps, or the generic code for status
+func errorCode(status int, err error) apierror.Code {
+	for _, sc := range serviceErrorCodes {
+		if errors.Is(err, sc.err) {
+			return sc.code
+		}
+	}
+	return apierror.ForStatus(status)
+}
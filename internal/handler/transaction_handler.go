@@ -1,12 +1,15 @@
 package handler
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
-	"log"
+	"mime"
 	"net/http"
-	"os"
-	///"path/filepath"
+	"net/url"
+	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -14,19 +17,42 @@ import (
 	"expense_tracker/internal/middleware"
 	"expense_tracker/internal/model"
 	"expense_tracker/internal/service"
+	"expense_tracker/internal/storage"
 
 	"github.com/gin-gonic/gin"
 )
 
 // TransactionHandler handles transaction related requests
 type TransactionHandler struct {
-	service    service.TransactionService
-	uploadsDir string
+	service        service.TransactionService
+	maxReceiptSize int64 // Largest receipt accepted, in bytes
 }
 
 // NewTransactionHandler creates a new TransactionHandler
-func NewTransactionHandler(s service.TransactionService, uploadsDir string) *TransactionHandler {
-	return &TransactionHandler{service: s, uploadsDir: uploadsDir}
+func NewTransactionHandler(s service.TransactionService, maxReceiptSize int64) *TransactionHandler {
+	return &TransactionHandler{service: s, maxReceiptSize: maxReceiptSize}
+}
+
+// multipartOverhead leaves room for the multipart framing and form fields around an uploaded file
+const multipartOverhead = 64 * 1024
+
+// limitUploadBody caps the request body at maxFileSize plus multipart framing, so an oversized
+// upload is rejected while it is being read instead of being buffered whole. It reports false,
+// after answering 413, when the declared Content-Length is already over the cap.
+func limitUploadBody(c *gin.Context, maxFileSize int64) bool {
+	limit := maxFileSize + multipartOverhead
+	if c.Request.ContentLength > limit {
+		respondError(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("Upload exceeds %d bytes", maxFileSize))
+		return false
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+	return true
+}
+
+// isBodyTooLarge reports whether err comes from a body cut off by limitUploadBody
+func isBodyTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
 }
 
 // Helper to get authenticated user ID from context
@@ -55,373 +81,1374 @@ func getAuthUserRole(c *gin.Context) (string, error) {
 	return role, nil
 }
 
+const (
+	defaultCategoryRankingLimit  = 10
+	maxCategoryRankingLimit      = 50
+	defaultTopMerchantsLimit     = 10
+	maxTopMerchantsLimit         = 50
+	defaultSuggestionsLimit      = 3
+	maxSuggestionsLimit          = 10
+	defaultRecentCategoriesLimit = 5
+	defaultTransactionPageSize   = 50
+	maxTransactionPageSize       = 100
+	defaultAdminPageSize         = 50
+	maxAdminPageSize             = 200
+	maxImportFileSize            = 2 * 1024 * 1024
+	// Retries of POST /transactions carrying the same key return the original transaction
+	idempotencyKeyHeader = "Idempotency-Key"
+)
+
+// parseTimeParam accepts either a date (YYYY-MM-DD, taken as UTC midnight) or an RFC3339
+// timestamp, and returns it in UTC
+func parseTimeParam(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// isPlainDate reports whether a time param is a bare YYYY-MM-DD date rather than a timestamp
+func isPlainDate(value string) bool {
+	_, err := time.Parse("2006-01-02", value)
+	return err == nil
+}
+
+// Helper to parse optional start_date/end_date query params for user-scoped queries.
+// The end date is returned as given; the service applies the configured inclusivity mode.
+// On invalid input it writes a 400 response and returns false.
+func parseDateRangeQuery(c *gin.Context) (*time.Time, *time.Time, bool) {
+	var startDate, endDate *time.Time
+	if startDateParam := c.Query("start_date"); startDateParam != "" {
+		parsedDate, err := parseTimeParam(startDateParam)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "Invalid date format for 'start_date', use YYYY-MM-DD or RFC3339")
+			return nil, nil, false
+		}
+		startDate = &parsedDate
+	}
+	if endDateParam := c.Query("end_date"); endDateParam != "" {
+		parsedDate, err := parseTimeParam(endDateParam)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "Invalid date format for 'end_date', use YYYY-MM-DD or RFC3339")
+			return nil, nil, false
+		}
+		endDate = &parsedDate
+	}
+	return startDate, endDate, true
+}
+
+// periodRange returns the [start, end) bounds of a named period containing now, in loc.
+// Weeks start on Monday. It reports false for an unknown period.
+func periodRange(period string, now time.Time, loc *time.Location) (time.Time, time.Time, bool) {
+	now = now.In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	switch period {
+	case "today":
+		return today, today.AddDate(0, 0, 1), true
+	case "this_week":
+		start := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+		return start, start.AddDate(0, 0, 7), true
+	case "this_month":
+		start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
+		return start, start.AddDate(0, 1, 0), true
+	case "this_year":
+		start := time.Date(now.Year(), 1, 1, 0, 0, 0, 0, loc)
+		return start, start.AddDate(1, 0, 0), true
+	}
+	return time.Time{}, time.Time{}, false
+}
+
+// periodFromQuery translates ?period= (today, this_week, this_month, this_year) into exact date
+// bounds, aligned to local midnight in the ?tz= IANA zone (UTC by default). It returns nil bounds
+// when no period is given. tz only applies to period, so it is rejected on its own rather than
+// silently ignored. The error text is fit for a 400 response.
+func periodFromQuery(c *gin.Context, now time.Time) (*time.Time, *time.Time, error) {
+	period := c.Query("period")
+	if period == "" {
+		if c.Query("tz") != "" {
+			return nil, nil, errors.New("tz can only be used together with period")
+		}
+		return nil, nil, nil
+	}
+	loc := time.UTC
+	if tz := c.Query("tz"); tz != "" {
+		var err error
+		if loc, err = time.LoadLocation(tz); err != nil {
+			return nil, nil, fmt.Errorf("Invalid tz %q, use an IANA time zone name such as Asia/Tashkent", tz)
+		}
+	}
+	start, end, ok := periodRange(period, now, loc)
+	if !ok {
+		return nil, nil, fmt.Errorf("Invalid period %q, use today, this_week, this_month or this_year", period)
+	}
+	start, end = start.UTC(), end.UTC()
+	return &start, &end, nil
+}
+
+// createdRangeFromQuery reads the created_after/created_before query params (YYYY-MM-DD or RFC3339),
+// which bound when transactions were recorded. A plain created_before date includes that whole day,
+// like end_date; a timestamp is an exact exclusive bound. The error text is fit for a 400 response.
+func createdRangeFromQuery(c *gin.Context) (*time.Time, *time.Time, error) {
+	var createdAfter, createdBefore *time.Time
+	for _, param := range []struct {
+		name string
+		dst  **time.Time
+	}{{"created_after", &createdAfter}, {"created_before", &createdBefore}} {
+		value := c.Query(param.name)
+		if value == "" {
+			continue
+		}
+		parsed, err := parseTimeParam(value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Invalid date format for '%s', use YYYY-MM-DD or RFC3339", param.name)
+		}
+		if param.name == "created_before" && isPlainDate(value) {
+			parsed = parsed.AddDate(0, 0, 1) // The repository compares created_at < created_before
+		}
+		*param.dst = &parsed
+	}
+	if createdAfter != nil && createdBefore != nil && createdAfter.After(*createdBefore) {
+		return nil, nil, errors.New("created_after must not be later than created_before")
+	}
+	return createdAfter, createdBefore, nil
+}
+
+// Helper to parse the optional currency query param; "" when absent.
+// On an unsupported code it writes a 400 response and returns false.
+func parseCurrencyQuery(c *gin.Context) (string, bool) {
+	currencyParam := c.Query("currency")
+	if currencyParam == "" {
+		return "", true
+	}
+	currency, ok := model.NormalizeCurrency(currencyParam)
+	if !ok {
+		respondError(c, http.StatusBadRequest, "Unsupported currency code")
+		return "", false
+	}
+	return currency, true
+}
+
+// Helper to parse optional min_amount/max_amount query params, in the currency minor unit.
+// On invalid input it writes a 400 response and returns false.
+func parseAmountRangeQuery(c *gin.Context) (*int64, *int64, bool) {
+	minAmount, maxAmount, err := amountRangeFromQuery(c)
+	if err != nil {
+		respondServiceError(c, http.StatusBadRequest, err)
+		return nil, nil, false
+	}
+	return minAmount, maxAmount, true
+}
+
+// amountRangeFromQuery reads the min_amount/max_amount query params; the error text is fit for a 400 response
+func amountRangeFromQuery(c *gin.Context) (*int64, *int64, error) {
+	var minAmount, maxAmount *int64
+	for _, param := range []struct {
+		name string
+		dst  **int64
+	}{{"min_amount", &minAmount}, {"max_amount", &maxAmount}} {
+		value := c.Query(param.name)
+		if value == "" {
+			continue
+		}
+		amount, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || amount < 0 {
+			return nil, nil, fmt.Errorf("Invalid %s, must be a non-negative integer", param.name)
+		}
+		*param.dst = &amount
+	}
+	if minAmount != nil && maxAmount != nil && *minAmount > *maxAmount {
+		return nil, nil, errors.New("min_amount must not exceed max_amount")
+	}
+	return minAmount, maxAmount, nil
+}
+
+// Helper to parse the filters shared by the admin listing, statistics and export endpoints
+// (user_id, type, category, merchant, q, start_date/end_date, amount range, created_after/created_before).
+// On invalid input it writes a 400 response and returns false.
+func parseAdminTransactionFilters(c *gin.Context) (model.AdminTransactionFilters, bool) {
+	filters, err := adminFiltersFromQuery(c)
+	if err != nil {
+		respondServiceError(c, http.StatusBadRequest, err)
+		return filters, false
+	}
+	return filters, true
+}
+
+// filterTypes are the values accepted in the type filter
+var filterTypes = []string{model.TransactionTypeIncome, model.TransactionTypeExpense, model.TransactionTypeTransfer}
+
+// listFromQuery reads a comma-separated query parameter ("food,transport") into its distinct,
+// non-blank values; nil when the parameter is absent or blank
+func listFromQuery(c *gin.Context, name string) []string {
+	var values []string
+	for _, v := range strings.Split(c.Query(name), ",") {
+		if v = strings.TrimSpace(v); v != "" && !slices.Contains(values, v) {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// typesFromQuery reads the comma-separated type filter; the error text is fit for a 400 response
+func typesFromQuery(c *gin.Context) ([]string, error) {
+	types := listFromQuery(c, "type")
+	for _, t := range types {
+		if !slices.Contains(filterTypes, t) {
+			return nil, fmt.Errorf("Invalid type %q, must be one of: %s", t, strings.Join(filterTypes, ", "))
+		}
+	}
+	return types, nil
+}
+
+// adminFiltersFromQuery reads the admin filters; the error text is fit for a 400 response
+func adminFiltersFromQuery(c *gin.Context) (model.AdminTransactionFilters, error) {
+	var filters model.AdminTransactionFilters
+	if userIDStr := c.Query("user_id"); userIDStr != "" {
+		uid, err := strconv.Atoi(userIDStr)
+		if err != nil {
+			return filters, errors.New("Invalid user_id format")
+		}
+		filters.UserID = &uid
+	}
+	var err error
+	if filters.Types, err = typesFromQuery(c); err != nil {
+		return filters, err
+	}
+	filters.Categories = listFromQuery(c, "category")
+	if merchantParam := c.Query("merchant"); merchantParam != "" {
+		filters.Merchant = &merchantParam
+	}
+	if tagParam := c.Query("tag"); tagParam != "" {
+		filters.Tag = &tagParam
+	}
+	if searchParam := strings.TrimSpace(c.Query("q")); searchParam != "" {
+		filters.Search = &searchParam
+	}
+	if startDateParam := c.Query("start_date"); startDateParam != "" {
+		parsedDate, err := time.Parse("2006-01-02", startDateParam)
+		if err != nil {
+			return filters, errors.New("Invalid date format for 'start_date', use YYYY-MM-DD")
+		}
+		filters.StartDate = &parsedDate
+	}
+	if endDateParam := c.Query("end_date"); endDateParam != "" {
+		parsedDate, err := time.Parse("2006-01-02", endDateParam)
+		if err != nil {
+			return filters, errors.New("Invalid date format for 'end_date', use YYYY-MM-DD")
+		}
+		// Adjust end date to include the whole day
+		endOfDay := time.Date(parsedDate.Year(), parsedDate.Month(), parsedDate.Day(), 23, 59, 59, 999999999, time.UTC)
+		filters.EndDate = &endOfDay
+	}
+	if filters.MinAmount, filters.MaxAmount, err = amountRangeFromQuery(c); err != nil {
+		return filters, err
+	}
+	if filters.CreatedAfter, filters.CreatedBefore, err = createdRangeFromQuery(c); err != nil {
+		return filters, err
+	}
+	return filters, nil
+}
+
+// Helper to parse the user listing filters (type, category, merchant, tag, q, amount range, date, period/tz or
+// start_date/end_date, created_after/created_before).
+// On invalid input it writes a 400 response and returns false.
+func parseUserTransactionFilters(c *gin.Context) (model.UserTransactionFilters, bool) {
+	var filters model.UserTransactionFilters
+	var ok bool
+	if filters.StartDate, filters.EndDate, ok = parseDateRangeQuery(c); !ok {
+		return filters, false
+	}
+	if filters.MinAmount, filters.MaxAmount, ok = parseAmountRangeQuery(c); !ok {
+		return filters, false
+	}
+	if filters.Currency, ok = parseCurrencyQuery(c); !ok {
+		return filters, false
+	}
+	var err error
+	if filters.CreatedAfter, filters.CreatedBefore, err = createdRangeFromQuery(c); err != nil {
+		respondServiceError(c, http.StatusBadRequest, err)
+		return filters, false
+	}
+	if filters.Types, err = typesFromQuery(c); err != nil {
+		respondServiceError(c, http.StatusBadRequest, err)
+		return filters, false
+	}
+	filters.Categories = listFromQuery(c, "category")
+	if merchantParam := c.Query("merchant"); merchantParam != "" {
+		filters.Merchant = &merchantParam
+	}
+	if tagParam := c.Query("tag"); tagParam != "" {
+		filters.Tag = &tagParam
+	}
+	if searchParam := strings.TrimSpace(c.Query("q")); searchParam != "" {
+		filters.Search = &searchParam
+	}
+	if dateParam := c.Query("date"); dateParam != "" {
+		parsedDate, err := time.Parse("2006-01-02", dateParam)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "Invalid date format for 'date', use YYYY-MM-DD")
+			return filters, false
+		}
+		// A single UTC day is the exact range [start of day, start of next day), independent of END_DATE_MODE
+		startOfDay := time.Date(parsedDate.Year(), parsedDate.Month(), parsedDate.Day(), 0, 0, 0, 0, time.UTC)
+		nextDay := startOfDay.AddDate(0, 0, 1)
+		filters.StartDate = &startOfDay
+		filters.EndDate = &nextDay
+		filters.EndDateExclusive = true
+	}
+	if c.Query("period") != "" && (c.Query("date") != "" || c.Query("start_date") != "" || c.Query("end_date") != "") {
+		respondError(c, http.StatusBadRequest, "period can't be combined with date, start_date or end_date")
+		return filters, false
+	}
+	periodStart, periodEnd, err := periodFromQuery(c, time.Now())
+	if err != nil {
+		respondServiceError(c, http.StatusBadRequest, err)
+		return filters, false
+	}
+	if periodStart != nil {
+		// Like date, a period is an exact range independent of END_DATE_MODE
+		filters.StartDate, filters.EndDate, filters.EndDateExclusive = periodStart, periodEnd, true
+	}
+	return filters, true
+}
+
 func (h *TransactionHandler) CreateTransaction(c *gin.Context) {
 	userID, err := getAuthUserID(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondError(c, http.StatusUnauthorized, err.Error())
 		return
 	}
 
 	var req model.CreateTransactionRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
+	req.IdempotencyKey = c.GetHeader(idempotencyKeyHeader)
 
 	transaction, err := h.service.CreateTransaction(c.Request.Context(), userID, req)
 	if err != nil {
-		log.Printf("Error creating transaction: %v", err) // Log detailed error
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create transaction"})
+		if isCreateValidationError(err) {
+			respondServiceError(c, http.StatusBadRequest, err)
+			return
+		}
+		middleware.Logger(c).Error("Error creating transaction", "error", err, "user_id", userID)
+		respondError(c, http.StatusInternalServerError, "Failed to create transaction")
+		return
+	}
+
+	// The transaction is already saved, so a failed balance lookup only drops the field
+	resp := model.CreateTransactionResponse{Transaction: transaction}
+	balance, err := h.service.GetBalance(c.Request.Context(), userID, transaction.Currency)
+	if err != nil {
+		middleware.Logger(c).Error("Error computing balance", "error", err, "user_id", userID)
+	} else {
+		resp.Balance = &balance
+	}
+	respondJSON(c, http.StatusCreated, resp)
+}
+
+// ValidateTransaction checks a create payload exactly as CreateTransaction would, without saving
+// it: {"valid": true}, or a 400 naming the offending field
+func (h *TransactionHandler) ValidateTransaction(c *gin.Context) {
+	userID, err := getAuthUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var req model.CreateTransactionRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if err := h.service.ValidateTransaction(c.Request.Context(), userID, req); err != nil {
+		if field := createValidationErrorField(err); field != "" {
+			respondFieldErrors(c, map[string]string{field: err.Error()})
+		} else if isCreateValidationError(err) {
+			respondServiceError(c, http.StatusBadRequest, err)
+		} else {
+			middleware.Logger(c).Error("Error validating transaction", "error", err, "user_id", userID)
+			respondError(c, http.StatusInternalServerError, "Failed to validate transaction")
+		}
 		return
 	}
-	c.JSON(http.StatusCreated, transaction)
+	respondJSON(c, http.StatusOK, gin.H{"valid": true})
+}
+
+// isCreateValidationError reports whether a CreateTransaction error means the payload was rejected (400)
+func isCreateValidationError(err error) bool {
+	return errors.Is(err, service.ErrAmountBelowMinimum) || errors.Is(err, service.ErrInvalidAmount) ||
+		errors.Is(err, service.ErrUnknownCategory) ||
+		errors.Is(err, service.ErrCategoryTypeMismatch) || errors.Is(err, service.ErrInvalidTransfer) ||
+		errors.Is(err, service.ErrAccountNotFound) || errors.Is(err, service.ErrInvalidIdempotencyKey) ||
+		errors.Is(err, service.ErrUnsupportedCurrency) || errors.Is(err, service.ErrFutureTransactionDate) ||
+		errors.Is(err, service.ErrDescriptionTooLong) || errors.Is(err, service.ErrInvalidTag)
+}
+
+// createValidationErrorField names the request field a create validation error is about, or ""
+func createValidationErrorField(err error) string {
+	switch {
+	case errors.Is(err, service.ErrAmountBelowMinimum), errors.Is(err, service.ErrInvalidAmount):
+		return "amount"
+	case errors.Is(err, service.ErrUnknownCategory), errors.Is(err, service.ErrCategoryTypeMismatch):
+		return "category"
+	case errors.Is(err, service.ErrUnsupportedCurrency):
+		return "currency"
+	case errors.Is(err, service.ErrFutureTransactionDate):
+		return "transaction_date"
+	case errors.Is(err, service.ErrDescriptionTooLong):
+		return "description"
+	case errors.Is(err, service.ErrInvalidTag):
+		return "tags"
+	case errors.Is(err, service.ErrInvalidTransfer), errors.Is(err, service.ErrAccountNotFound):
+		return "to_account_id"
+	default:
+		return ""
+	}
 }
 
 func (h *TransactionHandler) GetMyTransactions(c *gin.Context) {
 	userID, err := getAuthUserID(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondError(c, http.StatusUnauthorized, err.Error())
 		return
 	}
 
-	var filters model.UserTransactionFilters
-	if typeParam := c.Query("type"); typeParam != "" {
-		filters.Type = &typeParam
+	filters, ok := parseUserTransactionFilters(c)
+	if !ok {
+		return
 	}
-	if categoryParam := c.Query("category"); categoryParam != "" {
-		filters.Category = &categoryParam
+
+	if limitParam := c.Query("limit"); limitParam != "" {
+		limit, err := strconv.Atoi(limitParam)
+		if err != nil || limit < 1 {
+			respondError(c, http.StatusBadRequest, "Invalid limit, must be a positive integer")
+			return
+		}
+		filters.Limit = min(limit, maxTransactionPageSize)
 	}
-	if dateParam := c.Query("date"); dateParam != "" {
-		parsedDate, err := time.Parse("2006-01-02", dateParam)
+	if cursorParam := c.Query("cursor"); cursorParam != "" {
+		cursor, err := model.ParseTransactionCursor(cursorParam)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format for 'date', use YYYY-MM-DD"})
+			respondError(c, http.StatusBadRequest, "Invalid cursor")
 			return
 		}
-		// Set StartDate to the beginning of the parsed day and EndDate to the end of the parsed day
-		loc := time.Local // Or UTC, depending on desired behavior
-		startOfDay := time.Date(parsedDate.Year(), parsedDate.Month(), parsedDate.Day(), 0, 0, 0, 0, loc)
-		endOfDay := time.Date(parsedDate.Year(), parsedDate.Month(), parsedDate.Day(), 23, 59, 59, 999999999, loc)
-		filters.StartDate = &startOfDay
-		filters.EndDate = &endOfDay
+		filters.Cursor = cursor
+		if filters.Limit == 0 {
+			filters.Limit = defaultTransactionPageSize
+		}
+	}
+	if summaryParam := c.Query("summary"); summaryParam != "" {
+		withSummary, err := strconv.ParseBool(summaryParam)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "Invalid summary, must be true or false")
+			return
+		}
+		filters.WithSummary = withSummary
 	}
+	filters.SortBy, filters.SortOrder = strings.ToLower(c.Query("sort")), strings.ToLower(c.Query("order"))
 
-	transactions, err := h.service.GetUserTransactions(c.Request.Context(), userID, filters)
+	page, err := h.service.GetUserTransactions(c.Request.Context(), userID, filters)
 	if err != nil {
-		log.Printf("Error getting user transactions: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve transactions"})
+		middleware.Logger(c).Error("Error getting user transactions", "error", err, "user_id", userID)
+		respondError(c, http.StatusInternalServerError, "Failed to retrieve transactions")
 		return
 	}
-	c.JSON(http.StatusOK, transactions)
+	respondJSON(c, http.StatusOK, page)
 }
 
 func (h *TransactionHandler) GetTransactionByID(c *gin.Context) {
 	userID, err := getAuthUserID(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondError(c, http.StatusUnauthorized, err.Error())
 		return
 	}
 	userRole, err := getAuthUserRole(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User role not found"})
+		respondError(c, http.StatusUnauthorized, "User role not found")
 		return
 	}
 
 	transactionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transaction ID"})
+		respondError(c, http.StatusBadRequest, "Invalid transaction ID")
 		return
 	}
 
 	transaction, err := h.service.GetTransactionByID(c.Request.Context(), transactionID, userID, userRole)
 	if err != nil {
 		if errors.Is(err, service.ErrTransactionNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			respondServiceError(c, http.StatusNotFound, err)
 		} else if errors.Is(err, service.ErrForbidden) {
-			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			respondServiceError(c, http.StatusForbidden, err)
 		} else {
-			log.Printf("Error getting transaction by ID: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve transaction"})
+			middleware.Logger(c).Error("Error getting transaction by ID", "error", err, "transaction_id", transactionID)
+			respondError(c, http.StatusInternalServerError, "Failed to retrieve transaction")
 		}
 		return
 	}
-	c.JSON(http.StatusOK, transaction)
-}
 
-func (h *TransactionHandler) UpdateTransaction(c *gin.Context) {
-	userID, err := getAuthUserID(c)
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	// Clients may keep the transaction but must revalidate it; a match costs no body
+	etag := transactionETag(transaction)
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "private, no-cache")
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
 		return
 	}
+	respondJSON(c, http.StatusOK, transaction)
+}
 
-	transactionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transaction ID"})
-		return
-	}
+// transactionETag identifies a version of a transaction. updated_at moves on every change,
+// receipt uploads included, and version on every edit. It is weak because the bytes sent
+// differ with compression and the response envelope while the content is the same.
+func transactionETag(t *model.Transaction) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d:%d:%d", t.ID, t.Version, t.UpdatedAt.UnixNano())))
+	return `W/"` + hex.EncodeToString(sum[:16]) + `"`
+}
 
-	var req model.UpdateTransactionRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
-		return
+// etagMatches applies If-None-Match to etag with the weak comparison GET requests use
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
 	}
-
-	transaction, err := h.service.UpdateTransaction(c.Request.Context(), transactionID, userID, req)
-	if err != nil {
-		if errors.Is(err, service.ErrTransactionNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-		} else if errors.Is(err, service.ErrForbidden) {
-			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
-		} else {
-			log.Printf("Error updating transaction: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update transaction"})
+	if strings.TrimSpace(ifNoneMatch) == "*" {
+		return true
+	}
+	opaque := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == opaque {
+			return true
 		}
-		return
 	}
-	c.JSON(http.StatusOK, transaction)
+	return false
 }
 
-func (h *TransactionHandler) DeleteTransaction(c *gin.Context) {
+// GetTransactionHistory lists the earlier versions of a transaction, oldest first
+func (h *TransactionHandler) GetTransactionHistory(c *gin.Context) {
 	userID, err := getAuthUserID(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondError(c, http.StatusUnauthorized, err.Error())
 		return
 	}
 	userRole, err := getAuthUserRole(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User role not found"})
+		respondError(c, http.StatusUnauthorized, "User role not found")
 		return
 	}
 
 	transactionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transaction ID"})
+		respondError(c, http.StatusBadRequest, "Invalid transaction ID")
 		return
 	}
 
-	err = h.service.DeleteTransaction(c.Request.Context(), transactionID, userID, userRole)
+	history, err := h.service.GetTransactionHistory(c.Request.Context(), transactionID, userID, userRole)
 	if err != nil {
 		if errors.Is(err, service.ErrTransactionNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			respondServiceError(c, http.StatusNotFound, err)
 		} else if errors.Is(err, service.ErrForbidden) {
-			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			respondServiceError(c, http.StatusForbidden, err)
 		} else {
-			log.Printf("Error deleting transaction: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete transaction"})
+			middleware.Logger(c).Error("Error getting transaction history", "error", err, "transaction_id", transactionID)
+			respondError(c, http.StatusInternalServerError, "Failed to retrieve transaction history")
 		}
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "Transaction deleted successfully"})
+	respondJSON(c, http.StatusOK, history)
 }
 
-// --- Receipt Handling ---
-
-func (h *TransactionHandler) UploadReceipt(c *gin.Context) {
+func (h *TransactionHandler) UpdateTransaction(c *gin.Context) {
 	userID, err := getAuthUserID(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required: " + err.Error()})
+		respondError(c, http.StatusUnauthorized, err.Error())
 		return
 	}
 
 	transactionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transaction ID"})
+		respondError(c, http.StatusBadRequest, "Invalid transaction ID")
 		return
 	}
 
-	file, err := c.FormFile("receipt")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Receipt file is required: " + err.Error()})
+	var req model.UpdateTransactionRequest
+	if !bindJSON(c, &req) {
 		return
 	}
 
-	updatedTransaction, err := h.service.UploadReceipt(c.Request.Context(), transactionID, userID, file, h.uploadsDir)
+	transaction, err := h.service.UpdateTransaction(c.Request.Context(), transactionID, userID, req)
 	if err != nil {
 		if errors.Is(err, service.ErrTransactionNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			respondServiceError(c, http.StatusNotFound, err)
 		} else if errors.Is(err, service.ErrForbidden) {
-			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
-		} else if errors.Is(err, service.ErrInvalidFileFormat) || errors.Is(err, service.ErrFileSizeExceeded) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			respondServiceError(c, http.StatusForbidden, err)
+		} else if errors.Is(err, service.ErrVersionConflict) {
+			respondServiceError(c, http.StatusConflict, err)
+		} else if errors.Is(err, service.ErrAmountBelowMinimum) || errors.Is(err, service.ErrInvalidAmount) ||
+			errors.Is(err, service.ErrUnknownCategory) ||
+			errors.Is(err, service.ErrCategoryTypeMismatch) || errors.Is(err, service.ErrTransferNotEditable) ||
+			errors.Is(err, service.ErrUnsupportedCurrency) || errors.Is(err, service.ErrFutureTransactionDate) ||
+			errors.Is(err, service.ErrDescriptionTooLong) || errors.Is(err, service.ErrInvalidTag) {
+			respondServiceError(c, http.StatusBadRequest, err)
 		} else {
-			log.Printf("Error uploading receipt: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload receipt"})
+			middleware.Logger(c).Error("Error updating transaction", "error", err, "transaction_id", transactionID)
+			respondError(c, http.StatusInternalServerError, "Failed to update transaction")
 		}
 		return
 	}
-	c.JSON(http.StatusOK, updatedTransaction)
+	respondJSON(c, http.StatusOK, transaction)
 }
 
-func (h *TransactionHandler) GetReceipt(c *gin.Context) {
+func (h *TransactionHandler) DeleteTransaction(c *gin.Context) {
 	userID, err := getAuthUserID(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required: " + err.Error()})
+		respondError(c, http.StatusUnauthorized, err.Error())
 		return
 	}
 	userRole, err := getAuthUserRole(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User role not found"})
+		respondError(c, http.StatusUnauthorized, "User role not found")
 		return
 	}
 
 	transactionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transaction ID"})
+		respondError(c, http.StatusBadRequest, "Invalid transaction ID")
 		return
 	}
 
-	filePath, fileName, err := h.service.GetReceiptPath(c.Request.Context(), transactionID, userID, userRole)
+	err = h.service.DeleteTransaction(c.Request.Context(), transactionID, userID, userRole)
 	if err != nil {
-		if errors.Is(err, service.ErrTransactionNotFound) || strings.Contains(err.Error(), "receipt not found") {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		if errors.Is(err, service.ErrTransactionNotFound) {
+			respondServiceError(c, http.StatusNotFound, err)
 		} else if errors.Is(err, service.ErrForbidden) {
-			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			respondServiceError(c, http.StatusForbidden, err)
 		} else {
-			log.Printf("Error getting receipt path: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get receipt path"})
+			middleware.Logger(c).Error("Error deleting transaction", "error", err, "transaction_id", transactionID)
+			respondError(c, http.StatusInternalServerError, "Failed to delete transaction")
 		}
 		return
 	}
-
-	// Check if file exists before attempting to serve
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Receipt file not found on server"})
-		return
-	}
-
-	c.FileAttachment(filePath, fileName)
+	respondJSON(c, http.StatusOK, gin.H{"message": "Transaction deleted successfully"})
 }
 
-// --- Admin Routes ---
-
-func (h *TransactionHandler) GetAllTransactionsAdmin(c *gin.Context) {
-	var filters model.AdminTransactionFilters
-	if userIDStr := c.Query("user_id"); userIDStr != "" {
-		uid, err := strconv.Atoi(userIDStr)
-		if err == nil {
-			filters.UserID = &uid
-		} else {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user_id format"})
-			return
-		}
-	}
-	if typeParam := c.Query("type"); typeParam != "" {
-		filters.Type = &typeParam
+// DeleteTransactions deletes several transactions at once. Ids the caller may not delete are
+// skipped, so "deleted" can be lower than "requested".
+func (h *TransactionHandler) DeleteTransactions(c *gin.Context) {
+	userID, err := getAuthUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, err.Error())
+		return
 	}
-	if categoryParam := c.Query("category"); categoryParam != "" {
-		filters.Category = &categoryParam
+	userRole, err := getAuthUserRole(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, "User role not found")
+		return
 	}
-	if startDateParam := c.Query("start_date"); startDateParam != "" {
-		parsedDate, err := time.Parse("2006-01-02", startDateParam)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format for 'start_date', use YYYY-MM-DD"})
-			return
-		}
-		filters.StartDate = &parsedDate
+
+	var req model.BatchDeleteTransactionsRequest
+	if !bindJSON(c, &req) {
+		return
 	}
-	if endDateParam := c.Query("end_date"); endDateParam != "" {
-		parsedDate, err := time.Parse("2006-01-02", endDateParam)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format for 'end_date', use YYYY-MM-DD"})
-			return
-		}
-		// Adjust end date to include the whole day
-		endOfDay := time.Date(parsedDate.Year(), parsedDate.Month(), parsedDate.Day(), 23, 59, 59, 999999999, parsedDate.Location())
-		filters.EndDate = &endOfDay
+	if len(req.IDs) > model.MaxBatchDeleteSize {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("At most %d ids can be deleted at once", model.MaxBatchDeleteSize))
+		return
 	}
+	ids := uniqueIDs(req.IDs)
 
-	transactions, err := h.service.GetAllTransactionsAdmin(c.Request.Context(), filters)
+	deleted, err := h.service.DeleteTransactions(c.Request.Context(), ids, userID, userRole)
 	if err != nil {
-		log.Printf("Error getting all transactions for admin: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve transactions"})
+		middleware.Logger(c).Error("Error deleting transactions", "error", err, "count", len(ids))
+		respondError(c, http.StatusInternalServerError, "Failed to delete transactions")
 		return
 	}
-	c.JSON(http.StatusOK, transactions)
+	respondJSON(c, http.StatusOK, gin.H{"deleted": deleted, "requested": len(ids)})
 }
 
-func (h *TransactionHandler) GetStatisticsAdmin(c *gin.Context) {
-	var filters model.AdminTransactionFilters // Same filters as GetAllTransactionsAdmin
-	if userIDStr := c.Query("user_id"); userIDStr != "" {
-		uid, err := strconv.Atoi(userIDStr)
-		if err == nil {
-			filters.UserID = &uid
-		} else {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user_id format"})
-			return
+// uniqueIDs drops repeated ids, keeping the first occurrence of each
+func uniqueIDs(ids []int64) []int64 {
+	seen := make(map[int64]bool, len(ids))
+	unique := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
 		}
 	}
-	if typeParam := c.Query("type"); typeParam != "" {
-		filters.Type = &typeParam
+	return unique
+}
+
+func (h *TransactionHandler) GetCategoryRanking(c *gin.Context) {
+	userID, err := getAuthUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var filters model.UserTransactionFilters
+	var ok bool
+	if filters.StartDate, filters.EndDate, ok = parseDateRangeQuery(c); !ok {
+		return
 	}
-	if categoryParam := c.Query("category"); categoryParam != "" {
-		filters.Category = &categoryParam
+	if filters.Currency, ok = parseCurrencyQuery(c); !ok {
+		return
 	}
-	if startDateParam := c.Query("start_date"); startDateParam != "" {
-		parsedDate, err := time.Parse("2006-01-02", startDateParam)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format for 'start_date', use YYYY-MM-DD"})
+
+	limit := defaultCategoryRankingLimit
+	if limitParam := c.Query("limit"); limitParam != "" {
+		limit, err = strconv.Atoi(limitParam)
+		if err != nil || limit < 1 {
+			respondError(c, http.StatusBadRequest, "Invalid limit, must be a positive integer")
 			return
 		}
-		filters.StartDate = &parsedDate
-	}
-	if endDateParam := c.Query("end_date"); endDateParam != "" {
-		parsedDate, err := time.Parse("2006-01-02", endDateParam)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format for 'end_date', use YYYY-MM-DD"})
-			return
+		if limit > maxCategoryRankingLimit {
+			limit = maxCategoryRankingLimit
 		}
-		endOfDay := time.Date(parsedDate.Year(), parsedDate.Month(), parsedDate.Day(), 23, 59, 59, 999999999, parsedDate.Location())
-		filters.EndDate = &endOfDay
 	}
 
-	stats, err := h.service.GetStatisticsAdmin(c.Request.Context(), filters)
+	ranking, err := h.service.GetCategoryRanking(c.Request.Context(), userID, filters, limit)
 	if err != nil {
-		log.Printf("Error getting statistics for admin: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve statistics"})
+		middleware.Logger(c).Error("Error getting category ranking", "error", err, "user_id", userID)
+		respondError(c, http.StatusInternalServerError, "Failed to retrieve category ranking")
 		return
 	}
-	c.JSON(http.StatusOK, stats)
+	respondJSON(c, http.StatusOK, ranking)
 }
 
-func (h *TransactionHandler) ExportTransactionsCSVAdmin(c *gin.Context) {
-	var filters model.AdminTransactionFilters // Same filters as GetAllTransactionsAdmin
-	if userIDStr := c.Query("user_id"); userIDStr != "" {
-		uid, err := strconv.Atoi(userIDStr)
-		if err == nil {
-			filters.UserID = &uid
+func (h *TransactionHandler) GetTopMerchants(c *gin.Context) {
+	userID, err := getAuthUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var filters model.UserTransactionFilters
+	var ok bool
+	if filters.StartDate, filters.EndDate, ok = parseDateRangeQuery(c); !ok {
+		return
+	}
+	if filters.Currency, ok = parseCurrencyQuery(c); !ok {
+		return
+	}
+
+	limit := defaultTopMerchantsLimit
+	if limitParam := c.Query("limit"); limitParam != "" {
+		limit, err = strconv.Atoi(limitParam)
+		if err != nil || limit < 1 {
+			respondError(c, http.StatusBadRequest, "Invalid limit, must be a positive integer")
+			return
+		}
+		if limit > maxTopMerchantsLimit {
+			limit = maxTopMerchantsLimit
+		}
+	}
+
+	merchants, err := h.service.GetTopMerchants(c.Request.Context(), userID, filters, limit)
+	if err != nil {
+		middleware.Logger(c).Error("Error getting top merchants", "error", err, "user_id", userID)
+		respondError(c, http.StatusInternalServerError, "Failed to retrieve top merchants")
+		return
+	}
+	respondJSON(c, http.StatusOK, merchants)
+}
+
+func (h *TransactionHandler) GetTimeSeries(c *gin.Context) {
+	userID, err := getAuthUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	startParam, endParam := c.Query("start"), c.Query("end")
+	if startParam == "" || endParam == "" {
+		respondError(c, http.StatusBadRequest, "start and end are required")
+		return
+	}
+	start, err := parseTimeParam(startParam)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid date format for 'start', use YYYY-MM-DD or RFC3339")
+		return
+	}
+	end, err := parseTimeParam(endParam)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid date format for 'end', use YYYY-MM-DD or RFC3339")
+		return
+	}
+	granularity := strings.ToLower(c.DefaultQuery("granularity", model.GranularityMonth))
+	currency, ok := parseCurrencyQuery(c)
+	if !ok {
+		return
+	}
+
+	series, err := h.service.GetUserTimeSeries(c.Request.Context(), userID, currency, granularity, start, end)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidGranularity) || errors.Is(err, service.ErrInvalidDateRange) ||
+			errors.Is(err, service.ErrTimeSeriesRangeTooLarge) {
+			respondServiceError(c, http.StatusBadRequest, err)
+			return
+		}
+		middleware.Logger(c).Error("Error getting time series", "error", err, "user_id", userID)
+		respondError(c, http.StatusInternalServerError, "Failed to retrieve time series")
+		return
+	}
+	respondJSON(c, http.StatusOK, series)
+}
+
+// GetCategoryBreakdown returns each category's share of the user's income or expense, for pie
+// charts. The range defaults to the current UTC month, the type to expense and the currency to
+// the user's own.
+func (h *TransactionHandler) GetCategoryBreakdown(c *gin.Context) {
+	userID, err := getAuthUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	now := time.Now().UTC()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	end := now
+	if startParam := c.Query("start"); startParam != "" {
+		if start, err = parseTimeParam(startParam); err != nil {
+			respondError(c, http.StatusBadRequest, "Invalid date format for 'start', use YYYY-MM-DD or RFC3339")
+			return
+		}
+	}
+	if endParam := c.Query("end"); endParam != "" {
+		if end, err = parseTimeParam(endParam); err != nil {
+			respondError(c, http.StatusBadRequest, "Invalid date format for 'end', use YYYY-MM-DD or RFC3339")
+			return
+		}
+	}
+	txType := strings.ToLower(c.DefaultQuery("type", model.TransactionTypeExpense))
+	currency, ok := parseCurrencyQuery(c)
+	if !ok {
+		return
+	}
+
+	breakdown, err := h.service.GetUserCategoryBreakdown(c.Request.Context(), userID, currency, start, end, txType)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidBreakdownType) || errors.Is(err, service.ErrInvalidDateRange) {
+			respondServiceError(c, http.StatusBadRequest, err)
+			return
+		}
+		middleware.Logger(c).Error("Error getting category breakdown", "error", err, "user_id", userID)
+		respondError(c, http.StatusInternalServerError, "Failed to retrieve category breakdown")
+		return
+	}
+	respondJSON(c, http.StatusOK, breakdown)
+}
+
+func (h *TransactionHandler) ExportTransactionsPDF(c *gin.Context) {
+	userID, err := getAuthUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	filters, ok := parseUserTransactionFilters(c)
+	if !ok {
+		return
+	}
+
+	pdfBuffer, err := h.service.ExportUserTransactionsPDF(c.Request.Context(), userID, filters)
+	if err != nil {
+		middleware.Logger(c).Error("Error exporting transactions to PDF", "error", err, "user_id", userID)
+		respondError(c, http.StatusInternalServerError, "Failed to export transactions")
+		return
+	}
+
+	fileName := fmt.Sprintf("statement_%s.pdf", time.Now().UTC().Format("20060102_150405"))
+	c.Header("Content-Disposition", "attachment; filename="+fileName)
+	c.Data(http.StatusOK, "application/pdf", pdfBuffer.Bytes())
+}
+
+func (h *TransactionHandler) ImportTransactions(c *gin.Context) {
+	userID, err := getAuthUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if !limitUploadBody(c, maxImportFileSize) {
+		return
+	}
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		if isBodyTooLarge(err) {
+			respondError(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("CSV file exceeds %d MB", maxImportFileSize/(1024*1024)))
+			return
+		}
+		respondError(c, http.StatusBadRequest, "CSV file is required: "+err.Error())
+		return
+	}
+	if fileHeader.Size > maxImportFileSize {
+		respondError(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("CSV file exceeds %d MB", maxImportFileSize/(1024*1024)))
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		middleware.Logger(c).Error("Error opening import file", "error", err, "user_id", userID)
+		respondError(c, http.StatusInternalServerError, "Failed to read CSV file")
+		return
+	}
+	defer file.Close()
+
+	result, err := h.service.ImportTransactionsCSV(c.Request.Context(), userID, file)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCSV) || errors.Is(err, service.ErrTooManyImportRows) {
+			respondServiceError(c, http.StatusBadRequest, err)
+			return
+		}
+		middleware.Logger(c).Error("Error importing transactions", "error", err, "user_id", userID)
+		respondError(c, http.StatusInternalServerError, "Failed to import transactions")
+		return
+	}
+	if result.Failed > 0 {
+		respondJSON(c, http.StatusUnprocessableEntity, result)
+		return
+	}
+	respondJSON(c, http.StatusCreated, result)
+}
+
+func (h *TransactionHandler) GetMyStatistics(c *gin.Context) {
+	userID, err := getAuthUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	filters, ok := parseUserTransactionFilters(c)
+	if !ok {
+		return
+	}
+
+	stats, err := h.service.GetUserStatistics(c.Request.Context(), userID, filters)
+	if err != nil {
+		middleware.Logger(c).Error("Error getting user statistics", "error", err, "user_id", userID)
+		respondError(c, http.StatusInternalServerError, "Failed to retrieve statistics")
+		return
+	}
+	respondJSON(c, http.StatusOK, stats)
+}
+
+func (h *TransactionHandler) GetOverview(c *gin.Context) {
+	userID, err := getAuthUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	filters, ok := parseUserTransactionFilters(c)
+	if !ok {
+		return
+	}
+
+	overview, err := h.service.GetUserOverview(c.Request.Context(), userID, filters)
+	if err != nil {
+		middleware.Logger(c).Error("Error getting transaction overview", "error", err, "user_id", userID)
+		respondError(c, http.StatusInternalServerError, "Failed to retrieve overview")
+		return
+	}
+	respondJSON(c, http.StatusOK, overview)
+}
+
+func (h *TransactionHandler) SuggestCategory(c *gin.Context) {
+	userID, err := getAuthUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	description := c.Query("description")
+	if strings.TrimSpace(description) == "" {
+		respondError(c, http.StatusBadRequest, "Query parameter 'description' is required")
+		return
+	}
+
+	limit := defaultSuggestionsLimit
+	if limitParam := c.Query("limit"); limitParam != "" {
+		limit, err = strconv.Atoi(limitParam)
+		if err != nil || limit < 1 {
+			respondError(c, http.StatusBadRequest, "Invalid limit, must be a positive integer")
+			return
+		}
+		if limit > maxSuggestionsLimit {
+			limit = maxSuggestionsLimit
+		}
+	}
+
+	suggestions, err := h.service.SuggestCategories(c.Request.Context(), userID, description, limit)
+	if err != nil {
+		middleware.Logger(c).Error("Error suggesting categories", "error", err, "user_id", userID)
+		respondError(c, http.StatusInternalServerError, "Failed to suggest categories")
+		return
+	}
+	respondJSON(c, http.StatusOK, suggestions)
+}
+
+// GetRecentCategories lists the categories the user has used most, for quick picks when entering a transaction
+func (h *TransactionHandler) GetRecentCategories(c *gin.Context) {
+	userID, err := getAuthUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	limit := defaultRecentCategoriesLimit
+	if limitParam := c.Query("limit"); limitParam != "" {
+		limit, err = strconv.Atoi(limitParam)
+		if err != nil || limit < 1 {
+			respondError(c, http.StatusBadRequest, "Invalid limit, must be a positive integer")
+			return
+		}
+		if limit > service.MaxRecentCategories {
+			limit = service.MaxRecentCategories
+		}
+	}
+
+	categories, err := h.service.GetRecentCategories(c.Request.Context(), userID, limit)
+	if err != nil {
+		middleware.Logger(c).Error("Error getting recent categories", "error", err, "user_id", userID)
+		respondError(c, http.StatusInternalServerError, "Failed to retrieve category suggestions")
+		return
+	}
+	respondJSON(c, http.StatusOK, categories)
+}
+
+// --- Receipt Handling ---
+
+func (h *TransactionHandler) UploadReceipt(c *gin.Context) {
+	userID, err := getAuthUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, "Authentication required: "+err.Error())
+		return
+	}
+
+	transactionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid transaction ID")
+		return
+	}
+
+	if !limitUploadBody(c, h.maxReceiptSize) {
+		return
+	}
+	file, err := c.FormFile("receipt")
+	if err != nil {
+		if isBodyTooLarge(err) {
+			respondError(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("Receipt exceeds %d bytes", h.maxReceiptSize))
+			return
+		}
+		respondError(c, http.StatusBadRequest, "Receipt file is required: "+err.Error())
+		return
+	}
+
+	updatedTransaction, err := h.service.UploadReceipt(c.Request.Context(), transactionID, userID, file)
+	if err != nil {
+		if errors.Is(err, service.ErrTransactionNotFound) {
+			respondServiceError(c, http.StatusNotFound, err)
+		} else if errors.Is(err, service.ErrForbidden) {
+			respondServiceError(c, http.StatusForbidden, err)
+		} else if errors.Is(err, service.ErrFileSizeExceeded) {
+			respondServiceError(c, http.StatusRequestEntityTooLarge, err)
+		} else if errors.Is(err, service.ErrInvalidFileFormat) || errors.Is(err, storage.ErrInvalidKey) {
+			respondServiceError(c, http.StatusBadRequest, err)
 		} else {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user_id format"})
+			middleware.Logger(c).Error("Error uploading receipt", "error", err, "transaction_id", transactionID)
+			respondError(c, http.StatusInternalServerError, "Failed to upload receipt")
+		}
+		return
+	}
+	respondJSON(c, http.StatusOK, updatedTransaction)
+}
+
+// DeleteReceipt detaches the receipt from a transaction and removes the file
+func (h *TransactionHandler) DeleteReceipt(c *gin.Context) {
+	userID, err := getAuthUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, "Authentication required: "+err.Error())
+		return
+	}
+
+	transactionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid transaction ID")
+		return
+	}
+
+	updatedTransaction, err := h.service.DeleteReceipt(c.Request.Context(), transactionID, userID)
+	if err != nil {
+		if errors.Is(err, service.ErrTransactionNotFound) || errors.Is(err, service.ErrReceiptNotFound) {
+			respondServiceError(c, http.StatusNotFound, err)
+		} else if errors.Is(err, service.ErrForbidden) {
+			respondServiceError(c, http.StatusForbidden, err)
+		} else {
+			middleware.Logger(c).Error("Error deleting receipt", "error", err, "transaction_id", transactionID)
+			respondError(c, http.StatusInternalServerError, "Failed to delete receipt")
+		}
+		return
+	}
+	respondJSON(c, http.StatusOK, updatedTransaction)
+}
+
+// GetReceipt serves a transaction's receipt as a download, or with ?disposition=inline for the
+// browser to display
+func (h *TransactionHandler) GetReceipt(c *gin.Context) {
+	disposition := c.DefaultQuery("disposition", "attachment")
+	if disposition != "attachment" && disposition != "inline" {
+		respondError(c, http.StatusBadRequest, "Invalid disposition, must be attachment or inline")
+		return
+	}
+	h.serveReceipt(c, false, disposition)
+}
+
+// GetReceiptThumbnail serves the small JPEG preview of an image receipt
+func (h *TransactionHandler) GetReceiptThumbnail(c *gin.Context) {
+	h.serveReceipt(c, true, "inline")
+}
+
+// inlineReceiptType reports whether a receipt of contentType is safe to render in the browser.
+// Anything else, such as HTML allowed through ALLOWED_RECEIPT_EXTENSIONS, is always downloaded.
+func inlineReceiptType(contentType string) bool {
+	if contentType == "application/pdf" {
+		return true
+	}
+	return strings.HasPrefix(contentType, "image/") && contentType != "image/svg+xml" // SVG can carry scripts
+}
+
+// serveReceipt streams a transaction's receipt, or its thumbnail, from storage
+func (h *TransactionHandler) serveReceipt(c *gin.Context, thumbnail bool, disposition string) {
+	userID, err := getAuthUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, "Authentication required: "+err.Error())
+		return
+	}
+	userRole, err := getAuthUserRole(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, "User role not found")
+		return
+	}
+
+	transactionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid transaction ID")
+		return
+	}
+
+	getReceipt := h.service.GetReceipt
+	if thumbnail {
+		getReceipt = h.service.GetReceiptThumbnail
+	}
+	contents, fileName, err := getReceipt(c.Request.Context(), transactionID, userID, userRole)
+	if err != nil {
+		if errors.Is(err, service.ErrTransactionNotFound) || errors.Is(err, service.ErrReceiptNotFound) || errors.Is(err, service.ErrNoThumbnail) {
+			respondServiceError(c, http.StatusNotFound, err)
+		} else if errors.Is(err, storage.ErrNotFound) {
+			respondError(c, http.StatusNotFound, "Receipt file not found on server")
+		} else if errors.Is(err, storage.ErrInvalidKey) {
+			respondError(c, http.StatusBadRequest, "Invalid receipt path")
+		} else if errors.Is(err, service.ErrForbidden) {
+			respondServiceError(c, http.StatusForbidden, err)
+		} else {
+			middleware.Logger(c).Error("Error getting receipt", "error", err, "transaction_id", transactionID)
+			respondError(c, http.StatusInternalServerError, "Failed to get receipt")
+		}
+		return
+	}
+	defer contents.Close()
+
+	// The stored key keeps the uploaded name's extension, lower-cased
+	contentType := mime.TypeByExtension(strings.ToLower(filepath.Ext(fileName)))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	if disposition == "inline" && !inlineReceiptType(contentType) {
+		disposition = "attachment"
+	}
+	c.DataFromReader(http.StatusOK, -1, contentType, contents, map[string]string{
+		"Content-Disposition":    mime.FormatMediaType(disposition, map[string]string{"filename": fileName}),
+		"X-Content-Type-Options": "nosniff", // Browsers must not reinterpret the file as something more dangerous
+	})
+}
+
+// --- Admin Routes ---
+
+func (h *TransactionHandler) GetAllTransactionsAdmin(c *gin.Context) {
+	filters, ok := parseAdminTransactionFilters(c)
+	if !ok {
+		return
+	}
+
+	filters.SortBy, filters.SortOrder = strings.ToLower(c.Query("sort")), strings.ToLower(c.Query("order"))
+	if filters.Page, filters.PageSize, ok = parseAdminPageQuery(c); !ok {
+		return
+	}
+
+	page, err := h.service.GetAllTransactionsAdmin(c.Request.Context(), filters)
+	if err != nil {
+		middleware.Logger(c).Error("Error getting all transactions for admin", "error", err)
+		respondError(c, http.StatusInternalServerError, "Failed to retrieve transactions")
+		return
+	}
+	setPaginationHeaders(c, page.Page, page.PageSize, page.Total)
+	respondJSON(c, http.StatusOK, page)
+}
+
+// parseAdminPageQuery reads page (default 1) and page_size (default defaultAdminPageSize, at most
+// maxAdminPageSize) for the admin listings. On invalid input it writes a 400 response and returns false.
+func parseAdminPageQuery(c *gin.Context) (page, pageSize int, ok bool) {
+	page = 1
+	if pageParam := c.Query("page"); pageParam != "" {
+		var err error
+		page, err = strconv.Atoi(pageParam)
+		if err != nil || page < 1 {
+			respondError(c, http.StatusBadRequest, "Invalid page, must be an integer >= 1")
+			return 0, 0, false
+		}
+	}
+	pageSize = defaultAdminPageSize
+	if pageSizeParam := c.Query("page_size"); pageSizeParam != "" {
+		var err error
+		pageSize, err = strconv.Atoi(pageSizeParam)
+		if err != nil || pageSize < 1 || pageSize > maxAdminPageSize {
+			respondError(c, http.StatusBadRequest, fmt.Sprintf("Invalid page_size, must be between 1 and %d", maxAdminPageSize))
+			return 0, 0, false
+		}
+	}
+	return page, pageSize, true
+}
+
+// setPaginationHeaders mirrors the page envelope in X-Total-Count and a GitHub-style Link
+// header, for clients that paginate without parsing the body. Links repeat the request's
+// own query with only page changed.
+func setPaginationHeaders(c *gin.Context, page, pageSize int, total int64) {
+	c.Header("X-Total-Count", strconv.FormatInt(total, 10))
+
+	lastPage := int((total + int64(pageSize) - 1) / int64(pageSize))
+	if lastPage < 1 {
+		lastPage = 1
+	}
+	pageURL := func(n int) string {
+		query := c.Request.URL.Query()
+		query.Set("page", strconv.Itoa(n))
+		return (&url.URL{Path: c.Request.URL.Path, RawQuery: query.Encode()}).String()
+	}
+
+	var links []string
+	if page < lastPage {
+		links = append(links, fmt.Sprintf(`<%s>; rel="next"`, pageURL(page+1)))
+	}
+	if page > 1 {
+		links = append(links, fmt.Sprintf(`<%s>; rel="prev"`, pageURL(min(page-1, lastPage))))
+	}
+	links = append(links, fmt.Sprintf(`<%s>; rel="first"`, pageURL(1)), fmt.Sprintf(`<%s>; rel="last"`, pageURL(lastPage)))
+	c.Header("Link", strings.Join(links, ", "))
+}
+
+func (h *TransactionHandler) GetStatisticsAdmin(c *gin.Context) {
+	filters, ok := parseAdminTransactionFilters(c)
+	if !ok {
+		return
+	}
+
+	stats, err := h.service.GetStatisticsAdmin(c.Request.Context(), filters)
+	if err != nil {
+		middleware.Logger(c).Error("Error getting statistics for admin", "error", err)
+		respondError(c, http.StatusInternalServerError, "Failed to retrieve statistics")
+		return
+	}
+	respondJSON(c, http.StatusOK, stats)
+}
+
+// userStatSorts are the orders accepted by GetUserStatsAdmin
+var userStatSorts = []string{model.UserStatSortTotalSpent, model.UserStatSortTotalIncome, model.UserStatSortTransactionCount}
+
+// GetUserStatsAdmin pages through per-user totals, ranked by sort (total_spent by default) in
+// one currency, highest first unless order=asc
+func (h *TransactionHandler) GetUserStatsAdmin(c *gin.Context) {
+	filters, ok := parseAdminTransactionFilters(c)
+	if !ok {
+		return
+	}
+
+	filters.SortBy, filters.SortOrder = model.UserStatSortTotalSpent, strings.ToLower(c.Query("order"))
+	if sortParam := strings.ToLower(c.Query("sort")); sortParam != "" {
+		if !slices.Contains(userStatSorts, sortParam) {
+			respondError(c, http.StatusBadRequest, fmt.Sprintf("Invalid sort %q, must be one of: %s", sortParam, strings.Join(userStatSorts, ", ")))
 			return
 		}
+		filters.SortBy = sortParam
 	}
-	if typeParam := c.Query("type"); typeParam != "" {
-		filters.Type = &typeParam
+
+	currency := model.DefaultCurrency
+	if currencyParam := c.Query("currency"); currencyParam != "" {
+		if currency, ok = model.NormalizeCurrency(currencyParam); !ok {
+			respondError(c, http.StatusBadRequest, "Unsupported currency code")
+			return
+		}
 	}
-	if categoryParam := c.Query("category"); categoryParam != "" {
-		filters.Category = &categoryParam
+
+	if filters.Page, filters.PageSize, ok = parseAdminPageQuery(c); !ok {
+		return
 	}
-	if startDateParam := c.Query("start_date"); startDateParam != "" {
-		parsedDate, err := time.Parse("2006-01-02", startDateParam)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format for 'start_date', use YYYY-MM-DD"})
+
+	page, err := h.service.GetUserStatsAdmin(c.Request.Context(), filters, currency)
+	if err != nil {
+		middleware.Logger(c).Error("Error getting user statistics for admin", "error", err)
+		respondError(c, http.StatusInternalServerError, "Failed to retrieve user statistics")
+		return
+	}
+	setPaginationHeaders(c, page.Page, page.PageSize, page.Total)
+	respondJSON(c, http.StatusOK, page)
+}
+
+// GetTopCategoriesAdmin ranks expense categories across users by total spend in one currency
+func (h *TransactionHandler) GetTopCategoriesAdmin(c *gin.Context) {
+	filters, ok := parseAdminTransactionFilters(c)
+	if !ok {
+		return
+	}
+
+	currency := model.DefaultCurrency
+	if currencyParam := c.Query("currency"); currencyParam != "" {
+		if currency, ok = model.NormalizeCurrency(currencyParam); !ok {
+			respondError(c, http.StatusBadRequest, "Unsupported currency code")
 			return
 		}
-		filters.StartDate = &parsedDate
 	}
-	if endDateParam := c.Query("end_date"); endDateParam != "" {
-		parsedDate, err := time.Parse("2006-01-02", endDateParam)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format for 'end_date', use YYYY-MM-DD"})
+
+	limit := defaultCategoryRankingLimit
+	if limitParam := c.Query("limit"); limitParam != "" {
+		var err error
+		limit, err = strconv.Atoi(limitParam)
+		if err != nil || limit < 1 {
+			respondError(c, http.StatusBadRequest, "Invalid limit, must be a positive integer")
 			return
 		}
-		endOfDay := time.Date(parsedDate.Year(), parsedDate.Month(), parsedDate.Day(), 23, 59, 59, 999999999, parsedDate.Location())
-		filters.EndDate = &endOfDay
+		if limit > maxCategoryRankingLimit {
+			limit = maxCategoryRankingLimit
+		}
+	}
+
+	ranking, err := h.service.GetTopCategoriesAdmin(c.Request.Context(), filters, currency, limit)
+	if err != nil {
+		middleware.Logger(c).Error("Error getting top categories for admin", "error", err)
+		respondError(c, http.StatusInternalServerError, "Failed to retrieve top categories")
+		return
+	}
+	respondJSON(c, http.StatusOK, ranking)
+}
+
+func (h *TransactionHandler) ExportTransactionsCSVAdmin(c *gin.Context) {
+	filters, ok := parseAdminTransactionFilters(c)
+	if !ok {
+		return
 	}
 
 	csvBuffer, err := h.service.ExportTransactionsCSVAdmin(c.Request.Context(), filters)
 	if err != nil {
-		log.Printf("Error exporting transactions to CSV for admin: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export transactions to CSV"})
+		middleware.Logger(c).Error("Error exporting transactions to CSV for admin", "error", err)
+		respondError(c, http.StatusInternalServerError, "Failed to export transactions to CSV")
 		return
 	}
 
@@ -431,6 +1458,63 @@ func (h *TransactionHandler) ExportTransactionsCSVAdmin(c *gin.Context) {
 	c.Data(http.StatusOK, "text/csv", csvBuffer.Bytes())
 }
 
+// ExportTransactionsJSONAdmin exports the matching transactions as a JSON array file
+func (h *TransactionHandler) ExportTransactionsJSONAdmin(c *gin.Context) {
+	filters, ok := parseAdminTransactionFilters(c)
+	if !ok {
+		return
+	}
+
+	jsonBuffer, err := h.service.ExportTransactionsJSONAdmin(c.Request.Context(), filters)
+	if err != nil {
+		middleware.Logger(c).Error("Error exporting transactions to JSON for admin", "error", err)
+		respondError(c, http.StatusInternalServerError, "Failed to export transactions to JSON")
+		return
+	}
+
+	fileName := fmt.Sprintf("transactions_export_%s.json", time.Now().Format("20060102_150405"))
+	c.Header("Content-Description", "File Transfer")
+	c.Header("Content-Disposition", "attachment; filename="+fileName)
+	c.Data(http.StatusOK, "application/json; charset=utf-8", jsonBuffer.Bytes())
+}
+
+// GetReimbursableReportAdmin totals the expenses tagged reimbursable per user and currency.
+// It takes the admin filters; type and tag are fixed by the report.
+func (h *TransactionHandler) GetReimbursableReportAdmin(c *gin.Context) {
+	filters, ok := parseAdminTransactionFilters(c)
+	if !ok {
+		return
+	}
+
+	report, err := h.service.GetReimbursableReportAdmin(c.Request.Context(), filters)
+	if err != nil {
+		middleware.Logger(c).Error("Error getting reimbursable report for admin", "error", err)
+		respondError(c, http.StatusInternalServerError, "Failed to retrieve reimbursable report")
+		return
+	}
+	respondJSON(c, http.StatusOK, report)
+}
+
+// ExportReimbursableReportCSVAdmin is GetReimbursableReportAdmin as a CSV file
+func (h *TransactionHandler) ExportReimbursableReportCSVAdmin(c *gin.Context) {
+	filters, ok := parseAdminTransactionFilters(c)
+	if !ok {
+		return
+	}
+
+	csvBuffer, err := h.service.ExportReimbursableReportCSVAdmin(c.Request.Context(), filters)
+	if err != nil {
+		middleware.Logger(c).Error("Error exporting reimbursable report to CSV for admin", "error", err)
+		respondError(c, http.StatusInternalServerError, "Failed to export reimbursable report")
+		return
+	}
+
+	fileName := fmt.Sprintf("reimbursable_report_%s.csv", time.Now().Format("20060102_150405"))
+	c.Header("Content-Description", "File Transfer")
+	c.Header("Content-Disposition", "attachment; filename="+fileName)
+	c.Data(http.StatusOK, "text/csv", csvBuffer.Bytes())
+}
+
 // RegisterTransactionRoutes registers transaction routes
 func (h *TransactionHandler) RegisterTransactionRoutes(rg *gin.RouterGroup, authMW gin.HandlerFunc, userMW gin.HandlerFunc, adminMW gin.HandlerFunc) {
 	// User-specific transaction routes (requires auth, any authenticated user)
@@ -439,12 +1523,27 @@ func (h *TransactionHandler) RegisterTransactionRoutes(rg *gin.RouterGroup, auth
 	// userTxRoutes.Use(userMW) // Further ensures it's a user or admin acting as user
 	{
 		userTxRoutes.POST("", h.CreateTransaction)
+		userTxRoutes.POST("/validate", h.ValidateTransaction) // Same checks as create, nothing saved
 		userTxRoutes.GET("", h.GetMyTransactions)
-		userTxRoutes.GET("/:id", h.GetTransactionByID)     // Service layer handles ownership for non-admins
-		userTxRoutes.PUT("/:id", h.UpdateTransaction)      // Service layer handles ownership
-		userTxRoutes.DELETE("/:id", h.DeleteTransaction)   // Service layer handles ownership for non-admins
-		userTxRoutes.POST("/:id/receipt", h.UploadReceipt) // Service layer handles ownership
-		userTxRoutes.GET("/:id/receipt", h.GetReceipt)     // Service layer handles ownership for non-admins
+		userTxRoutes.GET("/stats", h.GetMyStatistics)
+		userTxRoutes.GET("/overview", h.GetOverview)
+		userTxRoutes.GET("/category-ranking", h.GetCategoryRanking)
+		userTxRoutes.GET("/top-merchants", h.GetTopMerchants)
+		userTxRoutes.GET("/timeseries", h.GetTimeSeries)
+		userTxRoutes.GET("/breakdown", h.GetCategoryBreakdown)
+		userTxRoutes.GET("/export/pdf", h.ExportTransactionsPDF)
+		userTxRoutes.POST("/import", h.ImportTransactions)
+		userTxRoutes.POST("/batch-delete", h.DeleteTransactions) // Non-admins only delete their own
+		userTxRoutes.GET("/suggest-category", h.SuggestCategory)
+		userTxRoutes.GET("/categories/suggestions", h.GetRecentCategories)
+		userTxRoutes.GET("/:id", h.GetTransactionByID)            // Service layer handles ownership for non-admins
+		userTxRoutes.PUT("/:id", h.UpdateTransaction)             // Service layer handles ownership
+		userTxRoutes.DELETE("/:id", h.DeleteTransaction)          // Service layer handles ownership for non-admins
+		userTxRoutes.GET("/:id/history", h.GetTransactionHistory) // Service layer handles ownership for non-admins
+		userTxRoutes.POST("/:id/receipt", h.UploadReceipt)        // Service layer handles ownership
+		userTxRoutes.GET("/:id/receipt", h.GetReceipt)            // Service layer handles ownership for non-admins
+		userTxRoutes.DELETE("/:id/receipt", h.DeleteReceipt)      // Service layer handles ownership
+		userTxRoutes.GET("/:id/receipt/thumbnail", h.GetReceiptThumbnail)
 	}
 
 	// Admin-specific transaction routes
@@ -454,6 +1553,11 @@ func (h *TransactionHandler) RegisterTransactionRoutes(rg *gin.RouterGroup, auth
 	{
 		adminRoutes.GET("/transactions", h.GetAllTransactionsAdmin)
 		adminRoutes.GET("/stats", h.GetStatisticsAdmin)
+		adminRoutes.GET("/stats/top-categories", h.GetTopCategoriesAdmin)
+		adminRoutes.GET("/stats/by-user", h.GetUserStatsAdmin)
 		adminRoutes.GET("/transactions/export/csv", h.ExportTransactionsCSVAdmin)
+		adminRoutes.GET("/transactions/export/json", h.ExportTransactionsJSONAdmin)
+		adminRoutes.GET("/reports/reimbursable", h.GetReimbursableReportAdmin)
+		adminRoutes.GET("/reports/reimbursable/export/csv", h.ExportReimbursableReportCSVAdmin)
 	}
 }
@@ -0,0 +1,806 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"expense_tracker/internal/apierror"
+	"expense_tracker/internal/middleware"
+	"expense_tracker/internal/model"
+	"expense_tracker/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubTransactionService panics if the handler gets as far as the service
+type stubTransactionService struct {
+	service.TransactionService
+}
+
+func newReceiptRouter(maxReceiptSize int64) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	h := NewTransactionHandler(stubTransactionService{}, maxReceiptSize)
+	router := gin.New()
+	router.POST("/transactions/:id/receipt", func(c *gin.Context) {
+		c.Set(middleware.AuthUserKey, 1)
+		h.UploadReceipt(c)
+	})
+	return router
+}
+
+func oversizedReceiptBody(t *testing.T, size int) (*bytes.Buffer, string) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, err := w.CreateFormFile("receipt", "receipt.jpg")
+	assert.NoError(t, err)
+	_, err = part.Write(bytes.Repeat([]byte{0xFF}, size))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	return &body, w.FormDataContentType()
+}
+
+func TestUploadReceipt_OversizedBody(t *testing.T) {
+	const maxReceiptSize = 1024
+	body, contentType := oversizedReceiptBody(t, maxReceiptSize+2*multipartOverhead)
+
+	t.Run("declared length", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/transactions/1/receipt", bytes.NewReader(body.Bytes()))
+		req.Header.Set("Content-Type", contentType)
+		rec := httptest.NewRecorder()
+		newReceiptRouter(maxReceiptSize).ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	})
+
+	t.Run("chunked", func(t *testing.T) {
+		// Hiding the reader behind io.MultiReader leaves ContentLength unknown, so the cap applies while reading
+		req := httptest.NewRequest(http.MethodPost, "/transactions/1/receipt", io.MultiReader(bytes.NewReader(body.Bytes())))
+		req.ContentLength = -1
+		req.Header.Set("Content-Type", contentType)
+		rec := httptest.NewRecorder()
+		newReceiptRouter(maxReceiptSize).ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	})
+}
+
+func queryContext(query string) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	c.Request = httptest.NewRequest(http.MethodGet, "/admin/transactions?"+query, nil)
+	return c, rec
+}
+
+func TestParseAdminTransactionFilters(t *testing.T) {
+	c, rec := queryContext("user_id=7&type=expense,income&category=Food,+Transport,Food,&merchant=Cafe&q=+latte+" +
+		"&start_date=2024-03-01&end_date=2024-03-31&min_amount=100&max_amount=5000" +
+		"&created_after=2024-04-01&created_before=2024-04-02T12:00:00Z")
+	filters, ok := parseAdminTransactionFilters(c)
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusOK, rec.Code) // Nothing written
+	assert.Equal(t, 7, *filters.UserID)
+	assert.Equal(t, []string{"expense", "income"}, filters.Types)
+	assert.Equal(t, []string{"Food", "Transport"}, filters.Categories)
+	assert.Equal(t, "Cafe", *filters.Merchant)
+	assert.Equal(t, "latte", *filters.Search)
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), *filters.StartDate)
+	assert.Equal(t, time.Date(2024, 3, 31, 23, 59, 59, 999999999, time.UTC), *filters.EndDate)
+	assert.Equal(t, int64(100), *filters.MinAmount)
+	assert.Equal(t, int64(5000), *filters.MaxAmount)
+	assert.Equal(t, time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), *filters.CreatedAfter)
+	assert.Equal(t, time.Date(2024, 4, 2, 12, 0, 0, 0, time.UTC), *filters.CreatedBefore)
+
+	c, _ = queryContext("")
+	filters, ok = parseAdminTransactionFilters(c)
+	assert.True(t, ok)
+	assert.Equal(t, model.AdminTransactionFilters{}, filters)
+}
+
+func TestCreatedRangeFromQuery_PlainDateIncludesWholeDay(t *testing.T) {
+	c, _ := queryContext("created_after=2024-04-02&created_before=2024-04-02")
+	after, before, err := createdRangeFromQuery(c)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 4, 2, 0, 0, 0, 0, time.UTC), *after)
+	assert.Equal(t, time.Date(2024, 4, 3, 0, 0, 0, 0, time.UTC), *before, "created_at < the next midnight")
+}
+
+func TestParseAdminTransactionFilters_Invalid(t *testing.T) {
+	for _, tt := range []struct {
+		query   string
+		message string
+	}{
+		{"user_id=abc", "user_id"},
+		{"type=expense,gift", `Invalid type \"gift\"`},
+		{"start_date=2024-13-01", "start_date"},
+		{"start_date=01.03.2024", "start_date"},
+		{"end_date=2024-02-30", "end_date"},
+		{"end_date=yesterday", "end_date"},
+		{"min_amount=-5", "min_amount"},
+		{"min_amount=500&max_amount=100", "min_amount must not exceed max_amount"},
+		{"created_after=last-week", "created_after"},
+		{"created_after=2024-05-01&created_before=2024-04-01", "created_after must not be later than created_before"},
+	} {
+		t.Run(tt.query, func(t *testing.T) {
+			c, rec := queryContext(tt.query)
+			_, ok := parseAdminTransactionFilters(c)
+			assert.False(t, ok)
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.Contains(t, rec.Body.String(), tt.message)
+		})
+	}
+}
+
+func TestPeriodRange(t *testing.T) {
+	tashkent := time.FixedZone("UZT", 5*60*60)
+	now := time.Date(2024, 3, 13, 21, 30, 0, 0, time.UTC) // Thursday 02:30 on the 14th in Tashkent
+
+	tests := []struct {
+		period     string
+		start, end time.Time
+	}{
+		{"today", time.Date(2024, 3, 14, 0, 0, 0, 0, tashkent), time.Date(2024, 3, 15, 0, 0, 0, 0, tashkent)},
+		{"this_week", time.Date(2024, 3, 11, 0, 0, 0, 0, tashkent), time.Date(2024, 3, 18, 0, 0, 0, 0, tashkent)},
+		{"this_month", time.Date(2024, 3, 1, 0, 0, 0, 0, tashkent), time.Date(2024, 4, 1, 0, 0, 0, 0, tashkent)},
+		{"this_year", time.Date(2024, 1, 1, 0, 0, 0, 0, tashkent), time.Date(2025, 1, 1, 0, 0, 0, 0, tashkent)},
+	}
+	for _, tt := range tests {
+		start, end, ok := periodRange(tt.period, now, tashkent)
+		assert.True(t, ok, tt.period)
+		assert.True(t, tt.start.Equal(start), "%s start: %v", tt.period, start)
+		assert.True(t, tt.end.Equal(end), "%s end: %v", tt.period, end)
+	}
+
+	// A Sunday belongs to the week that started the previous Monday
+	start, _, _ := periodRange("this_week", time.Date(2024, 3, 17, 12, 0, 0, 0, time.UTC), time.UTC)
+	assert.Equal(t, time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC), start)
+
+	_, _, ok := periodRange("last_decade", now, time.UTC)
+	assert.False(t, ok)
+}
+
+func TestParseUserTransactionFilters_Period(t *testing.T) {
+	c, rec := queryContext("period=this_month&tz=Asia/Tashkent")
+	filters, ok := parseUserTransactionFilters(c)
+	assert.True(t, ok, rec.Body.String())
+	assert.True(t, filters.EndDateExclusive)
+	assert.Equal(t, time.UTC, filters.StartDate.Location())
+	assert.Equal(t, 19, filters.StartDate.Hour()) // Local midnight in UTC+5
+
+	for _, query := range []string{"period=yesterday", "period=today&tz=Mars/Olympus", "period=today&start_date=2024-01-01",
+		"tz=Asia/Tashkent&start_date=2024-01-01"} {
+		c, rec := queryContext(query)
+		_, ok := parseUserTransactionFilters(c)
+		assert.False(t, ok, query)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+	}
+}
+
+func TestCreateTransaction_BindingErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewTransactionHandler(stubTransactionService{}, 1024)
+	router := gin.New()
+	router.POST("/transactions", func(c *gin.Context) {
+		c.Set(middleware.AuthUserKey, 1)
+		h.CreateTransaction(c)
+	})
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/transactions", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		fields map[string]string
+		error  string
+	}{
+		{"failed rules", `{"amount": -5, "type": "gift"}`, http.StatusBadRequest, map[string]string{
+			"amount":   "must be greater than 0",
+			"type":     "must be one of: income, expense, transfer",
+			"category": "is required",
+		}, "Invalid request"},
+		{"wrong type", `{"amount": "ten", "type": "expense", "category": "Food"}`, http.StatusBadRequest,
+			map[string]string{"amount": "must be an integer"}, "Invalid request"},
+		{"malformed", `{"amount": 10,`, http.StatusBadRequest, nil, "Invalid request: malformed JSON"},
+		{"empty", ``, http.StatusBadRequest, nil, "Invalid request: body is required"},
+		{"too large", `{"description": "` + strings.Repeat("x", maxJSONBodySize) + `"}`, http.StatusRequestEntityTooLarge, nil, "Request body too large"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := post(tt.body)
+			assert.Equal(t, tt.status, rec.Code)
+			var body struct {
+				Error apierror.Error `json:"error"`
+			}
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+			assert.Contains(t, body.Error.Message, tt.error)
+			assert.Equal(t, tt.fields, body.Error.Fields)
+		})
+	}
+}
+
+func TestSetPaginationHeaders(t *testing.T) {
+	c, rec := queryContext("type=expense&page=2&page_size=10")
+	setPaginationHeaders(c, 2, 10, 35)
+	assert.Equal(t, "35", rec.Header().Get("X-Total-Count"))
+	assert.Equal(t, `</admin/transactions?page=3&page_size=10&type=expense>; rel="next", `+
+		`</admin/transactions?page=1&page_size=10&type=expense>; rel="prev", `+
+		`</admin/transactions?page=1&page_size=10&type=expense>; rel="first", `+
+		`</admin/transactions?page=4&page_size=10&type=expense>; rel="last"`, rec.Header().Get("Link"))
+
+	// A single page has neither next nor prev
+	c, rec = queryContext("")
+	setPaginationHeaders(c, 1, 50, 0)
+	assert.Equal(t, "0", rec.Header().Get("X-Total-Count"))
+	assert.Equal(t, `</admin/transactions?page=1>; rel="first", </admin/transactions?page=1>; rel="last"`, rec.Header().Get("Link"))
+}
+
+func TestUpdateTransaction_NonPositiveAmountRejected(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewTransactionHandler(stubTransactionService{}, 1024)
+	router := gin.New()
+	router.PUT("/transactions/:id", func(c *gin.Context) {
+		c.Set(middleware.AuthUserKey, 1)
+		c.Set(middleware.AuthRoleKey, model.RoleUser)
+		h.UpdateTransaction(c)
+	})
+
+	for _, body := range []string{`{"amount": -100}`, `{"amount": 0}`} {
+		req := httptest.NewRequest(http.MethodPut, "/transactions/1", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req) // Reaching the stub service would panic
+		assert.Equal(t, http.StatusBadRequest, rec.Code, body)
+		assert.Contains(t, rec.Body.String(), "must be greater than 0", body)
+	}
+}
+
+func TestRespondServiceError_Codes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		status int
+		err    error
+		code   apierror.Code
+	}{
+		{http.StatusNotFound, service.ErrTransactionNotFound, apierror.CodeTransactionNotFound},
+		{http.StatusForbidden, service.ErrForbidden, apierror.CodeForbidden},
+		{http.StatusConflict, fmt.Errorf("update: %w", service.ErrVersionConflict), apierror.CodeVersionConflict},
+		{http.StatusBadRequest, errors.New("invalid page"), apierror.CodeBadRequest},
+		{http.StatusInternalServerError, errors.New("db down"), apierror.CodeInternal},
+	}
+	for _, tt := range tests {
+		c, rec := queryContext("")
+		respondServiceError(c, tt.status, tt.err)
+		assert.Equal(t, tt.status, rec.Code)
+		var body struct {
+			Error apierror.Error `json:"error"`
+		}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		assert.Equal(t, tt.code, body.Error.Code, tt.err.Error())
+		assert.Equal(t, tt.err.Error(), body.Error.Message)
+	}
+}
+
+// validatingTransactionService answers ValidateTransaction with err
+type validatingTransactionService struct {
+	stubTransactionService
+	err error
+}
+
+func (s validatingTransactionService) ValidateTransaction(ctx context.Context, userID int, req model.CreateTransactionRequest) error {
+	return s.err
+}
+
+func TestValidateTransaction(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	validate := func(svc service.TransactionService, body string) *httptest.ResponseRecorder {
+		h := NewTransactionHandler(svc, 1024)
+		router := gin.New()
+		router.POST("/transactions/validate", func(c *gin.Context) {
+			c.Set(middleware.AuthUserKey, 1)
+			h.ValidateTransaction(c)
+		})
+		req := httptest.NewRequest(http.MethodPost, "/transactions/validate", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	const valid = `{"amount": 500, "type": "expense", "category": "Food"}`
+
+	rec := validate(validatingTransactionService{}, valid)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"valid": true}`, rec.Body.String())
+
+	rec = validate(validatingTransactionService{err: service.ErrUnknownCategory}, valid)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	var body struct {
+		Error apierror.Error `json:"error"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, apierror.CodeValidationFailed, body.Error.Code)
+	assert.Equal(t, map[string]string{"category": service.ErrUnknownCategory.Error()}, body.Error.Fields)
+
+	// Binding errors come back the same way as from create, before the service is involved
+	rec = validate(stubTransactionService{}, `{"amount": 0, "type": "expense", "category": "Food"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"amount"`)
+}
+
+func TestValidateTransaction_DescriptionLengthIsLeftToTheService(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tooLong := fmt.Errorf("%w (at most 10 characters)", service.ErrDescriptionTooLong)
+	h := NewTransactionHandler(validatingTransactionService{err: tooLong}, 1024)
+	router := gin.New()
+	router.POST("/transactions/validate", func(c *gin.Context) {
+		c.Set(middleware.AuthUserKey, 1)
+		h.ValidateTransaction(c)
+	})
+	// Longer than the default limit, so binding would have answered if it still checked the length
+	body := `{"amount": 500, "type": "expense", "category": "Food", "description": "` + strings.Repeat("x", 501) + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/transactions/validate", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	var resp struct {
+		Error apierror.Error `json:"error"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, map[string]string{"description": tooLong.Error()}, resp.Error.Fields)
+}
+
+func TestDeleteTransactions_TooManyIDs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewTransactionHandler(stubTransactionService{}, 1024)
+	router := gin.New()
+	router.POST("/transactions/batch-delete", func(c *gin.Context) {
+		c.Set(middleware.AuthUserKey, 1)
+		c.Set(middleware.AuthRoleKey, model.RoleUser)
+		h.DeleteTransactions(c)
+	})
+	ids := make([]int64, model.MaxBatchDeleteSize+1)
+	for i := range ids {
+		ids[i] = int64(i + 1)
+	}
+	body, _ := json.Marshal(model.BatchDeleteTransactionsRequest{IDs: ids})
+	req := httptest.NewRequest(http.MethodPost, "/transactions/batch-delete", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), fmt.Sprintf("At most %d ids", model.MaxBatchDeleteSize))
+}
+
+// fixedTransactionService returns tx from GetTransactionByID
+type fixedTransactionService struct {
+	stubTransactionService
+	tx *model.Transaction
+}
+
+func (s fixedTransactionService) GetTransactionByID(ctx context.Context, transactionID int64, userID int, userRole string) (*model.Transaction, error) {
+	return s.tx, nil
+}
+
+func TestGetTransactionByID_ConditionalGet(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tx := &model.Transaction{ID: 1, UserID: 1, Amount: 500, Version: 1, UpdatedAt: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)}
+	get := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		h := NewTransactionHandler(fixedTransactionService{tx: tx}, 1024)
+		router := gin.New()
+		router.GET("/transactions/:id", func(c *gin.Context) {
+			c.Set(middleware.AuthUserKey, 1)
+			c.Set(middleware.AuthRoleKey, model.RoleUser)
+			h.GetTransactionByID(c)
+		})
+		req := httptest.NewRequest(http.MethodGet, "/transactions/1", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	etag := rec.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	rec = get(etag)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Empty(t, rec.Body.String())
+	assert.Equal(t, http.StatusNotModified, get(`"other", `+strings.TrimPrefix(etag, "W/")).Code)
+
+	// Any update yields a new ETag, so the stale one no longer matches
+	tx.Version, tx.UpdatedAt = 2, tx.UpdatedAt.Add(time.Second)
+	rec = get(etag)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEqual(t, etag, rec.Header().Get("ETag"))
+}
+
+// breakdownTransactionService records the arguments of GetUserCategoryBreakdown
+type breakdownTransactionService struct {
+	stubTransactionService
+	start, end       *time.Time
+	currency, txType *string
+}
+
+func (s breakdownTransactionService) GetUserCategoryBreakdown(ctx context.Context, userID int, currency string, start, end time.Time, txType string) ([]model.CategoryShare, error) {
+	*s.start, *s.end, *s.currency, *s.txType = start, end, currency, txType
+	if txType != model.TransactionTypeExpense && txType != model.TransactionTypeIncome {
+		return nil, service.ErrInvalidBreakdownType
+	}
+	return []model.CategoryShare{}, nil
+}
+
+func TestGetCategoryBreakdown(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var start, end time.Time
+	var currency, txType string
+	h := NewTransactionHandler(breakdownTransactionService{start: &start, end: &end, currency: &currency, txType: &txType}, 1024)
+	router := gin.New()
+	router.GET("/transactions/breakdown", func(c *gin.Context) {
+		c.Set(middleware.AuthUserKey, 1)
+		h.GetCategoryBreakdown(c)
+	})
+	get := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/transactions/breakdown"+query, nil))
+		return rec
+	}
+
+	// Defaults to this month's expenses
+	rec := get("")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `[]`, rec.Body.String())
+	assert.Equal(t, model.TransactionTypeExpense, txType)
+	assert.Empty(t, currency) // The service falls back to the user's currency
+	now := time.Now().UTC()
+	assert.Equal(t, time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC), start)
+
+	rec = get("?type=income&start=2024-05-01&end=2024-05-31&currency=usd")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, model.TransactionTypeIncome, txType)
+	assert.Equal(t, "USD", currency)
+	assert.Equal(t, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), start)
+	assert.Equal(t, time.Date(2024, 5, 31, 0, 0, 0, 0, time.UTC), end)
+
+	assert.Equal(t, http.StatusBadRequest, get("?type=transfer").Code)
+	assert.Equal(t, http.StatusBadRequest, get("?start=May").Code)
+	assert.Equal(t, http.StatusBadRequest, get("?currency=XYZ").Code)
+}
+
+// userStatsTransactionService records the query GetUserStatsAdmin is called with
+type userStatsTransactionService struct {
+	stubTransactionService
+	filters  *model.AdminTransactionFilters
+	currency *string
+}
+
+func (s userStatsTransactionService) GetUserStatsAdmin(ctx context.Context, filters model.AdminTransactionFilters, currency string) (*model.UserStatPage, error) {
+	*s.filters, *s.currency = filters, currency
+	return &model.UserStatPage{Data: []model.UserStat{}, Page: filters.Page, PageSize: filters.PageSize, Total: 120}, nil
+}
+
+func TestGetUserStatsAdmin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var filters model.AdminTransactionFilters
+	var currency string
+	h := NewTransactionHandler(userStatsTransactionService{filters: &filters, currency: &currency}, 1024)
+	router := gin.New()
+	router.GET("/admin/stats/by-user", h.GetUserStatsAdmin)
+	get := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/stats/by-user"+query, nil))
+		return rec
+	}
+
+	rec := get("")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"data": [], "page": 1, "page_size": 50, "total": 120}`, rec.Body.String())
+	assert.Equal(t, "120", rec.Header().Get("X-Total-Count"))
+	assert.Equal(t, model.UserStatSortTotalSpent, filters.SortBy)
+	assert.Equal(t, model.DefaultCurrency, currency)
+
+	rec = get("?sort=transaction_count&order=asc&currency=usd&page=2&page_size=10&type=income")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, model.UserStatSortTransactionCount, filters.SortBy)
+	assert.Equal(t, "asc", filters.SortOrder)
+	assert.Equal(t, "USD", currency)
+	assert.Equal(t, 2, filters.Page)
+	assert.Equal(t, 10, filters.PageSize)
+	assert.Equal(t, []string{model.TransactionTypeIncome}, filters.Types)
+
+	assert.Equal(t, http.StatusBadRequest, get("?sort=amount").Code)
+	assert.Equal(t, http.StatusBadRequest, get("?currency=XYZ").Code)
+	assert.Equal(t, http.StatusBadRequest, get("?page_size=1000").Code)
+}
+
+// receiptTransactionService serves every receipt under fileName
+type receiptTransactionService struct {
+	stubTransactionService
+	fileName string
+}
+
+func (s receiptTransactionService) GetReceipt(ctx context.Context, transactionID int64, userID int, userRole string) (io.ReadCloser, string, error) {
+	return io.NopCloser(strings.NewReader("contents")), s.fileName, nil
+}
+
+func TestGetReceipt_Disposition(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	get := func(fileName, query string) *httptest.ResponseRecorder {
+		h := NewTransactionHandler(receiptTransactionService{fileName: fileName}, 1024)
+		router := gin.New()
+		router.GET("/transactions/:id/receipt", func(c *gin.Context) {
+			c.Set(middleware.AuthUserKey, 1)
+			c.Set(middleware.AuthRoleKey, model.RoleUser)
+			h.GetReceipt(c)
+		})
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/transactions/1/receipt"+query, nil))
+		return rec
+	}
+
+	rec := get("Check.JPG", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "image/jpeg", rec.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename=Check.JPG`, rec.Header().Get("Content-Disposition"))
+	assert.Equal(t, "nosniff", rec.Header().Get("X-Content-Type-Options"))
+
+	rec = get("scan.pdf", "?disposition=inline")
+	assert.Equal(t, "application/pdf", rec.Header().Get("Content-Type"))
+	assert.Equal(t, `inline; filename=scan.pdf`, rec.Header().Get("Content-Disposition"))
+
+	// Types a browser could run script from are downloaded even when asked for inline
+	rec = get("page.html", "?disposition=inline")
+	assert.Equal(t, `attachment; filename=page.html`, rec.Header().Get("Content-Disposition"))
+
+	assert.Equal(t, http.StatusBadRequest, get("scan.pdf", "?disposition=preview").Code)
+}
+
+// listingTransactionService records the filters GetUserTransactions is called with
+type listingTransactionService struct {
+	stubTransactionService
+	filters *model.UserTransactionFilters
+}
+
+func (s listingTransactionService) GetUserTransactions(ctx context.Context, userID int, filters model.UserTransactionFilters) (*model.TransactionPage, error) {
+	*s.filters = filters
+	return &model.TransactionPage{Data: []model.Transaction{}}, nil
+}
+
+func TestGetMyTransactions_SortedCursorPages(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var filters model.UserTransactionFilters
+	h := NewTransactionHandler(listingTransactionService{filters: &filters}, 1024)
+	router := gin.New()
+	router.GET("/transactions", func(c *gin.Context) {
+		c.Set(middleware.AuthUserKey, 1)
+		h.GetMyTransactions(c)
+	})
+	get := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/transactions"+query, nil))
+		return rec
+	}
+
+	cursor := model.TransactionCursor{TransactionDate: time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC), Amount: 500, ID: 7}
+	rec := get("?sort=amount&order=asc&limit=10&cursor=" + cursor.Encode())
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "amount", filters.SortBy)
+	assert.Equal(t, "asc", filters.SortOrder)
+	assert.Equal(t, 10, filters.Limit)
+	if assert.NotNil(t, filters.Cursor) {
+		assert.Equal(t, int64(500), filters.Cursor.Amount)
+		assert.Equal(t, int64(7), filters.Cursor.ID)
+	}
+
+	assert.Equal(t, http.StatusBadRequest, get("?cursor=12345").Code)
+}
+
+// newTransactionRouter mounts the transaction routes the way main does, with auth stubbed
+// to an ordinary user with ID 1
+func newTransactionRouter(svc service.TransactionService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	auth := func(c *gin.Context) {
+		c.Set(middleware.AuthUserKey, 1)
+		c.Set(middleware.AuthRoleKey, model.RoleUser)
+	}
+	NewTransactionHandler(svc, 1024).RegisterTransactionRoutes(router.Group("/api/v1"), auth, nil, func(c *gin.Context) {})
+	return router
+}
+
+// rankingTransactionService records the arguments of GetCategoryRanking
+type rankingTransactionService struct {
+	stubTransactionService
+	filters *model.UserTransactionFilters
+	limit   *int
+}
+
+func (s rankingTransactionService) GetCategoryRanking(ctx context.Context, userID int, filters model.UserTransactionFilters, limit int) ([]model.CategoryRank, error) {
+	*s.filters, *s.limit = filters, limit
+	return []model.CategoryRank{{Rank: 1, Category: "Food", Amount: 7500, Count: 3, Percentage: 75}}, nil
+}
+
+func TestGetCategoryRanking(t *testing.T) {
+	var filters model.UserTransactionFilters
+	var limit int
+	router := newTransactionRouter(rankingTransactionService{filters: &filters, limit: &limit})
+	get := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/transactions/category-ranking"+query, nil))
+		return rec
+	}
+
+	rec := get("")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `[{"rank":1,"category":"Food","amount":7500,"count":3,"percentage":75}]`, rec.Body.String())
+	assert.Equal(t, defaultCategoryRankingLimit, limit)
+	assert.Nil(t, filters.StartDate)
+	assert.Empty(t, filters.Currency)
+
+	rec = get("?start_date=2024-05-01&end_date=2024-05-31&currency=usd&limit=500")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, maxCategoryRankingLimit, limit)
+	assert.Equal(t, "USD", filters.Currency)
+	if assert.NotNil(t, filters.StartDate) && assert.NotNil(t, filters.EndDate) {
+		assert.Equal(t, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), *filters.StartDate)
+		assert.Equal(t, time.Date(2024, 5, 31, 0, 0, 0, 0, time.UTC), *filters.EndDate)
+	}
+
+	assert.Equal(t, http.StatusBadRequest, get("?limit=0").Code)
+	assert.Equal(t, http.StatusBadRequest, get("?limit=ten").Code)
+	assert.Equal(t, http.StatusBadRequest, get("?start_date=May").Code)
+	assert.Equal(t, http.StatusBadRequest, get("?currency=XYZ").Code)
+}
+
+// merchantsTransactionService records the arguments of GetTopMerchants
+type merchantsTransactionService struct {
+	listingTransactionService
+	limit *int
+}
+
+func (s merchantsTransactionService) GetTopMerchants(ctx context.Context, userID int, filters model.UserTransactionFilters, limit int) ([]model.MerchantSpend, error) {
+	*s.filters, *s.limit = filters, limit
+	return []model.MerchantSpend{{Merchant: "Korzinka", TotalSpent: 120000, Count: 4}}, nil
+}
+
+func TestGetTopMerchants(t *testing.T) {
+	var filters model.UserTransactionFilters
+	var limit int
+	router := newTransactionRouter(merchantsTransactionService{listingTransactionService{filters: &filters}, &limit})
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/transactions"+path, nil))
+		return rec
+	}
+
+	rec := get("/top-merchants")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `[{"merchant":"Korzinka","total_spent":120000,"count":4}]`, rec.Body.String())
+	assert.Equal(t, defaultTopMerchantsLimit, limit)
+
+	rec = get("/top-merchants?start_date=2024-05-01&currency=usd&limit=500")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, maxTopMerchantsLimit, limit)
+	assert.Equal(t, "USD", filters.Currency)
+	if assert.NotNil(t, filters.StartDate) {
+		assert.Equal(t, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), *filters.StartDate)
+	}
+
+	assert.Equal(t, http.StatusBadRequest, get("/top-merchants?limit=-1").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/top-merchants?end_date=May").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/top-merchants?currency=XYZ").Code)
+
+	// The listing filters on merchant too
+	rec = get("?merchant=Korzinka")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	if assert.NotNil(t, filters.Merchant) {
+		assert.Equal(t, "Korzinka", *filters.Merchant)
+	}
+}
+
+// overviewTransactionService records the filters GetUserOverview is called with
+type overviewTransactionService struct {
+	listingTransactionService
+}
+
+func (s overviewTransactionService) GetUserOverview(ctx context.Context, userID int, filters model.UserTransactionFilters) (*model.TransactionOverview, error) {
+	*s.filters = filters
+	return &model.TransactionOverview{Currency: "UZS"}, nil
+}
+
+func TestGetOverview(t *testing.T) {
+	var filters model.UserTransactionFilters
+	router := newTransactionRouter(overviewTransactionService{listingTransactionService{filters: &filters}})
+	get := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/transactions/overview"+query, nil))
+		return rec
+	}
+
+	// Dates are null when nothing matches
+	rec := get("")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"currency":"UZS","count":0,"total_income":0,"total_expense":0,"balance":0,
+		"first_transaction_date":null,"last_transaction_date":null,"distinct_categories":0}`, rec.Body.String())
+
+	// Takes the same filters as the listing
+	rec = get("?type=expense&category=Food&date=2024-05-10")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, []string{model.TransactionTypeExpense}, filters.Types)
+	assert.Equal(t, []string{"Food"}, filters.Categories)
+	if assert.NotNil(t, filters.StartDate) && assert.NotNil(t, filters.EndDate) {
+		assert.Equal(t, time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC), *filters.StartDate)
+		assert.Equal(t, time.Date(2024, 5, 11, 0, 0, 0, 0, time.UTC), *filters.EndDate)
+		assert.True(t, filters.EndDateExclusive)
+	}
+
+	assert.Equal(t, http.StatusBadRequest, get("?type=gift").Code)
+	assert.Equal(t, http.StatusBadRequest, get("?date=10.05.2024").Code)
+	assert.Equal(t, http.StatusBadRequest, get("?period=month&date=2024-05-10").Code)
+}
+
+// statisticsTransactionService records the caller and filters GetUserStatistics is called with
+type statisticsTransactionService struct {
+	listingTransactionService
+	userID *int
+}
+
+func (s statisticsTransactionService) GetUserStatistics(ctx context.Context, userID int, filters model.UserTransactionFilters) (*model.UserStats, error) {
+	*s.filters, *s.userID = filters, userID
+	return &model.UserStats{
+		Currency:          "UZS",
+		TotalIncome:       500000,
+		TotalExpenses:     200000,
+		Balance:           300000,
+		ByCategoryIncome:  map[string]int64{"Salary": 500000},
+		ByCategoryExpense: map[string]int64{"Food": 200000},
+	}, nil
+}
+
+func TestGetMyStatistics(t *testing.T) {
+	var filters model.UserTransactionFilters
+	var userID int
+	router := newTransactionRouter(statisticsTransactionService{listingTransactionService{filters: &filters}, &userID})
+	get := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/transactions/stats"+query, nil))
+		return rec
+	}
+
+	rec := get("?start_date=2024-05-01&end_date=2024-05-31&currency=usd")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 1, userID) // Scoped to the caller
+	assert.Equal(t, "USD", filters.Currency)
+	if assert.NotNil(t, filters.StartDate) && assert.NotNil(t, filters.EndDate) {
+		assert.Equal(t, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), *filters.StartDate)
+		assert.Equal(t, time.Date(2024, 5, 31, 0, 0, 0, 0, time.UTC), *filters.EndDate)
+	}
+	var body map[string]interface{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, float64(300000), body["balance"])
+	assert.Equal(t, map[string]interface{}{"Food": float64(200000)}, body["by_category_expense"])
+
+	assert.Equal(t, http.StatusBadRequest, get("?start_date=May").Code)
+	assert.Equal(t, http.StatusBadRequest, get("?currency=XYZ").Code)
+	assert.Equal(t, http.StatusBadRequest, get("?min_amount=abc").Code)
+}
@@ -0,0 +1,26 @@
+package logger
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// ParseLevel maps LOG_LEVEL values (debug, info, warn, error) to a slog level, defaulting to info
+func ParseLevel(s string) slog.Level {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// New creates a logger writing JSON records to stdout at the given level
+func New(level string) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: ParseLevel(level)}))
+}
@@ -0,0 +1,29 @@
+// Package metrics holds the Prometheus collectors exposed at /metrics.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// HTTPRequestsTotal counts handled requests by method, route template and status code
+	HTTPRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Number of HTTP requests handled, by method, route and status code.",
+	}, []string{"method", "path", "status"})
+
+	// HTTPRequestDuration observes request latency by method, route template and status code
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency in seconds, by method, route and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "path", "status"})
+
+	// TransactionsCreated counts transactions stored through the API, including CSV imports and
+	// recurring occurrences
+	TransactionsCreated = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "transactions_created_total",
+		Help: "Number of transactions created.",
+	})
+)
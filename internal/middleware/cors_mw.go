@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+
+	"expense_tracker/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	corsAllowedHeaders = "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-Request-ID, Idempotency-Key, If-None-Match"
+	corsAllowedMethods = "POST, OPTIONS, GET, PUT, DELETE"
+	corsExposedHeaders = "X-Request-ID, X-Total-Count, Link, ETag"
+)
+
+// CORS answers preflight requests and sets CORS headers. With an allowlist, only a listed
+// request Origin is echoed back, together with Allow-Credentials; other origins get no CORS
+// headers at all. Without one, any origin is allowed, but never with credentials.
+func CORS(cfg *config.CORSConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.Writer.Header()
+		if len(cfg.AllowedOrigins) == 0 {
+			header.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			header.Add("Vary", "Origin")
+			if origin := c.GetHeader("Origin"); origin != "" && slices.Contains(cfg.AllowedOrigins, origin) {
+				header.Set("Access-Control-Allow-Origin", origin)
+				header.Set("Access-Control-Allow-Credentials", "true")
+			}
+		}
+		header.Set("Access-Control-Allow-Headers", corsAllowedHeaders)
+		header.Set("Access-Control-Allow-Methods", corsAllowedMethods)
+		header.Set("Access-Control-Expose-Headers", corsExposedHeaders)
+
+		if c.Request.Method == http.MethodOptions {
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		c.Next()
+	}
+}
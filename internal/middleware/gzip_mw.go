@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultGzipMinSize is the response size below which compression isn't worth its overhead
+const DefaultGzipMinSize = 1024
+
+// compressibleContentType reports whether a response is text that gzip shrinks. Images, PDFs and
+// other binary receipt types are already compressed, so they are passed through untouched.
+func compressibleContentType(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	return strings.HasPrefix(contentType, "text/") ||
+		strings.Contains(contentType, "json") ||
+		strings.Contains(contentType, "xml") ||
+		strings.Contains(contentType, "javascript") ||
+		strings.Contains(contentType, "csv")
+}
+
+// gzipResponseWriter holds back the first minSize bytes of the body, then decides
+// from the content type and final size whether to compress
+type gzipResponseWriter struct {
+	gin.ResponseWriter
+	minSize  int
+	buf      []byte
+	decided  bool
+	gzWriter *gzip.Writer
+}
+
+func (w *gzipResponseWriter) Write(data []byte) (int, error) {
+	if !w.decided {
+		w.buf = append(w.buf, data...)
+		if len(w.buf) < w.minSize {
+			return len(data), nil
+		}
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+		return len(data), nil
+	}
+	if w.gzWriter != nil {
+		return w.gzWriter.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *gzipResponseWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// decide picks compressed or plain output and writes out the held-back bytes
+func (w *gzipResponseWriter) decide(largeEnough bool) error {
+	w.decided = true
+	header := w.Header()
+	if largeEnough && header.Get("Content-Encoding") == "" && compressibleContentType(header.Get("Content-Type")) {
+		header.Set("Content-Encoding", "gzip")
+		header.Add("Vary", "Accept-Encoding")
+		header.Del("Content-Length")
+		w.gzWriter = gzip.NewWriter(w.ResponseWriter)
+		_, err := w.gzWriter.Write(w.buf)
+		w.buf = nil
+		return err
+	}
+	_, err := w.ResponseWriter.Write(w.buf)
+	w.buf = nil
+	return err
+}
+
+func (w *gzipResponseWriter) Flush() {
+	if !w.decided {
+		w.decide(len(w.buf) >= w.minSize)
+	}
+	if w.gzWriter != nil {
+		w.gzWriter.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// finish writes out anything still held back and terminates the gzip stream
+func (w *gzipResponseWriter) finish() {
+	if !w.decided && len(w.buf) > 0 {
+		w.decide(false)
+	}
+	if w.gzWriter != nil {
+		w.gzWriter.Close()
+	}
+}
+
+// GzipMiddleware compresses text responses of at least minSize bytes for clients that
+// send Accept-Encoding: gzip
+func GzipMiddleware(minSize int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodHead || !strings.Contains(c.GetHeader("Accept-Encoding"), "gzip") {
+			c.Next()
+			return
+		}
+
+		writer := &gzipResponseWriter{ResponseWriter: c.Writer, minSize: minSize}
+		c.Writer = writer
+		defer func() {
+			writer.finish()
+			c.Writer = writer.ResponseWriter
+		}()
+		c.Next()
+	}
+}
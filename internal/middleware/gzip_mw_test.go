@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func gzipRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(GzipMiddleware(DefaultGzipMinSize))
+	router.GET("/csv", func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/csv", []byte(strings.Repeat("1,2024-05-01,expense,Food\n", 200)))
+	})
+	router.GET("/small", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"status": "ok"}) })
+	router.GET("/image", func(c *gin.Context) {
+		c.Data(http.StatusOK, "image/png", make([]byte, 4096))
+	})
+	return router
+}
+
+func gzipGet(router *gin.Engine, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestGzipMiddleware_CompressesLargeText(t *testing.T) {
+	w := gzipGet(gzipRouter(), "/csv")
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+
+	reader, err := gzip.NewReader(w.Body)
+	assert.NoError(t, err)
+	body, err := io.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.Equal(t, strings.Repeat("1,2024-05-01,expense,Food\n", 200), string(body))
+}
+
+func TestGzipMiddleware_SkipsSmallAndBinaryResponses(t *testing.T) {
+	router := gzipRouter()
+
+	w := gzipGet(router, "/small")
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.JSONEq(t, `{"status":"ok"}`, w.Body.String())
+
+	w = gzipGet(router, "/image")
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Len(t, w.Body.Bytes(), 4096)
+
+	// Clients that don't ask for gzip get plain text
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/csv", nil))
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+}
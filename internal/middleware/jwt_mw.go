@@ -1,6 +1,8 @@
 package middleware
 
 import (
+	"context"
+	"log"
 	"net/http"
 	"strings"
 
@@ -10,35 +12,83 @@ import (
 )
 
 const (
-	AuthUserKey = "authUser"
-	AuthRoleKey = "authRole"
+	AuthUserKey        = "authUser"
+	AuthRoleKey        = "authRole"
+	AuthTokenIDKey     = "authTokenID"
+	AuthTokenExpiryKey = "authTokenExpiry"
 )
 
-// JWTAuthMiddleware creates a middleware for JWT authentication
-func JWTAuthMiddleware(jwtUtil *utils.JWTUtil) gin.HandlerFunc {
+// UserStatusChecker reports the current role of the user behind a valid token, or "" if the
+// user no longer exists
+type UserStatusChecker interface {
+	UserRole(ctx context.Context, userID int) (string, error)
+}
+
+// TokenBlacklist reports whether an access token has been revoked by its JWT ID
+type TokenBlacklist interface {
+	IsBlacklisted(ctx context.Context, jti string) (bool, error)
+}
+
+// JWTAuthMiddleware creates a middleware for JWT authentication.
+// Tokens whose jti is in the blacklist are rejected. When statusChecker is non-nil,
+// tokens of deleted users are rejected as well, and the user's current role replaces the one
+// in the token, so a role change applies at once instead of when the token expires.
+func JWTAuthMiddleware(jwtUtil *utils.JWTUtil, blacklist TokenBlacklist, statusChecker UserStatusChecker) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
+			abortWithError(c, http.StatusUnauthorized, "Authorization header required")
 			return
 		}
 
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header format"})
+			abortWithError(c, http.StatusUnauthorized, "Invalid authorization header format")
 			return
 		}
 
 		tokenString := parts[1]
 		claims, err := jwtUtil.ValidateToken(tokenString)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+			abortWithError(c, http.StatusUnauthorized, "Invalid or expired token")
 			return
 		}
 
+		if claims.ID != "" {
+			revoked, err := blacklist.IsBlacklisted(c.Request.Context(), claims.ID)
+			if err != nil {
+				log.Printf("Error checking token blacklist: %v", err)
+				abortWithError(c, http.StatusInternalServerError, "Failed to verify token")
+				return
+			}
+			if revoked {
+				abortWithError(c, http.StatusUnauthorized, "Token has been revoked")
+				return
+			}
+		}
+
+		role := claims.Role
+		if statusChecker != nil {
+			currentRole, err := statusChecker.UserRole(c.Request.Context(), claims.UserID)
+			if err != nil {
+				log.Printf("Error checking user status for user %d: %v", claims.UserID, err)
+				abortWithError(c, http.StatusInternalServerError, "Failed to verify user")
+				return
+			}
+			if currentRole == "" {
+				abortWithError(c, http.StatusUnauthorized, "User no longer exists")
+				return
+			}
+			role = currentRole
+		}
+
 		// Set user information in context
 		c.Set(AuthUserKey, claims.UserID)
-		c.Set(AuthRoleKey, claims.Role)
+		c.Set(AuthRoleKey, role)
+		c.Set(AuthTokenIDKey, claims.ID)
+		if claims.ExpiresAt != nil {
+			c.Set(AuthTokenExpiryKey, claims.ExpiresAt.Time)
+		}
 
 		c.Next()
 	}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LoggerKey is the context key for the request-scoped logger
+const LoggerKey = "logger"
+
+// RequestLoggerMiddleware stores a logger carrying the request ID in the context, so every
+// record a request logs can be tied together. It reuses the ID set by RequestIDMiddleware.
+func RequestLoggerMiddleware(base *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetString(RequestIDKey)
+		if requestID == "" {
+			requestID = newRequestID()
+			c.Set(RequestIDKey, requestID)
+		}
+		c.Set(LoggerKey, base.With("request_id", requestID))
+		c.Next()
+	}
+}
+
+// Logger returns the request-scoped logger, or the default logger outside RequestLoggerMiddleware
+func Logger(c *gin.Context) *slog.Logger {
+	if l, ok := c.Get(LoggerKey); ok {
+		if logger, ok := l.(*slog.Logger); ok {
+			return logger
+		}
+	}
+	return slog.Default()
+}
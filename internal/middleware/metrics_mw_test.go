@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"expense_tracker/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsMiddleware_LabelsByRouteTemplate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(MetricsMiddleware())
+	router.GET("/items/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	byTemplate := metrics.HTTPRequestsTotal.WithLabelValues(http.MethodGet, "/items/:id", "200")
+	unmatched := metrics.HTTPRequestsTotal.WithLabelValues(http.MethodGet, unmatchedRoute, "404")
+	beforeTemplate, beforeUnmatched := testutil.ToFloat64(byTemplate), testutil.ToFloat64(unmatched)
+
+	for _, path := range []string{"/items/1", "/items/2", "/nope/3"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	assert.Equal(t, beforeTemplate+2, testutil.ToFloat64(byTemplate))
+	assert.Equal(t, beforeUnmatched+1, testutil.ToFloat64(unmatched))
+}
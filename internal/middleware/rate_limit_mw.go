@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tokenBucket holds the remaining request allowance of a single client
+type tokenBucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// rateLimiter is a per-key token bucket: each key may burst up to limit requests,
+// refilled at limit per window
+type rateLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	limit     float64
+	window    time.Duration
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func newRateLimiter(limit int, window time.Duration) *rateLimiter {
+	return &rateLimiter{
+		buckets: make(map[string]*tokenBucket),
+		limit:   float64(limit),
+		window:  window,
+		now:     time.Now,
+	}
+}
+
+// allow consumes a token for key. When none is left it returns false and how long until one is.
+func (rl *rateLimiter) allow(key string) (bool, time.Duration) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := rl.now()
+	rl.sweep(now)
+
+	refillPerSecond := rl.limit / rl.window.Seconds()
+	bucket, ok := rl.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: rl.limit, lastSeen: now}
+		rl.buckets[key] = bucket
+	} else {
+		elapsed := now.Sub(bucket.lastSeen).Seconds()
+		bucket.tokens = math.Min(rl.limit, bucket.tokens+elapsed*refillPerSecond)
+		bucket.lastSeen = now
+	}
+
+	if bucket.tokens < 1 {
+		wait := time.Duration((1 - bucket.tokens) / refillPerSecond * float64(time.Second))
+		return false, wait
+	}
+	bucket.tokens--
+	return true, 0
+}
+
+// sweep drops buckets idle for a whole window (they would be full again anyway), at most once per window
+func (rl *rateLimiter) sweep(now time.Time) {
+	if now.Sub(rl.lastSweep) < rl.window {
+		return
+	}
+	for key, bucket := range rl.buckets {
+		if now.Sub(bucket.lastSeen) >= rl.window {
+			delete(rl.buckets, key)
+		}
+	}
+	rl.lastSweep = now
+}
+
+// RateLimitMiddleware limits each client IP to limit requests per window.
+// Requests over the limit get 429 with a Retry-After header.
+func RateLimitMiddleware(limit int, window time.Duration) gin.HandlerFunc {
+	limiter := newRateLimiter(limit, window)
+	return func(c *gin.Context) {
+		allowed, retryAfter := limiter.allow(c.ClientIP())
+		if !allowed {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			abortWithError(c, http.StatusTooManyRequests, "Too many requests, please try again later")
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter_RefillsOverWindow(t *testing.T) {
+	rl := newRateLimiter(2, time.Minute)
+	now := time.Now()
+	rl.now = func() time.Time { return now }
+
+	allowed, _ := rl.allow("1.2.3.4")
+	assert.True(t, allowed)
+	allowed, _ = rl.allow("1.2.3.4")
+	assert.True(t, allowed)
+	allowed, wait := rl.allow("1.2.3.4")
+	assert.False(t, allowed)
+	assert.Equal(t, 30*time.Second, wait)
+
+	// Other clients have their own bucket
+	allowed, _ = rl.allow("5.6.7.8")
+	assert.True(t, allowed)
+
+	now = now.Add(30 * time.Second)
+	allowed, _ = rl.allow("1.2.3.4")
+	assert.True(t, allowed)
+}
+
+func TestRateLimitMiddleware_Returns429WithRetryAfter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/login", RateLimitMiddleware(1, time.Minute), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/login", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/login", nil))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+}
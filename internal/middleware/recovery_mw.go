@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"syscall"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RecoveryMiddleware turns a panic in a later handler into a logged stack trace and a JSON 500,
+// so clients get the same error shape as for any other failure. It must run after the request
+// ID, logger and envelope middlewares, whose values the response and log record carry.
+func RecoveryMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if recovered == http.ErrAbortHandler {
+				panic(recovered) // Deliberate abort; net/http handles it quietly
+			}
+
+			err, ok := recovered.(error)
+			if !ok {
+				err = fmt.Errorf("%v", recovered)
+			}
+			if clientGone(err) {
+				// Nothing can be written to a closed connection
+				Logger(c).Warn("Client connection lost", "error", err, "path", c.Request.URL.Path)
+				c.Abort()
+				return
+			}
+
+			Logger(c).Error("Panic while handling request", "error", err,
+				"method", c.Request.Method, "path", c.Request.URL.Path, "stack", string(debug.Stack()))
+			if c.Writer.Written() {
+				c.Abort() // Too late for a JSON body; the client sees a truncated response
+				return
+			}
+			abortWithError(c, http.StatusInternalServerError, "internal server error")
+		}()
+		c.Next()
+	}
+}
+
+// clientGone reports whether err comes from writing to a connection the client already closed
+func clientGone(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && (errors.Is(opErr, syscall.EPIPE) || errors.Is(opErr, syscall.ECONNRESET))
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecoveryMiddleware_RespondsWithJSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var logs bytes.Buffer
+
+	router := gin.New()
+	router.Use(RequestIDMiddleware())
+	router.Use(RequestLoggerMiddleware(slog.New(slog.NewJSONHandler(&logs, nil))))
+	router.Use(RecoveryMiddleware())
+	router.GET("/", func(c *gin.Context) {
+		var m map[string]int
+		m["boom"]++ // Nil map write
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(RequestIDHeader, "req-42")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+	var body map[string]map[string]string
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, map[string]map[string]string{"error": {
+		"code": "INTERNAL_ERROR", "message": "internal server error", "request_id": "req-42",
+	}}, body)
+
+	var record map[string]interface{}
+	assert.NoError(t, json.Unmarshal(logs.Bytes(), &record))
+	assert.Equal(t, "req-42", record["request_id"])
+	assert.Contains(t, record["error"], "nil map")
+	assert.Contains(t, record["stack"], "recovery_mw_test.go")
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestIDMiddleware_EchoesOrGeneratesID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestIDMiddleware())
+	router.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(RequestIDHeader, "client-supplied-id")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, "client-supplied-id", w.Header().Get(RequestIDHeader))
+
+	// Unusable incoming IDs are replaced with a fresh UUID
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(RequestIDHeader, "has spaces")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, w.Header().Get(RequestIDHeader))
+}
+
+func TestErrorBody_IncludesRequestIDOnServerErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestIDMiddleware())
+	router.GET("/fail", func(c *gin.Context) { abortWithError(c, http.StatusInternalServerError, "boom") })
+	router.GET("/bad", func(c *gin.Context) { abortWithError(c, http.StatusBadRequest, "bad") })
+
+	req := httptest.NewRequest(http.MethodGet, "/fail", nil)
+	req.Header.Set(RequestIDHeader, "abc-123")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var body map[string]map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "abc-123", body["error"]["request_id"])
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/bad", nil))
+	body = nil
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.NotContains(t, body["error"], "request_id")
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"strings"
+
+	"expense_tracker/internal/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// ResponseEnvelopeKey marks in the context whether responses should be wrapped
+	ResponseEnvelopeKey = "responseEnvelope"
+	// EnvelopeMediaType lets a client opt into the envelope through the Accept header
+	EnvelopeMediaType = "application/vnd.expense.envelope+json"
+)
+
+// ResponseEnvelopeMiddleware decides per request whether responses are wrapped in
+// {"success": ..., "data"/"error": ...}. It must run before any middleware that can abort.
+func ResponseEnvelopeMiddleware(enabled bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		wrap := enabled || strings.Contains(c.GetHeader("Accept"), EnvelopeMediaType)
+		c.Set(ResponseEnvelopeKey, wrap)
+		c.Next()
+	}
+}
+
+// ErrorBody builds an error response body, {"error": {"code": ..., "message": ...}}, wrapped
+// when the envelope mode is active. Server errors carry the request ID so users can quote it
+// in support tickets.
+func ErrorBody(c *gin.Context, status int, apiErr apierror.Error) gin.H {
+	if requestID := c.GetString(RequestIDKey); status >= 500 && requestID != "" {
+		apiErr.RequestID = requestID
+	}
+	body := gin.H{"error": apiErr}
+	if c.GetBool(ResponseEnvelopeKey) {
+		body["success"] = false
+	}
+	return body
+}
+
+// abortWithError stops the chain with an error body shaped like the handlers' responses,
+// using the generic code for status
+func abortWithError(c *gin.Context, status int, message string) {
+	c.AbortWithStatusJSON(status, ErrorBody(c, status, apierror.Error{Code: apierror.ForStatus(status), Message: message}))
+}
@@ -13,13 +13,13 @@ func RoleMiddleware(allowedRoles ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		roleVal, exists := c.Get(AuthRoleKey)
 		if !exists {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Role not found in token, ensure JWT middleware runs first"})
+			abortWithError(c, http.StatusForbidden, "Role not found in token, ensure JWT middleware runs first")
 			return
 		}
 
 		userRole, ok := roleVal.(string)
 		if !ok {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Invalid role type in token"})
+			abortWithError(c, http.StatusForbidden, "Invalid role type in token")
 			return
 		}
 
@@ -32,7 +32,7 @@ func RoleMiddleware(allowedRoles ...string) gin.HandlerFunc {
 		}
 
 		if !isAllowed {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You do not have permission to access this resource"})
+			abortWithError(c, http.StatusForbidden, "You do not have permission to access this resource")
 			return
 		}
 
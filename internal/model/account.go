@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// Account is one of a user's wallets (cash, bank card, ...). Balance only changes through
+// transfers once the account exists.
+type Account struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"user_id"`
+	Name      string    `json:"name"`
+	Balance   int64     `json:"balance"` // In tiyns
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CreateAccountRequest is used for creating an account with an optional opening balance
+type CreateAccountRequest struct {
+	Name    string `json:"name" binding:"required,max=100"`
+	Balance int64  `json:"balance"`
+}
+
+// UpdateAccountRequest renames an account
+type UpdateAccountRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
@@ -0,0 +1,31 @@
+package model
+
+import "time"
+
+// Budget is a monthly spending cap for one expense category
+type Budget struct {
+	ID          int       `json:"id"`
+	UserID      int       `json:"user_id"`
+	Category    string    `json:"category"`
+	Month       string    `json:"month"`        // YYYY-MM
+	LimitAmount int64     `json:"limit_amount"` // In tiyns
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// SetBudgetRequest creates or replaces the budget for a category and month
+type SetBudgetRequest struct {
+	Category    string `json:"category" binding:"required"`
+	Month       string `json:"month" binding:"required"` // YYYY-MM
+	LimitAmount int64  `json:"limit_amount" binding:"required,gt=0"`
+}
+
+// BudgetStatus compares a budget with the expenses recorded against it in the user's currency
+type BudgetStatus struct {
+	Category    string `json:"category"`
+	Currency    string `json:"currency"`
+	LimitAmount int64  `json:"limit_amount"`
+	Spent       int64  `json:"spent"`
+	Remaining   int64  `json:"remaining"` // Negative once over budget
+	OverBudget  bool   `json:"over_budget"`
+}
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// Category is a transaction category. Global defaults have no UserID.
+type Category struct {
+	ID        int       `json:"id"`
+	UserID    *int      `json:"user_id,omitempty"` // nil for global defaults
+	Name      string    `json:"name"`
+	Type      string    `json:"type"` // "income" or "expense"
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreateCategoryRequest is used for creating a user's custom category
+type CreateCategoryRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+	Type string `json:"type" binding:"required,oneof=income expense"`
+}
@@ -0,0 +1,24 @@
+package model
+
+import "strings"
+
+// DefaultCurrency is the currency of users and transactions that never chose one
+const DefaultCurrency = "UZS"
+
+// supportedCurrencies lists the ISO 4217 codes accepted for users and transactions
+var supportedCurrencies = map[string]bool{
+	"UZS": true,
+	"USD": true,
+	"EUR": true,
+	"RUB": true,
+	"KZT": true,
+	"GBP": true,
+	"CNY": true,
+	"TRY": true,
+}
+
+// NormalizeCurrency upper-cases and trims an ISO 4217 code, reporting false when it is not supported
+func NormalizeCurrency(code string) (string, bool) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	return code, supportedCurrencies[code]
+}
@@ -0,0 +1,46 @@
+package model
+
+import "time"
+
+// Recurrence intervals for recurring transactions
+const (
+	IntervalDaily   = "daily"
+	IntervalWeekly  = "weekly"
+	IntervalMonthly = "monthly"
+)
+
+// RecurringTransaction is a rule that materializes a transaction every interval
+type RecurringTransaction struct {
+	ID          int       `json:"id"`
+	UserID      int       `json:"user_id"`
+	Amount      int64     `json:"amount"` // In tiyns
+	Type        string    `json:"type"`
+	Category    string    `json:"category"`
+	Description *string   `json:"description,omitempty"`
+	Interval    string    `json:"interval"`      // daily, weekly or monthly
+	NextRunDate time.Time `json:"next_run_date"` // Date the next transaction is created for
+	AnchorDay   int       `json:"-"`             // Day of month monthly runs fall on, from the run date the user set
+	Active      bool      `json:"active"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// CreateRecurringRequest is used for creating a recurring transaction rule
+type CreateRecurringRequest struct {
+	Amount      int64     `json:"amount" binding:"required,gt=0"`
+	Type        string    `json:"type" binding:"required,oneof=income expense"`
+	Category    string    `json:"category" binding:"required"`
+	Description *string   `json:"description"`
+	Interval    string    `json:"interval" binding:"required,oneof=daily weekly monthly"`
+	NextRunDate time.Time `json:"next_run_date"` // Defaults to today
+}
+
+// UpdateRecurringRequest partially updates a recurring transaction rule
+type UpdateRecurringRequest struct {
+	Amount      *int64     `json:"amount,omitempty" binding:"omitempty,gt=0"`
+	Category    *string    `json:"category,omitempty"`
+	Description *string    `json:"description,omitempty"`
+	Interval    *string    `json:"interval,omitempty" binding:"omitempty,oneof=daily weekly monthly"`
+	NextRunDate *time.Time `json:"next_run_date,omitempty"`
+	Active      *bool      `json:"active,omitempty"`
+}
@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// RefreshToken is a stored long-lived token used to obtain new access tokens.
+// Only the hash of the opaque token value is persisted.
+type RefreshToken struct {
+	ID        int64
+	UserID    int
+	TokenHash string
+	ExpiresAt time.Time
+	Revoked   bool
+	CreatedAt time.Time
+}
+
+// AuthTokens is the pair of tokens handed to a client after authentication
+type AuthTokens struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresAt    time.Time // When AccessToken expires
+}
@@ -1,74 +1,370 @@
 package model
 
-import "time"
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
 
 const (
 	TransactionTypeIncome  = "income"
 	TransactionTypeExpense = "expense"
+	// TransactionTypeTransfer moves money between two of the user's accounts; it is
+	// neither income nor expense and is left out of statistics
+	TransactionTypeTransfer = "transfer"
 )
 
+// TransferCategory is the category stored on transfers
+const TransferCategory = "Transfer"
+
 // Transaction represents an income or expense record
 type Transaction struct {
 	ID              int64     `json:"id"`
 	UserID          int       `json:"user_id"`
-	Amount          int64     `json:"amount"` // In tiyns
-	Type            string    `json:"type"`   // "income" or "expense"
+	Amount          int64     `json:"amount"`   // In tiyns
+	Currency        string    `json:"currency"` // ISO 4217 code
+	Type            string    `json:"type"`     // "income", "expense" or "transfer"
 	Category        string    `json:"category"`
+	Merchant        *string   `json:"merchant,omitempty"`    // Payee, kept apart from free-text description
 	Description     *string   `json:"description,omitempty"` // Pointer for optional field
+	Tags            []string  `json:"tags"`                  // Lower-case labels, never null
 	TransactionDate time.Time `json:"transaction_date"`
 	ReceiptPath     *string   `json:"receipt_path,omitempty"` // Pointer for optional field
+	ReceiptName     *string   `json:"receipt_name,omitempty"` // Original filename of the receipt, for display and downloads
 	CreatedAt       time.Time `json:"created_at"`
 	UpdatedAt       time.Time `json:"updated_at"`
+	Version         int       `json:"version"`                   // Incremented on every update
+	FromAccountID   *int      `json:"from_account_id,omitempty"` // Set on transfers only
+	ToAccountID     *int      `json:"to_account_id,omitempty"`
+	// AdminNote is only loaded by admin queries, so it never reaches the owner's views
+	AdminNote *string `json:"admin_note,omitempty"`
+}
+
+// Limits on transaction tags: how many one transaction may carry and how long each may be, in characters
+const (
+	MaxTagsPerTransaction = 20
+	MaxTagLength          = 32
+)
+
+// MaxAdminNoteLength is the longest admin note accepted, in characters
+const MaxAdminNoteLength = 1000
+
+// Actions recorded in a transaction's change history. Admin notes are not recorded: they are
+// hidden from the owner, who can read the history.
+const (
+	TransactionChangeUpdate        = "update"
+	TransactionChangeDelete        = "delete"
+	TransactionChangeReceiptUpload = "receipt_upload" // Also when it replaces a receipt
+	TransactionChangeReceiptDelete = "receipt_delete"
+)
+
+// TransactionChange is one entry of a transaction's history: the values it had before
+// an update, deletion or receipt change, and who made the change
+type TransactionChange struct {
+	ID            int64           `json:"id"`
+	TransactionID int64           `json:"transaction_id"`
+	UserID        int             `json:"-"` // Owner of the transaction, for access checks
+	Action        string          `json:"action"`
+	OldValues     json.RawMessage `json:"old_values"` // The transaction as it was before the change
+	ChangedBy     *int            `json:"changed_by"` // null once that user is deleted
+	ChangedAt     time.Time       `json:"changed_at"`
 }
 
 // CreateTransactionRequest is used for creating a new transaction
 type CreateTransactionRequest struct {
-	Amount          int64     `json:"amount" binding:"required,gt=0"`
-	Type            string    `json:"type" binding:"required,oneof=income expense"`
-	Category        string    `json:"category" binding:"required"`
-	Description     *string   `json:"description"`
+	Amount          int64     `json:"amount" binding:"required,gt=0"` // The service enforces config.MaxTransactionAmount
+	Currency        string    `json:"currency"`                       // ISO 4217; defaults to the user's currency
+	Type            string    `json:"type" binding:"required,oneof=income expense transfer"`
+	Category        string    `json:"category" binding:"required_unless=Type transfer"`
+	Merchant        *string   `json:"merchant" binding:"omitempty,max=255"`
+	Description     *string   `json:"description"` // Trimmed; blank is stored as NULL; the service enforces the length
+	Tags            []string  `json:"tags"`        // Format is checked by the service
 	TransactionDate time.Time `json:"transaction_date"`
+	// Transfers only: the accounts money moves from and to
+	FromAccountID *int `json:"from_account_id"`
+	ToAccountID   *int `json:"to_account_id"`
+	// IdempotencyKey comes from the Idempotency-Key header, not the body
+	IdempotencyKey string `json:"-"`
+}
+
+// MaxBatchDeleteSize caps how many transactions one batch delete may name
+const MaxBatchDeleteSize = 500
+
+// BatchDeleteTransactionsRequest names the transactions to delete in one go
+type BatchDeleteTransactionsRequest struct {
+	IDs []int64 `json:"ids" binding:"required,min=1,dive,gt=0"` // The handler enforces MaxBatchDeleteSize
 }
 
 type UpdateTransactionRequest struct {
-	Amount          *int64     `json:"amount,omitempty"` // Pointers to allow partial updates
+	Amount          *int64     `json:"amount,omitempty" binding:"omitempty,gt=0"` // Pointers to allow partial updates; the service enforces the upper bound
+	Currency        *string    `json:"currency,omitempty"`
 	Type            *string    `json:"type,omitempty" binding:"omitempty,oneof=income expense"`
 	Category        *string    `json:"category,omitempty"`
-	Description     *string    `json:"description,omitempty"`
+	Merchant        *string    `json:"merchant,omitempty" binding:"omitempty,max=255"`
+	Description     *string    `json:"description,omitempty"` // "" or blank clears it; the service enforces the length
+	Tags            *[]string  `json:"tags,omitempty"`        // Replaces all tags; [] clears them
 	TransactionDate *time.Time `json:"transaction_date,omitempty"`
+	// Version is the version the client last saw; if it is stale the update is rejected
+	Version *int `json:"version,omitempty"`
 }
 
 // AdminTransactionFilter contains filter parameters for admin transaction queries
 type AdminTransactionFilters struct {
-	UserID    *int
-	StartDate *time.Time
-	EndDate   *time.Time
-	Category  *string
-	Type      *string
+	UserID     *int
+	StartDate  *time.Time
+	EndDate    *time.Time
+	Categories []string // Any of these; empty means all
+	Merchant   *string
+	Tag        *string  // Transactions carrying this tag
+	Types      []string // Any of these; empty means all
+	Search     *string  // Case-insensitive substring of the description
+	MinAmount  *int64
+	MaxAmount  *int64
+	// CreatedAfter/CreatedBefore bound when a transaction was recorded (created_at >= after, < before),
+	// independent of its transaction_date
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	SortBy        string // transaction_date, amount or created_at, else the default order; per-user stats take a UserStatSort value
+	SortOrder     string // asc or desc (default)
+	Page          int    // 1-based; used only when PageSize > 0
+	PageSize      int    // 0 means no pagination
+}
+
+// AdminTransactionPage is one page of the admin transaction listing
+type AdminTransactionPage struct {
+	Data     []Transaction `json:"data"`
+	Page     int           `json:"page"`
+	PageSize int           `json:"page_size"`
+	Total    int64         `json:"total"`
 }
 
 // UserTransactionFilter contains filter parameters for user transaction queries
 type UserTransactionFilters struct {
-	Type      *string
-	Category  *string
-	StartDate *time.Time // For filtering by date (start of day)
-	EndDate   *time.Time // For filtering by date (end of day)
+	Currency   string   // ISO 4217; empty means all. Single-figure statistics default it to the user's currency.
+	Types      []string // Any of these; empty means all
+	Categories []string // Any of these; empty means all
+	Merchant   *string
+	Tag        *string // Transactions carrying this tag
+	Search     *string // Case-insensitive substring of the description
+	MinAmount  *int64
+	MaxAmount  *int64
+	StartDate  *time.Time
+	EndDate    *time.Time
+	// EndDateExclusive makes EndDate an exact exclusive bound (transaction_date < EndDate)
+	EndDateExclusive bool
+	// CreatedAfter/CreatedBefore bound when a transaction was recorded (created_at >= after, < before),
+	// independent of its transaction_date
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	// Limit caps the page size (0 means no limit); Cursor returns the rows after it
+	Limit  int
+	Cursor *TransactionCursor
+	// SortBy (transaction_date, amount or created_at) and SortOrder (asc/desc) order the listing;
+	// cursor pages break ties by id alone
+	SortBy    string
+	SortOrder string
+	// WithSummary also computes a TransactionSummary over all rows matching the filters
+	WithSummary bool
 }
 
-// AggregatedStats represents the statistics for admin
+// ErrInvalidCursor is returned by ParseTransactionCursor for a token it didn't issue
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// TransactionCursor marks the last row of a page by the values it can be sorted on, so the next
+// page starts right after it in whichever order the listing uses
+type TransactionCursor struct {
+	TransactionDate time.Time `json:"d"`
+	CreatedAt       time.Time `json:"c"`
+	Amount          int64     `json:"a"`
+	ID              int64     `json:"id"`
+}
+
+// CursorAfter returns the cursor of a page ending with t
+func CursorAfter(t Transaction) TransactionCursor {
+	return TransactionCursor{TransactionDate: t.TransactionDate, CreatedAt: t.CreatedAt, Amount: t.Amount, ID: t.ID}
+}
+
+// Encode returns the opaque token clients pass back as ?cursor=
+func (c TransactionCursor) Encode() string {
+	data, _ := json.Marshal(c) // A time and an int always marshal
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// ParseTransactionCursor decodes a token made by Encode
+func ParseTransactionCursor(token string) (*TransactionCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c TransactionCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID < 1 || c.TransactionDate.IsZero() {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// TransactionPage is one page of a user's transactions
+type TransactionPage struct {
+	Data       []Transaction       `json:"data"`
+	NextCursor *string             `json:"next_cursor"`       // null on the last page
+	Summary    *TransactionSummary `json:"summary,omitempty"` // Only when requested
+}
+
+// CreateTransactionResponse is returned when a transaction is created; Balance is the user's
+// total income minus total expense in the transaction's currency including the new transaction,
+// omitted if it couldn't be computed
+type CreateTransactionResponse struct {
+	Transaction *Transaction `json:"transaction"`
+	Balance     *int64       `json:"balance,omitempty"`
+}
+
+// TransactionSummary aggregates every transaction matching a listing's filters, not just one page.
+// Amounts are keyed by currency code, like AggregatedStats.
+type TransactionSummary struct {
+	Count        int64            `json:"count"`
+	TotalIncome  map[string]int64 `json:"total_income"` // Currency -> amount
+	TotalExpense map[string]int64 `json:"total_expense"`
+}
+
+// AggregatedStats represents the statistics for admin. Amounts are keyed by currency code,
+// since amounts in different currencies can't be added up. Per-user figures are paged
+// separately, see UserStatPage.
 type AggregatedStats struct {
-	TotalIncome       int64            `json:"total_income"`
-	TotalExpenses     int64            `json:"total_expenses"`
-	Balance           int64            `json:"balance"`
-	ByCategoryIncome  map[string]int64 `json:"by_category_income"`
-	ByCategoryExpense map[string]int64 `json:"by_category_expense"`
-	ByUserSpending    map[int]UserStat `json:"by_user_spending"` // UserID -> Stats
+	TotalIncome       map[string]int64            `json:"total_income"` // Currency -> amount
+	TotalExpenses     map[string]int64            `json:"total_expenses"`
+	Balance           map[string]int64            `json:"balance"`
+	ByCategoryIncome  map[string]map[string]int64 `json:"by_category_income"` // Currency -> category -> amount
+	ByCategoryExpense map[string]map[string]int64 `json:"by_category_expense"`
+}
+
+// UserStats represents a single user's own statistics; unlike AggregatedStats it has no per-user section.
+// The top-level totals and category sums cover only Currency; ByCurrency has the totals of every currency.
+type UserStats struct {
+	Currency          string                    `json:"currency"`
+	TotalIncome       int64                     `json:"total_income"`
+	TotalExpenses     int64                     `json:"total_expenses"`
+	Balance           int64                     `json:"balance"`
+	ByCategoryIncome  map[string]int64          `json:"by_category_income"`
+	ByCategoryExpense map[string]int64          `json:"by_category_expense"`
+	ByCurrency        map[string]CurrencyTotals `json:"by_currency"`
+}
+
+// CurrencyTotals are the totals of the transactions in one currency
+type CurrencyTotals struct {
+	TotalIncome   int64 `json:"total_income"`
+	TotalExpenses int64 `json:"total_expenses"`
+	Balance       int64 `json:"balance"`
 }
 
 type UserStat struct {
-	UserID           int    `json:"user_id"`
-	UserPhone        string `json:"user_phone"` // Added for easier display
-	TotalSpent       int64  `json:"total_spent"`
-	TotalIncome      int64  `json:"total_income"`
-	TransactionCount int64  `json:"transaction_count"`
+	UserID           int              `json:"user_id"`
+	UserPhone        string           `json:"user_phone"`  // Added for easier display
+	TotalSpent       map[string]int64 `json:"total_spent"` // Currency -> amount
+	TotalIncome      map[string]int64 `json:"total_income"`
+	TransactionCount int64            `json:"transaction_count"`
+}
+
+// Orders accepted by the admin per-user statistics. The amount orders rank users by their
+// total in a single currency, since amounts in different currencies can't be compared.
+const (
+	UserStatSortTotalSpent       = "total_spent"
+	UserStatSortTotalIncome      = "total_income"
+	UserStatSortTransactionCount = "transaction_count"
+)
+
+// UserStatPage is one page of the admin per-user statistics
+type UserStatPage struct {
+	Data     []UserStat `json:"data"`
+	Page     int        `json:"page"`
+	PageSize int        `json:"page_size"`
+	Total    int64      `json:"total"` // Users with at least one matching transaction
+}
+
+// TransactionOverview bundles a user's headline numbers for the dashboard header
+type TransactionOverview struct {
+	Currency             string     `json:"currency"` // Every figure covers this currency only
+	Count                int64      `json:"count"`
+	TotalIncome          int64      `json:"total_income"`
+	TotalExpense         int64      `json:"total_expense"`
+	Balance              int64      `json:"balance"`
+	FirstTransactionDate *time.Time `json:"first_transaction_date"` // null when nothing matches
+	LastTransactionDate  *time.Time `json:"last_transaction_date"`
+	DistinctCategories   int64      `json:"distinct_categories"`
+}
+
+// CategoryRank is a category's position in a user's spending ranking
+type CategoryRank struct {
+	Rank       int     `json:"rank"`
+	Category   string  `json:"category"`
+	Amount     int64   `json:"amount"`
+	Count      int64   `json:"count"`
+	Percentage float64 `json:"percentage"` // Share of total expense over the range, 0-100
+}
+
+// CategoryShare is a category's part of a user's income or expense over a date range
+type CategoryShare struct {
+	Category string  `json:"category"`
+	Total    int64   `json:"total"`
+	Percent  float64 `json:"percent"` // Share of the range's total for the type, 0-100 with two decimals
+}
+
+// CategorySuggestion is a likely category for a description, learned from the user's history
+type CategorySuggestion struct {
+	Category   string  `json:"category"`
+	Matches    int64   `json:"matches"`
+	Confidence float64 `json:"confidence"` // Share of matching past transactions, 0-1
+}
+
+// CategoryUsage is how many of a user's transactions use a category
+type CategoryUsage struct {
+	Category string `json:"category"`
+	Count    int64  `json:"count"`
+}
+
+// ImportResult summarizes a CSV import. Imports are all or nothing: when any row
+// fails, Imported is 0 and Errors lists the failing rows.
+type ImportResult struct {
+	Imported int              `json:"imported"`
+	Failed   int              `json:"failed"`
+	Errors   []ImportRowError `json:"errors"`
+}
+
+// ImportRowError explains why one CSV row (1-based, counting the header) was rejected
+type ImportRowError struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+// Time series granularities, named after their PostgreSQL date_trunc units
+const (
+	GranularityDay   = "day"
+	GranularityWeek  = "week"
+	GranularityMonth = "month"
+)
+
+// TimeSeriesPoint holds a user's totals for one period starting at Period (UTC)
+type TimeSeriesPoint struct {
+	Period  time.Time `json:"period"`
+	Income  int64     `json:"income"`
+	Expense int64     `json:"expense"`
+}
+
+// ReimbursableTag marks expenses to be paid back to the user, e.g. by their employer
+const ReimbursableTag = "reimbursable"
+
+// ReimbursableTotal is one user's reimbursable spending in one currency
+type ReimbursableTotal struct {
+	UserID            int    `json:"user_id"`
+	Phone             string `json:"phone"`
+	Currency          string `json:"currency"`
+	TotalReimbursable int64  `json:"total_reimbursable"`
+	Count             int64  `json:"count"`
+}
+
+// MerchantSpend is the total a user spent at a single merchant
+type MerchantSpend struct {
+	Merchant   string `json:"merchant"`
+	TotalSpent int64  `json:"total_spent"`
+	Count      int64  `json:"count"`
 }
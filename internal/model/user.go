@@ -13,5 +13,16 @@ type User struct {
 	Phone        string    `json:"phone"`
 	PasswordHash string    `json:"-"` // Do not expose password hash in JSON responses
 	Role         string    `json:"role"`
+	Currency     string    `json:"currency"` // ISO 4217 default for new transactions
 	CreatedAt    time.Time `json:"created_at"`
+	// MonthlyAlertThreshold is the month's expense total, in the user's currency, above which
+	// they are warned; nil when no alert is set
+	MonthlyAlertThreshold *int64 `json:"monthly_alert_threshold"`
+}
+
+// SpendingAlert compares a user's expenses this calendar month (UTC) with their alert threshold
+type SpendingAlert struct {
+	Threshold     *int64 `json:"monthly_alert_threshold"`
+	MonthSpent    int64  `json:"month_spent"` // In the user's currency only
+	OverThreshold bool   `json:"over_threshold"`
 }
\ No newline at end of file
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"expense_tracker/internal/model"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	// ErrDuplicateAccount is returned when a user already has an account with the same name
+	ErrDuplicateAccount = errors.New("account already exists")
+	// ErrAccountNotFound is returned when a transfer names an account the user doesn't own
+	ErrAccountNotFound = errors.New("account not found")
+)
+
+// AccountRepository defines operations for user accounts (wallets)
+type AccountRepository interface {
+	Create(ctx context.Context, account *model.Account) error
+	FindByUser(ctx context.Context, userID int) ([]model.Account, error)
+	FindByID(ctx context.Context, id int, userID int) (*model.Account, error)
+	Rename(ctx context.Context, account *model.Account) (bool, error)
+	Delete(ctx context.Context, id int, userID int) (bool, error)
+}
+
+type accountRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewAccountRepository creates a new AccountRepository
+func NewAccountRepository(db *pgxpool.Pool) AccountRepository {
+	return &accountRepository{db: db}
+}
+
+const accountColumns = `id, user_id, name, balance, created_at, updated_at`
+
+func scanAccount(row pgx.Row, a *model.Account) error {
+	return row.Scan(&a.ID, &a.UserID, &a.Name, &a.Balance, &a.CreatedAt, &a.UpdatedAt)
+}
+
+// isUniqueViolation reports whether err comes from a unique index
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation
+}
+
+// Create inserts a new account
+func (r *accountRepository) Create(ctx context.Context, account *model.Account) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	sql := `INSERT INTO accounts (user_id, name, balance) VALUES ($1, $2, $3) RETURNING id, created_at, updated_at`
+	err := r.db.QueryRow(ctx, sql, account.UserID, account.Name, account.Balance).Scan(&account.ID, &account.CreatedAt, &account.UpdatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrDuplicateAccount
+		}
+		return fmt.Errorf("failed to create account: %w", err)
+	}
+	return nil
+}
+
+// FindByUser lists a user's accounts by name
+func (r *accountRepository) FindByUser(ctx context.Context, userID int) ([]model.Account, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.db.Query(ctx, `SELECT `+accountColumns+` FROM accounts WHERE user_id = $1 ORDER BY LOWER(name), id`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list accounts: %w", err)
+	}
+	defer rows.Close()
+
+	accounts := []model.Account{}
+	for rows.Next() {
+		var a model.Account
+		if err := scanAccount(rows, &a); err != nil {
+			return nil, fmt.Errorf("failed to scan account: %w", err)
+		}
+		accounts = append(accounts, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating accounts: %w", err)
+	}
+	return accounts, nil
+}
+
+// FindByID retrieves one of the user's accounts
+func (r *accountRepository) FindByID(ctx context.Context, id int, userID int) (*model.Account, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	account := &model.Account{}
+	sql := `SELECT ` + accountColumns + ` FROM accounts WHERE id = $1 AND user_id = $2`
+	if err := scanAccount(r.db.QueryRow(ctx, sql, id, userID), account); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil // Not found
+		}
+		return nil, fmt.Errorf("failed to find account: %w", err)
+	}
+	return account, nil
+}
+
+// Rename changes an account's name and reloads it, reporting false if the user has no such account
+func (r *accountRepository) Rename(ctx context.Context, account *model.Account) (bool, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	sql := `UPDATE accounts SET name = $1, updated_at = NOW() WHERE id = $2 AND user_id = $3 RETURNING ` + accountColumns
+	if err := scanAccount(r.db.QueryRow(ctx, sql, account.Name, account.ID, account.UserID), account); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		if isUniqueViolation(err) {
+			return false, ErrDuplicateAccount
+		}
+		return false, fmt.Errorf("failed to rename account: %w", err)
+	}
+	return true, nil
+}
+
+// Delete removes one of the user's accounts, reporting false if it doesn't exist.
+// Transfers that used it stay, with the account reference cleared.
+func (r *accountRepository) Delete(ctx context.Context, id int, userID int) (bool, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	cmdTag, err := r.db.Exec(ctx, `DELETE FROM accounts WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete account: %w", err)
+	}
+	return cmdTag.RowsAffected() > 0, nil
+}
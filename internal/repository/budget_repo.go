@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"expense_tracker/internal/model"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// BudgetRepository defines operations for monthly category budgets
+type BudgetRepository interface {
+	Upsert(ctx context.Context, budget *model.Budget) error
+	GetStatus(ctx context.Context, userID int, currency, month string, monthStart, monthEnd time.Time) ([]model.BudgetStatus, error)
+}
+
+type budgetRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewBudgetRepository creates a new BudgetRepository
+func NewBudgetRepository(db *pgxpool.Pool) BudgetRepository {
+	return &budgetRepository{db: db}
+}
+
+// Upsert creates the budget for a category and month, or replaces its limit
+func (r *budgetRepository) Upsert(ctx context.Context, budget *model.Budget) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	sql := `INSERT INTO budgets (user_id, category, month, limit_amount)
+            VALUES ($1, $2, to_date($3, 'YYYY-MM'), $4)
+            ON CONFLICT (user_id, category, month)
+            DO UPDATE SET limit_amount = EXCLUDED.limit_amount, updated_at = NOW()
+            RETURNING id, created_at, updated_at`
+	err := r.db.QueryRow(ctx, sql, budget.UserID, budget.Category, budget.Month, budget.LimitAmount).
+		Scan(&budget.ID, &budget.CreatedAt, &budget.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save budget: %w", err)
+	}
+	return nil
+}
+
+// GetStatus returns each of the user's budgets for month (YYYY-MM) with the expenses in currency
+// recorded in [monthStart, monthEnd) for its category, compared case-insensitively. Remaining and
+// OverBudget are left to the caller.
+func (r *budgetRepository) GetStatus(ctx context.Context, userID int, currency, month string, monthStart, monthEnd time.Time) ([]model.BudgetStatus, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	sql := `SELECT b.category, b.limit_amount, COALESCE(SUM(t.amount), 0)
+            FROM budgets b
+            LEFT JOIN transactions t ON t.user_id = b.user_id
+                AND LOWER(t.category) = LOWER(b.category)
+                AND t.type = 'expense'
+                AND t.transaction_date >= $3 AND t.transaction_date < $4
+                AND t.currency = $5
+            WHERE b.user_id = $1 AND b.month = to_date($2, 'YYYY-MM')
+            GROUP BY b.id, b.category, b.limit_amount
+            ORDER BY b.category`
+	rows, err := r.db.Query(ctx, sql, userID, month, monthStart, monthEnd, currency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query budget status: %w", err)
+	}
+	defer rows.Close()
+
+	statuses := []model.BudgetStatus{}
+	for rows.Next() {
+		s := model.BudgetStatus{Currency: currency}
+		if err := rows.Scan(&s.Category, &s.LimitAmount, &s.Spent); err != nil {
+			return nil, fmt.Errorf("failed to scan budget status row: %w", err)
+		}
+		statuses = append(statuses, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating budget status rows: %w", err)
+	}
+	return statuses, nil
+}
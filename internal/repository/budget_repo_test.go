@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"expense_tracker/internal/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBudgetStatus_MatchesCategoryIgnoringCase(t *testing.T) {
+	pool := newTestDB(t)
+	users := NewUserRepository(pool)
+	transactions := NewTransactionRepository(pool, nil)
+	budgets := NewBudgetRepository(pool)
+	ctx := context.Background()
+
+	phone := fmt.Sprintf("+998%09d", time.Now().UnixNano()%1000000000)
+	t.Cleanup(func() { pool.Exec(ctx, `DELETE FROM users WHERE phone = $1`, phone) })
+	user := &model.User{Phone: phone, PasswordHash: "hash", Role: model.RoleUser, CreatedAt: time.Now()}
+	assert.NoError(t, users.Create(ctx, user))
+
+	assert.NoError(t, budgets.Upsert(ctx, &model.Budget{UserID: user.ID, Category: "Food", Month: "2024-05", LimitAmount: 100000}))
+	tx := &model.Transaction{UserID: user.ID, Amount: 30000, Currency: "UZS", Type: model.TransactionTypeExpense, Category: "food",
+		TransactionDate: time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC), CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	assert.NoError(t, transactions.Create(ctx, tx))
+
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	statuses, err := budgets.GetStatus(ctx, user.ID, "UZS", "2024-05", start, start.AddDate(0, 1, 0))
+	assert.NoError(t, err)
+	if assert.Len(t, statuses, 1) {
+		assert.Equal(t, int64(30000), statuses[0].Spent)
+	}
+}
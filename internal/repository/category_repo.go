@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"expense_tracker/internal/model"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrDuplicateCategory is returned when a user already has a category with the same name and type
+var ErrDuplicateCategory = errors.New("category already exists")
+
+// CategoryRepository defines operations for transaction categories
+type CategoryRepository interface {
+	List(ctx context.Context, userID int) ([]model.Category, error)
+	FindByName(ctx context.Context, userID int, name string) ([]model.Category, error)
+	Create(ctx context.Context, category *model.Category) error
+	Delete(ctx context.Context, id int, userID int) (bool, error)
+}
+
+type categoryRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewCategoryRepository creates a new CategoryRepository
+func NewCategoryRepository(db *pgxpool.Pool) CategoryRepository {
+	return &categoryRepository{db: db}
+}
+
+// queryCategories runs a categories query and scans all rows
+func (r *categoryRepository) queryCategories(ctx context.Context, sql string, args ...interface{}) ([]model.Category, error) {
+	rows, err := queryWithRetry(ctx, r.db, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []model.Category{}
+	for rows.Next() {
+		var c model.Category
+		if err := rows.Scan(&c.ID, &c.UserID, &c.Name, &c.Type, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		categories = append(categories, c)
+	}
+	return categories, rows.Err()
+}
+
+// List returns the global default categories plus the user's own, ordered by type and name
+func (r *categoryRepository) List(ctx context.Context, userID int) ([]model.Category, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	sql := `SELECT id, user_id, name, type, created_at FROM categories
+            WHERE user_id IS NULL OR user_id = $1
+            ORDER BY type, LOWER(name), user_id NULLS FIRST`
+	categories, err := r.queryCategories(ctx, sql, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list categories: %w", err)
+	}
+	return categories, nil
+}
+
+// FindByName returns the categories visible to the user whose name matches case-insensitively.
+// A name can match one category per type, e.g. the global income and expense "Other".
+func (r *categoryRepository) FindByName(ctx context.Context, userID int, name string) ([]model.Category, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	sql := `SELECT id, user_id, name, type, created_at FROM categories
+            WHERE (user_id IS NULL OR user_id = $1) AND LOWER(name) = LOWER($2)
+            ORDER BY user_id NULLS LAST`
+	categories, err := r.queryCategories(ctx, sql, userID, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find category by name: %w", err)
+	}
+	return categories, nil
+}
+
+// Create inserts a user's custom category
+func (r *categoryRepository) Create(ctx context.Context, category *model.Category) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	sql := `INSERT INTO categories (user_id, name, type) VALUES ($1, $2, $3) RETURNING id, created_at`
+	err := r.db.QueryRow(ctx, sql, category.UserID, category.Name, category.Type).Scan(&category.ID, &category.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return ErrDuplicateCategory
+		}
+		return fmt.Errorf("failed to create category: %w", err)
+	}
+	return nil
+}
+
+// Delete removes one of the user's own categories. Global defaults can't be deleted.
+// It reports false if no such category belongs to the user.
+func (r *categoryRepository) Delete(ctx context.Context, id int, userID int) (bool, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	cmdTag, err := r.db.Exec(ctx, `DELETE FROM categories WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete category: %w", err)
+	}
+	return cmdTag.RowsAffected() > 0, nil
+}
@@ -0,0 +1,189 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"expense_tracker/internal/model"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RecurringRepository defines operations for recurring transaction rules
+type RecurringRepository interface {
+	Create(ctx context.Context, rule *model.RecurringTransaction) error
+	FindByUser(ctx context.Context, userID int) ([]model.RecurringTransaction, error)
+	FindByID(ctx context.Context, id int, userID int) (*model.RecurringTransaction, error)
+	Update(ctx context.Context, rule *model.RecurringTransaction) error
+	Delete(ctx context.Context, id int, userID int) (bool, error)
+	FindDue(ctx context.Context, asOf time.Time) ([]model.RecurringTransaction, error)
+	Materialize(ctx context.Context, rule *model.RecurringTransaction, transactionDate, nextRunDate time.Time) (*model.Transaction, error)
+}
+
+type recurringRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewRecurringRepository creates a new RecurringRepository
+func NewRecurringRepository(db *pgxpool.Pool) RecurringRepository {
+	return &recurringRepository{db: db}
+}
+
+const recurringColumns = `id, user_id, amount, type, category, description, interval, next_run_date, anchor_day, active, created_at, updated_at`
+
+func scanRecurring(row pgx.Row, r *model.RecurringTransaction) error {
+	return row.Scan(&r.ID, &r.UserID, &r.Amount, &r.Type, &r.Category, &r.Description,
+		&r.Interval, &r.NextRunDate, &r.AnchorDay, &r.Active, &r.CreatedAt, &r.UpdatedAt)
+}
+
+func (r *recurringRepository) queryRules(ctx context.Context, sql string, args ...interface{}) ([]model.RecurringTransaction, error) {
+	rows, err := r.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []model.RecurringTransaction{}
+	for rows.Next() {
+		var rule model.RecurringTransaction
+		if err := scanRecurring(rows, &rule); err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// Create inserts a new recurring rule
+func (r *recurringRepository) Create(ctx context.Context, rule *model.RecurringTransaction) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	sql := `INSERT INTO recurring_transactions (user_id, amount, type, category, description, interval, next_run_date, anchor_day, active)
+            VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id, created_at, updated_at`
+	err := r.db.QueryRow(ctx, sql, rule.UserID, rule.Amount, rule.Type, rule.Category, rule.Description,
+		rule.Interval, rule.NextRunDate, rule.AnchorDay, rule.Active).Scan(&rule.ID, &rule.CreatedAt, &rule.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create recurring transaction: %w", err)
+	}
+	return nil
+}
+
+// FindByUser lists a user's recurring rules, soonest first
+func (r *recurringRepository) FindByUser(ctx context.Context, userID int) ([]model.RecurringTransaction, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	sql := `SELECT ` + recurringColumns + ` FROM recurring_transactions WHERE user_id = $1 ORDER BY next_run_date, id`
+	rules, err := r.queryRules(ctx, sql, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list recurring transactions: %w", err)
+	}
+	return rules, nil
+}
+
+// FindByID retrieves one of the user's recurring rules
+func (r *recurringRepository) FindByID(ctx context.Context, id int, userID int) (*model.RecurringTransaction, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rule := &model.RecurringTransaction{}
+	sql := `SELECT ` + recurringColumns + ` FROM recurring_transactions WHERE id = $1 AND user_id = $2`
+	if err := scanRecurring(r.db.QueryRow(ctx, sql, id, userID), rule); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil // Not found
+		}
+		return nil, fmt.Errorf("failed to find recurring transaction: %w", err)
+	}
+	return rule, nil
+}
+
+// Update saves changes to a recurring rule
+func (r *recurringRepository) Update(ctx context.Context, rule *model.RecurringTransaction) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	sql := `UPDATE recurring_transactions
+            SET amount = $1, category = $2, description = $3, interval = $4, next_run_date = $5, anchor_day = $6, active = $7, updated_at = NOW()
+            WHERE id = $8 AND user_id = $9 RETURNING updated_at`
+	err := r.db.QueryRow(ctx, sql, rule.Amount, rule.Category, rule.Description, rule.Interval,
+		rule.NextRunDate, rule.AnchorDay, rule.Active, rule.ID, rule.UserID).Scan(&rule.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update recurring transaction: %w", err)
+	}
+	return nil
+}
+
+// Delete removes one of the user's recurring rules, reporting false if it doesn't exist
+func (r *recurringRepository) Delete(ctx context.Context, id int, userID int) (bool, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	cmdTag, err := r.db.Exec(ctx, `DELETE FROM recurring_transactions WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete recurring transaction: %w", err)
+	}
+	return cmdTag.RowsAffected() > 0, nil
+}
+
+// FindDue returns active rules whose next run date is on or before asOf
+func (r *recurringRepository) FindDue(ctx context.Context, asOf time.Time) ([]model.RecurringTransaction, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	sql := `SELECT ` + recurringColumns + ` FROM recurring_transactions
+            WHERE active AND next_run_date <= $1 ORDER BY next_run_date, id`
+	rules, err := r.queryRules(ctx, sql, asOf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find due recurring transactions: %w", err)
+	}
+	return rules, nil
+}
+
+// Materialize creates the transaction for the rule's current run and advances it to nextRunDate,
+// atomically, and returns the stored transaction. The advance only applies if next_run_date is
+// still what the caller read, so a concurrent or overlapping run that already claimed this
+// occurrence makes it return nil without inserting anything.
+func (r *recurringRepository) Materialize(ctx context.Context, rule *model.RecurringTransaction, transactionDate, nextRunDate time.Time) (*model.Transaction, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var materialized *model.Transaction
+	err := WithTx(ctx, r.db, func(tx pgx.Tx) error {
+		cmdTag, err := tx.Exec(ctx, `UPDATE recurring_transactions SET next_run_date = $1, updated_at = NOW()
+            WHERE id = $2 AND next_run_date = $3 AND active`, nextRunDate, rule.ID, rule.NextRunDate)
+		if err != nil {
+			return fmt.Errorf("failed to advance recurring transaction: %w", err)
+		}
+		if cmdTag.RowsAffected() == 0 {
+			return nil // Already claimed by another run, or deactivated meanwhile
+		}
+
+		t := &model.Transaction{
+			UserID:          rule.UserID,
+			Amount:          rule.Amount,
+			Type:            rule.Type,
+			Category:        rule.Category,
+			Description:     rule.Description,
+			Tags:            []string{},
+			TransactionDate: transactionDate,
+		}
+		// Occurrences are recorded in the user's currency
+		err = tx.QueryRow(ctx, `INSERT INTO transactions (user_id, amount, type, category, description, transaction_date, currency, created_at, updated_at)
+            VALUES ($1, $2, $3, $4, $5, $6, (SELECT currency FROM users WHERE id = $1), NOW(), NOW())
+            RETURNING id, currency, created_at, updated_at, version`,
+			t.UserID, t.Amount, t.Type, t.Category, t.Description, t.TransactionDate).Scan(&t.ID, &t.Currency, &t.CreatedAt, &t.UpdatedAt, &t.Version)
+		if err != nil {
+			return fmt.Errorf("failed to insert recurring transaction occurrence: %w", err)
+		}
+		materialized = t
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return materialized, nil
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"expense_tracker/internal/model"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RefreshTokenRepository defines operations for refresh token data
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, token *model.RefreshToken) error
+	FindByHash(ctx context.Context, tokenHash string) (*model.RefreshToken, error)
+	Revoke(ctx context.Context, id int64) (bool, error)
+	RevokeAllForUser(ctx context.Context, userID int) error
+}
+
+type refreshTokenRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewRefreshTokenRepository creates a new RefreshTokenRepository
+func NewRefreshTokenRepository(db *pgxpool.Pool) RefreshTokenRepository {
+	return &refreshTokenRepository{db: db}
+}
+
+// Create stores a new refresh token
+func (r *refreshTokenRepository) Create(ctx context.Context, t *model.RefreshToken) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	sql := `INSERT INTO refresh_tokens (user_id, token_hash, expires_at)
+            VALUES ($1, $2, $3) RETURNING id, revoked, created_at`
+	err := r.db.QueryRow(ctx, sql, t.UserID, t.TokenHash, t.ExpiresAt).Scan(&t.ID, &t.Revoked, &t.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create refresh token: %w", err)
+	}
+	return nil
+}
+
+// FindByHash retrieves a refresh token by the hash of its value
+func (r *refreshTokenRepository) FindByHash(ctx context.Context, tokenHash string) (*model.RefreshToken, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	t := &model.RefreshToken{}
+	sql := `SELECT id, user_id, token_hash, expires_at, revoked, created_at FROM refresh_tokens WHERE token_hash = $1`
+	err := r.db.QueryRow(ctx, sql, tokenHash).Scan(&t.ID, &t.UserID, &t.TokenHash, &t.ExpiresAt, &t.Revoked, &t.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil // Not found
+		}
+		return nil, fmt.Errorf("failed to find refresh token: %w", err)
+	}
+	return t, nil
+}
+
+// Revoke marks a token as revoked. It reports false if the token was already revoked,
+// which lets concurrent rotations of the same token be detected as reuse.
+func (r *refreshTokenRepository) Revoke(ctx context.Context, id int64) (bool, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	sql := `UPDATE refresh_tokens SET revoked = TRUE WHERE id = $1 AND revoked = FALSE`
+	cmdTag, err := r.db.Exec(ctx, sql, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke refresh token: %w", err)
+	}
+	return cmdTag.RowsAffected() == 1, nil
+}
+
+// RevokeAllForUser revokes every outstanding refresh token of a user
+func (r *refreshTokenRepository) RevokeAllForUser(ctx context.Context, userID int) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	sql := `UPDATE refresh_tokens SET revoked = TRUE WHERE user_id = $1 AND revoked = FALSE`
+	if _, err := r.db.Exec(ctx, sql, userID); err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens for user: %w", err)
+	}
+	return nil
+}
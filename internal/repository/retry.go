@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// maxQueryRetries is how many times a failed read is retried after the first attempt
+const maxQueryRetries = 2
+
+// retryBackoff is the wait before the first retry; it doubles for each further one
+var retryBackoff = 50 * time.Millisecond
+
+// isRetriable reports whether err is a transient failure worth retrying: a dropped or refused
+// connection (e.g. during a rolling database restart) or a serialization failure. Cancellation,
+// timeouts and anything the query itself got wrong, like constraint violations, are not.
+func isRetriable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case pgerrcode.SerializationFailure, pgerrcode.AdminShutdown, pgerrcode.CannotConnectNow:
+			return true
+		}
+		return pgerrcode.IsConnectionException(pgErr.Code)
+	}
+
+	var connectErr *pgconn.ConnectError
+	return pgconn.SafeToRetry(err) || errors.As(err, &connectErr) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// withRetry runs fn, retrying it up to maxQueryRetries times with a short backoff while it fails
+// with a retriable error. Only use it for idempotent reads.
+func withRetry(ctx context.Context, fn func() error) error {
+	backoff := retryBackoff
+	err := fn()
+	for attempt := 0; attempt < maxQueryRetries && isRetriable(err); attempt++ {
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		err = fn()
+	}
+	return err
+}
+
+// queryWithRetry is db.Query for idempotent reads, retried on transient errors. Only sending the
+// query is retried; a failure while reading the rows is returned as is.
+func queryWithRetry(ctx context.Context, db *pgxpool.Pool, sql string, args ...interface{}) (pgx.Rows, error) {
+	var rows pgx.Rows
+	err := withRetry(ctx, func() error {
+		var err error
+		rows, err = db.Query(ctx, sql, args...)
+		return err
+	})
+	return rows, err
+}
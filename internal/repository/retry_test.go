@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsRetriable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"serialization failure", &pgconn.PgError{Code: pgerrcode.SerializationFailure}, true},
+		{"admin shutdown", &pgconn.PgError{Code: pgerrcode.AdminShutdown}, true},
+		{"connection failure", &pgconn.PgError{Code: pgerrcode.ConnectionFailure}, true},
+		{"connection closed", fmt.Errorf("read: %w", io.ErrUnexpectedEOF), true},
+		{"unique violation", &pgconn.PgError{Code: pgerrcode.UniqueViolation}, false},
+		{"no rows", pgx.ErrNoRows, false},
+		{"cancelled", context.Canceled, false},
+		{"timed out", fmt.Errorf("query: %w", context.DeadlineExceeded), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isRetriable(tt.err))
+		})
+	}
+}
+
+func withFastBackoff(t *testing.T) {
+	t.Helper()
+	previous := retryBackoff
+	retryBackoff = time.Millisecond
+	t.Cleanup(func() { retryBackoff = previous })
+}
+
+func TestWithRetry_RecoversFromTransientError(t *testing.T) {
+	withFastBackoff(t)
+	calls := 0
+	err := withRetry(context.Background(), func() error {
+		calls++
+		if calls < 3 {
+			return &pgconn.PgError{Code: pgerrcode.AdminShutdown}
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+}
+
+func TestWithRetry_GivesUpAfterMaxRetries(t *testing.T) {
+	withFastBackoff(t)
+	calls := 0
+	transient := &pgconn.PgError{Code: pgerrcode.SerializationFailure}
+	err := withRetry(context.Background(), func() error {
+		calls++
+		return transient
+	})
+	assert.ErrorIs(t, err, transient)
+	assert.Equal(t, 1+maxQueryRetries, calls)
+}
+
+func TestWithRetry_DoesNotRetryPermanentErrors(t *testing.T) {
+	withFastBackoff(t)
+	calls := 0
+	err := withRetry(context.Background(), func() error {
+		calls++
+		return &pgconn.PgError{Code: pgerrcode.UniqueViolation}
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestWithRetry_StopsWhenContextDone(t *testing.T) {
+	withFastBackoff(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	calls := 0
+	err := withRetry(ctx, func() error {
+		calls++
+		return io.ErrUnexpectedEOF
+	})
+	assert.True(t, errors.Is(err, io.ErrUnexpectedEOF))
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultQueryTimeout bounds a repository call when DB_QUERY_TIMEOUT_SECONDS isn't set
+const DefaultQueryTimeout = 5 * time.Second
+
+var queryTimeout = DefaultQueryTimeout
+
+// SetQueryTimeout sets the deadline applied to every repository call; call it once at startup.
+// Calls that run out of time fail with an error wrapping context.DeadlineExceeded.
+func SetQueryTimeout(d time.Duration) {
+	if d > 0 {
+		queryTimeout = d
+	}
+}
+
+// withQueryTimeout derives the context a repository call runs its queries under. The deadline
+// covers the whole call, so multi-query methods share one budget rather than one per query.
+func withQueryTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, queryTimeout)
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"expense_tracker/internal/model"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+)
+
+// newStuckPool returns a pool pointed at a server that accepts connections but never answers,
+// like a database stuck behind a lock or a dead network path
+func newStuckPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { conn.Close() })
+		}
+	}()
+
+	pool, err := pgxpool.New(context.Background(), "postgres://user:pass@"+ln.Addr().String()+"/db?sslmode=disable")
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+func TestQueryTimeout_ExpiredContextReturnsPromptly(t *testing.T) {
+	repo := NewTransactionRepository(newStuckPool(t), nil)
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	start := time.Now()
+	_, err := repo.GetAggregatedStats(ctx, model.AdminTransactionFilters{})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestQueryTimeout_BoundsStuckQuery(t *testing.T) {
+	SetQueryTimeout(100 * time.Millisecond)
+	defer SetQueryTimeout(DefaultQueryTimeout)
+	repo := NewUserRepository(newStuckPool(t))
+
+	start := time.Now()
+	_, err := repo.FindByID(context.Background(), 1)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 2*time.Second)
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// TokenBlacklistRepository defines operations for revoked access tokens
+type TokenBlacklistRepository interface {
+	Add(ctx context.Context, jti string, expiresAt time.Time) error
+	IsBlacklisted(ctx context.Context, jti string) (bool, error)
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+type tokenBlacklistRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewTokenBlacklistRepository creates a new TokenBlacklistRepository
+func NewTokenBlacklistRepository(db *pgxpool.Pool) TokenBlacklistRepository {
+	return &tokenBlacklistRepository{db: db}
+}
+
+// Add blacklists a token ID until its natural expiry
+func (r *tokenBlacklistRepository) Add(ctx context.Context, jti string, expiresAt time.Time) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	sql := `INSERT INTO token_blacklist (jti, expires_at) VALUES ($1, $2) ON CONFLICT (jti) DO NOTHING`
+	if _, err := r.db.Exec(ctx, sql, jti, expiresAt); err != nil {
+		return fmt.Errorf("failed to blacklist token: %w", err)
+	}
+	return nil
+}
+
+// IsBlacklisted reports whether a token ID has been revoked
+func (r *tokenBlacklistRepository) IsBlacklisted(ctx context.Context, jti string) (bool, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var exists bool
+	sql := `SELECT EXISTS (SELECT 1 FROM token_blacklist WHERE jti = $1)`
+	if err := r.db.QueryRow(ctx, sql, jti).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check token blacklist: %w", err)
+	}
+	return exists, nil
+}
+
+// DeleteExpired removes entries for tokens that have expired anyway
+func (r *tokenBlacklistRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	cmdTag, err := r.db.Exec(ctx, `DELETE FROM token_blacklist WHERE expires_at < NOW()`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired blacklist entries: %w", err)
+	}
+	return cmdTag.RowsAffected(), nil
+}
@@ -4,56 +4,219 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
+	"slices"
 	"strings"
 	"time"
 
 	"expense_tracker/internal/model"
+	"expense_tracker/internal/tracing"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+var (
+	// ErrVersionConflict is returned by Update when the transaction changed since it was read
+	ErrVersionConflict = errors.New("transaction was modified concurrently")
+	// ErrDuplicateIdempotencyKey is returned by CreateIdempotent when the key is already in use
+	ErrDuplicateIdempotencyKey = errors.New("idempotency key already used")
+)
+
 // TransactionRepository defines operations for transaction data
 type TransactionRepository interface {
 	Create(ctx context.Context, transaction *model.Transaction) error
+	CreateTransfer(ctx context.Context, transfer *model.Transaction) error
+	CreateIdempotent(ctx context.Context, transaction *model.Transaction, key string, expiredBefore time.Time) error
+	FindByIdempotencyKey(ctx context.Context, userID int, key string, expiredBefore time.Time) (*model.Transaction, error)
+	DeleteExpiredIdempotencyKeys(ctx context.Context, expiredBefore time.Time) (int64, error)
+	CreateBatch(ctx context.Context, transactions []model.Transaction) error
 	FindByID(ctx context.Context, id int64) (*model.Transaction, error)
 	FindByUser(ctx context.Context, userID int, filters model.UserTransactionFilters) ([]model.Transaction, error)
-	Update(ctx context.Context, transaction *model.Transaction) error
-	Delete(ctx context.Context, id int64) error
-	UpdateReceiptPath(ctx context.Context, id int64, receiptPath string) error
-	FindAll(ctx context.Context, filters model.AdminTransactionFilters) ([]model.Transaction, error)
+	Update(ctx context.Context, transaction *model.Transaction, change *model.TransactionChange) error
+	Delete(ctx context.Context, id int64, change *model.TransactionChange) error
+	DeleteTransfer(ctx context.Context, transfer *model.Transaction, change *model.TransactionChange) error
+	DeleteMany(ctx context.Context, ids []int64, ownerID *int, newChange func(*model.Transaction) (*model.TransactionChange, error)) ([]model.Transaction, error)
+	FindHistory(ctx context.Context, transactionID int64) ([]model.TransactionChange, error)
+	UpdateReceiptPath(ctx context.Context, id int64, receiptPath, receiptName string, change *model.TransactionChange) error
+	ClearReceiptPath(ctx context.Context, id int64, change *model.TransactionChange) error
+	ListReceiptPaths(ctx context.Context) ([]string, error)
+	SetAdminNote(ctx context.Context, id int64, note *string) (bool, error)
+	FindAll(ctx context.Context, filters model.AdminTransactionFilters) ([]model.Transaction, int64, error)
 	GetAggregatedStats(ctx context.Context, filters model.AdminTransactionFilters) (*model.AggregatedStats, error)
+	GetUserStatsAdmin(ctx context.Context, filters model.AdminTransactionFilters, currency string) ([]model.UserStat, int64, error)
+	GetUserStats(ctx context.Context, userID int, filters model.UserTransactionFilters) (*model.UserStats, error)
+	GetUserOverview(ctx context.Context, userID int, filters model.UserTransactionFilters) (*model.TransactionOverview, error)
+	SummarizeByUser(ctx context.Context, userID int, filters model.UserTransactionFilters) (*model.TransactionSummary, error)
+	GetBalance(ctx context.Context, userID int, currency string) (int64, error)
+	SumExpenses(ctx context.Context, userID int, currency string, from, to time.Time) (int64, error)
+	GetUserTimeSeries(ctx context.Context, userID int, currency, granularity string, start, end time.Time) ([]model.TimeSeriesPoint, error)
+	GetCategoryRanking(ctx context.Context, userID int, filters model.UserTransactionFilters, limit int) ([]model.CategoryRank, error)
+	GetCategoryTotals(ctx context.Context, userID int, filters model.UserTransactionFilters) ([]model.CategoryShare, error)
+	GetTopCategoriesAdmin(ctx context.Context, filters model.AdminTransactionFilters, currency string, limit int) ([]model.CategoryRank, error)
+	GetReimbursableTotals(ctx context.Context, filters model.AdminTransactionFilters) ([]model.ReimbursableTotal, error)
+	SuggestCategories(ctx context.Context, userID int, patterns []string, limit int) ([]model.CategorySuggestion, error)
+	GetRecentCategories(ctx context.Context, userID int, limit int) ([]model.CategoryUsage, error)
+	GetTopMerchants(ctx context.Context, userID int, filters model.UserTransactionFilters, limit int) ([]model.MerchantSpend, error)
+}
+
+// transactionColumns lists the columns read into model.Transaction, in scanTransaction order
+const transactionColumns = `id, user_id, amount, currency, type, category, merchant, description, tags, transaction_date, receipt_path, receipt_name, created_at, updated_at, version, from_account_id, to_account_id`
+
+// adminTransactionColumns adds the admin-only columns to transactionColumns, in scanAdminTransaction order
+const adminTransactionColumns = transactionColumns + `, admin_note`
+
+// scanAdminTransaction reads a row selected with adminTransactionColumns
+func scanAdminTransaction(row pgx.Row, t *model.Transaction) error {
+	return row.Scan(
+		&t.ID, &t.UserID, &t.Amount, &t.Currency, &t.Type, &t.Category, &t.Merchant, &t.Description, &t.Tags,
+		&t.TransactionDate, &t.ReceiptPath, &t.ReceiptName, &t.CreatedAt, &t.UpdatedAt, &t.Version,
+		&t.FromAccountID, &t.ToAccountID, &t.AdminNote,
+	)
+}
+
+// scanTransaction reads a row selected with transactionColumns
+func scanTransaction(row pgx.Row, t *model.Transaction) error {
+	return row.Scan(
+		&t.ID, &t.UserID, &t.Amount, &t.Currency, &t.Type, &t.Category, &t.Merchant, &t.Description, &t.Tags,
+		&t.TransactionDate, &t.ReceiptPath, &t.ReceiptName, &t.CreatedAt, &t.UpdatedAt, &t.Version,
+		&t.FromAccountID, &t.ToAccountID,
+	)
 }
 
 type transactionRepository struct {
-	db *pgxpool.Pool
+	db      *pgxpool.Pool
+	replica *pgxpool.Pool // Read-only; nil when there is no replica
+}
+
+// NewTransactionRepository creates a new TransactionRepository. The heavy read-only queries
+// (listings and admin stats) go to replica when it is not nil; everything else uses db.
+func NewTransactionRepository(db, replica *pgxpool.Pool) TransactionRepository {
+	return &transactionRepository{db: db, replica: replica}
+}
+
+// readDB returns the pool for heavy reads: the replica if configured, else the primary.
+// Replication lag means a write may take a moment to show up there.
+func (r *transactionRepository) readDB() *pgxpool.Pool {
+	if r.replica != nil {
+		return r.replica
+	}
+	return r.db
 }
 
-// NewTransactionRepository creates a new TransactionRepository
-func NewTransactionRepository(db *pgxpool.Pool) TransactionRepository {
-	return &transactionRepository{db: db}
+// queryRower is satisfied by both the pool and a pgx.Tx
+type queryRower interface {
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// insertTransaction inserts t through q and fills in its generated fields
+func insertTransaction(ctx context.Context, q queryRower, t *model.Transaction) error {
+	if t.Tags == nil {
+		t.Tags = []string{} // A nil slice would be sent as NULL
+	}
+	sql := `INSERT INTO transactions (user_id, amount, currency, type, category, merchant, description, tags, transaction_date, receipt_path,
+                from_account_id, to_account_id, created_at, updated_at)
+            VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14) RETURNING id, created_at, updated_at, version`
+	err := q.QueryRow(ctx, sql, t.UserID, t.Amount, t.Currency, t.Type, t.Category, t.Merchant, t.Description, t.Tags, t.TransactionDate, t.ReceiptPath,
+		t.FromAccountID, t.ToAccountID, t.CreatedAt, t.UpdatedAt).Scan(&t.ID, &t.CreatedAt, &t.UpdatedAt, &t.Version)
+	if err != nil {
+		return fmt.Errorf("failed to create transaction: %w", err)
+	}
+	return nil
 }
 
 // Create inserts a new transaction into the database
 func (r *transactionRepository) Create(ctx context.Context, t *model.Transaction) error {
-	sql := `INSERT INTO transactions (user_id, amount, type, category, description, transaction_date, receipt_path, created_at, updated_at)
-            VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id, created_at, updated_at`
-	err := r.db.QueryRow(ctx, sql, t.UserID, t.Amount, t.Type, t.Category, t.Description, t.TransactionDate, t.ReceiptPath, t.CreatedAt, t.UpdatedAt).Scan(&t.ID, &t.CreatedAt, &t.UpdatedAt)
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	return insertTransaction(ctx, r.db, t)
+}
+
+// CreateIdempotent inserts a transaction (moving the amount if it is a transfer) together with
+// the user's idempotency key for it, atomically. A key recorded before expiredBefore is reused;
+// a live one makes it return ErrDuplicateIdempotencyKey without storing anything.
+func (r *transactionRepository) CreateIdempotent(ctx context.Context, t *model.Transaction, key string, expiredBefore time.Time) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	return WithTx(ctx, r.db, func(tx pgx.Tx) error {
+		if t.Type == model.TransactionTypeTransfer {
+			if err := moveTransferAmount(ctx, tx, t); err != nil {
+				return err
+			}
+		}
+		if err := insertTransaction(ctx, tx, t); err != nil {
+			return err
+		}
+
+		cmdTag, err := tx.Exec(ctx, `INSERT INTO idempotency_keys (user_id, key, transaction_id) VALUES ($1, $2, $3)
+            ON CONFLICT (user_id, key) DO UPDATE SET transaction_id = EXCLUDED.transaction_id, created_at = NOW()
+            WHERE idempotency_keys.created_at < $4`, t.UserID, key, t.ID, expiredBefore)
+		if err != nil {
+			return fmt.Errorf("failed to save idempotency key: %w", err)
+		}
+		if cmdTag.RowsAffected() == 0 {
+			return ErrDuplicateIdempotencyKey
+		}
+		return nil
+	})
+}
+
+// FindByIdempotencyKey returns the transaction the user created with key, unless the key
+// was recorded before expiredBefore. It returns nil if there is none.
+func (r *transactionRepository) FindByIdempotencyKey(ctx context.Context, userID int, key string, expiredBefore time.Time) (*model.Transaction, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	t := &model.Transaction{}
+	sql := `SELECT ` + transactionColumns + ` FROM transactions WHERE id = (
+                SELECT transaction_id FROM idempotency_keys WHERE user_id = $1 AND key = $2 AND created_at >= $3)`
+	if err := scanTransaction(r.db.QueryRow(ctx, sql, userID, key, expiredBefore), t); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil // Not found
+		}
+		return nil, fmt.Errorf("failed to find transaction by idempotency key: %w", err)
+	}
+	return t, nil
+}
+
+// DeleteExpiredIdempotencyKeys removes keys recorded before expiredBefore and returns how many were deleted
+func (r *transactionRepository) DeleteExpiredIdempotencyKeys(ctx context.Context, expiredBefore time.Time) (int64, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	cmdTag, err := r.db.Exec(ctx, `DELETE FROM idempotency_keys WHERE created_at < $1`, expiredBefore)
 	if err != nil {
-		return fmt.Errorf("failed to create transaction: %w", err)
+		return 0, fmt.Errorf("failed to delete expired idempotency keys: %w", err)
+	}
+	return cmdTag.RowsAffected(), nil
+}
+
+// CreateBatch inserts many transactions with a single COPY, so either all rows are stored or none
+func (r *transactionRepository) CreateBatch(ctx context.Context, transactions []model.Transaction) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	columns := []string{"user_id", "amount", "currency", "type", "category", "merchant", "description", "transaction_date", "created_at", "updated_at"}
+	_, err := r.db.CopyFrom(ctx, pgx.Identifier{"transactions"}, columns, pgx.CopyFromSlice(len(transactions), func(i int) ([]interface{}, error) {
+		t := transactions[i]
+		return []interface{}{t.UserID, t.Amount, t.Currency, t.Type, t.Category, t.Merchant, t.Description, t.TransactionDate, t.CreatedAt, t.UpdatedAt}, nil
+	}))
+	if err != nil {
+		return fmt.Errorf("failed to insert transactions: %w", err)
 	}
 	return nil
 }
 
 // FindByID retrieves a transaction by its ID
 func (r *transactionRepository) FindByID(ctx context.Context, id int64) (*model.Transaction, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	t := &model.Transaction{}
-	sql := `SELECT id, user_id, amount, type, category, description, transaction_date, receipt_path, created_at, updated_at 
-            FROM transactions WHERE id = $1`
-	err := r.db.QueryRow(ctx, sql, id).Scan(
-		&t.ID, &t.UserID, &t.Amount, &t.Type, &t.Category, &t.Description,
-		&t.TransactionDate, &t.ReceiptPath, &t.CreatedAt, &t.UpdatedAt,
-	)
+	sql := `SELECT ` + transactionColumns + ` FROM transactions WHERE id = $1`
+	err := withRetry(ctx, func() error { return scanTransaction(r.db.QueryRow(ctx, sql, id), t) })
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, nil // Not found
@@ -63,38 +226,173 @@ func (r *transactionRepository) FindByID(ctx context.Context, id int64) (*model.
 	return t, nil
 }
 
-// FindByUser retrieves transactions for a specific user with optional filters
-func (r *transactionRepository) FindByUser(ctx context.Context, userID int, filters model.UserTransactionFilters) ([]model.Transaction, error) {
-	var queryBuilder strings.Builder
-	queryBuilder.WriteString(`SELECT id, user_id, amount, type, category, description, transaction_date, receipt_path, created_at, updated_at 
-                               FROM transactions WHERE user_id = $1`)
+// userFilterClause builds the WHERE clause and args shared by user-scoped queries
+func userFilterClause(userID int, filters model.UserTransactionFilters) (string, []interface{}) {
+	conditions := []string{"user_id = $1"}
 	args := []interface{}{userID}
 	argCount := 2 // Start after user_id
 
-	if filters.Type != nil && *filters.Type != "" {
-		queryBuilder.WriteString(fmt.Sprintf(" AND type = $%d", argCount))
-		args = append(args, *filters.Type)
+	if filters.Currency != "" {
+		conditions = append(conditions, fmt.Sprintf("currency = $%d", argCount))
+		args = append(args, filters.Currency)
+		argCount++
+	}
+	if len(filters.Types) > 0 {
+		conditions = append(conditions, fmt.Sprintf("type = ANY($%d)", argCount))
+		args = append(args, filters.Types)
 		argCount++
 	}
-	if filters.Category != nil && *filters.Category != "" {
-		queryBuilder.WriteString(fmt.Sprintf(" AND category = $%d", argCount))
-		args = append(args, *filters.Category)
+	if len(filters.Categories) > 0 {
+		conditions = append(conditions, fmt.Sprintf("category = ANY($%d)", argCount))
+		args = append(args, filters.Categories)
+		argCount++
+	}
+	if filters.Merchant != nil && *filters.Merchant != "" {
+		conditions = append(conditions, fmt.Sprintf("merchant = $%d", argCount))
+		args = append(args, *filters.Merchant)
+		argCount++
+	}
+	if filters.Tag != nil && *filters.Tag != "" {
+		conditions = append(conditions, fmt.Sprintf("$%d = ANY(tags)", argCount))
+		args = append(args, *filters.Tag)
+		argCount++
+	}
+	if filters.Search != nil && *filters.Search != "" {
+		conditions = append(conditions, fmt.Sprintf("description ILIKE '%%' || $%d || '%%'", argCount))
+		args = append(args, escapeLikePattern(*filters.Search))
+		argCount++
+	}
+	if filters.MinAmount != nil {
+		conditions = append(conditions, fmt.Sprintf("amount >= $%d", argCount))
+		args = append(args, *filters.MinAmount)
+		argCount++
+	}
+	if filters.MaxAmount != nil {
+		conditions = append(conditions, fmt.Sprintf("amount <= $%d", argCount))
+		args = append(args, *filters.MaxAmount)
 		argCount++
 	}
 	if filters.StartDate != nil {
-		queryBuilder.WriteString(fmt.Sprintf(" AND transaction_date >= $%d", argCount))
+		conditions = append(conditions, fmt.Sprintf("transaction_date >= $%d", argCount))
 		args = append(args, *filters.StartDate)
 		argCount++
 	}
 	if filters.EndDate != nil {
-		queryBuilder.WriteString(fmt.Sprintf(" AND transaction_date <= $%d", argCount))
+		operator := "<="
+		if filters.EndDateExclusive {
+			operator = "<"
+		}
+		conditions = append(conditions, fmt.Sprintf("transaction_date %s $%d", operator, argCount))
 		args = append(args, *filters.EndDate)
+		argCount++
+	}
+	if filters.CreatedAfter != nil {
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", argCount))
+		args = append(args, *filters.CreatedAfter)
+		argCount++
+	}
+	if filters.CreatedBefore != nil {
+		conditions = append(conditions, fmt.Sprintf("created_at < $%d", argCount))
+		args = append(args, *filters.CreatedBefore)
 		//argCount++
 	}
 
-	queryBuilder.WriteString(" ORDER BY transaction_date DESC, created_at DESC")
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// likeEscaper escapes LIKE wildcards so search text matches literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// excludeTransfers narrows a filter clause to income and expense, for statistics that transfers
+// between the user's own accounts must not skew
+func excludeTransfers(whereClause, prefix string) string {
+	condition := prefix + "type <> '" + model.TransactionTypeTransfer + "'"
+	if whereClause == "" {
+		return " WHERE " + condition
+	}
+	return whereClause + " AND " + condition
+}
+
+// escapeLikePattern makes user input safe to embed in a LIKE/ILIKE pattern
+func escapeLikePattern(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+// sortColumns whitelists the columns listings may be ordered by; user input never reaches SQL directly
+var sortColumns = map[string]bool{"transaction_date": true, "amount": true, "created_at": true}
+
+// orderByClause builds " ORDER BY ..." from a requested sort column and direction.
+// Unknown columns fall back to the default newest-first order; unknown directions to DESC.
+// Ties are broken by created_at and id so the order is stable across pages.
+func orderByClause(sortBy, sortOrder, prefix string) string {
+	sortBy, direction := normalizeSort(sortBy, sortOrder)
+	columns := []string{prefix + sortBy + " " + direction}
+	if sortBy != "created_at" {
+		columns = append(columns, prefix+"created_at "+direction)
+	}
+	columns = append(columns, prefix+"id "+direction)
+	return " ORDER BY " + strings.Join(columns, ", ")
+}
+
+// normalizeSort returns a whitelisted sort column and "ASC" or "DESC", applying the defaults
+func normalizeSort(sortBy, sortOrder string) (string, string) {
+	if !sortColumns[sortBy] {
+		sortBy = "transaction_date"
+	}
+	if strings.EqualFold(sortOrder, "asc") {
+		return sortBy, "ASC"
+	}
+	return sortBy, "DESC"
+}
+
+// keysetCondition builds the condition selecting the rows after cursor in the given order.
+// The id breaks ties, so no row is skipped or repeated between pages; placeholders start at argN.
+func keysetCondition(cursor *model.TransactionCursor, sortBy, direction string, argN int) (string, []interface{}) {
+	var value interface{}
+	switch sortBy {
+	case "amount":
+		value = cursor.Amount
+	case "created_at":
+		value = cursor.CreatedAt
+	default:
+		value = cursor.TransactionDate
+	}
+	operator := "<"
+	if direction == "ASC" {
+		operator = ">"
+	}
+	return fmt.Sprintf("(%s, id) %s ($%d, $%d)", sortBy, operator, argN, argN+1), []interface{}{value, cursor.ID}
+}
+
+// FindByUser retrieves transactions for a specific user with optional filters
+func (r *transactionRepository) FindByUser(ctx context.Context, userID int, filters model.UserTransactionFilters) ([]model.Transaction, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	whereClause, args := userFilterClause(userID, filters)
+
+	var queryBuilder strings.Builder
+	queryBuilder.WriteString(`SELECT ` + transactionColumns + ` FROM transactions`)
+	queryBuilder.WriteString(whereClause)
+
+	if filters.Cursor != nil || filters.Limit > 0 {
+		// Paginated: a keyset on (sort column, id) picks up where the previous page ended
+		sortBy, direction := normalizeSort(filters.SortBy, filters.SortOrder)
+		if filters.Cursor != nil {
+			condition, keysetArgs := keysetCondition(filters.Cursor, sortBy, direction, len(args)+1)
+			args = append(args, keysetArgs...)
+			queryBuilder.WriteString(" AND " + condition)
+		}
+		queryBuilder.WriteString(fmt.Sprintf(" ORDER BY %s %s, id %s", sortBy, direction, direction))
+		if filters.Limit > 0 {
+			args = append(args, filters.Limit)
+			queryBuilder.WriteString(fmt.Sprintf(" LIMIT $%d", len(args)))
+		}
+	} else {
+		queryBuilder.WriteString(orderByClause(filters.SortBy, filters.SortOrder, ""))
+	}
 
-	rows, err := r.db.Query(ctx, queryBuilder.String(), args...)
+	rows, err := queryWithRetry(ctx, r.readDB(), queryBuilder.String(), args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query transactions by user: %w", err)
 	}
@@ -103,10 +401,7 @@ func (r *transactionRepository) FindByUser(ctx context.Context, userID int, filt
 	var transactions []model.Transaction
 	for rows.Next() {
 		var t model.Transaction
-		if err := rows.Scan(
-			&t.ID, &t.UserID, &t.Amount, &t.Type, &t.Category, &t.Description,
-			&t.TransactionDate, &t.ReceiptPath, &t.CreatedAt, &t.UpdatedAt,
-		); err != nil {
+		if err := scanTransaction(rows, &t); err != nil {
 			return nil, fmt.Errorf("failed to scan transaction row: %w", err)
 		}
 		transactions = append(transactions, t)
@@ -117,55 +412,305 @@ func (r *transactionRepository) FindByUser(ctx context.Context, userID int, filt
 	return transactions, nil
 }
 
-// Update modifies an existing transaction
-func (r *transactionRepository) Update(ctx context.Context, t *model.Transaction) error {
+// Update modifies an existing transaction, recording change (if not nil) in its history atomically
+func (r *transactionRepository) Update(ctx context.Context, t *model.Transaction, change *model.TransactionChange) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	sql := `UPDATE transactions 
-            SET amount = $1, type = $2, category = $3, description = $4, transaction_date = $5, updated_at = NOW()
-            WHERE id = $6 AND user_id = $7 RETURNING updated_at` // ensure user_id matches for ownership
-	err := r.db.QueryRow(ctx, sql, t.Amount, t.Type, t.Category, t.Description, t.TransactionDate, t.ID, t.UserID).Scan(&t.UpdatedAt)
-	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return fmt.Errorf("transaction not found or not owned by user for update")
+            SET amount = $1, type = $2, category = $3, merchant = $4, description = $5, transaction_date = $6,
+                currency = $10, tags = $11, updated_at = NOW(), version = version + 1
+            WHERE id = $7 AND user_id = $8 AND version = $9 RETURNING updated_at, version` // ensure user_id matches for ownership
+	if t.Tags == nil {
+		t.Tags = []string{}
+	}
+	return WithTx(ctx, r.db, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, sql, t.Amount, t.Type, t.Category, t.Merchant, t.Description, t.TransactionDate, t.ID, t.UserID, t.Version, t.Currency, t.Tags).Scan(&t.UpdatedAt, &t.Version)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrVersionConflict // Changed (or deleted) since it was read
+			}
+			return fmt.Errorf("failed to update transaction: %w", err)
+		}
+		return insertTransactionChange(ctx, tx, change)
+	})
+}
+
+// Delete removes a transaction from the database, recording change (if not nil) in its history atomically
+func (r *transactionRepository) Delete(ctx context.Context, id int64, change *model.TransactionChange) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	sql := `DELETE FROM transactions WHERE id = $1`
+	return WithTx(ctx, r.db, func(tx pgx.Tx) error {
+		cmdTag, err := tx.Exec(ctx, sql, id)
+		if err != nil {
+			return fmt.Errorf("failed to delete transaction: %w", err)
+		}
+		if cmdTag.RowsAffected() == 0 {
+			return fmt.Errorf("transaction not found for deletion") // Or handle as non-error if idempotent delete is ok
 		}
-		return fmt.Errorf("failed to update transaction: %w", err)
+		return insertTransactionChange(ctx, tx, change)
+	})
+}
+
+// insertTransactionChange appends an entry to a transaction's history inside tx; a nil change records nothing
+func insertTransactionChange(ctx context.Context, tx pgx.Tx, change *model.TransactionChange) error {
+	if change == nil {
+		return nil
+	}
+	err := tx.QueryRow(ctx, `INSERT INTO transaction_history (transaction_id, user_id, action, old_values, changed_by)
+            VALUES ($1, $2, $3, $4, $5) RETURNING id, changed_at`,
+		change.TransactionID, change.UserID, change.Action, change.OldValues, change.ChangedBy).Scan(&change.ID, &change.ChangedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record transaction history: %w", err)
 	}
 	return nil
 }
 
-// Delete removes a transaction from the database
-func (r *transactionRepository) Delete(ctx context.Context, id int64) error {
+// FindHistory returns a transaction's change history, oldest first. It is kept after the
+// transaction itself is deleted.
+func (r *transactionRepository) FindHistory(ctx context.Context, transactionID int64) ([]model.TransactionChange, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
 
-	sql := `DELETE FROM transactions WHERE id = $1`
-	cmdTag, err := r.db.Exec(ctx, sql, id)
+	sql := `SELECT id, transaction_id, user_id, action, old_values, changed_by, changed_at
+            FROM transaction_history
+            WHERE transaction_id = $1
+            ORDER BY changed_at ASC, id ASC`
+
+	rows, err := queryWithRetry(ctx, r.db, sql, transactionID)
 	if err != nil {
-		return fmt.Errorf("failed to delete transaction: %w", err)
+		return nil, fmt.Errorf("failed to query transaction history: %w", err)
+	}
+	defer rows.Close()
+
+	history := []model.TransactionChange{}
+	for rows.Next() {
+		var tc model.TransactionChange
+		if err := rows.Scan(&tc.ID, &tc.TransactionID, &tc.UserID, &tc.Action, &tc.OldValues, &tc.ChangedBy, &tc.ChangedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan transaction history row: %w", err)
+		}
+		history = append(history, tc)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating transaction history rows: %w", err)
+	}
+	return history, nil
+}
+
+// adjustAccountBalance adds delta to one of the user's accounts inside tx
+func adjustAccountBalance(ctx context.Context, tx pgx.Tx, accountID, userID int, delta int64) error {
+	cmdTag, err := tx.Exec(ctx, `UPDATE accounts SET balance = balance + $1, updated_at = NOW() WHERE id = $2 AND user_id = $3`,
+		delta, accountID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update account balance: %w", err)
 	}
 	if cmdTag.RowsAffected() == 0 {
-		return fmt.Errorf("transaction not found for deletion") // Or handle as non-error if idempotent delete is ok
+		return ErrAccountNotFound
 	}
 	return nil
 }
 
-// UpdateReceiptPath updates the receipt path for a transaction
-func (r *transactionRepository) UpdateReceiptPath(ctx context.Context, id int64, receiptPath string) error {
-	sql := `UPDATE transactions SET receipt_path = $1, updated_at = NOW() WHERE id = $2 RETURNING updated_at`
-	var updatedAt time.Time
-	err := r.db.QueryRow(ctx, sql, receiptPath, id).Scan(&updatedAt)
+// moveTransferAmount takes a transfer's amount from one account and adds it to the other inside tx
+func moveTransferAmount(ctx context.Context, tx pgx.Tx, t *model.Transaction) error {
+	if err := adjustAccountBalance(ctx, tx, *t.FromAccountID, t.UserID, -t.Amount); err != nil {
+		return err
+	}
+	return adjustAccountBalance(ctx, tx, *t.ToAccountID, t.UserID, t.Amount)
+}
+
+// CreateTransfer stores a transfer and moves its amount between the two accounts atomically.
+// It returns ErrAccountNotFound if either account doesn't belong to the transfer's user.
+func (r *transactionRepository) CreateTransfer(ctx context.Context, t *model.Transaction) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	return WithTx(ctx, r.db, func(tx pgx.Tx) error {
+		if err := moveTransferAmount(ctx, tx, t); err != nil {
+			return err
+		}
+		return insertTransaction(ctx, tx, t)
+	})
+}
+
+// DeleteTransfer removes a transfer, moves its amount back and records change, atomically. Accounts deleted
+// since the transfer was made are skipped.
+func (r *transactionRepository) DeleteTransfer(ctx context.Context, t *model.Transaction, change *model.TransactionChange) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	return WithTx(ctx, r.db, func(tx pgx.Tx) error {
+		// Re-read the account references under lock; they are cleared when an account is deleted
+		var fromID, toID *int
+		err := tx.QueryRow(ctx, `SELECT from_account_id, to_account_id FROM transactions WHERE id = $1 FOR UPDATE`, t.ID).Scan(&fromID, &toID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return fmt.Errorf("transaction not found for deletion")
+			}
+			return fmt.Errorf("failed to lock transfer: %w", err)
+		}
+		if fromID != nil {
+			if err := adjustAccountBalance(ctx, tx, *fromID, t.UserID, t.Amount); err != nil && !errors.Is(err, ErrAccountNotFound) {
+				return err
+			}
+		}
+		if toID != nil {
+			if err := adjustAccountBalance(ctx, tx, *toID, t.UserID, -t.Amount); err != nil && !errors.Is(err, ErrAccountNotFound) {
+				return err
+			}
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM transactions WHERE id = $1`, t.ID); err != nil {
+			return fmt.Errorf("failed to delete transfer: %w", err)
+		}
+		return insertTransactionChange(ctx, tx, change)
+	})
+}
+
+// DeleteMany removes the listed transactions in a single statement and returns the rows it
+// deleted. With ownerID set, only that user's transactions are touched; other ids are skipped.
+// In the same DB transaction, deleted transfers have their amount moved back and every deleted
+// row gets the history entry newChange builds for it.
+func (r *transactionRepository) DeleteMany(ctx context.Context, ids []int64, ownerID *int, newChange func(*model.Transaction) (*model.TransactionChange, error)) ([]model.Transaction, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	sql := `DELETE FROM transactions
+            WHERE id = ANY($1) AND ($2::int IS NULL OR user_id = $2)
+            RETURNING ` + transactionColumns
+
+	var deleted []model.Transaction
+	err := WithTx(ctx, r.db, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, sql, ids, ownerID)
+		if err != nil {
+			return fmt.Errorf("failed to delete transactions: %w", err)
+		}
+		for rows.Next() {
+			var t model.Transaction
+			if err := scanTransaction(rows, &t); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan deleted transaction: %w", err)
+			}
+			deleted = append(deleted, t)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to delete transactions: %w", err)
+		}
+
+		for i := range deleted {
+			t := &deleted[i]
+			if t.Type == model.TransactionTypeTransfer {
+				// Account references are cleared when an account is deleted; those sides are skipped
+				if t.FromAccountID != nil {
+					if err := adjustAccountBalance(ctx, tx, *t.FromAccountID, t.UserID, t.Amount); err != nil && !errors.Is(err, ErrAccountNotFound) {
+						return err
+					}
+				}
+				if t.ToAccountID != nil {
+					if err := adjustAccountBalance(ctx, tx, *t.ToAccountID, t.UserID, -t.Amount); err != nil && !errors.Is(err, ErrAccountNotFound) {
+						return err
+					}
+				}
+			}
+			change, err := newChange(t)
+			if err != nil {
+				return err
+			}
+			if err := insertTransactionChange(ctx, tx, change); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+	return deleted, nil
+}
+
+// ClearReceiptPath detaches the receipt from a transaction, recording change (if not nil) in its history atomically
+func (r *transactionRepository) ClearReceiptPath(ctx context.Context, id int64, change *model.TransactionChange) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	sql := `UPDATE transactions SET receipt_path = NULL, receipt_name = NULL, updated_at = NOW() WHERE id = $1`
+	return WithTx(ctx, r.db, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, sql, id)
+		if err != nil {
+			return fmt.Errorf("failed to clear receipt path: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
 			return fmt.Errorf("transaction not found for receipt path update")
 		}
-		return fmt.Errorf("failed to update receipt path: %w", err)
+		return insertTransactionChange(ctx, tx, change)
+	})
+}
+
+// ListReceiptPaths returns every receipt path still attached to a transaction. It reads from the
+// primary, so a receipt attached a moment ago is never mistaken for an orphan because of replica lag.
+func (r *transactionRepository) ListReceiptPaths(ctx context.Context) ([]string, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := queryWithRetry(ctx, r.db, `SELECT receipt_path FROM transactions WHERE receipt_path IS NOT NULL`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query receipt paths: %w", err)
+	}
+	defer rows.Close()
+
+	paths := []string{}
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return nil, fmt.Errorf("failed to scan receipt path: %w", err)
+		}
+		paths = append(paths, p)
 	}
-	return nil
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating receipt path rows: %w", err)
+	}
+	return paths, nil
 }
 
-// FindAll retrieves all transactions with optional filters for admin
-func (r *transactionRepository) FindAll(ctx context.Context, filters model.AdminTransactionFilters) ([]model.Transaction, error) {
-	var queryBuilder strings.Builder
-	queryBuilder.WriteString(`SELECT t.id, t.user_id, t.amount, t.type, t.category, t.description, t.transaction_date, t.receipt_path, t.created_at, t.updated_at 
-                               FROM transactions t`)
+// SetAdminNote stores an admin's note on a transaction, or clears it when note is nil. It reports
+// false if there is no such transaction. The note is invisible to the owner, so version is left
+// alone, and the set_transactions_updated_at trigger skips updates that only touch admin_note
+// (migration 0008), keeping updated_at and the owner's ETag as they were. For the same reason
+// nothing is recorded in the owner-readable history.
+func (r *transactionRepository) SetAdminNote(ctx context.Context, id int64, note *string) (bool, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	tag, err := r.db.Exec(ctx, `UPDATE transactions SET admin_note = $1 WHERE id = $2`, note, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to set admin note: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
 
+// UpdateReceiptPath updates the receipt path and original filename for a transaction, recording
+// change (if not nil) in its history atomically
+func (r *transactionRepository) UpdateReceiptPath(ctx context.Context, id int64, receiptPath, receiptName string, change *model.TransactionChange) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	sql := `UPDATE transactions SET receipt_path = $1, receipt_name = $2, updated_at = NOW() WHERE id = $3 RETURNING updated_at`
+	return WithTx(ctx, r.db, func(tx pgx.Tx) error {
+		var updatedAt time.Time
+		err := tx.QueryRow(ctx, sql, receiptPath, receiptName, id).Scan(&updatedAt)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return fmt.Errorf("transaction not found for receipt path update")
+			}
+			return fmt.Errorf("failed to update receipt path: %w", err)
+		}
+		return insertTransactionChange(ctx, tx, change)
+	})
+}
+
+// adminFilterClause builds the optional WHERE clause and args shared by admin queries over "transactions t"
+func adminFilterClause(filters model.AdminTransactionFilters) (string, []interface{}) {
 	args := []interface{}{}
 	argCount := 1
 	var conditions []string
@@ -175,14 +720,39 @@ func (r *transactionRepository) FindAll(ctx context.Context, filters model.Admin
 		args = append(args, *filters.UserID)
 		argCount++
 	}
-	if filters.Type != nil && *filters.Type != "" {
-		conditions = append(conditions, fmt.Sprintf("t.type = $%d", argCount))
-		args = append(args, *filters.Type)
+	if len(filters.Types) > 0 {
+		conditions = append(conditions, fmt.Sprintf("t.type = ANY($%d)", argCount))
+		args = append(args, filters.Types)
+		argCount++
+	}
+	if len(filters.Categories) > 0 {
+		conditions = append(conditions, fmt.Sprintf("t.category = ANY($%d)", argCount))
+		args = append(args, filters.Categories)
+		argCount++
+	}
+	if filters.Merchant != nil && *filters.Merchant != "" {
+		conditions = append(conditions, fmt.Sprintf("t.merchant = $%d", argCount))
+		args = append(args, *filters.Merchant)
+		argCount++
+	}
+	if filters.Tag != nil && *filters.Tag != "" {
+		conditions = append(conditions, fmt.Sprintf("$%d = ANY(t.tags)", argCount))
+		args = append(args, *filters.Tag)
+		argCount++
+	}
+	if filters.Search != nil && *filters.Search != "" {
+		conditions = append(conditions, fmt.Sprintf("t.description ILIKE '%%' || $%d || '%%'", argCount))
+		args = append(args, escapeLikePattern(*filters.Search))
 		argCount++
 	}
-	if filters.Category != nil && *filters.Category != "" {
-		conditions = append(conditions, fmt.Sprintf("t.category = $%d", argCount))
-		args = append(args, *filters.Category)
+	if filters.MinAmount != nil {
+		conditions = append(conditions, fmt.Sprintf("t.amount >= $%d", argCount))
+		args = append(args, *filters.MinAmount)
+		argCount++
+	}
+	if filters.MaxAmount != nil {
+		conditions = append(conditions, fmt.Sprintf("t.amount <= $%d", argCount))
+		args = append(args, *filters.MaxAmount)
 		argCount++
 	}
 	if filters.StartDate != nil {
@@ -193,198 +763,756 @@ func (r *transactionRepository) FindAll(ctx context.Context, filters model.Admin
 	if filters.EndDate != nil {
 		conditions = append(conditions, fmt.Sprintf("t.transaction_date <= $%d", argCount))
 		args = append(args, *filters.EndDate)
+		argCount++
+	}
+	if filters.CreatedAfter != nil {
+		conditions = append(conditions, fmt.Sprintf("t.created_at >= $%d", argCount))
+		args = append(args, *filters.CreatedAfter)
+		argCount++
+	}
+	if filters.CreatedBefore != nil {
+		conditions = append(conditions, fmt.Sprintf("t.created_at < $%d", argCount))
+		args = append(args, *filters.CreatedBefore)
 		//argCount++
 	}
 
-	if len(conditions) > 0 {
-		queryBuilder.WriteString(" WHERE ")
-		queryBuilder.WriteString(strings.Join(conditions, " AND "))
+	if len(conditions) == 0 {
+		return "", args
 	}
-	queryBuilder.WriteString(" ORDER BY t.transaction_date DESC, t.created_at DESC")
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// FindAll retrieves transactions with optional filters for admin, paginated when PageSize is set.
+// It also returns the total number of rows matching the filters.
+func (r *transactionRepository) FindAll(ctx context.Context, filters model.AdminTransactionFilters) ([]model.Transaction, int64, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
 
-	rows, err := r.db.Query(ctx, queryBuilder.String(), args...)
+	whereClause, args := adminFilterClause(filters)
+
+	var queryBuilder strings.Builder
+	queryBuilder.WriteString(`SELECT ` + adminTransactionColumns + ` FROM transactions t`)
+	queryBuilder.WriteString(whereClause)
+	queryBuilder.WriteString(orderByClause(filters.SortBy, filters.SortOrder, "t."))
+
+	queryArgs := args
+	if filters.PageSize > 0 {
+		offset := (max(filters.Page, 1) - 1) * filters.PageSize
+		queryArgs = append(append([]interface{}{}, args...), filters.PageSize, offset) // args stays intact for the count
+		queryBuilder.WriteString(fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2))
+	}
+
+	rows, err := r.readDB().Query(ctx, queryBuilder.String(), queryArgs...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query all transactions: %w", err)
+		return nil, 0, fmt.Errorf("failed to query all transactions: %w", err)
 	}
 	defer rows.Close()
 
 	var transactions []model.Transaction
 	for rows.Next() {
 		var t model.Transaction
-		if err := rows.Scan(
-			&t.ID, &t.UserID, &t.Amount, &t.Type, &t.Category, &t.Description,
-			&t.TransactionDate, &t.ReceiptPath, &t.CreatedAt, &t.UpdatedAt,
-		); err != nil {
-			return nil, fmt.Errorf("failed to scan transaction row for admin: %w", err)
+		if err := scanAdminTransaction(rows, &t); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan transaction row for admin: %w", err)
 		}
 		transactions = append(transactions, t)
 	}
 	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating admin transaction rows: %w", err)
+		return nil, 0, fmt.Errorf("error iterating admin transaction rows: %w", err)
 	}
-	return transactions, nil
+
+	if filters.PageSize == 0 {
+		return transactions, int64(len(transactions)), nil
+	}
+
+	// Total uses the same WHERE clause so it matches what the pages walk through
+	var total int64
+	countQuery := `SELECT COUNT(t.id) FROM transactions t` + whereClause
+	if err := r.readDB().QueryRow(ctx, countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count admin transactions: %w", err)
+	}
+	return transactions, total, nil
 }
 
-// GetAggregatedStats calculates aggregated statistics for admin
+// addCurrencyAmount records amount under currency and key, creating the currency's map on first use
+func addCurrencyAmount(byCurrency map[string]map[string]int64, currency, key string, amount int64) {
+	if byCurrency[currency] == nil {
+		byCurrency[currency] = make(map[string]int64)
+	}
+	byCurrency[currency][key] += amount
+}
+
+// GetAggregatedStats calculates aggregated statistics for admin. Every amount is grouped by
+// currency so totals never mix currencies. Each aggregate query gets its own trace span.
 func (r *transactionRepository) GetAggregatedStats(ctx context.Context, filters model.AdminTransactionFilters) (*model.AggregatedStats, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+	ctx, span := tracing.Start(ctx, "TransactionRepository.GetAggregatedStats")
+	defer span.End()
+
 	stats := &model.AggregatedStats{
-		ByCategoryIncome:  make(map[string]int64),
-		ByCategoryExpense: make(map[string]int64),
-		ByUserSpending:    make(map[int]model.UserStat),
+		TotalIncome:       make(map[string]int64),
+		TotalExpenses:     make(map[string]int64),
+		Balance:           make(map[string]int64),
+		ByCategoryIncome:  make(map[string]map[string]int64),
+		ByCategoryExpense: make(map[string]map[string]int64),
 	}
 
-	var baseQuery strings.Builder
-	baseQuery.WriteString("FROM transactions t JOIN users u ON t.user_id = u.id")
+	// Type filter applies to the totals too; the per-type category queries add their own type condition
+	whereClause, args := adminFilterClause(filters)
+	whereClause = excludeTransfers(whereClause, "t.")
 
-	args := []interface{}{}
-	argCount := 1
-	var conditions []string
-
-	if filters.UserID != nil {
-		conditions = append(conditions, fmt.Sprintf("t.user_id = $%d", argCount))
-		args = append(args, *filters.UserID)
-		argCount++
-	}
-	// Apply type filter if specified, otherwise it applies to income/expense specific queries
-	// For overall stats, type filter might be restrictive but let's allow it.
-	if filters.Type != nil && *filters.Type != "" {
-		conditions = append(conditions, fmt.Sprintf("t.type = $%d", argCount))
-		args = append(args, *filters.Type)
-		argCount++
+	if err := r.sumStatsTotals(ctx, stats, whereClause, args); err != nil {
+		return nil, err
 	}
-	if filters.Category != nil && *filters.Category != "" {
-		conditions = append(conditions, fmt.Sprintf("t.category = $%d", argCount))
-		args = append(args, *filters.Category)
-		argCount++
+	if len(stats.TotalIncome) == 0 {
+		// Nothing matched: report explicit zeros in the default currency rather than empty totals,
+		// so dashboards always have a figure to render
+		stats.TotalIncome[model.DefaultCurrency] = 0
+		stats.TotalExpenses[model.DefaultCurrency] = 0
+		stats.Balance[model.DefaultCurrency] = 0
 	}
-	if filters.StartDate != nil {
-		conditions = append(conditions, fmt.Sprintf("t.transaction_date >= $%d", argCount))
-		args = append(args, *filters.StartDate)
-		argCount++
+
+	// Only when the type filter lets the type through; the type filter may list several types,
+	// so the type condition is always added
+	if len(filters.Types) == 0 || slices.Contains(filters.Types, model.TransactionTypeIncome) {
+		if err := r.sumStatsByCategory(ctx, stats.ByCategoryIncome, model.TransactionTypeIncome, whereClause, args); err != nil {
+			return nil, err
+		}
 	}
-	if filters.EndDate != nil {
-		conditions = append(conditions, fmt.Sprintf("t.transaction_date <= $%d", argCount))
-		args = append(args, *filters.EndDate)
-		argCount++
+	if len(filters.Types) == 0 || slices.Contains(filters.Types, model.TransactionTypeExpense) {
+		if err := r.sumStatsByCategory(ctx, stats.ByCategoryExpense, model.TransactionTypeExpense, whereClause, args); err != nil {
+			return nil, err
+		}
 	}
+	return stats, nil
+}
 
-	whereClause := ""
-	if len(conditions) > 0 {
-		whereClause = " WHERE " + strings.Join(conditions, " AND ")
-	}
+// statsFrom is the FROM clause of the admin statistics queries, which filter with the t alias
+const statsFrom = "FROM transactions t JOIN users u ON t.user_id = u.id"
+
+// sumStatsTotals fills the income, expense and balance totals per currency: amounts in
+// different currencies are never added up
+func (r *transactionRepository) sumStatsTotals(ctx context.Context, stats *model.AggregatedStats, whereClause string, args []interface{}) error {
+	ctx, span := tracing.Start(ctx, "TransactionRepository.GetAggregatedStats totals")
+	defer span.End()
 
-	// Total Income and Expenses
 	sumQuery := fmt.Sprintf(`
         SELECT 
+            t.currency,
             COALESCE(SUM(CASE WHEN t.type = 'income' THEN t.amount ELSE 0 END), 0) as total_income,
             COALESCE(SUM(CASE WHEN t.type = 'expense' THEN t.amount ELSE 0 END), 0) as total_expenses
-        %s %s`, baseQuery.String(), whereClause)
+        %s %s GROUP BY t.currency`, statsFrom, whereClause)
 
-	err := r.db.QueryRow(ctx, sumQuery, args...).Scan(&stats.TotalIncome, &stats.TotalExpenses)
-	if err != nil && !errors.Is(err, pgx.ErrNoRows) { // ErrNoRows could mean 0 transactions match filter
-		return nil, fmt.Errorf("failed to get total income/expenses: %w", err)
+	rows, err := r.readDB().Query(ctx, sumQuery, args...)
+	if err != nil {
+		return fmt.Errorf("failed to get total income/expenses: %w", err)
 	}
-	stats.Balance = stats.TotalIncome - stats.TotalExpenses
+	defer rows.Close()
+	for rows.Next() {
+		var currency string
+		var income, expenses int64
+		if err := rows.Scan(&currency, &income, &expenses); err != nil {
+			return fmt.Errorf("failed to scan total income/expenses: %w", err)
+		}
+		stats.TotalIncome[currency] = income
+		stats.TotalExpenses[currency] = expenses
+		stats.Balance[currency] = income - expenses
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("error iterating total income/expenses: %w", err)
+	}
+	return nil
+}
 
-	// By Category (Income)
-	incomeCategoryArgs := make([]interface{}, len(args))
-	copy(incomeCategoryArgs, args)
-	incomeCategoryWhereClause := whereClause
-	incomeCategoryArgCount := argCount
-
-	// Add type = 'income' if not already filtered by type, or if filtered by type='income'
-	if filters.Type == nil || (filters.Type != nil && *filters.Type == model.TransactionTypeIncome) {
-		if filters.Type == nil { // if no type filter add it
-			if incomeCategoryWhereClause == "" {
-				incomeCategoryWhereClause = fmt.Sprintf(" WHERE t.type = $%d", incomeCategoryArgCount)
-			} else {
-				incomeCategoryWhereClause += fmt.Sprintf(" AND t.type = $%d", incomeCategoryArgCount)
-			}
-			incomeCategoryArgs = append(incomeCategoryArgs, model.TransactionTypeIncome)
-			//incomeCategoryArgCount++
+// sumStatsByCategory fills byCurrency with the per-category sums of txType transactions.
+// whereClause must not be empty, as excludeTransfers guarantees.
+func (r *transactionRepository) sumStatsByCategory(ctx context.Context, byCurrency map[string]map[string]int64, txType, whereClause string, args []interface{}) error {
+	ctx, span := tracing.Start(ctx, "TransactionRepository.GetAggregatedStats "+txType+" by category")
+	defer span.End()
+
+	categoryArgs := append(append([]interface{}{}, args...), txType) // The caller reuses args
+	categoryQuery := fmt.Sprintf(`SELECT t.currency, t.category, COALESCE(SUM(t.amount), 0) %s %s AND t.type = $%d GROUP BY t.currency, t.category`,
+		statsFrom, whereClause, len(categoryArgs))
+
+	rows, err := r.readDB().Query(ctx, categoryQuery, categoryArgs...)
+	if err != nil {
+		return fmt.Errorf("failed to get %s by category: %w", txType, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var currency, category string
+		var sum int64
+		if err := rows.Scan(&currency, &category, &sum); err != nil {
+			return fmt.Errorf("failed to scan %s by category: %w", txType, err)
 		}
-		categoryIncomeQuery := fmt.Sprintf(`SELECT t.category, COALESCE(SUM(t.amount), 0) %s %s GROUP BY t.category`, baseQuery.String(), incomeCategoryWhereClause)
-		rows, err := r.db.Query(ctx, categoryIncomeQuery, incomeCategoryArgs...)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get income by category: %w", err)
+		addCurrencyAmount(byCurrency, currency, category, sum)
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("error iterating %s by category: %w", txType, err)
+	}
+	return nil
+}
+
+// GetUserStatsAdmin returns one page of per-user totals for the transactions matching the admin
+// filters, ordered by filters.SortBy (total_spent by default) in currency, plus the number of
+// users overall. Transfers are left out, as in GetAggregatedStats.
+func (r *transactionRepository) GetUserStatsAdmin(ctx context.Context, filters model.AdminTransactionFilters, currency string) ([]model.UserStat, int64, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	whereClause, args := adminFilterClause(filters)
+	whereClause = excludeTransfers(whereClause, "t.")
+
+	// Total uses the same WHERE clause so it matches what the pages walk through
+	var total int64
+	countQuery := `SELECT COUNT(DISTINCT t.user_id) FROM transactions t` + whereClause
+	if err := r.readDB().QueryRow(ctx, countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count users for stats: %w", err)
+	}
+
+	pageArgs := append([]interface{}{}, args...) // args stays intact for the totals below
+	var sortExpr string
+	switch filters.SortBy {
+	case model.UserStatSortTransactionCount:
+		sortExpr = "COUNT(t.id)"
+	case model.UserStatSortTotalIncome:
+		pageArgs = append(pageArgs, currency)
+		sortExpr = fmt.Sprintf("SUM(CASE WHEN t.type = 'income' AND t.currency = $%d THEN t.amount ELSE 0 END)", len(pageArgs))
+	default:
+		pageArgs = append(pageArgs, currency)
+		sortExpr = fmt.Sprintf("SUM(CASE WHEN t.type = 'expense' AND t.currency = $%d THEN t.amount ELSE 0 END)", len(pageArgs))
+	}
+	direction := "DESC"
+	if strings.EqualFold(filters.SortOrder, "asc") {
+		direction = "ASC"
+	}
+	pageSize := filters.PageSize
+	if pageSize <= 0 {
+		pageSize = int(total)
+	}
+	pageArgs = append(pageArgs, pageSize, (max(filters.Page, 1)-1)*pageSize)
+
+	// Page through users first, then total only the users on this page per currency
+	pageQuery := fmt.Sprintf(`SELECT t.user_id, u.phone
+            FROM transactions t JOIN users u ON t.user_id = u.id %s
+            GROUP BY t.user_id, u.phone
+            ORDER BY %s %s, t.user_id ASC
+            LIMIT $%d OFFSET $%d`, whereClause, sortExpr, direction, len(pageArgs)-1, len(pageArgs))
+
+	rows, err := queryWithRetry(ctx, r.readDB(), pageQuery, pageArgs...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query users for stats: %w", err)
+	}
+	userStats := []model.UserStat{}
+	positions := make(map[int]int)
+	for rows.Next() {
+		us := model.UserStat{TotalSpent: make(map[string]int64), TotalIncome: make(map[string]int64)}
+		if err := rows.Scan(&us.UserID, &us.UserPhone); err != nil {
+			rows.Close()
+			return nil, 0, fmt.Errorf("failed to scan user for stats: %w", err)
 		}
-		for rows.Next() {
-			var category string
-			var sum int64
-			if err := rows.Scan(&category, &sum); err != nil {
-				rows.Close()
-				return nil, fmt.Errorf("failed to scan income by category: %w", err)
+		positions[us.UserID] = len(userStats)
+		userStats = append(userStats, us)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating users for stats: %w", err)
+	}
+	if len(userStats) == 0 {
+		return userStats, total, nil
+	}
+
+	userIDs := make([]int, 0, len(userStats))
+	for _, us := range userStats {
+		userIDs = append(userIDs, us.UserID)
+	}
+	totalsArgs := append(append([]interface{}{}, args...), userIDs)
+	totalsQuery := fmt.Sprintf(`
+        SELECT
+            t.user_id,
+            t.currency,
+            COALESCE(SUM(CASE WHEN t.type = 'expense' THEN t.amount ELSE 0 END), 0) as total_spent,
+            COALESCE(SUM(CASE WHEN t.type = 'income' THEN t.amount ELSE 0 END), 0) as total_income,
+            COUNT(t.id) as transaction_count
+        FROM transactions t %s AND t.user_id = ANY($%d)
+        GROUP BY t.user_id, t.currency`, whereClause, len(totalsArgs)) // excludeTransfers guarantees a WHERE
+
+	rows, err = queryWithRetry(ctx, r.readDB(), totalsQuery, totalsArgs...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get stats by user: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var userID int
+		var currency string
+		var spent, income, count int64
+		if err := rows.Scan(&userID, &currency, &spent, &income, &count); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan user stats: %w", err)
+		}
+		us := &userStats[positions[userID]]
+		us.TotalSpent[currency] = spent
+		us.TotalIncome[currency] = income
+		us.TransactionCount += count
+	}
+	if err = rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating user stats: %w", err)
+	}
+	return userStats, total, nil
+}
+
+// GetUserStats calculates income/expense totals and per-category sums for one user in
+// filters.Currency, and the totals of each currency under ByCurrency
+func (r *transactionRepository) GetUserStats(ctx context.Context, userID int, filters model.UserTransactionFilters) (*model.UserStats, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	stats := &model.UserStats{
+		Currency:          filters.Currency,
+		ByCategoryIncome:  make(map[string]int64),
+		ByCategoryExpense: make(map[string]int64),
+		ByCurrency:        make(map[string]model.CurrencyTotals),
+	}
+
+	filters.Currency = "" // ByCurrency covers them all; the top level picks one below
+	whereClause, args := userFilterClause(userID, filters)
+	sql := fmt.Sprintf(`SELECT type, category, currency, SUM(amount) FROM transactions %s GROUP BY type, category, currency`, excludeTransfers(whereClause, ""))
+
+	rows, err := queryWithRetry(ctx, r.db, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user stats: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var txType, category, currency string
+		var sum int64
+		if err := rows.Scan(&txType, &category, &currency, &sum); err != nil {
+			return nil, fmt.Errorf("failed to scan user stats: %w", err)
+		}
+		totals := stats.ByCurrency[currency]
+		selected := currency == stats.Currency
+		switch txType {
+		case model.TransactionTypeIncome:
+			totals.TotalIncome += sum
+			if selected {
+				stats.ByCategoryIncome[category] += sum
+				stats.TotalIncome += sum
+			}
+		case model.TransactionTypeExpense:
+			totals.TotalExpenses += sum
+			if selected {
+				stats.ByCategoryExpense[category] += sum
+				stats.TotalExpenses += sum
 			}
-			stats.ByCategoryIncome[category] = sum
 		}
-		rows.Close()
-		if err = rows.Err(); err != nil {
-			return nil, fmt.Errorf("error iterating income by category: %w", err)
+		totals.Balance = totals.TotalIncome - totals.TotalExpenses
+		stats.ByCurrency[currency] = totals
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating user stats: %w", err)
+	}
+
+	stats.Balance = stats.TotalIncome - stats.TotalExpenses
+	return stats, nil
+}
+
+// GetUserOverview computes a user's headline numbers over the filtered range in one query.
+// filters.Currency should be set, since amounts in different currencies can't be added up.
+func (r *transactionRepository) GetUserOverview(ctx context.Context, userID int, filters model.UserTransactionFilters) (*model.TransactionOverview, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	whereClause, args := userFilterClause(userID, filters)
+	sql := fmt.Sprintf(`
+        SELECT
+            COUNT(id),
+            COALESCE(SUM(CASE WHEN type = 'income' THEN amount ELSE 0 END), 0),
+            COALESCE(SUM(CASE WHEN type = 'expense' THEN amount ELSE 0 END), 0),
+            MIN(transaction_date),
+            MAX(transaction_date),
+            COUNT(DISTINCT category)
+        FROM transactions %s`, excludeTransfers(whereClause, ""))
+
+	o := &model.TransactionOverview{Currency: filters.Currency}
+	err := withRetry(ctx, func() error {
+		return r.db.QueryRow(ctx, sql, args...).Scan(
+			&o.Count, &o.TotalIncome, &o.TotalExpense,
+			&o.FirstTransactionDate, &o.LastTransactionDate, &o.DistinctCategories,
+		)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction overview: %w", err)
+	}
+	o.Balance = o.TotalIncome - o.TotalExpense
+	return o, nil
+}
+
+// SummarizeByUser counts and sums by type and currency the income and expense transactions
+// FindByUser would return for the same filters, ignoring pagination. Transfers are left out of
+// the count as well as the sums, so the count matches the totals.
+func (r *transactionRepository) SummarizeByUser(ctx context.Context, userID int, filters model.UserTransactionFilters) (*model.TransactionSummary, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	whereClause, args := userFilterClause(userID, filters)
+	whereClause = excludeTransfers(whereClause, "")
+	sql := fmt.Sprintf(`
+        SELECT
+            currency,
+            COUNT(id),
+            COALESCE(SUM(CASE WHEN type = 'income' THEN amount ELSE 0 END), 0),
+            COALESCE(SUM(CASE WHEN type = 'expense' THEN amount ELSE 0 END), 0)
+        FROM transactions %s
+        GROUP BY currency`, whereClause)
+
+	// Same pool as FindByUser, so the summary describes the rows the listing returned
+	rows, err := queryWithRetry(ctx, r.readDB(), sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize transactions: %w", err)
+	}
+	defer rows.Close()
+
+	summary := &model.TransactionSummary{TotalIncome: make(map[string]int64), TotalExpense: make(map[string]int64)}
+	for rows.Next() {
+		var currency string
+		var count, income, expense int64
+		if err := rows.Scan(&currency, &count, &income, &expense); err != nil {
+			return nil, fmt.Errorf("failed to scan transaction summary: %w", err)
 		}
+		summary.Count += count
+		summary.TotalIncome[currency] = income
+		summary.TotalExpense[currency] = expense
 	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating transaction summary: %w", err)
+	}
+	return summary, nil
+}
 
-	// By Category (Expense)
-	expenseCategoryArgs := make([]interface{}, len(args))
-	copy(expenseCategoryArgs, args)
-	expenseCategoryWhereClause := whereClause
-	expenseCategoryArgCount := argCount
+// GetBalance returns the user's total income minus total expense over all transactions in currency
+func (r *transactionRepository) GetBalance(ctx context.Context, userID int, currency string) (int64, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
 
-	if filters.Type == nil || (filters.Type != nil && *filters.Type == model.TransactionTypeExpense) {
-		if filters.Type == nil {
-			if expenseCategoryWhereClause == "" {
-				expenseCategoryWhereClause = fmt.Sprintf(" WHERE t.type = $%d", expenseCategoryArgCount)
-			} else {
-				expenseCategoryWhereClause += fmt.Sprintf(" AND t.type = $%d", expenseCategoryArgCount)
-			}
-			expenseCategoryArgs = append(expenseCategoryArgs, model.TransactionTypeExpense)
-			//expenseCategoryArgCount++
+	sql := `SELECT COALESCE(SUM(CASE type WHEN 'income' THEN amount WHEN 'expense' THEN -amount ELSE 0 END), 0)
+            FROM transactions WHERE user_id = $1 AND currency = $2`
+	var balance int64
+	if err := withRetry(ctx, func() error { return r.db.QueryRow(ctx, sql, userID, currency).Scan(&balance) }); err != nil {
+		return 0, fmt.Errorf("failed to compute balance: %w", err)
+	}
+	return balance, nil
+}
+
+// SumExpenses totals a user's expenses in currency dated within [from, to)
+func (r *transactionRepository) SumExpenses(ctx context.Context, userID int, currency string, from, to time.Time) (int64, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	sql := `SELECT COALESCE(SUM(amount), 0) FROM transactions
+            WHERE user_id = $1 AND type = 'expense' AND currency = $2
+                AND transaction_date >= $3 AND transaction_date < $4`
+	var total int64
+	err := withRetry(ctx, func() error {
+		return r.db.QueryRow(ctx, sql, userID, currency, from, to).Scan(&total)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to sum expenses: %w", err)
+	}
+	return total, nil
+}
+
+// GetUserTimeSeries totals a user's income and expense in currency per day, week or month for
+// transactions in [start, end). Periods without transactions are omitted. granularity must be
+// validated by the caller.
+func (r *transactionRepository) GetUserTimeSeries(ctx context.Context, userID int, currency, granularity string, start, end time.Time) ([]model.TimeSeriesPoint, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	sql := `
+        SELECT
+            date_trunc($2, transaction_date AT TIME ZONE 'UTC') AS period,
+            COALESCE(SUM(CASE WHEN type = 'income' THEN amount ELSE 0 END), 0),
+            COALESCE(SUM(CASE WHEN type = 'expense' THEN amount ELSE 0 END), 0)
+        FROM transactions
+        WHERE user_id = $1 AND transaction_date >= $3 AND transaction_date < $4 AND type <> 'transfer'
+            AND currency = $5
+        GROUP BY period
+        ORDER BY period`
+
+	rows, err := r.db.Query(ctx, sql, userID, granularity, start, end, currency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get time series: %w", err)
+	}
+	defer rows.Close()
+
+	points := []model.TimeSeriesPoint{}
+	for rows.Next() {
+		var p model.TimeSeriesPoint
+		if err := rows.Scan(&p.Period, &p.Income, &p.Expense); err != nil {
+			return nil, fmt.Errorf("failed to scan time series row: %w", err)
 		}
-		categoryExpenseQuery := fmt.Sprintf(`SELECT t.category, COALESCE(SUM(t.amount), 0) %s %s GROUP BY t.category`, baseQuery.String(), expenseCategoryWhereClause)
-		rows, err := r.db.Query(ctx, categoryExpenseQuery, expenseCategoryArgs...)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get expense by category: %w", err)
+		points = append(points, p)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating time series rows: %w", err)
+	}
+	return points, nil
+}
+
+// GetCategoryRanking returns a user's expense categories ranked by total spend. filters.Currency
+// should be set, since amounts in different currencies can't be added up.
+func (r *transactionRepository) GetCategoryRanking(ctx context.Context, userID int, filters model.UserTransactionFilters, limit int) ([]model.CategoryRank, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	filters.Types = []string{model.TransactionTypeExpense} // Ranking is about spending only
+	whereClause, args := userFilterClause(userID, filters)
+	args = append(args, limit)
+
+	// The window sum is computed before LIMIT, so percentages are relative to all categories
+	sql := fmt.Sprintf(`SELECT category, SUM(amount), COUNT(id), SUM(SUM(amount)) OVER ()
+            FROM transactions %s
+            GROUP BY category
+            ORDER BY SUM(amount) DESC, category ASC
+            LIMIT $%d`, whereClause, len(args))
+
+	rows, err := r.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query category ranking: %w", err)
+	}
+	defer rows.Close()
+
+	ranking := []model.CategoryRank{}
+	for rows.Next() {
+		var cr model.CategoryRank
+		var totalExpense int64
+		if err := rows.Scan(&cr.Category, &cr.Amount, &cr.Count, &totalExpense); err != nil {
+			return nil, fmt.Errorf("failed to scan category ranking row: %w", err)
 		}
-		for rows.Next() {
-			var category string
-			var sum int64
-			if err := rows.Scan(&category, &sum); err != nil {
-				rows.Close()
-				return nil, fmt.Errorf("failed to scan expense by category: %w", err)
-			}
-			stats.ByCategoryExpense[category] = sum
+		cr.Rank = len(ranking) + 1
+		if totalExpense > 0 {
+			cr.Percentage = math.Round(float64(cr.Amount)*10000/float64(totalExpense)) / 100
 		}
-		rows.Close()
-		if err = rows.Err(); err != nil {
-			return nil, fmt.Errorf("error iterating expense by category: %w", err)
+		ranking = append(ranking, cr)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating category ranking rows: %w", err)
+	}
+	return ranking, nil
+}
+
+// GetCategoryTotals sums a user's transactions matching filters per category, largest first.
+// Percent is left for the caller, which knows the grand total. filters.Currency should be set,
+// since amounts in different currencies can't be added up.
+func (r *transactionRepository) GetCategoryTotals(ctx context.Context, userID int, filters model.UserTransactionFilters) ([]model.CategoryShare, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	whereClause, args := userFilterClause(userID, filters)
+	sql := fmt.Sprintf(`SELECT category, SUM(amount)
+            FROM transactions %s
+            GROUP BY category
+            ORDER BY SUM(amount) DESC, category ASC`, whereClause)
+
+	rows, err := r.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query category totals: %w", err)
+	}
+	defer rows.Close()
+
+	totals := []model.CategoryShare{}
+	for rows.Next() {
+		var cs model.CategoryShare
+		if err := rows.Scan(&cs.Category, &cs.Total); err != nil {
+			return nil, fmt.Errorf("failed to scan category total row: %w", err)
 		}
+		totals = append(totals, cs)
 	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating category total rows: %w", err)
+	}
+	return totals, nil
+}
 
-	// By User Spending
+// GetTopCategoriesAdmin ranks expense categories across all users matching the admin filters.
+// Only one currency is ranked at a time, since amounts in different currencies can't be compared.
+func (r *transactionRepository) GetTopCategoriesAdmin(ctx context.Context, filters model.AdminTransactionFilters, currency string, limit int) ([]model.CategoryRank, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
 
-	userSpendingQuery := fmt.Sprintf(`
-        SELECT 
-            t.user_id, 
-            u.phone,
-            COALESCE(SUM(CASE WHEN t.type = 'expense' THEN t.amount ELSE 0 END), 0) as total_spent,
-            COALESCE(SUM(CASE WHEN t.type = 'income' THEN t.amount ELSE 0 END), 0) as total_income,
-            COUNT(t.id) as transaction_count
-        %s %s GROUP BY t.user_id, u.phone`, baseQuery.String(), whereClause)
+	filters.Types = []string{model.TransactionTypeExpense} // Ranking is about spending only
+	whereClause, args := adminFilterClause(filters)
+	args = append(args, currency)
+	whereClause += fmt.Sprintf(" AND t.currency = $%d", len(args)) // The type condition guarantees a WHERE
+	args = append(args, limit)
 
-	rows, err := r.db.Query(ctx, userSpendingQuery, args...)
+	// The window sum is computed before LIMIT, so percentages are relative to all categories
+	sql := fmt.Sprintf(`SELECT t.category, SUM(t.amount), COUNT(t.id), SUM(SUM(t.amount)) OVER ()
+            FROM transactions t %s
+            GROUP BY t.category
+            ORDER BY SUM(t.amount) DESC, t.category ASC
+            LIMIT $%d`, whereClause, len(args))
+
+	rows, err := r.db.Query(ctx, sql, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get stats by user: %w", err)
+		return nil, fmt.Errorf("failed to query top categories: %w", err)
 	}
 	defer rows.Close()
+
+	ranking := []model.CategoryRank{}
 	for rows.Next() {
-		var us model.UserStat
-		if err := rows.Scan(&us.UserID, &us.UserPhone, &us.TotalSpent, &us.TotalIncome, &us.TransactionCount); err != nil {
-			return nil, fmt.Errorf("failed to scan user stats: %w", err)
+		var cr model.CategoryRank
+		var totalExpense int64
+		if err := rows.Scan(&cr.Category, &cr.Amount, &cr.Count, &totalExpense); err != nil {
+			return nil, fmt.Errorf("failed to scan top category row: %w", err)
 		}
-		stats.ByUserSpending[us.UserID] = us
+		cr.Rank = len(ranking) + 1
+		if totalExpense > 0 {
+			cr.Percentage = math.Round(float64(cr.Amount)*10000/float64(totalExpense)) / 100
+		}
+		ranking = append(ranking, cr)
 	}
 	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating user stats: %w", err)
+		return nil, fmt.Errorf("error iterating top category rows: %w", err)
 	}
+	return ranking, nil
+}
 
-	return stats, nil
+// GetReimbursableTotals sums the expenses tagged model.ReimbursableTag per user and currency,
+// among those matching the admin filters. Users without any are left out.
+func (r *transactionRepository) GetReimbursableTotals(ctx context.Context, filters model.AdminTransactionFilters) ([]model.ReimbursableTotal, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	tag := model.ReimbursableTag
+	filters.Types = []string{model.TransactionTypeExpense}
+	filters.Tag = &tag
+	whereClause, args := adminFilterClause(filters)
+
+	sql := fmt.Sprintf(`SELECT t.user_id, u.phone, t.currency, SUM(t.amount), COUNT(t.id)
+            FROM transactions t JOIN users u ON t.user_id = u.id %s
+            GROUP BY t.user_id, u.phone, t.currency
+            ORDER BY t.user_id, t.currency`, whereClause)
+
+	rows, err := queryWithRetry(ctx, r.readDB(), sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reimbursable totals: %w", err)
+	}
+	defer rows.Close()
+
+	totals := []model.ReimbursableTotal{}
+	for rows.Next() {
+		var rt model.ReimbursableTotal
+		if err := rows.Scan(&rt.UserID, &rt.Phone, &rt.Currency, &rt.TotalReimbursable, &rt.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan reimbursable total row: %w", err)
+		}
+		totals = append(totals, rt)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating reimbursable total rows: %w", err)
+	}
+	return totals, nil
+}
+
+// GetTopMerchants returns the merchants a user spent the most at
+func (r *transactionRepository) GetTopMerchants(ctx context.Context, userID int, filters model.UserTransactionFilters, limit int) ([]model.MerchantSpend, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	filters.Types = []string{model.TransactionTypeExpense}
+	whereClause, args := userFilterClause(userID, filters)
+	args = append(args, limit)
+
+	sql := fmt.Sprintf(`SELECT merchant, SUM(amount), COUNT(id)
+            FROM transactions %s AND merchant IS NOT NULL
+            GROUP BY merchant
+            ORDER BY SUM(amount) DESC, merchant ASC
+            LIMIT $%d`, whereClause, len(args))
+
+	rows, err := r.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query top merchants: %w", err)
+	}
+	defer rows.Close()
+
+	merchants := []model.MerchantSpend{}
+	for rows.Next() {
+		var ms model.MerchantSpend
+		if err := rows.Scan(&ms.Merchant, &ms.TotalSpent, &ms.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan top merchant row: %w", err)
+		}
+		merchants = append(merchants, ms)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating top merchant rows: %w", err)
+	}
+	return merchants, nil
+}
+
+// SuggestCategories counts categories of the user's past transactions whose description matches any ILIKE pattern
+func (r *transactionRepository) SuggestCategories(ctx context.Context, userID int, patterns []string, limit int) ([]model.CategorySuggestion, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	sql := `SELECT category, COUNT(id), SUM(COUNT(id)) OVER ()
+            FROM transactions
+            WHERE user_id = $1 AND description ILIKE ANY($2) AND type <> 'transfer'
+            GROUP BY category
+            ORDER BY COUNT(id) DESC, category ASC
+            LIMIT $3`
+
+	rows, err := r.db.Query(ctx, sql, userID, patterns, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query category suggestions: %w", err)
+	}
+	defer rows.Close()
+
+	suggestions := []model.CategorySuggestion{}
+	for rows.Next() {
+		var cs model.CategorySuggestion
+		var totalMatches int64
+		if err := rows.Scan(&cs.Category, &cs.Matches, &totalMatches); err != nil {
+			return nil, fmt.Errorf("failed to scan category suggestion row: %w", err)
+		}
+		if totalMatches > 0 {
+			cs.Confidence = math.Round(float64(cs.Matches)*100/float64(totalMatches)) / 100
+		}
+		suggestions = append(suggestions, cs)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating category suggestion rows: %w", err)
+	}
+	return suggestions, nil
+}
+
+// GetRecentCategories returns the categories the user has used most, most used first
+func (r *transactionRepository) GetRecentCategories(ctx context.Context, userID int, limit int) ([]model.CategoryUsage, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	// Served by idx_transactions_user_category
+	sql := `SELECT category, COUNT(*)
+            FROM transactions
+            WHERE user_id = $1 AND type <> 'transfer'
+            GROUP BY category
+            ORDER BY COUNT(*) DESC, category ASC
+            LIMIT $2`
+
+	rows, err := r.db.Query(ctx, sql, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query recent categories: %w", err)
+	}
+	defer rows.Close()
+
+	categories := []model.CategoryUsage{}
+	for rows.Next() {
+		var cu model.CategoryUsage
+		if err := rows.Scan(&cu.Category, &cu.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan recent category row: %w", err)
+		}
+		categories = append(categories, cu)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating recent category rows: %w", err)
+	}
+	return categories, nil
 }
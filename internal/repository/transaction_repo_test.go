@@ -0,0 +1,550 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"expense_tracker/internal/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserFilterClause_Search(t *testing.T) {
+	search := "50%_off"
+	where, args := userFilterClause(7, model.UserTransactionFilters{Search: &search})
+
+	assert.Equal(t, " WHERE user_id = $1 AND description ILIKE '%' || $2 || '%'", where)
+	assert.Equal(t, []interface{}{7, `50\%\_off`}, args)
+}
+
+func TestAdminFilterClause_Search(t *testing.T) {
+	search := "coffee"
+	where, args := adminFilterClause(model.AdminTransactionFilters{Search: &search})
+
+	assert.Equal(t, " WHERE t.description ILIKE '%' || $1 || '%'", where)
+	assert.Equal(t, []interface{}{"coffee"}, args)
+}
+
+func TestFilterClauses_MultipleTypesAndCategories(t *testing.T) {
+	types, categories := []string{"expense", "income"}, []string{"Food", "Transport"}
+
+	where, args := userFilterClause(7, model.UserTransactionFilters{Types: types, Categories: categories})
+	assert.Equal(t, " WHERE user_id = $1 AND type = ANY($2) AND category = ANY($3)", where)
+	assert.Equal(t, []interface{}{7, types, categories}, args)
+
+	where, args = adminFilterClause(model.AdminTransactionFilters{Types: types, Categories: categories})
+	assert.Equal(t, " WHERE t.type = ANY($1) AND t.category = ANY($2)", where)
+	assert.Equal(t, []interface{}{types, categories}, args)
+}
+
+func TestUserFilterClause_AmountRange(t *testing.T) {
+	minAmount, maxAmount := int64(100), int64(5000)
+	where, args := userFilterClause(7, model.UserTransactionFilters{MinAmount: &minAmount, MaxAmount: &maxAmount})
+
+	assert.Equal(t, " WHERE user_id = $1 AND amount >= $2 AND amount <= $3", where)
+	assert.Equal(t, []interface{}{7, int64(100), int64(5000)}, args)
+}
+
+func TestFilterClauses_CreatedRange(t *testing.T) {
+	after := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	before := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	where, args := userFilterClause(7, model.UserTransactionFilters{StartDate: &start, CreatedAfter: &after, CreatedBefore: &before})
+	assert.Equal(t, " WHERE user_id = $1 AND transaction_date >= $2 AND created_at >= $3 AND created_at < $4", where)
+	assert.Equal(t, []interface{}{7, start, after, before}, args)
+
+	where, args = adminFilterClause(model.AdminTransactionFilters{CreatedAfter: &after})
+	assert.Equal(t, " WHERE t.created_at >= $1", where)
+	assert.Equal(t, []interface{}{after}, args)
+}
+
+func TestExcludeTransfers(t *testing.T) {
+	assert.Equal(t, " WHERE type <> 'transfer'", excludeTransfers("", ""))
+	assert.Equal(t, " WHERE t.user_id = $1 AND t.type <> 'transfer'", excludeTransfers(" WHERE t.user_id = $1", "t."))
+}
+
+func TestOrderByClause(t *testing.T) {
+	tests := []struct {
+		sortBy, sortOrder, prefix string
+		want                      string
+	}{
+		{"", "", "", " ORDER BY transaction_date DESC, created_at DESC, id DESC"},
+		{"amount", "asc", "", " ORDER BY amount ASC, created_at ASC, id ASC"},
+		{"created_at", "DESC", "t.", " ORDER BY t.created_at DESC, t.id DESC"},
+		{"amount; DROP TABLE users", "asc", "", " ORDER BY transaction_date ASC, created_at ASC, id ASC"},
+		{"amount", "sideways", "", " ORDER BY amount DESC, created_at DESC, id DESC"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, orderByClause(tt.sortBy, tt.sortOrder, tt.prefix))
+	}
+}
+
+func TestKeysetCondition(t *testing.T) {
+	date := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+	cursor := &model.TransactionCursor{TransactionDate: date, CreatedAt: date.Add(time.Hour), Amount: 500, ID: 7}
+
+	condition, args := keysetCondition(cursor, "transaction_date", "DESC", 3)
+	assert.Equal(t, "(transaction_date, id) < ($3, $4)", condition)
+	assert.Equal(t, []interface{}{date, int64(7)}, args)
+
+	condition, args = keysetCondition(cursor, "amount", "ASC", 2)
+	assert.Equal(t, "(amount, id) > ($2, $3)", condition)
+	assert.Equal(t, []interface{}{int64(500), int64(7)}, args)
+
+	_, args = keysetCondition(cursor, "created_at", "DESC", 2)
+	assert.Equal(t, []interface{}{date.Add(time.Hour), int64(7)}, args)
+}
+
+func TestGetAggregatedStats_GroupsByCurrency(t *testing.T) {
+	pool := newTestDB(t)
+	users := NewUserRepository(pool)
+	repo := NewTransactionRepository(pool, nil)
+	ctx := context.Background()
+
+	phone := fmt.Sprintf("+998%09d", time.Now().UnixNano()%1000000000)
+	t.Cleanup(func() { pool.Exec(ctx, `DELETE FROM users WHERE phone = $1`, phone) })
+	user := &model.User{Phone: phone, PasswordHash: "hash", Role: model.RoleUser, CreatedAt: time.Now()}
+	assert.NoError(t, users.Create(ctx, user))
+
+	for _, tx := range []model.Transaction{
+		{Amount: 500000, Currency: "UZS", Type: model.TransactionTypeIncome, Category: "Salary"},
+		{Amount: 120000, Currency: "UZS", Type: model.TransactionTypeExpense, Category: "Food"},
+		{Amount: 2500, Currency: "USD", Type: model.TransactionTypeExpense, Category: "Food"},
+	} {
+		tx.UserID, tx.TransactionDate, tx.CreatedAt, tx.UpdatedAt = user.ID, time.Now(), time.Now(), time.Now()
+		assert.NoError(t, repo.Create(ctx, &tx))
+	}
+
+	stats, err := repo.GetAggregatedStats(ctx, model.AdminTransactionFilters{UserID: &user.ID})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int64{"UZS": 500000, "USD": 0}, stats.TotalIncome)
+	assert.Equal(t, map[string]int64{"UZS": 120000, "USD": 2500}, stats.TotalExpenses)
+	assert.Equal(t, map[string]int64{"UZS": 380000, "USD": -2500}, stats.Balance)
+	assert.Equal(t, map[string]map[string]int64{"UZS": {"Food": 120000}, "USD": {"Food": 2500}}, stats.ByCategoryExpense)
+}
+
+func TestUserAggregates_KeepCurrenciesApart(t *testing.T) {
+	pool := newTestDB(t)
+	users := NewUserRepository(pool)
+	repo := NewTransactionRepository(pool, nil)
+	ctx := context.Background()
+
+	phone := fmt.Sprintf("+998%09d", time.Now().UnixNano()%1000000000)
+	t.Cleanup(func() { pool.Exec(ctx, `DELETE FROM users WHERE phone = $1`, phone) })
+	user := &model.User{Phone: phone, PasswordHash: "hash", Role: model.RoleUser, CreatedAt: time.Now()}
+	assert.NoError(t, users.Create(ctx, user))
+
+	date := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	for _, tx := range []model.Transaction{
+		{Amount: 500000, Currency: "UZS", Type: model.TransactionTypeIncome, Category: "Salary"},
+		{Amount: 120000, Currency: "UZS", Type: model.TransactionTypeExpense, Category: "Food"},
+		{Amount: 2500, Currency: "USD", Type: model.TransactionTypeExpense, Category: "Food"},
+		{Amount: 70000, Currency: "UZS", Type: model.TransactionTypeTransfer, Category: "Transfer"},
+	} {
+		tx.UserID, tx.TransactionDate, tx.CreatedAt, tx.UpdatedAt = user.ID, date, time.Now(), time.Now()
+		assert.NoError(t, repo.Create(ctx, &tx))
+	}
+
+	summary, err := repo.SummarizeByUser(ctx, user.ID, model.UserTransactionFilters{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), summary.Count, "transfers are neither counted nor summed")
+	assert.Equal(t, map[string]int64{"UZS": 500000, "USD": 0}, summary.TotalIncome)
+	assert.Equal(t, map[string]int64{"UZS": 120000, "USD": 2500}, summary.TotalExpense)
+
+	balance, err := repo.GetBalance(ctx, user.ID, "USD")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-2500), balance)
+
+	stats, err := repo.GetUserStats(ctx, user.ID, model.UserTransactionFilters{Currency: "UZS"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(120000), stats.TotalExpenses)
+	assert.Equal(t, map[string]int64{"Food": 120000}, stats.ByCategoryExpense)
+	assert.Equal(t, model.CurrencyTotals{TotalExpenses: 2500, Balance: -2500}, stats.ByCurrency["USD"])
+
+	overview, err := repo.GetUserOverview(ctx, user.ID, model.UserTransactionFilters{Currency: "USD"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), overview.Count)
+	assert.Equal(t, int64(2500), overview.TotalExpense)
+
+	series, err := repo.GetUserTimeSeries(ctx, user.ID, "UZS", model.GranularityMonth, date.AddDate(0, 0, -10), date.AddDate(0, 0, 10))
+	assert.NoError(t, err)
+	if assert.Len(t, series, 1) {
+		assert.Equal(t, int64(120000), series[0].Expense)
+	}
+}
+
+func TestSumExpenses(t *testing.T) {
+	pool := newTestDB(t)
+	users := NewUserRepository(pool)
+	repo := NewTransactionRepository(pool, nil)
+	ctx := context.Background()
+
+	phone := fmt.Sprintf("+998%09d", time.Now().UnixNano()%1000000000)
+	t.Cleanup(func() { pool.Exec(ctx, `DELETE FROM users WHERE phone = $1`, phone) })
+	user := &model.User{Phone: phone, PasswordHash: "hash", Role: model.RoleUser, CreatedAt: time.Now()}
+	assert.NoError(t, users.Create(ctx, user))
+
+	from := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	for _, tx := range []model.Transaction{
+		{Amount: 70000, Currency: "UZS", Type: model.TransactionTypeExpense, TransactionDate: from},
+		{Amount: 50000, Currency: "UZS", Type: model.TransactionTypeExpense, TransactionDate: from.AddDate(0, 1, -1)},
+		{Amount: 900000, Currency: "UZS", Type: model.TransactionTypeIncome, TransactionDate: from},
+		{Amount: 2500, Currency: "USD", Type: model.TransactionTypeExpense, TransactionDate: from},
+		{Amount: 30000, Currency: "UZS", Type: model.TransactionTypeExpense, TransactionDate: from.AddDate(0, 1, 0)},
+	} {
+		tx.UserID, tx.Category, tx.CreatedAt, tx.UpdatedAt = user.ID, "Food", time.Now(), time.Now()
+		assert.NoError(t, repo.Create(ctx, &tx))
+	}
+
+	total, err := repo.SumExpenses(ctx, user.ID, "UZS", from, from.AddDate(0, 1, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(120000), total)
+}
+
+func TestGetAggregatedStats_NoTransactions(t *testing.T) {
+	pool := newTestDB(t)
+	users := NewUserRepository(pool)
+	repo := NewTransactionRepository(pool, nil)
+	ctx := context.Background()
+
+	phone := fmt.Sprintf("+998%09d", time.Now().UnixNano()%1000000000)
+	t.Cleanup(func() { pool.Exec(ctx, `DELETE FROM users WHERE phone = $1`, phone) })
+	user := &model.User{Phone: phone, PasswordHash: "hash", Role: model.RoleUser, CreatedAt: time.Now()}
+	assert.NoError(t, users.Create(ctx, user))
+
+	stats, err := repo.GetAggregatedStats(ctx, model.AdminTransactionFilters{UserID: &user.ID})
+	assert.NoError(t, err)
+	body, err := json.Marshal(stats)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"total_income": {"UZS": 0},
+		"total_expenses": {"UZS": 0},
+		"balance": {"UZS": 0},
+		"by_category_income": {},
+		"by_category_expense": {}
+	}`, string(body))
+}
+
+func TestGetUserStatsAdmin_PagesInOrder(t *testing.T) {
+	pool := newTestDB(t)
+	users := NewUserRepository(pool)
+	repo := NewTransactionRepository(pool, nil)
+	ctx := context.Background()
+
+	category := fmt.Sprintf("UserStats%d", time.Now().UnixNano())
+	spent := []int64{30000, 10000, 20000}
+	var ids []int
+	for i, amount := range spent {
+		phone := fmt.Sprintf("+998%09d", (time.Now().UnixNano()+int64(i))%1000000000)
+		t.Cleanup(func() { pool.Exec(ctx, `DELETE FROM users WHERE phone = $1`, phone) })
+		user := &model.User{Phone: phone, PasswordHash: "hash", Role: model.RoleUser, CreatedAt: time.Now()}
+		assert.NoError(t, users.Create(ctx, user))
+		ids = append(ids, user.ID)
+		for _, tx := range []model.Transaction{
+			{Amount: amount, Currency: "UZS", Type: model.TransactionTypeExpense, Category: category},
+			{Amount: 100, Currency: "USD", Type: model.TransactionTypeExpense, Category: category},
+		} {
+			tx.UserID, tx.TransactionDate, tx.CreatedAt, tx.UpdatedAt = user.ID, time.Now(), time.Now(), time.Now()
+			assert.NoError(t, repo.Create(ctx, &tx))
+		}
+	}
+
+	filters := model.AdminTransactionFilters{Categories: []string{category}, SortBy: model.UserStatSortTotalSpent, Page: 1, PageSize: 2}
+	page, total, err := repo.GetUserStatsAdmin(ctx, filters, "UZS")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	if assert.Len(t, page, 2) {
+		assert.Equal(t, ids[0], page[0].UserID)
+		assert.Equal(t, ids[2], page[1].UserID)
+		assert.Equal(t, map[string]int64{"UZS": 30000, "USD": 100}, page[0].TotalSpent)
+		assert.Equal(t, int64(2), page[0].TransactionCount)
+	}
+
+	filters.Page, filters.SortOrder = 2, "asc"
+	page, _, err = repo.GetUserStatsAdmin(ctx, filters, "UZS")
+	assert.NoError(t, err)
+	if assert.Len(t, page, 1) {
+		assert.Equal(t, ids[0], page[0].UserID)
+	}
+}
+
+func TestGetTopCategoriesAdmin(t *testing.T) {
+	pool := newTestDB(t)
+	users := NewUserRepository(pool)
+	repo := NewTransactionRepository(pool, nil)
+	ctx := context.Background()
+
+	phone := fmt.Sprintf("+998%09d", time.Now().UnixNano()%1000000000)
+	t.Cleanup(func() { pool.Exec(ctx, `DELETE FROM users WHERE phone = $1`, phone) })
+	user := &model.User{Phone: phone, PasswordHash: "hash", Role: model.RoleUser, CreatedAt: time.Now()}
+	assert.NoError(t, users.Create(ctx, user))
+
+	for _, tx := range []model.Transaction{
+		{Amount: 500000, Currency: "UZS", Type: model.TransactionTypeIncome, Category: "Salary"},
+		{Amount: 100000, Currency: "UZS", Type: model.TransactionTypeExpense, Category: "Food"},
+		{Amount: 200000, Currency: "UZS", Type: model.TransactionTypeExpense, Category: "Food"},
+		{Amount: 100000, Currency: "UZS", Type: model.TransactionTypeExpense, Category: "Housing"},
+		{Amount: 900000, Currency: "USD", Type: model.TransactionTypeExpense, Category: "Travel"},
+	} {
+		tx.UserID, tx.TransactionDate, tx.CreatedAt, tx.UpdatedAt = user.ID, time.Now(), time.Now(), time.Now()
+		assert.NoError(t, repo.Create(ctx, &tx))
+	}
+
+	ranking, err := repo.GetTopCategoriesAdmin(ctx, model.AdminTransactionFilters{UserID: &user.ID}, "UZS", 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []model.CategoryRank{
+		{Rank: 1, Category: "Food", Amount: 300000, Count: 2, Percentage: 75},
+		{Rank: 2, Category: "Housing", Amount: 100000, Count: 1, Percentage: 25},
+	}, ranking)
+
+	ranking, err = repo.GetTopCategoriesAdmin(ctx, model.AdminTransactionFilters{UserID: &user.ID}, "UZS", 1)
+	assert.NoError(t, err)
+	assert.Len(t, ranking, 1)
+}
+
+func TestGetCategoryTotals(t *testing.T) {
+	pool := newTestDB(t)
+	users := NewUserRepository(pool)
+	repo := NewTransactionRepository(pool, nil)
+	ctx := context.Background()
+
+	phone := fmt.Sprintf("+998%09d", time.Now().UnixNano()%1000000000)
+	t.Cleanup(func() { pool.Exec(ctx, `DELETE FROM users WHERE phone = $1`, phone) })
+	user := &model.User{Phone: phone, PasswordHash: "hash", Role: model.RoleUser, CreatedAt: time.Now()}
+	assert.NoError(t, users.Create(ctx, user))
+
+	for _, tx := range []model.Transaction{
+		{Amount: 500000, Type: model.TransactionTypeIncome, Category: "Salary"},
+		{Amount: 100000, Type: model.TransactionTypeExpense, Category: "Food"},
+		{Amount: 200000, Type: model.TransactionTypeExpense, Category: "Food"},
+		{Amount: 100000, Type: model.TransactionTypeExpense, Category: "Housing"},
+	} {
+		tx.UserID, tx.Currency, tx.TransactionDate, tx.CreatedAt, tx.UpdatedAt = user.ID, "UZS", time.Now(), time.Now(), time.Now()
+		assert.NoError(t, repo.Create(ctx, &tx))
+	}
+	usd := model.Transaction{UserID: user.ID, Amount: 9000000, Currency: "USD", Type: model.TransactionTypeExpense, Category: "Food",
+		TransactionDate: time.Now(), CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	assert.NoError(t, repo.Create(ctx, &usd))
+
+	expenses := model.UserTransactionFilters{Currency: "UZS", Types: []string{model.TransactionTypeExpense}}
+	totals, err := repo.GetCategoryTotals(ctx, user.ID, expenses)
+	assert.NoError(t, err)
+	assert.Equal(t, []model.CategoryShare{{Category: "Food", Total: 300000}, {Category: "Housing", Total: 100000}}, totals)
+
+	past := time.Now().AddDate(-1, 0, 0)
+	expenses.EndDate = &past
+	totals, err = repo.GetCategoryTotals(ctx, user.ID, expenses)
+	assert.NoError(t, err)
+	assert.Equal(t, []model.CategoryShare{}, totals)
+}
+
+func TestTransactionTags(t *testing.T) {
+	pool := newTestDB(t)
+	users := NewUserRepository(pool)
+	repo := NewTransactionRepository(pool, nil)
+	ctx := context.Background()
+
+	phone := fmt.Sprintf("+998%09d", time.Now().UnixNano()%1000000000)
+	t.Cleanup(func() { pool.Exec(ctx, `DELETE FROM users WHERE phone = $1`, phone) })
+	user := &model.User{Phone: phone, PasswordHash: "hash", Role: model.RoleUser, CreatedAt: time.Now()}
+	assert.NoError(t, users.Create(ctx, user))
+
+	var created []model.Transaction
+	for _, tags := range [][]string{{"work", "reimbursable"}, {"work"}, nil} {
+		tx := model.Transaction{UserID: user.ID, Amount: 1000, Currency: "UZS", Type: model.TransactionTypeExpense, Category: "Food",
+			Tags: tags, TransactionDate: time.Now(), CreatedAt: time.Now(), UpdatedAt: time.Now()}
+		assert.NoError(t, repo.Create(ctx, &tx))
+		created = append(created, tx)
+	}
+
+	found, err := repo.FindByID(ctx, created[2].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{}, found.Tags)
+
+	tag := "reimbursable"
+	listed, err := repo.FindByUser(ctx, user.ID, model.UserTransactionFilters{Tag: &tag})
+	assert.NoError(t, err)
+	if assert.Len(t, listed, 1) {
+		assert.Equal(t, created[0].ID, listed[0].ID)
+		assert.Equal(t, []string{"work", "reimbursable"}, listed[0].Tags)
+	}
+
+	tag = "work"
+	all, _, err := repo.FindAll(ctx, model.AdminTransactionFilters{UserID: &user.ID, Tag: &tag})
+	assert.NoError(t, err)
+	assert.Len(t, all, 2)
+
+	found.Tags = []string{"home"}
+	assert.NoError(t, repo.Update(ctx, found, nil))
+	found, err = repo.FindByID(ctx, created[2].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"home"}, found.Tags)
+}
+
+func TestGetReimbursableTotals(t *testing.T) {
+	pool := newTestDB(t)
+	users := NewUserRepository(pool)
+	repo := NewTransactionRepository(pool, nil)
+	ctx := context.Background()
+
+	phone := fmt.Sprintf("+998%09d", time.Now().UnixNano()%1000000000)
+	t.Cleanup(func() { pool.Exec(ctx, `DELETE FROM users WHERE phone = $1`, phone) })
+	user := &model.User{Phone: phone, PasswordHash: "hash", Role: model.RoleUser, CreatedAt: time.Now()}
+	assert.NoError(t, users.Create(ctx, user))
+
+	for _, tx := range []model.Transaction{
+		{Amount: 1000, Currency: "UZS", Type: model.TransactionTypeExpense, Tags: []string{"work", model.ReimbursableTag}},
+		{Amount: 2000, Currency: "UZS", Type: model.TransactionTypeExpense, Tags: []string{model.ReimbursableTag}},
+		{Amount: 300, Currency: "USD", Type: model.TransactionTypeExpense, Tags: []string{model.ReimbursableTag}},
+		{Amount: 5000, Currency: "UZS", Type: model.TransactionTypeExpense, Tags: []string{"work"}},
+		{Amount: 9000, Currency: "UZS", Type: model.TransactionTypeIncome, Tags: []string{model.ReimbursableTag}},
+	} {
+		tx.UserID, tx.Category, tx.TransactionDate, tx.CreatedAt, tx.UpdatedAt = user.ID, "Travel", time.Now(), time.Now(), time.Now()
+		assert.NoError(t, repo.Create(ctx, &tx))
+	}
+
+	totals, err := repo.GetReimbursableTotals(ctx, model.AdminTransactionFilters{UserID: &user.ID})
+	assert.NoError(t, err)
+	assert.Equal(t, []model.ReimbursableTotal{
+		{UserID: user.ID, Phone: phone, Currency: "USD", TotalReimbursable: 300, Count: 1},
+		{UserID: user.ID, Phone: phone, Currency: "UZS", TotalReimbursable: 3000, Count: 2},
+	}, totals)
+}
+
+func TestDeleteMany(t *testing.T) {
+	pool := newTestDB(t)
+	users := NewUserRepository(pool)
+	repo := NewTransactionRepository(pool, nil)
+	ctx := context.Background()
+
+	var owners [2]*model.User
+	for i := range owners {
+		phone := fmt.Sprintf("+998%09d", (time.Now().UnixNano()+int64(i))%1000000000)
+		t.Cleanup(func() { pool.Exec(ctx, `DELETE FROM users WHERE phone = $1`, phone) })
+		owners[i] = &model.User{Phone: phone, PasswordHash: "hash", Role: model.RoleUser, CreatedAt: time.Now()}
+		assert.NoError(t, users.Create(ctx, owners[i]))
+	}
+
+	var ids []int64
+	for _, owner := range []*model.User{owners[0], owners[0], owners[1]} {
+		tx := model.Transaction{UserID: owner.ID, Amount: 1000, Currency: "UZS", Type: model.TransactionTypeExpense, Category: "Food",
+			TransactionDate: time.Now(), CreatedAt: time.Now(), UpdatedAt: time.Now()}
+		assert.NoError(t, repo.Create(ctx, &tx))
+		ids = append(ids, tx.ID)
+	}
+	newChange := func(t *model.Transaction) (*model.TransactionChange, error) {
+		return &model.TransactionChange{TransactionID: t.ID, UserID: t.UserID, Action: model.TransactionChangeDelete, OldValues: []byte(`{}`)}, nil
+	}
+
+	deleted, err := repo.DeleteMany(ctx, ids, &owners[0].ID, newChange)
+	assert.NoError(t, err)
+	assert.Len(t, deleted, 2)
+	remaining, err := repo.FindByID(ctx, ids[2])
+	assert.NoError(t, err)
+	assert.NotNil(t, remaining) // Not the owner's, so left alone
+	history, err := repo.FindHistory(ctx, ids[0])
+	assert.NoError(t, err)
+	assert.Len(t, history, 1)
+
+	deleted, err = repo.DeleteMany(ctx, ids, nil, newChange)
+	assert.NoError(t, err)
+	assert.Len(t, deleted, 1)
+}
+
+func TestSetAdminNote_OnlyInAdminListing(t *testing.T) {
+	pool := newTestDB(t)
+	users := NewUserRepository(pool)
+	repo := NewTransactionRepository(pool, nil)
+	ctx := context.Background()
+
+	phone := fmt.Sprintf("+998%09d", time.Now().UnixNano()%1000000000)
+	t.Cleanup(func() { pool.Exec(ctx, `DELETE FROM users WHERE phone = $1`, phone) })
+	user := &model.User{Phone: phone, PasswordHash: "hash", Role: model.RoleUser, CreatedAt: time.Now()}
+	assert.NoError(t, users.Create(ctx, user))
+	tx := model.Transaction{UserID: user.ID, Amount: 1000, Currency: "UZS", Type: model.TransactionTypeExpense, Category: "Food",
+		TransactionDate: time.Now(), CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	assert.NoError(t, repo.Create(ctx, &tx))
+	before, err := repo.FindByID(ctx, tx.ID)
+	assert.NoError(t, err)
+
+	note := "Checked against the bank statement"
+	found, err := repo.SetAdminNote(ctx, tx.ID, &note)
+	assert.NoError(t, err)
+	assert.True(t, found)
+
+	// The owner's copy, and so its ETag, is untouched
+	after, err := repo.FindByID(ctx, tx.ID)
+	assert.NoError(t, err)
+	assert.True(t, before.UpdatedAt.Equal(after.UpdatedAt))
+	assert.Equal(t, before.Version, after.Version)
+
+	listed, _, err := repo.FindAll(ctx, model.AdminTransactionFilters{UserID: &user.ID})
+	assert.NoError(t, err)
+	if assert.Len(t, listed, 1) {
+		assert.Equal(t, note, *listed[0].AdminNote)
+	}
+	own, err := repo.FindByUser(ctx, user.ID, model.UserTransactionFilters{})
+	assert.NoError(t, err)
+	if assert.Len(t, own, 1) {
+		assert.Nil(t, own[0].AdminNote)
+	}
+
+	found, err = repo.SetAdminNote(ctx, -1, &note)
+	assert.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestAddCurrencyAmount(t *testing.T) {
+	byCurrency := make(map[string]map[string]int64)
+	addCurrencyAmount(byCurrency, "UZS", "Food", 100)
+	addCurrencyAmount(byCurrency, "USD", "Food", 2)
+	addCurrencyAmount(byCurrency, "UZS", "Food", 50)
+	assert.Equal(t, map[string]map[string]int64{"UZS": {"Food": 150}, "USD": {"Food": 2}}, byCurrency)
+}
+
+func TestFindByUser_CursorPagesFollowTransactionDate(t *testing.T) {
+	pool := newTestDB(t)
+	users := NewUserRepository(pool)
+	repo := NewTransactionRepository(pool, nil)
+	ctx := context.Background()
+
+	phone := fmt.Sprintf("+998%09d", time.Now().UnixNano()%1000000000)
+	t.Cleanup(func() { pool.Exec(ctx, `DELETE FROM users WHERE phone = $1`, phone) })
+	user := &model.User{Phone: phone, PasswordHash: "hash", Role: model.RoleUser, CreatedAt: time.Now()}
+	assert.NoError(t, users.Create(ctx, user))
+
+	// Inserted out of date order, as a backdated or imported transaction would be
+	base := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	var ids []int64
+	for _, daysAgo := range []int{3, 0, 4, 1, 1} {
+		tx := model.Transaction{UserID: user.ID, Amount: 1000, Currency: "UZS", Type: model.TransactionTypeExpense, Category: "Food",
+			TransactionDate: base.AddDate(0, 0, -daysAgo), CreatedAt: time.Now(), UpdatedAt: time.Now()}
+		assert.NoError(t, repo.Create(ctx, &tx))
+		ids = append(ids, tx.ID)
+	}
+
+	walk := func(filters model.UserTransactionFilters) []int64 {
+		var got []int64
+		filters.Limit = 2
+		for {
+			page, err := repo.FindByUser(ctx, user.ID, filters)
+			assert.NoError(t, err)
+			if len(page) == 0 {
+				return got
+			}
+			for _, tx := range page {
+				got = append(got, tx.ID)
+			}
+			cursor := model.CursorAfter(page[len(page)-1])
+			filters.Cursor = &cursor
+		}
+	}
+	assert.Equal(t, []int64{ids[1], ids[4], ids[3], ids[0], ids[2]}, walk(model.UserTransactionFilters{}))
+	assert.Equal(t, []int64{ids[2], ids[0], ids[3], ids[4], ids[1]}, walk(model.UserTransactionFilters{SortOrder: "asc"}))
+	// Equal amounts: the id alone orders the pages
+	assert.Equal(t, ids, walk(model.UserTransactionFilters{SortBy: "amount", SortOrder: "asc"}))
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeTx records whether it was committed or rolled back; other methods panic via the nil embedded interface
+type fakeTx struct {
+	pgx.Tx
+	committed, rolledBack bool
+}
+
+func (tx *fakeTx) Commit(ctx context.Context) error {
+	tx.committed = true
+	return nil
+}
+
+func (tx *fakeTx) Rollback(ctx context.Context) error {
+	if !tx.committed {
+		tx.rolledBack = true
+	}
+	return nil
+}
+
+type fakeBeginner struct{ tx *fakeTx }
+
+func (b *fakeBeginner) Begin(ctx context.Context) (pgx.Tx, error) { return b.tx, nil }
+
+func TestWithTx_CommitsOnSuccess(t *testing.T) {
+	db := &fakeBeginner{tx: &fakeTx{}}
+
+	err := WithTx(context.Background(), db, func(tx pgx.Tx) error { return nil })
+
+	assert.NoError(t, err)
+	assert.True(t, db.tx.committed)
+	assert.False(t, db.tx.rolledBack)
+}
+
+func TestWithTx_RollsBackOnError(t *testing.T) {
+	db := &fakeBeginner{tx: &fakeTx{}}
+	errBoom := errors.New("boom")
+
+	err := WithTx(context.Background(), db, func(tx pgx.Tx) error { return errBoom })
+
+	assert.ErrorIs(t, err, errBoom)
+	assert.False(t, db.tx.committed)
+	assert.True(t, db.tx.rolledBack)
+}
+
+func TestWithTx_NothingCommittedAfterMidTransactionError(t *testing.T) {
+	pool := newTestDB(t)
+	ctx := context.Background()
+
+	phone := fmt.Sprintf("+998%09d", time.Now().UnixNano()%1000000000)
+	t.Cleanup(func() { pool.Exec(ctx, `DELETE FROM users WHERE phone = $1`, phone) })
+
+	errBoom := errors.New("boom")
+	err := WithTx(ctx, pool, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `INSERT INTO users (phone, password_hash, role) VALUES ($1, 'hash', 'user')`, phone); err != nil {
+			return err
+		}
+		return errBoom // Fails after the first write went through
+	})
+	assert.ErrorIs(t, err, errBoom)
+
+	var count int
+	assert.NoError(t, pool.QueryRow(ctx, `SELECT COUNT(*) FROM users WHERE phone = $1`, phone).Scan(&count))
+	assert.Equal(t, 0, count)
+}
@@ -7,15 +7,32 @@ import (
 
 	"expense_tracker/internal/model"
 
+	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+var (
+	// ErrDuplicatePhone is returned when inserting a user whose phone is already registered
+	ErrDuplicatePhone = errors.New("phone number already registered")
+	// ErrLastAdmin is returned when a change would leave no admin
+	ErrLastAdmin = errors.New("the last remaining admin can't be removed")
+)
+
 // UserRepository defines operations for user data
 type UserRepository interface {
 	Create(ctx context.Context, user *model.User) error
+	CreateFirstAdmin(ctx context.Context, user *model.User) error
 	FindByPhone(ctx context.Context, phone string) (*model.User, error)
 	FindByID(ctx context.Context, id int) (*model.User, error)
+	UpdatePassword(ctx context.Context, id int, passwordHash string) error
+	UpdateRole(ctx context.Context, id int, role string) error
+	CountByRole(ctx context.Context, role string) (int, error)
+	GetCurrency(ctx context.Context, id int) (string, error)
+	UpdateCurrency(ctx context.Context, id int, currency string) error
+	UpdateAlertThreshold(ctx context.Context, id int, threshold *int64) error
+	Delete(ctx context.Context, id int) ([]string, error)
 }
 
 type userRepository struct {
@@ -29,11 +46,53 @@ func NewUserRepository(db *pgxpool.Pool) UserRepository {
 
 // Create inserts a new user into the database
 func (r *userRepository) Create(ctx context.Context, user *model.User) error {
-	sql := `INSERT INTO users (phone, password_hash, role, created_at) 
-            VALUES ($1, $2, $3, $4) RETURNING id`
-	err := r.db.QueryRow(ctx, sql, user.Phone, user.PasswordHash, user.Role, user.CreatedAt).Scan(&user.ID)
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	return insertUser(ctx, r.db, user)
+}
+
+// adminBootstrapLockKey is the advisory lock that serializes first-admin registrations
+const adminBootstrapLockKey = 7361001
+
+// CreateFirstAdmin inserts user as an admin if no admin exists yet and as a regular user
+// otherwise, setting user.Role to whichever was stored. Concurrent calls are serialized, so
+// at most one of them can become the first admin.
+func (r *userRepository) CreateFirstAdmin(ctx context.Context, user *model.User) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	return WithTx(ctx, r.db, func(tx pgx.Tx) error {
+		// There is no admin row to lock yet, so lock on a fixed key instead
+		if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, adminBootstrapLockKey); err != nil {
+			return fmt.Errorf("failed to lock admin bootstrap: %w", err)
+		}
+		var hasAdmin bool
+		err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE role = $1)`, model.RoleAdmin).Scan(&hasAdmin)
+		if err != nil {
+			return fmt.Errorf("failed to check for an admin: %w", err)
+		}
+		user.Role = model.RoleAdmin
+		if hasAdmin {
+			user.Role = model.RoleUser
+		}
+		return insertUser(ctx, tx, user)
+	})
+}
+
+// insertUser inserts user through q and fills in its ID
+func insertUser(ctx context.Context, q queryRower, user *model.User) error {
+	if user.Currency == "" {
+		user.Currency = model.DefaultCurrency
+	}
+	sql := `INSERT INTO users (phone, password_hash, role, currency, created_at) 
+            VALUES ($1, $2, $3, $4, $5) RETURNING id`
+	err := q.QueryRow(ctx, sql, user.Phone, user.PasswordHash, user.Role, user.Currency, user.CreatedAt).Scan(&user.ID)
 	if err != nil {
-		// TODO: Check for unique constraint violation specifically pgerrcode.UniqueViolation
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation && pgErr.ConstraintName == "users_phone_key" {
+			return ErrDuplicatePhone
+		}
 		return fmt.Errorf("failed to create user: %w", err)
 	}
 	return nil
@@ -41,9 +100,14 @@ func (r *userRepository) Create(ctx context.Context, user *model.User) error {
 
 // FindByPhone retrieves a user by their phone number
 func (r *userRepository) FindByPhone(ctx context.Context, phone string) (*model.User, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	user := &model.User{}
-	sql := `SELECT id, phone, password_hash, role, created_at FROM users WHERE phone = $1`
-	err := r.db.QueryRow(ctx, sql, phone).Scan(&user.ID, &user.Phone, &user.PasswordHash, &user.Role, &user.CreatedAt)
+	sql := `SELECT id, phone, password_hash, role, currency, created_at, monthly_alert_threshold FROM users WHERE phone = $1`
+	err := withRetry(ctx, func() error {
+		return r.db.QueryRow(ctx, sql, phone).Scan(&user.ID, &user.Phone, &user.PasswordHash, &user.Role, &user.Currency, &user.CreatedAt, &user.MonthlyAlertThreshold)
+	})
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, nil // User not found is not an error for this method's contract, service layer handles it
@@ -55,9 +119,14 @@ func (r *userRepository) FindByPhone(ctx context.Context, phone string) (*model.
 
 // FindByID retrieves a user by their ID
 func (r *userRepository) FindByID(ctx context.Context, id int) (*model.User, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	user := &model.User{}
-	sql := `SELECT id, phone, password_hash, role, created_at FROM users WHERE id = $1`
-	err := r.db.QueryRow(ctx, sql, id).Scan(&user.ID, &user.Phone, &user.PasswordHash, &user.Role, &user.CreatedAt)
+	sql := `SELECT id, phone, password_hash, role, currency, created_at, monthly_alert_threshold FROM users WHERE id = $1`
+	err := withRetry(ctx, func() error {
+		return r.db.QueryRow(ctx, sql, id).Scan(&user.ID, &user.Phone, &user.PasswordHash, &user.Role, &user.Currency, &user.CreatedAt, &user.MonthlyAlertThreshold)
+	})
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, nil // User not found
@@ -66,3 +135,160 @@ func (r *userRepository) FindByID(ctx context.Context, id int) (*model.User, err
 	}
 	return user, nil
 }
+
+// UpdatePassword replaces a user's password hash
+func (r *userRepository) UpdatePassword(ctx context.Context, id int, passwordHash string) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	sql := `UPDATE users SET password_hash = $1 WHERE id = $2`
+	cmdTag, err := r.db.Exec(ctx, sql, passwordHash, id)
+	if err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// UpdateRole changes a user's role. Demoting the last admin fails with ErrLastAdmin.
+func (r *userRepository) UpdateRole(ctx context.Context, id int, role string) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	return WithTx(ctx, r.db, func(tx pgx.Tx) error {
+		if role != model.RoleAdmin {
+			if err := ensureAnotherAdmin(ctx, tx, id); err != nil {
+				return err
+			}
+		}
+		cmdTag, err := tx.Exec(ctx, `UPDATE users SET role = $1 WHERE id = $2`, role, id)
+		if err != nil {
+			return fmt.Errorf("failed to update role: %w", err)
+		}
+		if cmdTag.RowsAffected() == 0 {
+			return pgx.ErrNoRows
+		}
+		return nil
+	})
+}
+
+// ensureAnotherAdmin returns ErrLastAdmin if id is the only admin. It locks every admin row
+// until tx ends, so concurrent demotions or deletions queue up and each sees the others' result.
+func ensureAnotherAdmin(ctx context.Context, tx pgx.Tx, id int) error {
+	rows, err := tx.Query(ctx, `SELECT id FROM users WHERE role = $1 FOR UPDATE`, model.RoleAdmin)
+	if err != nil {
+		return fmt.Errorf("failed to lock admins: %w", err)
+	}
+	admins, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		return fmt.Errorf("failed to scan admin id: %w", err)
+	}
+	if len(admins) == 1 && admins[0] == id {
+		return ErrLastAdmin
+	}
+	return nil
+}
+
+// GetCurrency returns the currency a user's new transactions default to, or
+// model.DefaultCurrency if the user doesn't exist
+func (r *userRepository) GetCurrency(ctx context.Context, id int) (string, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var currency string
+	err := withRetry(ctx, func() error {
+		return r.db.QueryRow(ctx, `SELECT currency FROM users WHERE id = $1`, id).Scan(&currency)
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.DefaultCurrency, nil
+		}
+		return "", fmt.Errorf("failed to get user currency: %w", err)
+	}
+	return currency, nil
+}
+
+// UpdateCurrency changes the currency a user's new transactions default to
+func (r *userRepository) UpdateCurrency(ctx context.Context, id int, currency string) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	cmdTag, err := r.db.Exec(ctx, `UPDATE users SET currency = $1 WHERE id = $2`, currency, id)
+	if err != nil {
+		return fmt.Errorf("failed to update currency: %w", err)
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// UpdateAlertThreshold sets the monthly expense total a user is warned above; nil removes the alert
+func (r *userRepository) UpdateAlertThreshold(ctx context.Context, id int, threshold *int64) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	cmdTag, err := r.db.Exec(ctx, `UPDATE users SET monthly_alert_threshold = $1 WHERE id = $2`, threshold, id)
+	if err != nil {
+		return fmt.Errorf("failed to update alert threshold: %w", err)
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// CountByRole returns how many users have the given role
+func (r *userRepository) CountByRole(ctx context.Context, role string) (int, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var count int
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM users WHERE role = $1`, role).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count users by role: %w", err)
+	}
+	return count, nil
+}
+
+// Delete removes a user; their transactions, tokens and other rows go with them through
+// ON DELETE CASCADE. It returns the receipt keys those transactions referenced, so the caller
+// can remove the files, pgx.ErrNoRows if there was no such user, or ErrLastAdmin if the user
+// is the only admin.
+func (r *userRepository) Delete(ctx context.Context, id int) ([]string, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin user deletion: %w", err)
+	}
+	defer tx.Rollback(ctx) // No-op after commit
+
+	// Without an admin, the next registration would bootstrap a new one
+	if err := ensureAnotherAdmin(ctx, tx, id); err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, `SELECT receipt_path FROM transactions WHERE user_id = $1 AND receipt_path IS NOT NULL AND receipt_path <> ''`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list receipts of user: %w", err)
+	}
+	receiptKeys, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan receipt path: %w", err)
+	}
+
+	cmdTag, err := tx.Exec(ctx, `DELETE FROM users WHERE id = $1`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete user: %w", err)
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return nil, pgx.ErrNoRows
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit user deletion: %w", err)
+	}
+	return receiptKeys, nil
+}
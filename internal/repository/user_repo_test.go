@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"expense_tracker/internal/config"
+	"expense_tracker/internal/model"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestDB connects to TEST_DATABASE_URL and migrates it, skipping the test when it is unset
+func newTestDB(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	pool, err := pgxpool.New(context.Background(), dsn)
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	if err := config.AutoMigrate(pool); err != nil {
+		t.Fatalf("failed to migrate test database: %v", err)
+	}
+	return pool
+}
+
+func TestUserRepository_Create_DuplicatePhone(t *testing.T) {
+	pool := newTestDB(t)
+	repo := NewUserRepository(pool)
+	ctx := context.Background()
+
+	phone := fmt.Sprintf("+998%09d", time.Now().UnixNano()%1000000000)
+	t.Cleanup(func() { pool.Exec(ctx, `DELETE FROM users WHERE phone = $1`, phone) })
+
+	first := &model.User{Phone: phone, PasswordHash: "hash", Role: model.RoleUser, CreatedAt: time.Now()}
+	assert.NoError(t, repo.Create(ctx, first))
+
+	second := &model.User{Phone: phone, PasswordHash: "hash", Role: model.RoleUser, CreatedAt: time.Now()}
+	assert.ErrorIs(t, repo.Create(ctx, second), ErrDuplicatePhone)
+}
+
+func TestUserRepository_CreateFirstAdmin_Concurrent(t *testing.T) {
+	pool := newTestDB(t)
+	repo := NewUserRepository(pool)
+	ctx := context.Background()
+
+	adminsBefore, err := repo.CountByRole(ctx, model.RoleAdmin)
+	assert.NoError(t, err)
+
+	base := time.Now().UnixNano() % 100000000
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		phone := fmt.Sprintf("+998%09d", base*10+int64(i))
+		t.Cleanup(func() { pool.Exec(ctx, `DELETE FROM users WHERE phone = $1`, phone) })
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			user := &model.User{Phone: phone, PasswordHash: "hash", CreatedAt: time.Now()}
+			assert.NoError(t, repo.CreateFirstAdmin(ctx, user))
+		}()
+	}
+	wg.Wait()
+
+	adminsAfter, err := repo.CountByRole(ctx, model.RoleAdmin)
+	assert.NoError(t, err)
+	if adminsBefore == 0 {
+		assert.Equal(t, 1, adminsAfter, "exactly one concurrent registration becomes the first admin")
+	} else {
+		assert.Equal(t, adminsBefore, adminsAfter, "no registration becomes admin once one exists")
+	}
+}
+
+func TestUserRepository_AlertThreshold(t *testing.T) {
+	pool := newTestDB(t)
+	repo := NewUserRepository(pool)
+	ctx := context.Background()
+
+	phone := fmt.Sprintf("+998%09d", time.Now().UnixNano()%1000000000)
+	t.Cleanup(func() { pool.Exec(ctx, `DELETE FROM users WHERE phone = $1`, phone) })
+	user := &model.User{Phone: phone, PasswordHash: "hash", Role: model.RoleUser, CreatedAt: time.Now()}
+	assert.NoError(t, repo.Create(ctx, user))
+
+	threshold := int64(100000)
+	assert.NoError(t, repo.UpdateAlertThreshold(ctx, user.ID, &threshold))
+	found, err := repo.FindByID(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, &threshold, found.MonthlyAlertThreshold)
+	assert.NoError(t, repo.UpdateAlertThreshold(ctx, user.ID, nil))
+	found, err = repo.FindByID(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, found.MonthlyAlertThreshold)
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"expense_tracker/internal/model"
+	"expense_tracker/internal/repository"
+	"expense_tracker/internal/tracing"
+)
+
+var (
+	ErrAccountNotFound  = repository.ErrAccountNotFound
+	ErrAccountExists    = errors.New("an account with this name already exists")
+	ErrBlankAccountName = errors.New("account name must not be blank")
+)
+
+// AccountService manages a user's accounts (wallets) that transfers move money between
+type AccountService interface {
+	ListAccounts(ctx context.Context, userID int) ([]model.Account, error)
+	GetAccount(ctx context.Context, accountID int, userID int) (*model.Account, error)
+	CreateAccount(ctx context.Context, userID int, req model.CreateAccountRequest) (*model.Account, error)
+	RenameAccount(ctx context.Context, accountID int, userID int, req model.UpdateAccountRequest) (*model.Account, error)
+	DeleteAccount(ctx context.Context, accountID int, userID int) error
+}
+
+type accountService struct {
+	repo repository.AccountRepository
+}
+
+// NewAccountService creates a new AccountService
+func NewAccountService(repo repository.AccountRepository) AccountService {
+	return &accountService{repo: repo}
+}
+
+func (s *accountService) ListAccounts(ctx context.Context, userID int) ([]model.Account, error) {
+	ctx, span := tracing.Start(ctx, "AccountService.ListAccounts")
+	defer span.End()
+
+	return s.repo.FindByUser(ctx, userID)
+}
+
+func (s *accountService) GetAccount(ctx context.Context, accountID int, userID int) (*model.Account, error) {
+	ctx, span := tracing.Start(ctx, "AccountService.GetAccount")
+	defer span.End()
+
+	account, err := s.repo.FindByID(ctx, accountID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		return nil, ErrAccountNotFound
+	}
+	return account, nil
+}
+
+func (s *accountService) CreateAccount(ctx context.Context, userID int, req model.CreateAccountRequest) (*model.Account, error) {
+	ctx, span := tracing.Start(ctx, "AccountService.CreateAccount")
+	defer span.End()
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, ErrBlankAccountName
+	}
+
+	account := &model.Account{UserID: userID, Name: name, Balance: req.Balance}
+	if err := s.repo.Create(ctx, account); err != nil {
+		if errors.Is(err, repository.ErrDuplicateAccount) {
+			return nil, ErrAccountExists
+		}
+		return nil, err
+	}
+	return account, nil
+}
+
+func (s *accountService) RenameAccount(ctx context.Context, accountID int, userID int, req model.UpdateAccountRequest) (*model.Account, error) {
+	ctx, span := tracing.Start(ctx, "AccountService.RenameAccount")
+	defer span.End()
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, ErrBlankAccountName
+	}
+
+	account := &model.Account{ID: accountID, UserID: userID, Name: name}
+	renamed, err := s.repo.Rename(ctx, account)
+	if err != nil {
+		if errors.Is(err, repository.ErrDuplicateAccount) {
+			return nil, ErrAccountExists
+		}
+		return nil, err
+	}
+	if !renamed {
+		return nil, ErrAccountNotFound
+	}
+	return account, nil
+}
+
+func (s *accountService) DeleteAccount(ctx context.Context, accountID int, userID int) error {
+	ctx, span := tracing.Start(ctx, "AccountService.DeleteAccount")
+	defer span.End()
+
+	deleted, err := s.repo.Delete(ctx, accountID, userID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrAccountNotFound
+	}
+	return nil
+}
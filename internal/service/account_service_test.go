@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"expense_tracker/internal/model"
+	"expense_tracker/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeAccountRepo keeps accounts in memory; unimplemented methods panic via the nil embedded interface
+type fakeAccountRepo struct {
+	repository.AccountRepository
+	accounts []model.Account
+}
+
+func (r *fakeAccountRepo) Create(ctx context.Context, account *model.Account) error {
+	for _, a := range r.accounts {
+		if a.UserID == account.UserID && strings.EqualFold(a.Name, account.Name) {
+			return repository.ErrDuplicateAccount
+		}
+	}
+	account.ID = len(r.accounts) + 1
+	r.accounts = append(r.accounts, *account)
+	return nil
+}
+
+func (r *fakeAccountRepo) Rename(ctx context.Context, account *model.Account) (bool, error) {
+	for i, a := range r.accounts {
+		if a.ID == account.ID && a.UserID == account.UserID {
+			r.accounts[i].Name = account.Name
+			*account = r.accounts[i]
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestCreateAccount(t *testing.T) {
+	svc := NewAccountService(&fakeAccountRepo{})
+	ctx := context.Background()
+
+	account, err := svc.CreateAccount(ctx, 1, model.CreateAccountRequest{Name: "  Cash ", Balance: 5000})
+	assert.NoError(t, err)
+	assert.Equal(t, "Cash", account.Name)
+	assert.Equal(t, int64(5000), account.Balance)
+
+	_, err = svc.CreateAccount(ctx, 1, model.CreateAccountRequest{Name: "cash"})
+	assert.ErrorIs(t, err, ErrAccountExists)
+
+	_, err = svc.CreateAccount(ctx, 1, model.CreateAccountRequest{Name: "   "})
+	assert.ErrorIs(t, err, ErrBlankAccountName)
+}
+
+func TestRenameAccount(t *testing.T) {
+	repo := &fakeAccountRepo{}
+	svc := NewAccountService(repo)
+	ctx := context.Background()
+	account, err := svc.CreateAccount(ctx, 1, model.CreateAccountRequest{Name: "Cash", Balance: 5000})
+	assert.NoError(t, err)
+
+	renamed, err := svc.RenameAccount(ctx, account.ID, 1, model.UpdateAccountRequest{Name: "Wallet"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Wallet", renamed.Name)
+	assert.Equal(t, int64(5000), renamed.Balance)
+
+	_, err = svc.RenameAccount(ctx, account.ID, 2, model.UpdateAccountRequest{Name: "Mine now"})
+	assert.ErrorIs(t, err, ErrAccountNotFound)
+}
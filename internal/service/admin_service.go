@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+
+	"expense_tracker/internal/model"
+	"expense_tracker/internal/repository"
+	"expense_tracker/internal/tracing"
+)
+
+var (
+	ErrInvalidRole = fmt.Errorf("role must be %q or %q", model.RoleUser, model.RoleAdmin)
+	ErrLastAdmin   = errors.New("cannot demote the last remaining admin")
+	// ErrAdminNoteTooLong is returned when an admin note exceeds model.MaxAdminNoteLength
+	ErrAdminNoteTooLong = fmt.Errorf("note must be at most %d characters", model.MaxAdminNoteLength)
+)
+
+// AdminService provides user management and transaction review for admins
+type AdminService interface {
+	SetUserRole(ctx context.Context, actorID, userID int, role string) (*model.User, error)
+	SetTransactionNote(ctx context.Context, transactionID int64, note string) (*string, error)
+}
+
+type adminService struct {
+	userRepo         repository.UserRepository
+	transactionRepo  repository.TransactionRepository
+	refreshTokenRepo repository.RefreshTokenRepository
+	userStatus       UserStatusInvalidator // Optional
+}
+
+// NewAdminService creates a new AdminService. userStatus may be nil.
+func NewAdminService(userRepo repository.UserRepository, transactionRepo repository.TransactionRepository, refreshTokenRepo repository.RefreshTokenRepository, userStatus UserStatusInvalidator) AdminService {
+	return &adminService{userRepo: userRepo, transactionRepo: transactionRepo, refreshTokenRepo: refreshTokenRepo, userStatus: userStatus}
+}
+
+// SetUserRole changes a user's role on behalf of the admin actorID. Demoting the only
+// admin is refused, since nobody could promote anyone afterwards. The user's refresh tokens
+// are revoked, so they sign in again to get tokens carrying the new role.
+func (s *adminService) SetUserRole(ctx context.Context, actorID, userID int, role string) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "AdminService.SetUserRole")
+	defer span.End()
+
+	if role != model.RoleUser && role != model.RoleAdmin {
+		return nil, ErrInvalidRole
+	}
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error finding user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	if user.Role == role {
+		return user, nil
+	}
+
+	if err := s.userRepo.UpdateRole(ctx, userID, role); err != nil {
+		if errors.Is(err, repository.ErrLastAdmin) {
+			return nil, ErrLastAdmin
+		}
+		return nil, fmt.Errorf("failed to update role: %w", err)
+	}
+	slog.Info("User role changed", "actor_id", actorID, "user_id", userID, "old_role", user.Role, "new_role", role)
+
+	// The role has changed either way, so the cleanup failing is only logged
+	if s.userStatus != nil {
+		s.userStatus.Invalidate(userID)
+	}
+	if err := s.refreshTokenRepo.RevokeAllForUser(ctx, userID); err != nil {
+		slog.Error("Failed to revoke refresh tokens after role change", "user_id", userID, "error", err)
+	}
+
+	user.Role = role
+	return user, nil
+}
+
+// SetTransactionNote attaches an admin's note to a transaction, replacing any earlier one, and
+// returns the note as stored. The note is trimmed; a blank note removes it.
+func (s *adminService) SetTransactionNote(ctx context.Context, transactionID int64, note string) (*string, error) {
+	ctx, span := tracing.Start(ctx, "AdminService.SetTransactionNote")
+	defer span.End()
+
+	var stored *string
+	if trimmed := strings.TrimSpace(note); trimmed != "" {
+		if utf8.RuneCountInString(trimmed) > model.MaxAdminNoteLength {
+			return nil, ErrAdminNoteTooLong
+		}
+		stored = &trimmed
+	}
+
+	found, err := s.transactionRepo.SetAdminNote(ctx, transactionID, stored)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set admin note: %w", err)
+	}
+	if !found {
+		return nil, ErrTransactionNotFound
+	}
+	return stored, nil
+}
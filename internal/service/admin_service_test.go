@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"expense_tracker/internal/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetUserRole(t *testing.T) {
+	users := &fakeUserRepo{users: map[int]*model.User{
+		1: {ID: 1, Role: model.RoleAdmin},
+		2: {ID: 2, Role: model.RoleUser},
+	}}
+	tokens := newFakeRefreshTokenRepo()
+	tokens.tokens[1] = &model.RefreshToken{ID: 1, UserID: 1}
+	status := NewUserStatusCache(users, time.Minute)
+	svc := NewAdminService(users, newFakeTransactionRepo(), tokens, status)
+
+	_, err := svc.SetUserRole(context.Background(), 1, 2, "superuser")
+	assert.ErrorIs(t, err, ErrInvalidRole)
+
+	_, err = svc.SetUserRole(context.Background(), 1, 42, model.RoleAdmin)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+
+	// The only admin can't demote themselves
+	_, err = svc.SetUserRole(context.Background(), 1, 1, model.RoleUser)
+	assert.ErrorIs(t, err, ErrLastAdmin)
+
+	user, err := svc.SetUserRole(context.Background(), 1, 2, model.RoleAdmin)
+	assert.NoError(t, err)
+	assert.Equal(t, model.RoleAdmin, user.Role)
+
+	role, _ := status.UserRole(context.Background(), 1)
+	assert.Equal(t, model.RoleAdmin, role)
+
+	// With a second admin in place, stepping down is fine, and the old sessions go with it
+	_, err = svc.SetUserRole(context.Background(), 1, 1, model.RoleUser)
+	assert.NoError(t, err)
+	assert.Equal(t, model.RoleUser, users.users[1].Role)
+	assert.True(t, tokens.tokens[1].Revoked)
+	role, _ = status.UserRole(context.Background(), 1)
+	assert.Equal(t, model.RoleUser, role)
+}
+
+func TestSetTransactionNote(t *testing.T) {
+	repo := newFakeTransactionRepo()
+	repo.transactions[1] = &model.Transaction{ID: 1, UserID: 2}
+	svc := NewAdminService(&fakeUserRepo{}, repo, newFakeRefreshTokenRepo(), nil)
+	ctx := context.Background()
+
+	note, err := svc.SetTransactionNote(ctx, 1, "  Looks like a duplicate of #7 ")
+	assert.NoError(t, err)
+	assert.Equal(t, "Looks like a duplicate of #7", *note)
+	assert.Equal(t, "Looks like a duplicate of #7", *repo.transactions[1].AdminNote)
+
+	_, err = svc.SetTransactionNote(ctx, 1, strings.Repeat("я", model.MaxAdminNoteLength+1))
+	assert.ErrorIs(t, err, ErrAdminNoteTooLong)
+
+	note, err = svc.SetTransactionNote(ctx, 1, "  ")
+	assert.NoError(t, err)
+	assert.Nil(t, note)
+	assert.Nil(t, repo.transactions[1].AdminNote)
+
+	_, err = svc.SetTransactionNote(ctx, 42, "note")
+	assert.ErrorIs(t, err, ErrTransactionNotFound)
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"crypto/subtle"
 	"errors"
 	"fmt"
 	"log"
@@ -10,100 +11,432 @@ import (
 
 	"expense_tracker/internal/model"
 	"expense_tracker/internal/repository"
+	"expense_tracker/internal/storage"
+	"expense_tracker/internal/tracing"
 	"expense_tracker/internal/utils"
 
 	"github.com/jackc/pgx/v5"
 )
 
 var (
-	ErrUserAlreadyExists  = errors.New("user with this phone number already exists")
-	ErrUserNotFound       = errors.New("user not found") // Though Login groups this with InvalidCredentials
-	ErrInvalidCredentials = errors.New("invalid phone or password")
+	ErrUserAlreadyExists   = errors.New("user with this phone number already exists")
+	ErrUserNotFound        = errors.New("user not found") // Though Login groups this with InvalidCredentials
+	ErrInvalidCredentials  = errors.New("invalid phone or password")
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrIncorrectPassword   = errors.New("current password is incorrect")
+	ErrPasswordTooShort    = fmt.Errorf("password must be at least %d characters", MinPasswordLength)
+	ErrInvalidPhone        = utils.ErrInvalidPhone
+	ErrLastAdminAccount    = errors.New("the last remaining admin can't delete their account")
+	ErrInvalidThreshold    = errors.New("alert threshold must not be negative")
 )
 
+// MinPasswordLength is the shortest password accepted at registration and on password change
+const MinPasswordLength = 6
+
 // AuthService provides authentication related services
 type AuthService interface {
-	Register(ctx context.Context, phone, password string) (*model.User, string, error)
-	Login(ctx context.Context, phone, password string) (*model.User, string, error)
+	Register(ctx context.Context, phone, password, invite string) (*model.User, *model.AuthTokens, error)
+	Login(ctx context.Context, phone, password string) (*model.User, *model.AuthTokens, error)
+	Refresh(ctx context.Context, refreshToken string) (*model.User, *model.AuthTokens, error)
+	Logout(ctx context.Context, userID int, jti string, expiresAt time.Time, refreshToken string) error
+	ChangePassword(ctx context.Context, userID int, oldPassword, newPassword string) error
+	GetProfile(ctx context.Context, userID int) (*model.User, error)
+	SetCurrency(ctx context.Context, userID int, currency string) (*model.User, error)
+	SetAlertThreshold(ctx context.Context, userID int, threshold *int64) (*model.User, error)
+	GetSpendingAlert(ctx context.Context, user *model.User) (*model.SpendingAlert, error)
+	DeleteAccount(ctx context.Context, userID int, password, jti string, expiresAt time.Time) error
 }
 
 type authService struct {
-	userRepo repository.UserRepository
-	jwtUtil  *utils.JWTUtil
+	userRepo         repository.UserRepository
+	transactionRepo  repository.TransactionRepository
+	refreshTokenRepo repository.RefreshTokenRepository
+	blacklistRepo    repository.TokenBlacklistRepository
+	jwtUtil          *utils.JWTUtil
+	storage          storage.ReceiptStorage
+	userStatus       UserStatusInvalidator // Optional
+	hooks            TransactionHooks      // Optional
 }
 
-// NewAuthService creates a new AuthService
-func NewAuthService(userRepo repository.UserRepository, jwtUtil *utils.JWTUtil) AuthService {
+// NewAuthService creates a new AuthService. userStatus and hooks may be nil.
+func NewAuthService(userRepo repository.UserRepository, transactionRepo repository.TransactionRepository, refreshTokenRepo repository.RefreshTokenRepository, blacklistRepo repository.TokenBlacklistRepository, jwtUtil *utils.JWTUtil, receiptStorage storage.ReceiptStorage, userStatus UserStatusInvalidator, hooks TransactionHooks) AuthService {
 	return &authService{
-		userRepo: userRepo,
-		jwtUtil:  jwtUtil,
+		userRepo:         userRepo,
+		transactionRepo:  transactionRepo,
+		refreshTokenRepo: refreshTokenRepo,
+		blacklistRepo:    blacklistRepo,
+		jwtUtil:          jwtUtil,
+		storage:          receiptStorage,
+		userStatus:       userStatus,
+		hooks:            hooks,
+	}
+}
+
+// issueTokens generates an access token and stores a new refresh token for the user
+func (s *authService) issueTokens(ctx context.Context, user *model.User) (*model.AuthTokens, error) {
+	// Taken before signing, so the reported expiry is never later than the token's own
+	expiresAt := s.jwtUtil.ExpiresAt(time.Now()).Truncate(time.Second)
+	accessToken, err := s.jwtUtil.GenerateToken(user.ID, user.Role)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	refresh, err := s.jwtUtil.GenerateRefreshToken(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.refreshTokenRepo.Create(ctx, &model.RefreshToken{
+		UserID:    user.ID,
+		TokenHash: refresh.Hash,
+		ExpiresAt: refresh.ExpiresAt,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to store refresh token: %w", err)
+	}
+
+	return &model.AuthTokens{AccessToken: accessToken, RefreshToken: refresh.Token, ExpiresAt: expiresAt}, nil
+}
+
+// isAdminBootstrap reports whether a registration should create the first admin. Only while
+// no admin exists: with ADMIN_INVITE_CODE set the invite must match it, with INITIAL_ADMIN_PHONE
+// set the phone must match it, and with neither the first registration qualifies. Once an admin
+// exists every registration is a plain user, whatever the environment says. This is only a
+// pre-check: CreateFirstAdmin repeats the admin check under a lock when inserting.
+func (s *authService) isAdminBootstrap(ctx context.Context, phone, invite string) (bool, error) {
+	admins, err := s.userRepo.CountByRole(ctx, model.RoleAdmin)
+	if err != nil {
+		return false, fmt.Errorf("failed to count admins: %w", err)
+	}
+	if admins > 0 {
+		return false, nil
 	}
+
+	if inviteCode := os.Getenv("ADMIN_INVITE_CODE"); inviteCode != "" {
+		return subtle.ConstantTimeCompare([]byte(invite), []byte(inviteCode)) == 1, nil
+	}
+	if initialAdminPhone := os.Getenv("INITIAL_ADMIN_PHONE"); initialAdminPhone != "" {
+		normalized, err := utils.NormalizePhone(initialAdminPhone)
+		return err == nil && phone == normalized, nil
+	}
+	return true, nil
 }
 
 // Register creates a new user account
-func (s *authService) Register(ctx context.Context, phone, password string) (*model.User, string, error) {
+func (s *authService) Register(ctx context.Context, phone, password, invite string) (*model.User, *model.AuthTokens, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.Register")
+	defer span.End()
+
+	phone, err := utils.NormalizePhone(phone)
+	if err != nil {
+		return nil, nil, ErrInvalidPhone
+	}
+
 	existingUser, err := s.userRepo.FindByPhone(ctx, phone)
 	// We expect pgx.ErrNoRows if the user does not exist, which is not an error in this context.
 	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
-		return nil, "", fmt.Errorf("failed to check existing user: %w", err)
+		return nil, nil, fmt.Errorf("failed to check existing user: %w", err)
 	}
 	if existingUser != nil {
-		return nil, "", ErrUserAlreadyExists
+		return nil, nil, ErrUserAlreadyExists
 	}
 
 	hashedPassword, err := utils.HashPassword(password)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to hash password: %w", err)
+		return nil, nil, fmt.Errorf("failed to hash password: %w", err)
 	}
 
-	userRole := model.RoleUser // Default role
-
-	// Check for initial admin setup via environment variable
-	initialAdminPhone := os.Getenv("INITIAL_ADMIN_PHONE")
-	if initialAdminPhone != "" && phone == initialAdminPhone {
-
-		userRole = model.RoleAdmin
-		log.Printf("INFO: User %s is being registered as ADMIN via INITIAL_ADMIN_PHONE.", phone)
+	bootstrap, err := s.isAdminBootstrap(ctx, phone, invite)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	user := &model.User{
 		Phone:        phone,
 		PasswordHash: hashedPassword,
-		Role:         userRole, // Set role based on logic above
+		Role:         model.RoleUser, // Default role
+		Currency:     model.DefaultCurrency,
 		CreatedAt:    time.Now(),
 	}
 
-	if err := s.userRepo.Create(ctx, user); err != nil {
-		return nil, "", fmt.Errorf("failed to create user in repository: %w", err)
+	create := s.userRepo.Create
+	if bootstrap {
+		create = s.userRepo.CreateFirstAdmin // Decides the role atomically with the insert
+	}
+	if err := create(ctx, user); err != nil {
+		if errors.Is(err, repository.ErrDuplicatePhone) { // Lost a race with a concurrent registration
+			return nil, nil, ErrUserAlreadyExists
+		}
+		return nil, nil, fmt.Errorf("failed to create user in repository: %w", err)
+	}
+	if user.Role == model.RoleAdmin {
+		log.Printf("INFO: User %s was registered as the first ADMIN.", phone)
 	}
 
-	token, err := s.jwtUtil.GenerateToken(user.ID, user.Role)
+	tokens, err := s.issueTokens(ctx, user)
 	if err != nil {
 		log.Printf("ERROR: User %s (ID: %d) created, but failed to generate token: %v", user.Phone, user.ID, err)
-		return user, "", fmt.Errorf("user created, but failed to generate token: %w", err)
+		return user, nil, fmt.Errorf("user created, but failed to generate token: %w", err)
 	}
 
-	return user, token, nil
+	return user, tokens, nil
 }
 
 // Login authenticates a user and returns a JWT token
-func (s *authService) Login(ctx context.Context, phone, password string) (*model.User, string, error) {
-	user, err := s.userRepo.FindByPhone(ctx, phone)
+func (s *authService) Login(ctx context.Context, phone, password string) (*model.User, *model.AuthTokens, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.Login")
+	defer span.End()
+
+	normalized, err := utils.NormalizePhone(phone)
+	if err != nil {
+		return nil, nil, ErrInvalidCredentials // No account can have this phone
+	}
+
+	user, err := s.userRepo.FindByPhone(ctx, normalized)
+	if err == nil && user == nil && normalized != phone {
+		// Accounts registered before normalization keep the phone exactly as it was typed
+		user, err = s.userRepo.FindByPhone(ctx, phone)
+	}
 	if err != nil && !errors.Is(err, pgx.ErrNoRows) { // Handle actual DB errors
-		return nil, "", fmt.Errorf("error finding user by phone: %w", err)
+		return nil, nil, fmt.Errorf("error finding user by phone: %w", err)
 	}
 	if user == nil { // This covers pgx.ErrNoRows or if FindByPhone returns nil for not found
-		return nil, "", ErrInvalidCredentials // User not found
+		return nil, nil, ErrInvalidCredentials // User not found
 	}
 
 	if !utils.CheckPasswordHash(password, user.PasswordHash) {
-		return nil, "", ErrInvalidCredentials // Password mismatch
+		return nil, nil, ErrInvalidCredentials // Password mismatch
+	}
+
+	tokens, err := s.issueTokens(ctx, user)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	token, err := s.jwtUtil.GenerateToken(user.ID, user.Role)
+	return user, tokens, nil
+}
+
+// Refresh rotates a refresh token: the presented token is revoked and a new pair is issued.
+// Presenting an already-revoked token is treated as theft and revokes all of the user's refresh tokens.
+func (s *authService) Refresh(ctx context.Context, refreshToken string) (*model.User, *model.AuthTokens, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.Refresh")
+	defer span.End()
+
+	stored, err := s.refreshTokenRepo.FindByHash(ctx, utils.HashRefreshToken(refreshToken))
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to generate token: %w", err)
+		return nil, nil, fmt.Errorf("error finding refresh token: %w", err)
+	}
+	if stored == nil {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+	if stored.Revoked {
+		return nil, nil, s.handleRefreshTokenReuse(ctx, stored.UserID)
+	}
+	if time.Now().After(stored.ExpiresAt) {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+
+	revoked, err := s.refreshTokenRepo.Revoke(ctx, stored.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !revoked { // Another request rotated this token first
+		return nil, nil, s.handleRefreshTokenReuse(ctx, stored.UserID)
+	}
+
+	user, err := s.userRepo.FindByID(ctx, stored.UserID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error finding user for refresh: %w", err)
+	}
+	if user == nil {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+
+	tokens, err := s.issueTokens(ctx, user)
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, tokens, nil
+}
+
+// handleRefreshTokenReuse revokes the user's whole token chain after a rotated token was replayed
+func (s *authService) handleRefreshTokenReuse(ctx context.Context, userID int) error {
+	log.Printf("WARNING: Reuse of a rotated refresh token detected for user %d, revoking all refresh tokens", userID)
+	if err := s.refreshTokenRepo.RevokeAllForUser(ctx, userID); err != nil {
+		return err
+	}
+	return ErrInvalidRefreshToken
+}
+
+// Logout revokes the current access token until it expires. If a refresh token
+// belonging to the same user is supplied, it is revoked as well.
+func (s *authService) Logout(ctx context.Context, userID int, jti string, expiresAt time.Time, refreshToken string) error {
+	ctx, span := tracing.Start(ctx, "AuthService.Logout")
+	defer span.End()
+
+	if jti != "" {
+		if err := s.blacklistRepo.Add(ctx, jti, expiresAt); err != nil {
+			return err
+		}
 	}
 
-	return user, token, nil
+	if refreshToken == "" {
+		return nil
+	}
+	stored, err := s.refreshTokenRepo.FindByHash(ctx, utils.HashRefreshToken(refreshToken))
+	if err != nil {
+		return fmt.Errorf("error finding refresh token: %w", err)
+	}
+	if stored == nil || stored.UserID != userID || stored.Revoked {
+		return nil // Nothing of this user's left to revoke
+	}
+	if _, err := s.refreshTokenRepo.Revoke(ctx, stored.ID); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ChangePassword replaces the user's password after verifying the current one
+func (s *authService) ChangePassword(ctx context.Context, userID int, oldPassword, newPassword string) error {
+	ctx, span := tracing.Start(ctx, "AuthService.ChangePassword")
+	defer span.End()
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("error finding user: %w", err)
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+
+	if !utils.CheckPasswordHash(oldPassword, user.PasswordHash) {
+		return ErrIncorrectPassword
+	}
+	if len(newPassword) < MinPasswordLength {
+		return ErrPasswordTooShort
+	}
+
+	hashedPassword, err := utils.HashPassword(newPassword)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+	return s.userRepo.UpdatePassword(ctx, userID, hashedPassword)
+}
+
+// DeleteAccount deletes the user and all their data after verifying their password, then
+// removes their receipt files and revokes the access token the request was made with
+func (s *authService) DeleteAccount(ctx context.Context, userID int, password, jti string, expiresAt time.Time) error {
+	ctx, span := tracing.Start(ctx, "AuthService.DeleteAccount")
+	defer span.End()
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("error finding user: %w", err)
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+	if !utils.CheckPasswordHash(password, user.PasswordHash) {
+		return ErrIncorrectPassword
+	}
+
+	receiptKeys, err := s.userRepo.Delete(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		if errors.Is(err, repository.ErrLastAdmin) {
+			return ErrLastAdminAccount
+		}
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+	log.Printf("INFO: User %d deleted their account.", userID)
+	if s.userStatus != nil {
+		s.userStatus.Invalidate(userID)
+	}
+	if s.hooks != nil {
+		s.hooks.AfterUserDeleted(userID) // Their transactions went with them
+	}
+
+	// The account is already gone, so leftover files and a failed revocation are only logged
+	for _, key := range receiptKeys {
+		removeReceiptFiles(ctx, s.storage, key)
+	}
+	if jti != "" {
+		if err := s.blacklistRepo.Add(ctx, jti, expiresAt); err != nil {
+			log.Printf("Error revoking access token of deleted user %d: %v", userID, err)
+		}
+	}
+	return nil
+}
+
+// GetProfile returns the user a token was issued to; ErrUserNotFound if the account is gone
+func (s *authService) GetProfile(ctx context.Context, userID int) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.GetProfile")
+	defer span.End()
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error finding user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+// SetCurrency changes the currency the user's new transactions default to
+func (s *authService) SetCurrency(ctx context.Context, userID int, currency string) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.SetCurrency")
+	defer span.End()
+
+	currency, ok := model.NormalizeCurrency(currency)
+	if !ok {
+		return nil, ErrUnsupportedCurrency
+	}
+	user, err := s.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.UpdateCurrency(ctx, userID, currency); err != nil {
+		return nil, fmt.Errorf("failed to update currency: %w", err)
+	}
+	user.Currency = currency
+	return user, nil
+}
+
+// SetAlertThreshold sets the monthly expense total the user is warned above; nil removes the alert
+func (s *authService) SetAlertThreshold(ctx context.Context, userID int, threshold *int64) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.SetAlertThreshold")
+	defer span.End()
+
+	if threshold != nil && *threshold < 0 {
+		return nil, ErrInvalidThreshold
+	}
+	user, err := s.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.UpdateAlertThreshold(ctx, userID, threshold); err != nil {
+		return nil, fmt.Errorf("failed to update alert threshold: %w", err)
+	}
+	user.MonthlyAlertThreshold = threshold
+	return user, nil
+}
+
+// GetSpendingAlert reports the user's expenses so far this calendar month (UTC), in their
+// currency, and whether they exceed the user's alert threshold
+func (s *authService) GetSpendingAlert(ctx context.Context, user *model.User) (*model.SpendingAlert, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.GetSpendingAlert")
+	defer span.End()
+
+	now := time.Now().UTC()
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	spent, err := s.transactionRepo.SumExpenses(ctx, user.ID, user.Currency, monthStart, monthStart.AddDate(0, 1, 0))
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum month expenses: %w", err)
+	}
+	return &model.SpendingAlert{
+		Threshold:     user.MonthlyAlertThreshold,
+		MonthSpent:    spent,
+		OverThreshold: user.MonthlyAlertThreshold != nil && spent > *user.MonthlyAlertThreshold,
+	}, nil
 }
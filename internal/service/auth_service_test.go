@@ -0,0 +1,437 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"expense_tracker/internal/model"
+	"expense_tracker/internal/repository"
+	"expense_tracker/internal/storage"
+	"expense_tracker/internal/utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRefreshTokenRepo keeps refresh tokens in memory
+type fakeRefreshTokenRepo struct {
+	repository.RefreshTokenRepository
+	tokens map[int64]*model.RefreshToken
+	nextID int64
+}
+
+func newFakeRefreshTokenRepo() *fakeRefreshTokenRepo {
+	return &fakeRefreshTokenRepo{tokens: make(map[int64]*model.RefreshToken)}
+}
+
+func (r *fakeRefreshTokenRepo) Create(ctx context.Context, t *model.RefreshToken) error {
+	r.nextID++
+	t.ID = r.nextID
+	stored := *t
+	r.tokens[t.ID] = &stored
+	return nil
+}
+
+func (r *fakeRefreshTokenRepo) FindByHash(ctx context.Context, tokenHash string) (*model.RefreshToken, error) {
+	for _, t := range r.tokens {
+		if t.TokenHash == tokenHash {
+			found := *t
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeRefreshTokenRepo) Revoke(ctx context.Context, id int64) (bool, error) {
+	t, ok := r.tokens[id]
+	if !ok || t.Revoked {
+		return false, nil
+	}
+	t.Revoked = true
+	return true, nil
+}
+
+func (r *fakeRefreshTokenRepo) RevokeAllForUser(ctx context.Context, userID int) error {
+	for _, t := range r.tokens {
+		if t.UserID == userID {
+			t.Revoked = true
+		}
+	}
+	return nil
+}
+
+// fakeTokenBlacklistRepo keeps blacklisted token IDs in memory
+type fakeTokenBlacklistRepo struct {
+	repository.TokenBlacklistRepository
+	entries map[string]time.Time
+}
+
+func (r *fakeTokenBlacklistRepo) Add(ctx context.Context, jti string, expiresAt time.Time) error {
+	r.entries[jti] = expiresAt
+	return nil
+}
+
+func newTestAuthService() (*authService, *fakeRefreshTokenRepo) {
+	users := &fakeUserRepo{users: map[int]*model.User{1: {ID: 1, Role: model.RoleUser}}}
+	tokens := newFakeRefreshTokenRepo()
+	blacklist := &fakeTokenBlacklistRepo{entries: make(map[string]time.Time)}
+	svc := NewAuthService(users, newFakeTransactionRepo(), tokens, blacklist, utils.NewJWTUtil("secret", 1), nil, NewUserStatusCache(users, time.Minute), nil).(*authService)
+	return svc, tokens
+}
+
+func TestRefresh_RotatesToken(t *testing.T) {
+	svc, _ := newTestAuthService()
+	initial, err := svc.issueTokens(context.Background(), &model.User{ID: 1, Role: model.RoleUser})
+	assert.NoError(t, err)
+
+	user, rotated, err := svc.Refresh(context.Background(), initial.RefreshToken)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, user.ID)
+	assert.NotEmpty(t, rotated.AccessToken)
+	assert.NotEqual(t, initial.RefreshToken, rotated.RefreshToken)
+
+	// The rotated-out token can no longer be used
+	_, _, err = svc.Refresh(context.Background(), initial.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+}
+
+func TestIssueTokens_ExpiresAtMatchesToken(t *testing.T) {
+	svc, _ := newTestAuthService()
+	tokens, err := svc.issueTokens(context.Background(), &model.User{ID: 1, Role: model.RoleUser})
+	assert.NoError(t, err)
+
+	claims, err := svc.jwtUtil.ValidateToken(tokens.AccessToken)
+	assert.NoError(t, err)
+	assert.WithinDuration(t, claims.ExpiresAt.Time, tokens.ExpiresAt, time.Second)
+	assert.False(t, tokens.ExpiresAt.After(claims.ExpiresAt.Time))
+}
+
+func TestRefresh_ReuseRevokesWholeChain(t *testing.T) {
+	svc, tokens := newTestAuthService()
+	initial, _ := svc.issueTokens(context.Background(), &model.User{ID: 1, Role: model.RoleUser})
+	_, rotated, err := svc.Refresh(context.Background(), initial.RefreshToken)
+	assert.NoError(t, err)
+
+	// Replaying the old token revokes the newest one as well
+	_, _, err = svc.Refresh(context.Background(), initial.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	for _, tok := range tokens.tokens {
+		assert.True(t, tok.Revoked)
+	}
+	_, _, err = svc.Refresh(context.Background(), rotated.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+}
+
+func TestRefresh_ExpiredAndUnknownTokens(t *testing.T) {
+	svc, tokens := newTestAuthService()
+	initial, _ := svc.issueTokens(context.Background(), &model.User{ID: 1, Role: model.RoleUser})
+	for _, tok := range tokens.tokens {
+		tok.ExpiresAt = time.Now().Add(-time.Minute)
+	}
+
+	_, _, err := svc.Refresh(context.Background(), initial.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+	_, _, err = svc.Refresh(context.Background(), "not-a-real-token")
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+}
+
+func TestLogout_BlacklistsAccessTokenAndRevokesRefreshToken(t *testing.T) {
+	svc, tokens := newTestAuthService()
+	issued, err := svc.issueTokens(context.Background(), &model.User{ID: 1, Role: model.RoleUser})
+	assert.NoError(t, err)
+	claims, err := svc.jwtUtil.ValidateToken(issued.AccessToken)
+	assert.NoError(t, err)
+
+	err = svc.Logout(context.Background(), 1, claims.ID, claims.ExpiresAt.Time, issued.RefreshToken)
+	assert.NoError(t, err)
+
+	blacklist := svc.blacklistRepo.(*fakeTokenBlacklistRepo)
+	assert.Equal(t, claims.ExpiresAt.Time, blacklist.entries[claims.ID])
+	for _, stored := range tokens.tokens {
+		assert.True(t, stored.Revoked)
+	}
+}
+
+func TestLogout_IgnoresOtherUsersRefreshToken(t *testing.T) {
+	svc, tokens := newTestAuthService()
+	issued, err := svc.issueTokens(context.Background(), &model.User{ID: 2, Role: model.RoleUser})
+	assert.NoError(t, err)
+
+	err = svc.Logout(context.Background(), 1, "jti", time.Now().Add(time.Hour), issued.RefreshToken)
+	assert.NoError(t, err)
+	for _, stored := range tokens.tokens {
+		assert.False(t, stored.Revoked)
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	svc, _ := newTestAuthService()
+	users := svc.userRepo.(*fakeUserRepo)
+	hash, err := utils.HashPassword("oldpass")
+	assert.NoError(t, err)
+	users.users[1].PasswordHash = hash
+
+	err = svc.ChangePassword(context.Background(), 1, "wrongpass", "newpass1")
+	assert.ErrorIs(t, err, ErrIncorrectPassword)
+
+	err = svc.ChangePassword(context.Background(), 1, "oldpass", "short")
+	assert.ErrorIs(t, err, ErrPasswordTooShort)
+
+	err = svc.ChangePassword(context.Background(), 1, "oldpass", "newpass1")
+	assert.NoError(t, err)
+	assert.True(t, utils.CheckPasswordHash("newpass1", users.users[1].PasswordHash))
+}
+
+// racingUserRepo simulates a concurrent registration winning between FindByPhone and Create
+type racingUserRepo struct {
+	fakeUserRepo
+}
+
+func (r *racingUserRepo) FindByPhone(ctx context.Context, phone string) (*model.User, error) {
+	return nil, nil
+}
+
+func (r *racingUserRepo) Create(ctx context.Context, user *model.User) error {
+	return repository.ErrDuplicatePhone
+}
+
+func (r *racingUserRepo) CreateFirstAdmin(ctx context.Context, user *model.User) error {
+	return repository.ErrDuplicatePhone
+}
+
+// lateAdminUserRepo simulates another registration becoming the first admin between the
+// bootstrap pre-check and the insert
+type lateAdminUserRepo struct {
+	fakeUserRepo
+}
+
+func (r *lateAdminUserRepo) CountByRole(ctx context.Context, role string) (int, error) {
+	return 0, nil
+}
+
+func TestRegister_FirstAdminRace(t *testing.T) {
+	svc, _ := newTestAuthService()
+	repo := &lateAdminUserRepo{fakeUserRepo{users: map[int]*model.User{
+		1: {ID: 1, Phone: "+998901111111", Role: model.RoleAdmin},
+	}}}
+	svc.userRepo = repo
+
+	user, _, err := svc.Register(context.Background(), "+998902222222", "password", "")
+	assert.NoError(t, err)
+	assert.Equal(t, model.RoleUser, user.Role, "only one registration may become the first admin")
+	assert.Equal(t, 1, repo.countRole(model.RoleAdmin))
+}
+
+func TestRegister_DuplicatePhoneOnInsert(t *testing.T) {
+	svc, _ := newTestAuthService()
+	svc.userRepo = &racingUserRepo{}
+
+	_, _, err := svc.Register(context.Background(), "+998901234567", "password", "")
+	assert.ErrorIs(t, err, ErrUserAlreadyExists)
+}
+
+func TestRegisterAndLogin_NormalizePhone(t *testing.T) {
+	svc, _ := newTestAuthService()
+
+	user, _, err := svc.Register(context.Background(), "+998 (90) 123-45-67", "password", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "+998901234567", user.Phone)
+
+	_, _, err = svc.Register(context.Background(), "90 123 45 67", "password", "")
+	assert.ErrorIs(t, err, ErrUserAlreadyExists)
+
+	loggedIn, _, err := svc.Login(context.Background(), "00998901234567", "password")
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, loggedIn.ID)
+
+	_, _, err = svc.Register(context.Background(), "not a phone", "password", "")
+	assert.ErrorIs(t, err, ErrInvalidPhone)
+}
+
+func TestRegister_AdminBootstrap(t *testing.T) {
+	t.Setenv("ADMIN_INVITE_CODE", "")
+	t.Setenv("INITIAL_ADMIN_PHONE", "")
+
+	t.Run("first registration without an admin", func(t *testing.T) {
+		svc, _ := newTestAuthService()
+		first, _, err := svc.Register(context.Background(), "+998901111111", "password", "")
+		assert.NoError(t, err)
+		assert.Equal(t, model.RoleAdmin, first.Role)
+
+		second, _, err := svc.Register(context.Background(), "+998902222222", "password", "")
+		assert.NoError(t, err)
+		assert.Equal(t, model.RoleUser, second.Role)
+	})
+
+	t.Run("invite code required when configured", func(t *testing.T) {
+		t.Setenv("ADMIN_INVITE_CODE", "let-me-in")
+		svc, _ := newTestAuthService()
+		user, _, err := svc.Register(context.Background(), "+998901111111", "password", "wrong")
+		assert.NoError(t, err)
+		assert.Equal(t, model.RoleUser, user.Role)
+
+		admin, _, err := svc.Register(context.Background(), "+998902222222", "password", "let-me-in")
+		assert.NoError(t, err)
+		assert.Equal(t, model.RoleAdmin, admin.Role)
+
+		// The code is spent once an admin exists
+		again, _, err := svc.Register(context.Background(), "+998903333333", "password", "let-me-in")
+		assert.NoError(t, err)
+		assert.Equal(t, model.RoleUser, again.Role)
+	})
+
+	t.Run("initial admin phone only while there is no admin", func(t *testing.T) {
+		t.Setenv("INITIAL_ADMIN_PHONE", "+998904444444")
+		svc, _ := newTestAuthService()
+		svc.userRepo.(*fakeUserRepo).users[1].Role = model.RoleAdmin
+
+		user, _, err := svc.Register(context.Background(), "+998904444444", "password", "")
+		assert.NoError(t, err)
+		assert.Equal(t, model.RoleUser, user.Role)
+	})
+}
+
+func TestDeleteAccount(t *testing.T) {
+	svc, _ := newTestAuthService()
+	users := svc.userRepo.(*fakeUserRepo)
+	hash, err := utils.HashPassword("password")
+	assert.NoError(t, err)
+	users.users[1].PasswordHash = hash
+
+	uploadsDir := t.TempDir()
+	local, err := storage.NewLocalStorage(uploadsDir)
+	assert.NoError(t, err)
+	svc.storage = local
+	receiptKey := "transactions/5/receipt.png"
+	_, err = local.Save(context.Background(), receiptKey, bytes.NewReader(pngBytes))
+	assert.NoError(t, err)
+	users.receipts = map[int][]string{1: {receiptKey}}
+
+	transactions := newTestTransactionService(newFakeTransactionRepo(), nil)
+	transactions.stats = newStatsCache(time.Minute)
+	transactions.stats.set("all", &model.AggregatedStats{}, time.Now())
+	svc.hooks = transactions
+
+	status := svc.userStatus.(*UserStatusCache)
+	role, _ := status.UserRole(context.Background(), 1)
+	assert.Equal(t, model.RoleUser, role)
+
+	expiresAt := time.Now().Add(time.Hour)
+	err = svc.DeleteAccount(context.Background(), 1, "wrong", "jti-1", expiresAt)
+	assert.ErrorIs(t, err, ErrIncorrectPassword)
+	assert.Contains(t, users.users, 1)
+
+	assert.NoError(t, svc.DeleteAccount(context.Background(), 1, "password", "jti-1", expiresAt))
+	assert.NotContains(t, users.users, 1)
+	role, _ = status.UserRole(context.Background(), 1)
+	assert.Empty(t, role, "tokens of the deleted user stop working at once")
+	_, cached := transactions.stats.get("all", time.Now())
+	assert.False(t, cached, "the deleted user's transactions no longer count in admin stats")
+	assert.NoFileExists(t, filepath.Join(uploadsDir, receiptKey))
+	assert.Contains(t, svc.blacklistRepo.(*fakeTokenBlacklistRepo).entries, "jti-1")
+
+	err = svc.DeleteAccount(context.Background(), 1, "password", "", expiresAt)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}
+
+func TestDeleteAccount_LastAdmin(t *testing.T) {
+	svc, _ := newTestAuthService()
+	users := svc.userRepo.(*fakeUserRepo)
+	hash, err := utils.HashPassword("password")
+	assert.NoError(t, err)
+	users.users[1].PasswordHash = hash
+	users.users[1].Role = model.RoleAdmin
+
+	err = svc.DeleteAccount(context.Background(), 1, "password", "", time.Time{})
+	assert.ErrorIs(t, err, ErrLastAdminAccount)
+	assert.Contains(t, users.users, 1)
+}
+
+func TestGetProfile(t *testing.T) {
+	svc, _ := newTestAuthService()
+
+	user, err := svc.GetProfile(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, user.ID)
+
+	_, err = svc.GetProfile(context.Background(), 42)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}
+
+func TestSetCurrency(t *testing.T) {
+	svc, _ := newTestAuthService()
+
+	user, err := svc.SetCurrency(context.Background(), 1, "usd")
+	assert.NoError(t, err)
+	assert.Equal(t, "USD", user.Currency)
+
+	_, err = svc.SetCurrency(context.Background(), 1, "DOLLARS")
+	assert.ErrorIs(t, err, ErrUnsupportedCurrency)
+	profile, _ := svc.GetProfile(context.Background(), 1)
+	assert.Equal(t, "USD", profile.Currency)
+
+	_, err = svc.SetCurrency(context.Background(), 42, "EUR")
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}
+
+func TestSetAlertThreshold(t *testing.T) {
+	svc, _ := newTestAuthService()
+	threshold := int64(500000)
+
+	user, err := svc.SetAlertThreshold(context.Background(), 1, &threshold)
+	assert.NoError(t, err)
+	assert.Equal(t, &threshold, user.MonthlyAlertThreshold)
+
+	negative := int64(-1)
+	_, err = svc.SetAlertThreshold(context.Background(), 1, &negative)
+	assert.ErrorIs(t, err, ErrInvalidThreshold)
+	profile, _ := svc.GetProfile(context.Background(), 1)
+	assert.Equal(t, &threshold, profile.MonthlyAlertThreshold)
+
+	user, err = svc.SetAlertThreshold(context.Background(), 1, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, user.MonthlyAlertThreshold)
+
+	_, err = svc.SetAlertThreshold(context.Background(), 42, &threshold)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}
+
+func TestGetSpendingAlert(t *testing.T) {
+	svc, _ := newTestAuthService()
+	repo := svc.transactionRepo.(*fakeTransactionRepo)
+	now := time.Now().UTC()
+	for _, tx := range []model.Transaction{
+		{UserID: 1, Amount: 200000, Currency: "UZS", Type: model.TransactionTypeExpense, TransactionDate: now},
+		{UserID: 1, Amount: 100000, Currency: "UZS", Type: model.TransactionTypeExpense, TransactionDate: now},
+		{UserID: 1, Amount: 50000, Currency: "UZS", Type: model.TransactionTypeIncome, TransactionDate: now},
+		{UserID: 1, Amount: 2500, Currency: "USD", Type: model.TransactionTypeExpense, TransactionDate: now},
+		{UserID: 1, Amount: 70000, Currency: "UZS", Type: model.TransactionTypeExpense, TransactionDate: now.AddDate(0, -1, -1)},
+		{UserID: 2, Amount: 90000, Currency: "UZS", Type: model.TransactionTypeExpense, TransactionDate: now},
+	} {
+		assert.NoError(t, repo.Create(context.Background(), &tx))
+	}
+	user := &model.User{ID: 1, Currency: "UZS"}
+
+	// Without a threshold there is nothing to exceed
+	alert, err := svc.GetSpendingAlert(context.Background(), user)
+	assert.NoError(t, err)
+	assert.Equal(t, &model.SpendingAlert{MonthSpent: 300000}, alert)
+
+	for _, tc := range []struct {
+		threshold int64
+		over      bool
+	}{
+		{threshold: 200000, over: true},
+		{threshold: 300000, over: false}, // Reaching the threshold isn't exceeding it
+		{threshold: 400000, over: false},
+	} {
+		user.MonthlyAlertThreshold = &tc.threshold
+		alert, err := svc.GetSpendingAlert(context.Background(), user)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.over, alert.OverThreshold, "threshold %d", tc.threshold)
+		assert.Equal(t, int64(300000), alert.MonthSpent)
+	}
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/bits"
+	"time"
+
+	"expense_tracker/internal/model"
+	"expense_tracker/internal/tracing"
+)
+
+var ErrInvalidBreakdownType = errors.New("type must be income or expense")
+
+// GetUserCategoryBreakdown returns each category's total and share of all the user's transactions
+// of txType in currency (the user's own when empty) between start and end, largest first. end
+// follows the configured end date mode.
+func (s *transactionService) GetUserCategoryBreakdown(ctx context.Context, userID int, currency string, start, end time.Time, txType string) ([]model.CategoryShare, error) {
+	ctx, span := tracing.Start(ctx, "TransactionService.GetUserCategoryBreakdown")
+	defer span.End()
+
+	if txType != model.TransactionTypeIncome && txType != model.TransactionTypeExpense {
+		return nil, ErrInvalidBreakdownType
+	}
+	if start.After(end) {
+		return nil, ErrInvalidDateRange
+	}
+
+	currency, err := s.resolveCurrency(ctx, userID, currency)
+	if err != nil {
+		return nil, err
+	}
+	filters := model.UserTransactionFilters{Currency: currency, StartDate: &start, EndDate: &end, Types: []string{txType}}
+	s.applyEndDateMode(&filters)
+
+	shares, err := s.repo.GetCategoryTotals(ctx, userID, filters)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get category totals from repo: %w", err)
+	}
+
+	// Amounts are positive, and a uint64 holds the sum of any realistic number of them
+	var grandTotal uint64
+	for _, cs := range shares {
+		grandTotal += uint64(cs.Total)
+	}
+	for i := range shares {
+		shares[i].Percent = percentShare(uint64(shares[i].Total), grandTotal)
+	}
+	return shares, nil
+}
+
+// percentShare returns part as a percentage of whole rounded half up to two decimals. The
+// intermediate part*10000 is computed in 128 bits, so large totals cannot overflow.
+func percentShare(part, whole uint64) float64 {
+	if whole == 0 || part > whole {
+		return 0
+	}
+	hi, lo := bits.Mul64(part, 10000)
+	basisPoints, rem := bits.Div64(hi, lo, whole) // part <= whole keeps the quotient within 10000
+	if rem >= whole-rem {
+		basisPoints++
+	}
+	return float64(basisPoints) / 100
+}
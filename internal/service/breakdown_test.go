@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"expense_tracker/internal/config"
+	"expense_tracker/internal/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// categoryTotalsRepo returns canned category totals and records the filters used
+type categoryTotalsRepo struct {
+	*fakeTransactionRepo
+	totals  []model.CategoryShare
+	filters model.UserTransactionFilters
+}
+
+func (r *categoryTotalsRepo) GetCategoryTotals(ctx context.Context, userID int, filters model.UserTransactionFilters) ([]model.CategoryShare, error) {
+	r.filters = filters
+	return append([]model.CategoryShare{}, r.totals...), nil
+}
+
+func TestGetUserCategoryBreakdown(t *testing.T) {
+	repo := &categoryTotalsRepo{
+		fakeTransactionRepo: newFakeTransactionRepo(),
+		totals:              []model.CategoryShare{{Category: "food", Total: 2000}, {Category: "rent", Total: 1000}, {Category: "fun", Total: 1}},
+	}
+	svc := newTestTransactionService(repo.fakeTransactionRepo, nil)
+	svc.repo = repo
+
+	start, end := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 5, 31, 0, 0, 0, 0, time.UTC)
+	breakdown, err := svc.GetUserCategoryBreakdown(context.Background(), 1, "", start, end, model.TransactionTypeExpense)
+	assert.NoError(t, err)
+	assert.Equal(t, []model.CategoryShare{
+		{Category: "food", Total: 2000, Percent: 66.64},
+		{Category: "rent", Total: 1000, Percent: 33.32},
+		{Category: "fun", Total: 1, Percent: 0.03},
+	}, breakdown)
+	assert.Equal(t, []string{model.TransactionTypeExpense}, repo.filters.Types)
+	// The user's currency by default
+	assert.Equal(t, model.DefaultCurrency, repo.filters.Currency)
+	assert.Equal(t, time.Date(2024, 5, 31, 23, 59, 59, 999999999, time.UTC), *repo.filters.EndDate) // Inclusive end date mode
+
+	repo.totals = []model.CategoryShare{}
+	breakdown, err = svc.GetUserCategoryBreakdown(context.Background(), 1, "", start, end, model.TransactionTypeIncome)
+	assert.NoError(t, err)
+	assert.NotNil(t, breakdown)
+	assert.Empty(t, breakdown)
+}
+
+func TestGetUserCategoryBreakdown_InvalidInput(t *testing.T) {
+	svc := newTestTransactionService(newFakeTransactionRepo(), &config.TransactionConfig{})
+	now := time.Now()
+
+	_, err := svc.GetUserCategoryBreakdown(context.Background(), 1, "", now, now, model.TransactionTypeTransfer)
+	assert.ErrorIs(t, err, ErrInvalidBreakdownType)
+	_, err = svc.GetUserCategoryBreakdown(context.Background(), 1, "", now, now.Add(-time.Hour), model.TransactionTypeExpense)
+	assert.ErrorIs(t, err, ErrInvalidDateRange)
+}
+
+func TestPercentShare(t *testing.T) {
+	assert.Equal(t, 0.0, percentShare(5, 0))
+	assert.Equal(t, 100.0, percentShare(7, 7))
+	assert.Equal(t, 33.33, percentShare(1, 3))
+	assert.Equal(t, 66.67, percentShare(2, 3))
+	// part*10000 would overflow 64 bits
+	assert.Equal(t, 50.0, percentShare(math.MaxUint64/2, math.MaxUint64-1))
+}
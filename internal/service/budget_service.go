@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"expense_tracker/internal/model"
+	"expense_tracker/internal/repository"
+	"expense_tracker/internal/tracing"
+)
+
+var ErrInvalidMonth = errors.New("invalid month, use YYYY-MM")
+
+// budgetMonthLayout is the YYYY-MM format budgets are keyed by
+const budgetMonthLayout = "2006-01"
+
+// BudgetService manages monthly category budgets
+type BudgetService interface {
+	SetBudget(ctx context.Context, userID int, req model.SetBudgetRequest) (*model.Budget, error)
+	GetBudgetStatus(ctx context.Context, userID int, month string) ([]model.BudgetStatus, error)
+}
+
+type budgetService struct {
+	repo         repository.BudgetRepository
+	categoryRepo repository.CategoryRepository
+	userRepo     repository.UserRepository
+}
+
+// NewBudgetService creates a new BudgetService
+func NewBudgetService(repo repository.BudgetRepository, categoryRepo repository.CategoryRepository, userRepo repository.UserRepository) BudgetService {
+	return &budgetService{repo: repo, categoryRepo: categoryRepo, userRepo: userRepo}
+}
+
+// parseBudgetMonth returns the bounds [start, end) of a YYYY-MM month in UTC
+func parseBudgetMonth(month string) (time.Time, time.Time, error) {
+	parsed, err := time.Parse(budgetMonthLayout, month)
+	if err != nil {
+		return time.Time{}, time.Time{}, ErrInvalidMonth
+	}
+	return parsed, parsed.AddDate(0, 1, 0), nil
+}
+
+func (s *budgetService) SetBudget(ctx context.Context, userID int, req model.SetBudgetRequest) (*model.Budget, error) {
+	ctx, span := tracing.Start(ctx, "BudgetService.SetBudget")
+	defer span.End()
+
+	if _, _, err := parseBudgetMonth(req.Month); err != nil {
+		return nil, err
+	}
+
+	// Budgets are matched against transaction categories, so use the same canonical spelling
+	matches, err := s.categoryRepo.FindByName(ctx, userID, req.Category)
+	if err != nil {
+		return nil, err
+	}
+	category := ""
+	for _, c := range matches {
+		if c.Type == model.TransactionTypeExpense {
+			category = c.Name
+			break
+		}
+	}
+	if category == "" {
+		return nil, ErrUnknownCategory
+	}
+
+	budget := &model.Budget{
+		UserID:      userID,
+		Category:    category,
+		Month:       req.Month,
+		LimitAmount: req.LimitAmount,
+	}
+	if err := s.repo.Upsert(ctx, budget); err != nil {
+		return nil, err
+	}
+	return budget, nil
+}
+
+func (s *budgetService) GetBudgetStatus(ctx context.Context, userID int, month string) ([]model.BudgetStatus, error) {
+	ctx, span := tracing.Start(ctx, "BudgetService.GetBudgetStatus")
+	defer span.End()
+
+	monthStart, monthEnd, err := parseBudgetMonth(month)
+	if err != nil {
+		return nil, err
+	}
+
+	// Limits are set in the user's currency, so only expenses in it count against them
+	currency, err := s.userRepo.GetCurrency(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	statuses, err := s.repo.GetStatus(ctx, userID, currency, month, monthStart, monthEnd)
+	if err != nil {
+		return nil, err
+	}
+	for i := range statuses {
+		statuses[i].Remaining = statuses[i].LimitAmount - statuses[i].Spent
+		statuses[i].OverBudget = statuses[i].Spent > statuses[i].LimitAmount
+	}
+	return statuses, nil
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"expense_tracker/internal/model"
+	"expense_tracker/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeBudgetRepo records upserts and returns canned status rows
+type fakeBudgetRepo struct {
+	repository.BudgetRepository
+	saved        []model.Budget
+	statuses     []model.BudgetStatus
+	lastCurrency string
+	lastStart    time.Time
+	lastEnd      time.Time
+}
+
+func (r *fakeBudgetRepo) Upsert(ctx context.Context, budget *model.Budget) error {
+	r.saved = append(r.saved, *budget)
+	return nil
+}
+
+func (r *fakeBudgetRepo) GetStatus(ctx context.Context, userID int, currency, month string, monthStart, monthEnd time.Time) ([]model.BudgetStatus, error) {
+	r.lastCurrency, r.lastStart, r.lastEnd = currency, monthStart, monthEnd
+	return append([]model.BudgetStatus(nil), r.statuses...), nil
+}
+
+func TestSetBudget(t *testing.T) {
+	repo := &fakeBudgetRepo{}
+	svc := NewBudgetService(repo, newFakeCategoryRepo(), &fakeUserRepo{})
+
+	budget, err := svc.SetBudget(context.Background(), 1, model.SetBudgetRequest{Category: "food", Month: "2024-05", LimitAmount: 500000})
+	assert.NoError(t, err)
+	assert.Equal(t, "Food", budget.Category)
+
+	_, err = svc.SetBudget(context.Background(), 1, model.SetBudgetRequest{Category: "Food", Month: "May 2024", LimitAmount: 500000})
+	assert.ErrorIs(t, err, ErrInvalidMonth)
+
+	// Income categories can't be budgeted
+	_, err = svc.SetBudget(context.Background(), 1, model.SetBudgetRequest{Category: "Salary", Month: "2024-05", LimitAmount: 500000})
+	assert.ErrorIs(t, err, ErrUnknownCategory)
+}
+
+func TestGetBudgetStatus_FlagsOverBudget(t *testing.T) {
+	repo := &fakeBudgetRepo{statuses: []model.BudgetStatus{
+		{Category: "Food", LimitAmount: 1000, Spent: 1500},
+		{Category: "Transport", LimitAmount: 1000, Spent: 1000},
+		{Category: "Other", LimitAmount: 1000, Spent: 0},
+	}}
+	svc := NewBudgetService(repo, newFakeCategoryRepo(), &fakeUserRepo{users: map[int]*model.User{1: {ID: 1, Currency: "USD"}}})
+
+	statuses, err := svc.GetBudgetStatus(context.Background(), 1, "2024-12")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-500), statuses[0].Remaining)
+	assert.True(t, statuses[0].OverBudget)
+	assert.Equal(t, int64(0), statuses[1].Remaining)
+	assert.False(t, statuses[1].OverBudget) // Exactly at the limit is not over
+	assert.False(t, statuses[2].OverBudget)
+
+	assert.Equal(t, "USD", repo.lastCurrency) // Only expenses in the user's currency count
+	assert.Equal(t, time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC), repo.lastStart)
+	assert.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), repo.lastEnd)
+}
+
+func TestParseBudgetMonth_UTCWhateverServerZone(t *testing.T) {
+	defer func(loc *time.Location) { time.Local = loc }(time.Local)
+	time.Local = time.FixedZone("UTC-7", -7*60*60)
+
+	start, end, err := parseBudgetMonth("2024-02")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), start)
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), end)
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"expense_tracker/internal/model"
+	"expense_tracker/internal/repository"
+	"expense_tracker/internal/tracing"
+)
+
+var (
+	ErrCategoryNotFound = errors.New("category not found")
+	ErrCategoryExists   = errors.New("a category with this name and type already exists")
+	ErrUnknownCategory  = errors.New("unknown category, create it first via /categories")
+	ErrBlankCategory    = errors.New("category name must not be blank")
+	// ErrCategoryTypeMismatch means the category exists, but only for the other transaction type
+	ErrCategoryTypeMismatch = errors.New("category type does not match the transaction type")
+)
+
+// CategoryService manages global and user-defined transaction categories
+type CategoryService interface {
+	ListCategories(ctx context.Context, userID int) ([]model.Category, error)
+	CreateCategory(ctx context.Context, userID int, req model.CreateCategoryRequest) (*model.Category, error)
+	DeleteCategory(ctx context.Context, categoryID int, userID int) error
+}
+
+type categoryService struct {
+	repo repository.CategoryRepository
+}
+
+// NewCategoryService creates a new CategoryService
+func NewCategoryService(repo repository.CategoryRepository) CategoryService {
+	return &categoryService{repo: repo}
+}
+
+func (s *categoryService) ListCategories(ctx context.Context, userID int) ([]model.Category, error) {
+	ctx, span := tracing.Start(ctx, "CategoryService.ListCategories")
+	defer span.End()
+
+	return s.repo.List(ctx, userID)
+}
+
+func (s *categoryService) CreateCategory(ctx context.Context, userID int, req model.CreateCategoryRequest) (*model.Category, error) {
+	ctx, span := tracing.Start(ctx, "CategoryService.CreateCategory")
+	defer span.End()
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, ErrBlankCategory
+	}
+
+	// Global defaults aren't covered by the per-user unique index, so check them here
+	existing, err := s.repo.FindByName(ctx, userID, name)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range existing {
+		if c.Type == req.Type {
+			return nil, ErrCategoryExists
+		}
+	}
+
+	category := &model.Category{UserID: &userID, Name: name, Type: req.Type}
+	if err := s.repo.Create(ctx, category); err != nil {
+		if errors.Is(err, repository.ErrDuplicateCategory) {
+			return nil, ErrCategoryExists
+		}
+		return nil, err
+	}
+	return category, nil
+}
+
+func (s *categoryService) DeleteCategory(ctx context.Context, categoryID int, userID int) error {
+	ctx, span := tracing.Start(ctx, "CategoryService.DeleteCategory")
+	defer span.End()
+
+	deleted, err := s.repo.Delete(ctx, categoryID, userID)
+	if err != nil {
+		return err
+	}
+	if !deleted { // Missing, another user's, or a global default
+		return ErrCategoryNotFound
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"expense_tracker/internal/model"
+	"expense_tracker/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeCategoryRepo keeps categories in memory, seeded with a few globals
+type fakeCategoryRepo struct {
+	repository.CategoryRepository
+	categories []model.Category
+	nextID     int
+}
+
+func newFakeCategoryRepo() *fakeCategoryRepo {
+	return &fakeCategoryRepo{
+		categories: []model.Category{
+			{ID: 1, Name: "Food", Type: model.TransactionTypeExpense},
+			{ID: 2, Name: "Salary", Type: model.TransactionTypeIncome},
+			{ID: 3, Name: "Other", Type: model.TransactionTypeExpense},
+			{ID: 4, Name: "Other", Type: model.TransactionTypeIncome},
+		},
+		nextID: 4,
+	}
+}
+
+func (r *fakeCategoryRepo) FindByName(ctx context.Context, userID int, name string) ([]model.Category, error) {
+	var matches []model.Category
+	for _, c := range r.categories {
+		if (c.UserID == nil || *c.UserID == userID) && strings.EqualFold(c.Name, name) {
+			matches = append(matches, c)
+		}
+	}
+	return matches, nil
+}
+
+func (r *fakeCategoryRepo) Create(ctx context.Context, category *model.Category) error {
+	for _, c := range r.categories {
+		if c.UserID != nil && *c.UserID == *category.UserID && strings.EqualFold(c.Name, category.Name) && c.Type == category.Type {
+			return repository.ErrDuplicateCategory
+		}
+	}
+	r.nextID++
+	category.ID = r.nextID
+	r.categories = append(r.categories, *category)
+	return nil
+}
+
+func (r *fakeCategoryRepo) Delete(ctx context.Context, id int, userID int) (bool, error) {
+	for i, c := range r.categories {
+		if c.ID == id && c.UserID != nil && *c.UserID == userID {
+			r.categories = append(r.categories[:i], r.categories[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestCreateCategory(t *testing.T) {
+	svc := NewCategoryService(newFakeCategoryRepo())
+
+	category, err := svc.CreateCategory(context.Background(), 1, model.CreateCategoryRequest{Name: " Pets ", Type: model.TransactionTypeExpense})
+	assert.NoError(t, err)
+	assert.Equal(t, "Pets", category.Name)
+	assert.Equal(t, 1, *category.UserID)
+
+	_, err = svc.CreateCategory(context.Background(), 1, model.CreateCategoryRequest{Name: "pets", Type: model.TransactionTypeExpense})
+	assert.ErrorIs(t, err, ErrCategoryExists)
+
+	// Same name as a global default of the same type
+	_, err = svc.CreateCategory(context.Background(), 1, model.CreateCategoryRequest{Name: "food", Type: model.TransactionTypeExpense})
+	assert.ErrorIs(t, err, ErrCategoryExists)
+
+	// A different type is a different category
+	_, err = svc.CreateCategory(context.Background(), 1, model.CreateCategoryRequest{Name: "Food", Type: model.TransactionTypeIncome})
+	assert.NoError(t, err)
+}
+
+func TestDeleteCategory_OnlyOwn(t *testing.T) {
+	repo := newFakeCategoryRepo()
+	svc := NewCategoryService(repo)
+	category, err := svc.CreateCategory(context.Background(), 1, model.CreateCategoryRequest{Name: "Pets", Type: model.TransactionTypeExpense})
+	assert.NoError(t, err)
+
+	assert.ErrorIs(t, svc.DeleteCategory(context.Background(), 1, 1), ErrCategoryNotFound) // Global default
+	assert.ErrorIs(t, svc.DeleteCategory(context.Background(), category.ID, 2), ErrCategoryNotFound)
+	assert.NoError(t, svc.DeleteCategory(context.Background(), category.ID, 1))
+}
+
+func TestCreateTransaction_ValidatesCategory(t *testing.T) {
+	svc := newTestTransactionService(newFakeTransactionRepo(), nil)
+
+	_, err := svc.CreateTransaction(context.Background(), 1, model.CreateTransactionRequest{
+		Amount: 100, Type: model.TransactionTypeExpense, Category: "Fod",
+	})
+	assert.ErrorIs(t, err, ErrUnknownCategory)
+
+	// Known categories are stored with their canonical spelling
+	tx, err := svc.CreateTransaction(context.Background(), 1, model.CreateTransactionRequest{
+		Amount: 100, Type: model.TransactionTypeExpense, Category: "food",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "Food", tx.Category)
+}
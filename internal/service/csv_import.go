@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"expense_tracker/internal/metrics"
+	"expense_tracker/internal/model"
+	"expense_tracker/internal/tracing"
+)
+
+// MaxImportRows caps how many transactions one CSV import may create
+const MaxImportRows = 1000
+
+var (
+	ErrInvalidCSV         = errors.New("invalid CSV file")
+	ErrTooManyImportRows  = fmt.Errorf("CSV has more than %d rows", MaxImportRows)
+	requiredImportColumns = []string{"amount", "type", "category"}
+)
+
+// parseImportDate accepts a date (YYYY-MM-DD, UTC midnight) or an RFC3339 timestamp
+func parseImportDate(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// ImportTransactionsCSV creates the user's transactions from a CSV with a header row naming
+// amount, type and category columns, plus optional currency, merchant, description and transaction_date.
+// Every row is validated first and the batch is only inserted if all of them pass, in one
+// statement, so the import is all or nothing. Rows that fail are listed in the result.
+func (s *transactionService) ImportTransactionsCSV(ctx context.Context, userID int, r io.Reader) (*model.ImportResult, error) {
+	ctx, span := tracing.Start(ctx, "TransactionService.ImportTransactionsCSV")
+	defer span.End()
+
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: missing header row", ErrInvalidCSV)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	for _, name := range requiredImportColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("%w: missing %q column", ErrInvalidCSV, name)
+		}
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	defaultCurrency, err := s.resolveCurrency(ctx, userID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	result := &model.ImportResult{Errors: []model.ImportRowError{}}
+	var transactions []model.Transaction
+	categories := make(map[string]string) // type + name -> canonical name, saves repeat lookups
+	now := time.Now()
+	for row := 2; ; row++ { // Row 1 is the header
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if row-1 > MaxImportRows {
+			return nil, ErrTooManyImportRows
+		}
+		rowErr := func(format string, args ...interface{}) {
+			result.Failed++
+			result.Errors = append(result.Errors, model.ImportRowError{Row: row, Error: fmt.Sprintf(format, args...)})
+		}
+		if err != nil {
+			rowErr("malformed row: %v", err)
+			continue
+		}
+
+		amount, err := strconv.ParseInt(field(record, "amount"), 10, 64)
+		if err != nil {
+			rowErr("amount must be an integer in tiyns")
+			continue
+		}
+		if err := s.validateAmount(amount); err != nil {
+			rowErr("%v", err)
+			continue
+		}
+		currency := defaultCurrency
+		if code := field(record, "currency"); code != "" {
+			var supported bool
+			if currency, supported = model.NormalizeCurrency(code); !supported {
+				rowErr("unsupported currency %q", code)
+				continue
+			}
+		}
+		txType := strings.ToLower(field(record, "type"))
+		if txType != model.TransactionTypeIncome && txType != model.TransactionTypeExpense {
+			rowErr("type must be income or expense")
+			continue
+		}
+
+		categoryName := field(record, "category")
+		cacheKey := txType + "\x00" + strings.ToLower(categoryName)
+		category, ok := categories[cacheKey]
+		if !ok {
+			category, err = s.resolveCategory(ctx, userID, categoryName, txType)
+			if errors.Is(err, ErrUnknownCategory) {
+				rowErr("unknown category %q", categoryName)
+				continue
+			}
+			if errors.Is(err, ErrCategoryTypeMismatch) {
+				rowErr("%v", err)
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			categories[cacheKey] = category
+		}
+
+		transactionDate := now
+		if dateValue := field(record, "transaction_date"); dateValue != "" {
+			transactionDate, err = parseImportDate(dateValue)
+			if err != nil {
+				rowErr("transaction_date must be YYYY-MM-DD or RFC3339")
+				continue
+			}
+			if err := s.validateTransactionDate(transactionDate); err != nil {
+				rowErr("%v", err)
+				continue
+			}
+		}
+
+		desc := field(record, "description")
+		description, err := s.normalizeDescription(&desc)
+		if err != nil {
+			rowErr("%v", err)
+			continue
+		}
+		merchant := field(record, "merchant")
+
+		transactions = append(transactions, model.Transaction{
+			UserID:          userID,
+			Amount:          amount,
+			Currency:        currency,
+			Type:            txType,
+			Category:        category,
+			Merchant:        normalizeMerchant(&merchant),
+			Description:     description,
+			TransactionDate: transactionDate,
+			CreatedAt:       now,
+			UpdatedAt:       now,
+		})
+	}
+
+	if result.Failed > 0 || len(transactions) == 0 {
+		return result, nil
+	}
+	if err := s.repo.CreateBatch(ctx, transactions); err != nil {
+		return nil, fmt.Errorf("failed to import transactions: %w", err)
+	}
+	result.Imported = len(transactions)
+	s.recent.invalidate(userID)
+	s.stats.clear()
+	metrics.TransactionsCreated.Add(float64(result.Imported))
+	return result, nil
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"expense_tracker/internal/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportTransactionsCSV(t *testing.T) {
+	repo := newFakeTransactionRepo()
+	svc := newTestTransactionService(repo, nil)
+
+	csvData := "Amount,Type,Category,Description,Transaction_Date,Currency\n" +
+		"50000,expense,food,Lunch,2024-03-05,\n" +
+		"1500000,income,Salary,,2024-03-01T09:00:00+05:00,usd\n"
+	result, err := svc.ImportTransactionsCSV(context.Background(), 7, strings.NewReader(csvData))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result.Imported)
+	assert.Equal(t, 0, result.Failed)
+	assert.Empty(t, result.Errors)
+	assert.Len(t, repo.transactions, 2)
+
+	lunch := repo.transactions[1]
+	assert.Equal(t, 7, lunch.UserID)
+	assert.Equal(t, "Food", lunch.Category) // Canonical category name
+	assert.Equal(t, "Lunch", *lunch.Description)
+	assert.Equal(t, time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), lunch.TransactionDate)
+	assert.Nil(t, repo.transactions[2].Description)
+	assert.Equal(t, time.Date(2024, 3, 1, 4, 0, 0, 0, time.UTC), repo.transactions[2].TransactionDate)
+	assert.Equal(t, model.DefaultCurrency, lunch.Currency)
+	assert.Equal(t, "USD", repo.transactions[2].Currency)
+}
+
+func TestImportTransactionsCSVRejectsWholeBatchOnBadRows(t *testing.T) {
+	repo := newFakeTransactionRepo()
+	svc := newTestTransactionService(repo, nil)
+
+	csvData := "amount,type,category\n" +
+		"50000,expense,Food\n" +
+		"abc,expense,Food\n" +
+		"100,transfer,Food\n" +
+		"100,expense,Rent\n"
+	result, err := svc.ImportTransactionsCSV(context.Background(), 7, strings.NewReader(csvData))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, result.Imported)
+	assert.Equal(t, 3, result.Failed)
+	if assert.Len(t, result.Errors, 3) {
+		assert.Equal(t, 3, result.Errors[0].Row)
+		assert.Equal(t, 4, result.Errors[1].Row)
+		assert.Equal(t, 5, result.Errors[2].Row)
+	}
+	assert.Empty(t, repo.transactions, "valid rows must not be stored when others fail")
+
+	result, err = svc.ImportTransactionsCSV(context.Background(), 7, strings.NewReader("amount,type,category,currency\n100,expense,Food,ABC\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Failed)
+	assert.Empty(t, repo.transactions, "valid rows must not be stored when others fail")
+}
+
+func TestImportTransactionsCSVInvalidFile(t *testing.T) {
+	svc := newTestTransactionService(newFakeTransactionRepo(), nil)
+
+	_, err := svc.ImportTransactionsCSV(context.Background(), 7, strings.NewReader(""))
+	assert.True(t, errors.Is(err, ErrInvalidCSV))
+
+	_, err = svc.ImportTransactionsCSV(context.Background(), 7, strings.NewReader("amount,category\n100,Food\n"))
+	assert.True(t, errors.Is(err, ErrInvalidCSV))
+
+	var b strings.Builder
+	b.WriteString("amount,type,category\n")
+	for i := 0; i <= MaxImportRows; i++ {
+		b.WriteString("100,expense,Food\n")
+	}
+	_, err = svc.ImportTransactionsCSV(context.Background(), 7, strings.NewReader(b.String()))
+	assert.True(t, errors.Is(err, ErrTooManyImportRows))
+}
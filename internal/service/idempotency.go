@@ -0,0 +1,42 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"expense_tracker/internal/repository"
+)
+
+// MaxIdempotencyKeyLength is the longest Idempotency-Key accepted, matching the column size
+const MaxIdempotencyKeyLength = 255
+
+var ErrInvalidIdempotencyKey = fmt.Errorf("idempotency key must be at most %d characters", MaxIdempotencyKeyLength)
+
+// StartIdempotencyKeyCleanup periodically removes idempotency keys older than ttl until ctx is cancelled.
+// wg is done once the cleanup has stopped.
+func StartIdempotencyKeyCleanup(ctx context.Context, wg *sync.WaitGroup, repo repository.TransactionRepository, ttl, interval time.Duration) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				deleted, err := repo.DeleteExpiredIdempotencyKeys(ctx, time.Now().Add(-ttl))
+				if err != nil {
+					log.Printf("Error cleaning up idempotency keys: %v", err)
+					continue
+				}
+				if deleted > 0 {
+					log.Printf("Removed %d expired idempotency keys", deleted)
+				}
+			}
+		}
+	}()
+}
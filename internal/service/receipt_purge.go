@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"expense_tracker/internal/config"
+	"expense_tracker/internal/repository"
+	"expense_tracker/internal/storage"
+)
+
+// receiptKeyPrefix is where uploaded receipts and their thumbnails are stored; anything else under
+// the uploads directory is left alone
+const receiptKeyPrefix = "transactions/"
+
+// ReceiptPurgeResult summarizes one pass of PurgeOrphanedReceipts
+type ReceiptPurgeResult struct {
+	Scanned int // Receipt files seen
+	Removed int // Unreferenced files deleted
+	Failed  int // Unreferenced files that could not be deleted
+}
+
+// PurgeOrphanedReceipts deletes receipt files that no transaction refers to, such as those left
+// behind by a crash between deleting a transaction and its files. Files modified within grace, and
+// never within config.MinReceiptPurgeGrace, are kept: an upload is saved before its path is stored.
+func PurgeOrphanedReceipts(ctx context.Context, repo repository.TransactionRepository, store storage.WalkableStorage, grace time.Duration) (ReceiptPurgeResult, error) {
+	var result ReceiptPurgeResult
+	if grace < config.MinReceiptPurgeGrace {
+		grace = config.MinReceiptPurgeGrace
+	}
+	// Taken before listing paths, so a file saved and attached during the pass is still too young
+	cutoff := time.Now().Add(-grace)
+
+	paths, err := repo.ListReceiptPaths(ctx)
+	if err != nil {
+		return result, err
+	}
+	referenced := make(map[string]struct{}, len(paths))
+	for _, p := range paths {
+		key := store.NormalizeKey(p)
+		referenced[key] = struct{}{}
+		if isImageReceipt(key) {
+			referenced[thumbnailKey(key)] = struct{}{}
+		}
+	}
+
+	// Collect first so deleting emptied directories doesn't disturb the walk
+	var orphans []string
+	err = store.Walk(ctx, func(key string, modTime time.Time) error {
+		if !strings.HasPrefix(key, receiptKeyPrefix) {
+			return nil
+		}
+		result.Scanned++
+		if _, ok := referenced[key]; ok || modTime.After(cutoff) {
+			return nil
+		}
+		orphans = append(orphans, key)
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	for _, key := range orphans {
+		if err := store.Delete(ctx, key); err != nil {
+			log.Printf("Error removing orphaned receipt %s: %v", key, err)
+			result.Failed++
+			continue
+		}
+		result.Removed++
+	}
+	return result, nil
+}
+
+// StartReceiptPurge periodically runs PurgeOrphanedReceipts until ctx is cancelled. The goroutine
+// is tracked in wg, so shutdown can wait for a purge that is under way.
+func StartReceiptPurge(ctx context.Context, wg *sync.WaitGroup, repo repository.TransactionRepository, store storage.WalkableStorage, grace, interval time.Duration) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				result, err := PurgeOrphanedReceipts(ctx, repo, store, grace)
+				if err != nil {
+					log.Printf("Error purging orphaned receipts: %v", err)
+					continue
+				}
+				log.Printf("Orphaned receipt purge: scanned %d files, removed %d, failed %d", result.Scanned, result.Removed, result.Failed)
+			}
+		}
+	}()
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"expense_tracker/internal/model"
+	"expense_tracker/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPurgeOrphanedReceipts(t *testing.T) {
+	baseDir := filepath.Join(t.TempDir(), "uploads")
+	store, err := storage.NewLocalStorage(baseDir)
+	assert.NoError(t, err)
+	ctx := context.Background()
+
+	old := time.Now().Add(-48 * time.Hour)
+	save := func(key string, modTime time.Time) {
+		_, err := store.Save(ctx, key, strings.NewReader("data"))
+		assert.NoError(t, err)
+		assert.NoError(t, os.Chtimes(filepath.Join(baseDir, filepath.FromSlash(key)), modTime, modTime))
+	}
+	save("transactions/1/kept.png", old)
+	save("transactions/1/kept_thumb.jpg", old)
+	save("transactions/2/legacy.pdf", old)
+	save("transactions/3/orphan.png", old)
+	save("transactions/3/orphan_thumb.jpg", old)
+	save("transactions/4/uploading.png", time.Now().Add(-time.Minute))
+	save("other/unrelated.txt", old)
+
+	repo := newFakeTransactionRepo()
+	kept := "transactions/1/kept.png"
+	legacy := filepath.ToSlash(baseDir) + "/transactions/2/legacy.pdf"
+	repo.transactions[1] = &model.Transaction{ID: 1, ReceiptPath: &kept}
+	repo.transactions[2] = &model.Transaction{ID: 2, ReceiptPath: &legacy}
+
+	result, err := PurgeOrphanedReceipts(ctx, repo, store, time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, ReceiptPurgeResult{Scanned: 6, Removed: 2}, result)
+
+	for _, key := range []string{"transactions/1/kept.png", "transactions/1/kept_thumb.jpg", "transactions/2/legacy.pdf", "transactions/4/uploading.png", "other/unrelated.txt"} {
+		assert.FileExists(t, filepath.Join(baseDir, filepath.FromSlash(key)))
+	}
+	assert.NoDirExists(t, filepath.Join(baseDir, "transactions", "3"))
+}
+
+func TestPurgeOrphanedReceipts_GraceNeverBelowMinimum(t *testing.T) {
+	baseDir := t.TempDir()
+	store, err := storage.NewLocalStorage(baseDir)
+	assert.NoError(t, err)
+	ctx := context.Background()
+
+	_, err = store.Save(ctx, "transactions/1/fresh.png", strings.NewReader("data"))
+	assert.NoError(t, err)
+
+	result, err := PurgeOrphanedReceipts(ctx, newFakeTransactionRepo(), store, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, result.Removed)
+	assert.FileExists(t, filepath.Join(baseDir, "transactions", "1", "fresh.png"))
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"expense_tracker/internal/model"
+)
+
+const (
+	// recentCategoriesTTL is how long a user's recent categories are served from memory;
+	// they are derived from the whole history and change slowly
+	recentCategoriesTTL = time.Minute
+	// MaxRecentCategories is the most categories one lookup returns, and how many are cached per user
+	MaxRecentCategories = 20
+)
+
+// recentCategoriesCache keeps each user's most used categories for a short TTL
+type recentCategoriesCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[int]recentCategoriesEntry
+}
+
+type recentCategoriesEntry struct {
+	categories []model.CategoryUsage
+	expiresAt  time.Time
+}
+
+func newRecentCategoriesCache(ttl time.Duration) *recentCategoriesCache {
+	return &recentCategoriesCache{ttl: ttl, entries: make(map[int]recentCategoriesEntry)}
+}
+
+func (c *recentCategoriesCache) get(userID int, now time.Time) ([]model.CategoryUsage, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[userID]
+	if !ok || !now.Before(entry.expiresAt) {
+		return nil, false
+	}
+	return entry.categories, true
+}
+
+func (c *recentCategoriesCache) set(userID int, categories []model.CategoryUsage, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Users who stop asking would otherwise keep their entry forever, so sweep expired ones here
+	for id, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, id)
+		}
+	}
+	c.entries[userID] = recentCategoriesEntry{categories: categories, expiresAt: now.Add(c.ttl)}
+}
+
+// invalidate drops the user's cached categories after their transactions change
+func (c *recentCategoriesCache) invalidate(userID int) {
+	c.mu.Lock()
+	delete(c.entries, userID)
+	c.mu.Unlock()
+}
@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"expense_tracker/internal/model"
+	"expense_tracker/internal/repository"
+	"expense_tracker/internal/tracing"
+)
+
+var ErrRecurringNotFound = errors.New("recurring transaction not found")
+
+// RecurringService manages recurring transaction rules and materializes due occurrences
+type RecurringService interface {
+	CreateRecurring(ctx context.Context, userID int, req model.CreateRecurringRequest) (*model.RecurringTransaction, error)
+	ListRecurring(ctx context.Context, userID int) ([]model.RecurringTransaction, error)
+	GetRecurring(ctx context.Context, id int, userID int) (*model.RecurringTransaction, error)
+	UpdateRecurring(ctx context.Context, id int, userID int, req model.UpdateRecurringRequest) (*model.RecurringTransaction, error)
+	DeleteRecurring(ctx context.Context, id int, userID int) error
+	ProcessDue(ctx context.Context, now time.Time) (int, error)
+}
+
+type recurringService struct {
+	repo         repository.RecurringRepository
+	categoryRepo repository.CategoryRepository
+	hooks        TransactionHooks // Optional
+}
+
+// NewRecurringService creates a new RecurringService. hooks, usually the TransactionService, is
+// told about every occurrence created; it may be nil.
+func NewRecurringService(repo repository.RecurringRepository, categoryRepo repository.CategoryRepository, hooks TransactionHooks) RecurringService {
+	return &recurringService{repo: repo, categoryRepo: categoryRepo, hooks: hooks}
+}
+
+// dateOnly returns t's calendar date as midnight UTC, the form DATE columns round-trip as
+func dateOnly(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// advanceRunDate returns the run date following date. Monthly rules fall on anchorDay, clamped
+// to the last day of shorter months (Jan 31 -> Feb 29 -> Mar 31), so one short month doesn't
+// move every later run.
+func advanceRunDate(date time.Time, interval string, anchorDay int) time.Time {
+	switch interval {
+	case model.IntervalDaily:
+		return date.AddDate(0, 0, 1)
+	case model.IntervalWeekly:
+		return date.AddDate(0, 0, 7)
+	default: // model.IntervalMonthly
+		firstOfNext := time.Date(date.Year(), date.Month()+1, 1, 0, 0, 0, 0, date.Location())
+		lastDay := firstOfNext.AddDate(0, 1, -1).Day()
+		return time.Date(firstOfNext.Year(), firstOfNext.Month(), min(anchorDay, lastDay), 0, 0, 0, 0, date.Location())
+	}
+}
+
+func (s *recurringService) CreateRecurring(ctx context.Context, userID int, req model.CreateRecurringRequest) (*model.RecurringTransaction, error) {
+	ctx, span := tracing.Start(ctx, "RecurringService.CreateRecurring")
+	defer span.End()
+
+	category, err := resolveCategoryName(ctx, s.categoryRepo, userID, req.Category, req.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	nextRunDate := req.NextRunDate
+	if nextRunDate.IsZero() {
+		nextRunDate = time.Now().UTC()
+	}
+
+	rule := &model.RecurringTransaction{
+		UserID:      userID,
+		Amount:      req.Amount,
+		Type:        req.Type,
+		Category:    category,
+		Description: req.Description,
+		Interval:    req.Interval,
+		NextRunDate: dateOnly(nextRunDate),
+		AnchorDay:   nextRunDate.Day(),
+		Active:      true,
+	}
+	if err := s.repo.Create(ctx, rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (s *recurringService) ListRecurring(ctx context.Context, userID int) ([]model.RecurringTransaction, error) {
+	ctx, span := tracing.Start(ctx, "RecurringService.ListRecurring")
+	defer span.End()
+
+	return s.repo.FindByUser(ctx, userID)
+}
+
+func (s *recurringService) GetRecurring(ctx context.Context, id int, userID int) (*model.RecurringTransaction, error) {
+	ctx, span := tracing.Start(ctx, "RecurringService.GetRecurring")
+	defer span.End()
+
+	rule, err := s.repo.FindByID(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if rule == nil {
+		return nil, ErrRecurringNotFound
+	}
+	return rule, nil
+}
+
+func (s *recurringService) UpdateRecurring(ctx context.Context, id int, userID int, req model.UpdateRecurringRequest) (*model.RecurringTransaction, error) {
+	ctx, span := tracing.Start(ctx, "RecurringService.UpdateRecurring")
+	defer span.End()
+
+	rule, err := s.GetRecurring(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Amount != nil {
+		rule.Amount = *req.Amount
+	}
+	if req.Category != nil {
+		category, err := resolveCategoryName(ctx, s.categoryRepo, userID, *req.Category, rule.Type)
+		if err != nil {
+			return nil, err
+		}
+		rule.Category = category
+	}
+	if req.Description != nil {
+		rule.Description = req.Description
+	}
+	if req.Interval != nil {
+		rule.Interval = *req.Interval
+	}
+	if req.NextRunDate != nil {
+		rule.NextRunDate = dateOnly(*req.NextRunDate)
+		rule.AnchorDay = rule.NextRunDate.Day()
+	}
+	if req.Active != nil {
+		rule.Active = *req.Active
+	}
+
+	if err := s.repo.Update(ctx, rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (s *recurringService) DeleteRecurring(ctx context.Context, id int, userID int) error {
+	ctx, span := tracing.Start(ctx, "RecurringService.DeleteRecurring")
+	defer span.End()
+
+	deleted, err := s.repo.Delete(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrRecurringNotFound
+	}
+	return nil
+}
+
+// ProcessDue creates the transactions of every rule due on or before now's date, catching up
+// on occurrences missed while the server was down. It returns how many transactions were created.
+func (s *recurringService) ProcessDue(ctx context.Context, now time.Time) (int, error) {
+	ctx, span := tracing.Start(ctx, "RecurringService.ProcessDue")
+	defer span.End()
+
+	today := dateOnly(now.UTC())
+	rules, err := s.repo.FindDue(ctx, today)
+	if err != nil {
+		return 0, err
+	}
+
+	created := 0
+	var firstErr error
+	for i := range rules {
+		rule := &rules[i]
+		for !rule.NextRunDate.After(today) {
+			runDate := rule.NextRunDate
+			nextRunDate := advanceRunDate(runDate, rule.Interval, rule.AnchorDay)
+			transaction, err := s.repo.Materialize(ctx, rule, runDate, nextRunDate)
+			if err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("recurring transaction %d: %w", rule.ID, err)
+				}
+				break
+			}
+			if transaction == nil { // Another run got here first; it owns the rest of this rule's backlog
+				break
+			}
+			if s.hooks != nil {
+				s.hooks.AfterCreate(*transaction)
+			}
+			created++
+			rule.NextRunDate = nextRunDate
+		}
+	}
+	return created, firstErr
+}
+
+// StartRecurringScheduler runs ProcessDue immediately and then every interval until ctx is cancelled;
+// wg lets shutdown wait for a run in progress before the database pool closes
+func StartRecurringScheduler(ctx context.Context, wg *sync.WaitGroup, svc RecurringService, interval time.Duration) {
+	run := func() {
+		created, err := svc.ProcessDue(ctx, time.Now())
+		if err != nil {
+			log.Printf("Error processing recurring transactions: %v", err)
+		}
+		if created > 0 {
+			log.Printf("Created %d transactions from recurring rules", created)
+		}
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		run()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				run()
+			}
+		}
+	}()
+}
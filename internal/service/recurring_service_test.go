@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"expense_tracker/internal/model"
+	"expense_tracker/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRecurringRepo keeps rules in memory and records materialized transaction dates
+type fakeRecurringRepo struct {
+	repository.RecurringRepository
+	rules        map[int]*model.RecurringTransaction
+	materialized []time.Time
+}
+
+func (r *fakeRecurringRepo) Create(ctx context.Context, rule *model.RecurringTransaction) error {
+	rule.ID = len(r.rules) + 1
+	stored := *rule
+	r.rules[rule.ID] = &stored
+	return nil
+}
+
+func (r *fakeRecurringRepo) FindDue(ctx context.Context, asOf time.Time) ([]model.RecurringTransaction, error) {
+	due := []model.RecurringTransaction{}
+	for _, rule := range r.rules {
+		if rule.Active && !rule.NextRunDate.After(asOf) {
+			due = append(due, *rule)
+		}
+	}
+	return due, nil
+}
+
+func (r *fakeRecurringRepo) Materialize(ctx context.Context, rule *model.RecurringTransaction, transactionDate, nextRunDate time.Time) (*model.Transaction, error) {
+	stored := r.rules[rule.ID]
+	if !stored.NextRunDate.Equal(rule.NextRunDate) {
+		return nil, nil
+	}
+	stored.NextRunDate = nextRunDate
+	r.materialized = append(r.materialized, transactionDate)
+	return &model.Transaction{ID: int64(len(r.materialized)), UserID: rule.UserID, Amount: rule.Amount, Type: rule.Type,
+		Category: rule.Category, TransactionDate: transactionDate}, nil
+}
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestProcessDue_CatchesUpMissedRuns(t *testing.T) {
+	repo := &fakeRecurringRepo{rules: map[int]*model.RecurringTransaction{
+		1: {ID: 1, UserID: 1, Amount: 100, Type: model.TransactionTypeExpense, Category: "Food",
+			Interval: model.IntervalWeekly, NextRunDate: date(2024, time.May, 1), Active: true},
+	}}
+	svc := NewRecurringService(repo, newFakeCategoryRepo(), nil)
+
+	created, err := svc.ProcessDue(context.Background(), time.Date(2024, time.May, 16, 9, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, created) // May 1, 8 and 15
+	assert.Equal(t, date(2024, time.May, 22), repo.rules[1].NextRunDate)
+
+	// A second, overlapping tick finds nothing left to do
+	created, err = svc.ProcessDue(context.Background(), time.Date(2024, time.May, 16, 9, 1, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, created)
+	assert.Len(t, repo.materialized, 3)
+}
+
+func TestProcessDue_RunsTransactionHooks(t *testing.T) {
+	repo := &fakeRecurringRepo{rules: map[int]*model.RecurringTransaction{
+		1: {ID: 1, UserID: 1, Amount: 100, Type: model.TransactionTypeExpense, Category: "Food",
+			Interval: model.IntervalDaily, NextRunDate: date(2024, time.May, 1), Active: true},
+	}}
+	notifier := &recordingNotifier{}
+	transactions := newTestTransactionService(newFakeTransactionRepo(), nil)
+	transactions.notifier = notifier
+	svc := NewRecurringService(repo, newFakeCategoryRepo(), transactions)
+
+	// Warm the recent-categories cache so the occurrence has something to invalidate
+	transactions.recent.set(1, []model.CategoryUsage{{Category: "Transport", Count: 1}}, time.Now())
+
+	created, err := svc.ProcessDue(context.Background(), date(2024, time.May, 2))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, created)
+	assert.Len(t, notifier.created, 2)
+	_, cached := transactions.recent.get(1, time.Now())
+	assert.False(t, cached, "a new occurrence invalidates the user's recent categories")
+}
+
+func TestProcessDue_StopsWhenOccurrenceAlreadyClaimed(t *testing.T) {
+	repo := &fakeRecurringRepo{rules: map[int]*model.RecurringTransaction{
+		1: {ID: 1, Interval: model.IntervalDaily, NextRunDate: date(2024, time.May, 1), Active: true},
+	}}
+	svc := NewRecurringService(&racingRecurringRepo{repo}, newFakeCategoryRepo(), nil)
+
+	created, err := svc.ProcessDue(context.Background(), date(2024, time.May, 3))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, created)
+	assert.Empty(t, repo.materialized)
+}
+
+// racingRecurringRepo simulates an overlapping run advancing every rule right after FindDue
+type racingRecurringRepo struct {
+	*fakeRecurringRepo
+}
+
+func (r *racingRecurringRepo) FindDue(ctx context.Context, asOf time.Time) ([]model.RecurringTransaction, error) {
+	due, err := r.fakeRecurringRepo.FindDue(ctx, asOf)
+	for _, rule := range r.rules {
+		rule.NextRunDate = asOf.AddDate(0, 0, 1)
+	}
+	return due, err
+}
+
+func TestAdvanceRunDate(t *testing.T) {
+	assert.Equal(t, date(2024, time.May, 2), advanceRunDate(date(2024, time.May, 1), model.IntervalDaily, 1))
+	assert.Equal(t, date(2024, time.May, 8), advanceRunDate(date(2024, time.May, 1), model.IntervalWeekly, 1))
+	assert.Equal(t, date(2024, time.June, 1), advanceRunDate(date(2024, time.May, 1), model.IntervalMonthly, 1))
+	assert.Equal(t, date(2024, time.February, 29), advanceRunDate(date(2024, time.January, 31), model.IntervalMonthly, 31))
+	assert.Equal(t, date(2025, time.January, 31), advanceRunDate(date(2024, time.December, 31), model.IntervalMonthly, 31))
+	// Clamped in February, back on the anchor day afterwards
+	assert.Equal(t, date(2024, time.March, 31), advanceRunDate(date(2024, time.February, 29), model.IntervalMonthly, 31))
+	assert.Equal(t, date(2024, time.April, 30), advanceRunDate(date(2024, time.March, 31), model.IntervalMonthly, 31))
+}
+
+func TestProcessDue_MonthlyRuleKeepsAnchorDay(t *testing.T) {
+	repo := &fakeRecurringRepo{rules: map[int]*model.RecurringTransaction{}}
+	svc := NewRecurringService(repo, newFakeCategoryRepo(), nil)
+	_, err := svc.CreateRecurring(context.Background(), 1, model.CreateRecurringRequest{
+		Amount: 100, Type: model.TransactionTypeExpense, Category: "food", Interval: model.IntervalMonthly,
+		NextRunDate: date(2024, time.January, 31),
+	})
+	assert.NoError(t, err)
+
+	_, err = svc.ProcessDue(context.Background(), date(2024, time.May, 1))
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Time{
+		date(2024, time.January, 31), date(2024, time.February, 29), date(2024, time.March, 31), date(2024, time.April, 30),
+	}, repo.materialized)
+	assert.Equal(t, date(2024, time.May, 31), repo.rules[1].NextRunDate)
+}
+
+func TestCreateRecurring_ResolvesCategoryAndDefaultsRunDate(t *testing.T) {
+	repo := &fakeRecurringRepo{rules: map[int]*model.RecurringTransaction{}}
+	svc := NewRecurringService(repo, newFakeCategoryRepo(), nil)
+
+	rule, err := svc.CreateRecurring(context.Background(), 1, model.CreateRecurringRequest{
+		Amount: 100, Type: model.TransactionTypeExpense, Category: "food", Interval: model.IntervalMonthly,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "Food", rule.Category)
+	assert.Equal(t, dateOnly(time.Now()), rule.NextRunDate)
+
+	_, err = svc.CreateRecurring(context.Background(), 1, model.CreateRecurringRequest{
+		Amount: 100, Type: model.TransactionTypeExpense, Category: "nope", Interval: model.IntervalMonthly,
+	})
+	assert.ErrorIs(t, err, ErrUnknownCategory)
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"expense_tracker/internal/model"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// statementColumns are the PDF table's headers and widths in mm (A4 portrait leaves 190mm)
+var statementColumns = []struct {
+	header string
+	width  float64
+}{
+	{"Date", 25}, {"Category", 35}, {"Type", 20}, {"Amount", 30}, {"Description", 80},
+}
+
+// formatMinorUnits renders an amount in tiyns as sums with two decimals, e.g. 150050 -> "1500.50"
+func formatMinorUnits(amount int64) string {
+	return formatAmount(amount, 100)
+}
+
+// formatAmount renders an amount in minor units as a decimal with one digit per power of ten in
+// divisor, e.g. 1234 with divisor 100 -> "12.34"; a divisor of 1 or less leaves it whole
+func formatAmount(amount, divisor int64) string {
+	if divisor <= 1 {
+		return strconv.FormatInt(amount, 10)
+	}
+	sign := ""
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+	digits := len(strconv.FormatInt(divisor, 10)) - 1
+	return fmt.Sprintf("%s%d.%0*d", sign, amount/divisor, digits, amount%divisor)
+}
+
+// statementTitle describes the filtered date range so a printed statement is self-describing
+func statementTitle(filters model.UserTransactionFilters) string {
+	const layout = "2006-01-02"
+	var end string
+	if filters.EndDate != nil {
+		endDate := *filters.EndDate
+		if filters.EndDateExclusive {
+			endDate = endDate.Add(-time.Nanosecond) // Show the last day actually included
+		}
+		end = endDate.Format(layout)
+	}
+	switch {
+	case filters.StartDate != nil && filters.EndDate != nil:
+		return fmt.Sprintf("Transaction statement: %s to %s", filters.StartDate.Format(layout), end)
+	case filters.StartDate != nil:
+		return fmt.Sprintf("Transaction statement: from %s", filters.StartDate.Format(layout))
+	case filters.EndDate != nil:
+		return fmt.Sprintf("Transaction statement: up to %s", end)
+	default:
+		return "Transaction statement: all dates"
+	}
+}
+
+// statementTotals are the income and expense of a statement in one currency
+type statementTotals struct {
+	currency        string
+	income, expense int64
+}
+
+// sumStatement totals transactions per currency, in order of currency code, since amounts in
+// different currencies can't be added up
+func sumStatement(transactions []model.Transaction) []statementTotals {
+	byCurrency := make(map[string]*statementTotals)
+	var totals []statementTotals
+	for _, t := range transactions {
+		if byCurrency[t.Currency] == nil {
+			byCurrency[t.Currency] = &statementTotals{currency: t.Currency}
+		}
+		switch t.Type {
+		case model.TransactionTypeIncome:
+			byCurrency[t.Currency].income += t.Amount
+		case model.TransactionTypeExpense:
+			byCurrency[t.Currency].expense += t.Amount
+		}
+	}
+	for _, st := range byCurrency {
+		totals = append(totals, *st)
+	}
+	sort.Slice(totals, func(i, j int) bool { return totals[i].currency < totals[j].currency })
+	return totals
+}
+
+// renderStatementPDF lays out transactions as a table under a title and income/expense totals per currency
+func renderStatementPDF(title string, transactions []model.Transaction) (*bytes.Buffer, error) {
+
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle(title, true)
+	// Core fonts are cp1252; characters outside it are replaced rather than breaking the document
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.AddPage()
+
+	pdf.SetFont("Helvetica", "B", 14)
+	pdf.CellFormat(0, 10, tr(title), "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	for _, st := range sumStatement(transactions) {
+		pdf.CellFormat(0, 6, fmt.Sprintf("%s   Total income: %s   Total expense: %s   Balance: %s", st.currency,
+			formatMinorUnits(st.income), formatMinorUnits(st.expense), formatMinorUnits(st.income-st.expense)), "", 1, "L", false, 0, "")
+	}
+	pdf.CellFormat(0, 6, fmt.Sprintf("Transactions: %d", len(transactions)), "", 1, "L", false, 0, "")
+	pdf.Ln(4)
+
+	pdf.SetFont("Helvetica", "B", 10)
+	for _, col := range statementColumns {
+		pdf.CellFormat(col.width, 7, col.header, "1", 0, "L", false, 0, "")
+	}
+	pdf.Ln(-1)
+
+	pdf.SetFont("Helvetica", "", 9)
+	for _, t := range transactions {
+		var desc string
+		if t.Description != nil {
+			desc = *t.Description
+		}
+		cells := []string{
+			t.TransactionDate.UTC().Format("2006-01-02"),
+			tr(t.Category),
+			t.Type,
+			formatMinorUnits(t.Amount) + " " + t.Currency,
+			tr(desc),
+		}
+		for i, col := range statementColumns {
+			align := "L"
+			if col.header == "Amount" {
+				align = "R"
+			}
+			text := cells[i]
+			// Long text is cut to the column rather than wrapped, keeping one row per transaction
+			for len(text) > 0 && pdf.GetStringWidth(text) > col.width-2 {
+				text = text[:len(text)-1]
+			}
+			pdf.CellFormat(col.width, 6, text, "1", 0, align, false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+
+	buffer := &bytes.Buffer{}
+	if err := pdf.Output(buffer); err != nil {
+		return nil, fmt.Errorf("failed to render PDF: %w", err)
+	}
+	return buffer, nil
+}
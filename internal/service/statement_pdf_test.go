@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"expense_tracker/internal/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatementTitle(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 3, 31, 23, 59, 59, 999999999, time.UTC)
+	nextDay := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, "Transaction statement: all dates", statementTitle(model.UserTransactionFilters{}))
+	assert.Equal(t, "Transaction statement: 2024-01-01 to 2024-03-31",
+		statementTitle(model.UserTransactionFilters{StartDate: &start, EndDate: &end}))
+	assert.Equal(t, "Transaction statement: 2024-01-01 to 2024-03-31",
+		statementTitle(model.UserTransactionFilters{StartDate: &start, EndDate: &nextDay, EndDateExclusive: true}))
+}
+
+func TestFormatMinorUnits(t *testing.T) {
+	assert.Equal(t, "1500.50", formatMinorUnits(150050))
+	assert.Equal(t, "0.05", formatMinorUnits(5))
+	assert.Equal(t, "-12.00", formatMinorUnits(-1200))
+}
+
+func TestFormatAmount(t *testing.T) {
+	assert.Equal(t, "12.34", formatAmount(1234, 100))
+	assert.Equal(t, "1.234", formatAmount(1234, 1000))
+	assert.Equal(t, "-0.5", formatAmount(-5, 10))
+	assert.Equal(t, "1234", formatAmount(1234, 1))
+}
+
+func TestSumStatement_PerCurrency(t *testing.T) {
+	totals := sumStatement([]model.Transaction{
+		{Currency: "UZS", Type: model.TransactionTypeIncome, Amount: 1000},
+		{Currency: "USD", Type: model.TransactionTypeExpense, Amount: 25},
+		{Currency: "UZS", Type: model.TransactionTypeExpense, Amount: 300},
+	})
+	assert.Equal(t, []statementTotals{
+		{currency: "USD", expense: 25},
+		{currency: "UZS", income: 1000, expense: 300},
+	}, totals)
+}
+
+func TestExportUserTransactionsPDF(t *testing.T) {
+	repo := newFakeTransactionRepo()
+	svc := newTestTransactionService(repo, nil)
+	desc := "Lunch at a place with a very long name that does not fit into the description column at all"
+	assert.NoError(t, repo.Create(context.Background(), &model.Transaction{
+		UserID: 1, Amount: 150050, Type: model.TransactionTypeExpense, Category: "Food", Description: &desc,
+	}))
+
+	buffer, err := svc.ExportUserTransactionsPDF(context.Background(), 1, model.UserTransactionFilters{})
+	assert.NoError(t, err)
+	assert.True(t, len(buffer.Bytes()) > 4 && string(buffer.Bytes()[:4]) == "%PDF")
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"expense_tracker/internal/model"
+)
+
+// statsCache keeps admin statistics per filter set for a short TTL, so admins polling the same
+// dashboard don't each rerun the aggregations. A nil cache stores nothing.
+type statsCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]statsCacheEntry
+}
+
+type statsCacheEntry struct {
+	stats     *model.AggregatedStats
+	expiresAt time.Time
+}
+
+// newStatsCache returns nil when ttl disables caching
+func newStatsCache(ttl time.Duration) *statsCache {
+	if ttl <= 0 {
+		return nil
+	}
+	return &statsCache{ttl: ttl, entries: make(map[string]statsCacheEntry)}
+}
+
+// statsCacheKey serializes every filter field, so two filter sets share an entry only when equal
+func statsCacheKey(filters model.AdminTransactionFilters) (string, bool) {
+	key, err := json.Marshal(filters)
+	if err != nil {
+		return "", false
+	}
+	return string(key), true
+}
+
+func (c *statsCache) get(key string, now time.Time) (*model.AggregatedStats, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || !now.Before(entry.expiresAt) {
+		return nil, false
+	}
+	return entry.stats, true
+}
+
+func (c *statsCache) set(key string, stats *model.AggregatedStats, now time.Time) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Filter sets vary freely, so expired entries are swept here rather than left to pile up
+	for k, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = statsCacheEntry{stats: stats, expiresAt: now.Add(c.ttl)}
+}
+
+// clear drops every entry after a write, since any transaction can count towards any filter set
+func (c *statsCache) clear() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	clear(c.entries)
+	c.mu.Unlock()
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	_ "image/png" // Registers the PNG decoder for image.Decode
+	"path"
+	"strings"
+
+	"golang.org/x/image/draw"
+)
+
+// thumbnailMaxEdge is the longest side of a generated receipt thumbnail, in pixels
+const thumbnailMaxEdge = 300
+
+// thumbnailKey returns the storage key of the thumbnail for a receipt, e.g. "transactions/1/receipt_thumb.jpg"
+func thumbnailKey(receiptKey string) string {
+	ext := path.Ext(receiptKey)
+	return strings.TrimSuffix(receiptKey, ext) + "_thumb.jpg"
+}
+
+// isImageReceipt reports whether a receipt can be thumbnailed (PDFs cannot)
+func isImageReceipt(receiptKey string) bool {
+	return strings.HasPrefix(receiptContentTypes[strings.ToLower(path.Ext(receiptKey))], "image/")
+}
+
+// makeThumbnail decodes a JPEG or PNG and encodes a JPEG scaled down to fit thumbnailMaxEdge
+func makeThumbnail(data []byte) ([]byte, error) {
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > thumbnailMaxEdge || height > thumbnailMaxEdge { // Never upscale small images
+		if width >= height {
+			height = max(1, height*thumbnailMaxEdge/width)
+			width = thumbnailMaxEdge
+		} else {
+			width = max(1, width*thumbnailMaxEdge/height)
+			height = thumbnailMaxEdge
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Src, nil)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+	return buf.Bytes(), nil
+}
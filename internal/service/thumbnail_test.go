@@ -0,0 +1,104 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"expense_tracker/internal/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func encodeTestPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		img.Set(x, 0, color.RGBA{R: 255, A: 255})
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestThumbnailKey(t *testing.T) {
+	assert.Equal(t, "transactions/1/receipt_thumb.jpg", thumbnailKey("transactions/1/receipt.png"))
+	assert.Equal(t, "transactions/1/scan.v2_thumb.jpg", thumbnailKey("transactions/1/scan.v2.JPEG"))
+}
+
+func TestMakeThumbnail_FitsLongEdge(t *testing.T) {
+	tests := []struct {
+		width, height         int
+		wantWidth, wantHeight int
+	}{
+		{1200, 600, 300, 150},
+		{400, 800, 150, 300},
+		{120, 80, 120, 80}, // Small images are not upscaled
+	}
+	for _, tt := range tests {
+		thumb, err := makeThumbnail(encodeTestPNG(t, tt.width, tt.height))
+		assert.NoError(t, err)
+		cfg, err := jpeg.DecodeConfig(bytes.NewReader(thumb))
+		assert.NoError(t, err)
+		assert.Equal(t, tt.wantWidth, cfg.Width)
+		assert.Equal(t, tt.wantHeight, cfg.Height)
+	}
+}
+
+func TestUploadReceipt_GeneratesThumbnail(t *testing.T) {
+	repo := newFakeTransactionRepo()
+	repo.transactions[1] = &model.Transaction{ID: 1, UserID: 1}
+	svc := newTestTransactionService(repo, nil)
+	uploadsDir := withLocalStorage(t, svc)
+
+	tx, err := svc.UploadReceipt(context.Background(), 1, 1, newFileHeader(t, "receipt.png", encodeTestPNG(t, 900, 600)))
+	assert.NoError(t, err)
+	assert.FileExists(t, filepath.Join(uploadsDir, filepath.FromSlash(thumbnailKey(*tx.ReceiptPath))))
+
+	contents, fileName, err := svc.GetReceiptThumbnail(context.Background(), 1, 1, model.RoleUser)
+	assert.NoError(t, err)
+	defer contents.Close()
+	assert.Equal(t, "receipt_thumb.jpg", fileName)
+	cfg, err := jpeg.DecodeConfig(contents)
+	assert.NoError(t, err)
+	assert.Equal(t, 300, cfg.Width)
+
+	// Deleting the transaction removes both files
+	assert.NoError(t, svc.DeleteTransaction(context.Background(), 1, 1, model.RoleUser))
+	assert.NoDirExists(t, filepath.Join(uploadsDir, "transactions", "1"))
+}
+
+func TestGetReceiptThumbnail_FallsBackToOriginal(t *testing.T) {
+	repo := newFakeTransactionRepo()
+	repo.transactions[1] = &model.Transaction{ID: 1, UserID: 1}
+	svc := newTestTransactionService(repo, nil)
+	uploadsDir := withLocalStorage(t, svc)
+
+	tx, err := svc.UploadReceipt(context.Background(), 1, 1, newFileHeader(t, "receipt.png", encodeTestPNG(t, 900, 600)))
+	assert.NoError(t, err)
+	assert.NoError(t, os.Remove(filepath.Join(uploadsDir, filepath.FromSlash(thumbnailKey(*tx.ReceiptPath)))))
+
+	contents, fileName, err := svc.GetReceiptThumbnail(context.Background(), 1, 1, model.RoleUser)
+	assert.NoError(t, err)
+	contents.Close()
+	assert.Equal(t, "receipt.png", fileName)
+}
+
+func TestGetReceiptThumbnail_PDF(t *testing.T) {
+	repo := newFakeTransactionRepo()
+	repo.transactions[1] = &model.Transaction{ID: 1, UserID: 1}
+	svc := newTestTransactionService(repo, nil)
+	withLocalStorage(t, svc)
+
+	_, err := svc.UploadReceipt(context.Background(), 1, 1, newFileHeader(t, "receipt.pdf", pdfBytes))
+	assert.NoError(t, err)
+
+	_, _, err = svc.GetReceiptThumbnail(context.Background(), 1, 1, model.RoleUser)
+	assert.ErrorIs(t, err, ErrNoThumbnail)
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"expense_tracker/internal/model"
+	"expense_tracker/internal/tracing"
+)
+
+// MaxTimeSeriesPoints caps how many periods one time series request may span
+const MaxTimeSeriesPoints = 366
+
+var (
+	ErrInvalidGranularity      = errors.New("granularity must be day, week or month")
+	ErrInvalidDateRange        = errors.New("start must not be after end")
+	ErrTimeSeriesRangeTooLarge = fmt.Errorf("range spans more than %d periods, use a coarser granularity", MaxTimeSeriesPoints)
+)
+
+// truncatePeriod returns the UTC start of the period containing t, matching PostgreSQL's
+// date_trunc (weeks start on Monday)
+func truncatePeriod(t time.Time, granularity string) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch granularity {
+	case model.GranularityWeek:
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case model.GranularityMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return day
+	}
+}
+
+// nextPeriod returns the start of the period after the one starting at period
+func nextPeriod(period time.Time, granularity string) time.Time {
+	switch granularity {
+	case model.GranularityWeek:
+		return period.AddDate(0, 0, 7)
+	case model.GranularityMonth:
+		return period.AddDate(0, 1, 0)
+	default:
+		return period.AddDate(0, 0, 1)
+	}
+}
+
+// GetUserTimeSeries returns income and expense in currency (the user's own when empty) per period
+// from start through the whole UTC day of end, with a zero point for every period without
+// transactions so charts have no gaps
+func (s *transactionService) GetUserTimeSeries(ctx context.Context, userID int, currency, granularity string, start, end time.Time) ([]model.TimeSeriesPoint, error) {
+	ctx, span := tracing.Start(ctx, "TransactionService.GetUserTimeSeries")
+	defer span.End()
+
+	if granularity != model.GranularityDay && granularity != model.GranularityWeek && granularity != model.GranularityMonth {
+		return nil, ErrInvalidGranularity
+	}
+	start, end = start.UTC(), end.UTC()
+	if start.After(end) {
+		return nil, ErrInvalidDateRange
+	}
+
+	var periods []time.Time
+	last := truncatePeriod(end, granularity)
+	for p := truncatePeriod(start, granularity); !p.After(last); p = nextPeriod(p, granularity) {
+		if len(periods) == MaxTimeSeriesPoints {
+			return nil, ErrTimeSeriesRangeTooLarge
+		}
+		periods = append(periods, p)
+	}
+
+	currency, err := s.resolveCurrency(ctx, userID, currency)
+	if err != nil {
+		return nil, err
+	}
+	endExclusive := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, 1)
+	totals, err := s.repo.GetUserTimeSeries(ctx, userID, currency, granularity, start, endExclusive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get time series from repo: %w", err)
+	}
+
+	byPeriod := make(map[time.Time]model.TimeSeriesPoint, len(totals))
+	for _, p := range totals {
+		byPeriod[p.Period.UTC()] = p
+	}
+	series := make([]model.TimeSeriesPoint, len(periods))
+	for i, period := range periods {
+		point := byPeriod[period]
+		point.Period = period
+		series[i] = point
+	}
+	return series, nil
+}
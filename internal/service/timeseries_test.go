@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"expense_tracker/internal/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// timeSeriesRepo returns canned per-period totals and records the queried range
+type timeSeriesRepo struct {
+	*fakeTransactionRepo
+	points     []model.TimeSeriesPoint
+	currency   string
+	start, end time.Time
+}
+
+func (r *timeSeriesRepo) GetUserTimeSeries(ctx context.Context, userID int, currency, granularity string, start, end time.Time) ([]model.TimeSeriesPoint, error) {
+	r.currency, r.start, r.end = currency, start, end
+	return r.points, nil
+}
+
+func TestGetUserTimeSeries_ZeroFillsGaps(t *testing.T) {
+	repo := &timeSeriesRepo{
+		fakeTransactionRepo: newFakeTransactionRepo(),
+		points: []model.TimeSeriesPoint{
+			{Period: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), Income: 1000, Expense: 200},
+		},
+	}
+	svc := newTestTransactionService(repo.fakeTransactionRepo, nil)
+	svc.repo = repo
+
+	series, err := svc.GetUserTimeSeries(context.Background(), 1, "", model.GranularityMonth,
+		time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, []model.TimeSeriesPoint{
+		{Period: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{Period: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), Income: 1000, Expense: 200},
+		{Period: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+	}, series)
+	assert.Equal(t, time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC), repo.end) // end day is included
+	assert.Equal(t, model.DefaultCurrency, repo.currency)                   // The user's currency by default
+}
+
+func TestGetUserTimeSeries_Validation(t *testing.T) {
+	svc := newTestTransactionService(newFakeTransactionRepo(), nil)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	_, err := svc.GetUserTimeSeries(context.Background(), 1, "", "hour", start, start)
+	assert.ErrorIs(t, err, ErrInvalidGranularity)
+
+	_, err = svc.GetUserTimeSeries(context.Background(), 1, "", model.GranularityDay, start, start.AddDate(0, 0, -1))
+	assert.ErrorIs(t, err, ErrInvalidDateRange)
+
+	_, err = svc.GetUserTimeSeries(context.Background(), 1, "", model.GranularityDay, start, start.AddDate(2, 0, 0))
+	assert.ErrorIs(t, err, ErrTimeSeriesRangeTooLarge)
+}
+
+func TestTruncatePeriod_WeeksStartOnMonday(t *testing.T) {
+	sunday := time.Date(2024, 3, 10, 15, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC), truncatePeriod(sunday, model.GranularityWeek))
+	monday := time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, monday, truncatePeriod(monday, model.GranularityWeek))
+}
@@ -0,0 +1,36 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"expense_tracker/internal/repository"
+)
+
+// StartTokenBlacklistCleanup periodically deletes expired blacklist entries until ctx is cancelled,
+// marking the cleanup goroutine in wg
+func StartTokenBlacklistCleanup(ctx context.Context, wg *sync.WaitGroup, repo repository.TokenBlacklistRepository, interval time.Duration) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				deleted, err := repo.DeleteExpired(ctx)
+				if err != nil {
+					log.Printf("Error cleaning up token blacklist: %v", err)
+					continue
+				}
+				if deleted > 0 {
+					log.Printf("Removed %d expired token blacklist entries", deleted)
+				}
+			}
+		}
+	}()
+}
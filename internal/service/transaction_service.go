@@ -4,79 +4,353 @@ import (
 	"bytes"
 	"context"
 	"encoding/csv"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"log"
 	"mime/multipart"
-	"os"
+	"net/http"
+	"path"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
+	"expense_tracker/internal/config"
+	"expense_tracker/internal/metrics"
 	"expense_tracker/internal/model"
 	"expense_tracker/internal/repository"
+	"expense_tracker/internal/storage"
+	"expense_tracker/internal/tracing"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 var (
-	ErrTransactionNotFound = errors.New("transaction not found")
-	ErrForbidden           = errors.New("forbidden: user does not have permission for this action")
-	ErrInvalidFileFormat   = errors.New("invalid file format. only .jpg, .png, .pdf are allowed")
-	ErrFileSizeExceeded    = errors.New("file size exceeds limit")
+	ErrTransactionNotFound   = errors.New("transaction not found")
+	ErrForbidden             = errors.New("forbidden: user does not have permission for this action")
+	ErrInvalidFileFormat     = errors.New("invalid file format")
+	ErrFileSizeExceeded      = errors.New("file size exceeds limit")
+	ErrAmountBelowMinimum    = errors.New("amount is below the minimum transaction amount")
+	ErrInvalidAmount         = fmt.Errorf("amount must be greater than 0 and at most %d", config.MaxTransactionAmount)
+	ErrNoThumbnail           = errors.New("thumbnails are not available for PDF receipts")
+	ErrReceiptNotFound       = errors.New("receipt not found for this transaction")
+	ErrVersionConflict       = repository.ErrVersionConflict
+	ErrInvalidTransfer       = errors.New("a transfer needs two different accounts in from_account_id and to_account_id")
+	ErrTransferNotEditable   = errors.New("transfers can't be edited; delete and recreate the transfer instead")
+	ErrUnsupportedCurrency   = errors.New("unsupported currency code")
+	ErrFutureTransactionDate = errors.New("transaction_date is too far in the future")
+	ErrDescriptionTooLong    = errors.New("description is too long")
+	ErrInvalidTag            = fmt.Errorf("tags must be lower-case, without spaces or commas, 1 to %d characters each, at most %d per transaction",
+		model.MaxTagLength, model.MaxTagsPerTransaction)
 )
 
-const MaxFileSize = 5 * 1024 * 1024 // 5MB
+// receiptContentTypes maps receipt extensions to the content type their bytes must sniff as.
+// Configured extensions missing here (e.g. .heic) can't be sniffed and are accepted by extension alone.
+var receiptContentTypes = map[string]string{
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".pdf":  "application/pdf",
+}
+
+// sniffLen is how many leading bytes http.DetectContentType considers
+const sniffLen = 512
+
+const (
+	minSuggestionKeywordLength = 3 // Shorter words ("a", "to") match too broadly
+	maxSuggestionKeywords      = 5
+)
+
+// TransactionHooks lets services that write transactions themselves, such as the recurring
+// scheduler and account deletion, keep TransactionService's caches, metrics and webhook in step
+// with the table
+type TransactionHooks interface {
+	// AfterCreate is called once a new transaction has been stored
+	AfterCreate(t model.Transaction)
+	// AfterUserDeleted is called once a user, and with them all their transactions, is gone
+	AfterUserDeleted(userID int)
+}
 
 // TransactionService defines operations for transactions
 type TransactionService interface {
+	TransactionHooks
+
 	CreateTransaction(ctx context.Context, userID int, req model.CreateTransactionRequest) (*model.Transaction, error)
+	ValidateTransaction(ctx context.Context, userID int, req model.CreateTransactionRequest) error
 	GetTransactionByID(ctx context.Context, transactionID int64, userID int, userRole string) (*model.Transaction, error)
-	GetUserTransactions(ctx context.Context, userID int, filters model.UserTransactionFilters) ([]model.Transaction, error)
+	GetTransactionHistory(ctx context.Context, transactionID int64, userID int, userRole string) ([]model.TransactionChange, error)
+	GetBalance(ctx context.Context, userID int, currency string) (int64, error)
+	GetUserTransactions(ctx context.Context, userID int, filters model.UserTransactionFilters) (*model.TransactionPage, error)
 	UpdateTransaction(ctx context.Context, transactionID int64, userID int, req model.UpdateTransactionRequest) (*model.Transaction, error)
 	DeleteTransaction(ctx context.Context, transactionID int64, userID int, userRole string) error
-	UploadReceipt(ctx context.Context, transactionID int64, userID int, file *multipart.FileHeader, uploadsDir string) (*model.Transaction, error)
-	GetReceiptPath(ctx context.Context, transactionID int64, userID int, userRole string) (string, string, error) // returns path and filename
+	DeleteTransactions(ctx context.Context, ids []int64, userID int, userRole string) (int64, error)
+	UploadReceipt(ctx context.Context, transactionID int64, userID int, file *multipart.FileHeader) (*model.Transaction, error)
+	DeleteReceipt(ctx context.Context, transactionID int64, userID int) (*model.Transaction, error)
+	GetReceipt(ctx context.Context, transactionID int64, userID int, userRole string) (io.ReadCloser, string, error) // returns contents and filename
+	GetReceiptThumbnail(ctx context.Context, transactionID int64, userID int, userRole string) (io.ReadCloser, string, error)
+	GetUserStatistics(ctx context.Context, userID int, filters model.UserTransactionFilters) (*model.UserStats, error)
+	GetUserOverview(ctx context.Context, userID int, filters model.UserTransactionFilters) (*model.TransactionOverview, error)
+	GetCategoryRanking(ctx context.Context, userID int, filters model.UserTransactionFilters, limit int) ([]model.CategoryRank, error)
+	GetTopMerchants(ctx context.Context, userID int, filters model.UserTransactionFilters, limit int) ([]model.MerchantSpend, error)
+	GetUserCategoryBreakdown(ctx context.Context, userID int, currency string, start, end time.Time, txType string) ([]model.CategoryShare, error)
+	GetUserTimeSeries(ctx context.Context, userID int, currency, granularity string, start, end time.Time) ([]model.TimeSeriesPoint, error)
+	ExportUserTransactionsPDF(ctx context.Context, userID int, filters model.UserTransactionFilters) (*bytes.Buffer, error)
+	ImportTransactionsCSV(ctx context.Context, userID int, r io.Reader) (*model.ImportResult, error)
+	SuggestCategories(ctx context.Context, userID int, description string, limit int) ([]model.CategorySuggestion, error)
+	GetRecentCategories(ctx context.Context, userID int, limit int) ([]model.CategoryUsage, error)
 
 	// Admin methods
-	GetAllTransactionsAdmin(ctx context.Context, filters model.AdminTransactionFilters) ([]model.Transaction, error)
+	GetAllTransactionsAdmin(ctx context.Context, filters model.AdminTransactionFilters) (*model.AdminTransactionPage, error)
 	GetStatisticsAdmin(ctx context.Context, filters model.AdminTransactionFilters) (*model.AggregatedStats, error)
+	GetUserStatsAdmin(ctx context.Context, filters model.AdminTransactionFilters, currency string) (*model.UserStatPage, error)
+	GetTopCategoriesAdmin(ctx context.Context, filters model.AdminTransactionFilters, currency string, limit int) ([]model.CategoryRank, error)
 	ExportTransactionsCSVAdmin(ctx context.Context, filters model.AdminTransactionFilters) (*bytes.Buffer, error)
+	ExportTransactionsJSONAdmin(ctx context.Context, filters model.AdminTransactionFilters) (*bytes.Buffer, error)
+	GetReimbursableReportAdmin(ctx context.Context, filters model.AdminTransactionFilters) ([]model.ReimbursableTotal, error)
+	ExportReimbursableReportCSVAdmin(ctx context.Context, filters model.AdminTransactionFilters) (*bytes.Buffer, error)
+}
+
+// TransactionNotifier tells external integrations about new transactions. It must return without
+// waiting for the delivery, so a slow integration never delays the API response.
+type TransactionNotifier interface {
+	TransactionCreated(t model.Transaction)
 }
 
 type transactionService struct {
-	repo       repository.TransactionRepository
-	uploadsDir string
+	repo         repository.TransactionRepository
+	userRepo     repository.UserRepository
+	categoryRepo repository.CategoryRepository
+	storage      storage.ReceiptStorage
+	cfg          *config.TransactionConfig
+	recent       *recentCategoriesCache
+	stats        *statsCache         // nil when STATS_CACHE_TTL_SECONDS is 0
+	notifier     TransactionNotifier // Optional
+}
+
+// NewTransactionService creates a new TransactionService. notifier may be nil.
+func NewTransactionService(repo repository.TransactionRepository, userRepo repository.UserRepository, categoryRepo repository.CategoryRepository, receiptStorage storage.ReceiptStorage, cfg *config.TransactionConfig, notifier TransactionNotifier) TransactionService {
+	return &transactionService{
+		repo:         repo,
+		userRepo:     userRepo,
+		categoryRepo: categoryRepo,
+		storage:      receiptStorage,
+		cfg:          cfg,
+		recent:       newRecentCategoriesCache(recentCategoriesTTL),
+		stats:        newStatsCache(cfg.StatsCacheTTL),
+		notifier:     notifier,
+	}
+}
+
+// validateAmount checks the amount is positive, within MaxTransactionAmount and at least the configured minimum
+func (s *transactionService) validateAmount(amount int64) error {
+	if amount <= 0 || amount > config.MaxTransactionAmount {
+		return ErrInvalidAmount
+	}
+	if amount < s.cfg.MinAmount {
+		return fmt.Errorf("%w (%d)", ErrAmountBelowMinimum, s.cfg.MinAmount)
+	}
+	return nil
+}
+
+// validateTransactionDate rejects dates further ahead than the configured tolerance; backdating is always allowed
+func (s *transactionService) validateTransactionDate(date time.Time) error {
+	if date.After(time.Now().Add(s.cfg.MaxFutureDate)) {
+		return fmt.Errorf("%w (at most %v ahead)", ErrFutureTransactionDate, s.cfg.MaxFutureDate)
+	}
+	return nil
 }
 
-// NewTransactionService creates a new TransactionService
-func NewTransactionService(repo repository.TransactionRepository, uploadsDir string) TransactionService {
-	return &transactionService{repo: repo, uploadsDir: uploadsDir}
+// resolveCurrency validates a requested ISO 4217 code, falling back to the user's currency when none is given
+func (s *transactionService) resolveCurrency(ctx context.Context, userID int, requested string) (string, error) {
+	if strings.TrimSpace(requested) == "" {
+		currency, err := s.userRepo.GetCurrency(ctx, userID)
+		if err != nil {
+			return "", fmt.Errorf("failed to get default currency: %w", err)
+		}
+		return currency, nil
+	}
+	currency, ok := model.NormalizeCurrency(requested)
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnsupportedCurrency, requested)
+	}
+	return currency, nil
+}
+
+// normalizeMerchant trims the merchant name and maps blank values to NULL
+func normalizeMerchant(merchant *string) *string {
+	if merchant == nil {
+		return nil
+	}
+	trimmed := strings.TrimSpace(*merchant)
+	if trimmed == "" {
+		return nil
+	}
+	return &trimmed
+}
+
+// normalizeDescription trims the description, maps blank values to NULL and enforces the configured length
+func (s *transactionService) normalizeDescription(description *string) (*string, error) {
+	if description == nil {
+		return nil, nil
+	}
+	trimmed := strings.TrimSpace(*description)
+	if trimmed == "" {
+		return nil, nil
+	}
+	if s.cfg.MaxDescriptionLength > 0 && utf8.RuneCountInString(trimmed) > s.cfg.MaxDescriptionLength {
+		return nil, fmt.Errorf("%w (at most %d characters)", ErrDescriptionTooLong, s.cfg.MaxDescriptionLength)
+	}
+	return &trimmed, nil
 }
 
+// normalizeTags validates tags and drops duplicates, keeping the first occurrence's position.
+// Tags are matched exactly, so rather than silently lower-casing them it rejects upper-case ones.
+func normalizeTags(tags []string) ([]string, error) {
+	normalized := []string{}
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		if tag == "" || utf8.RuneCountInString(tag) > model.MaxTagLength || tag != strings.ToLower(tag) ||
+			strings.ContainsFunc(tag, func(r rune) bool { return unicode.IsSpace(r) || r == ',' }) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidTag, tag)
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+	if len(normalized) > model.MaxTagsPerTransaction {
+		return nil, ErrInvalidTag
+	}
+	return normalized, nil
+}
+
+// resolveCategory checks that a category of the transaction's type exists for the user (or
+// globally) and returns its stored spelling, so "food" and "Food" end up as the same category
+func (s *transactionService) resolveCategory(ctx context.Context, userID int, name, txType string) (string, error) {
+	return resolveCategoryName(ctx, s.categoryRepo, userID, name, txType)
+}
+
+// resolveCategoryName is resolveCategory for services that keep their own CategoryRepository
+func resolveCategoryName(ctx context.Context, categoryRepo repository.CategoryRepository, userID int, name, txType string) (string, error) {
+	matches, err := categoryRepo.FindByName(ctx, userID, strings.TrimSpace(name))
+	if err != nil {
+		return "", fmt.Errorf("failed to look up category: %w", err)
+	}
+	if len(matches) == 0 {
+		return "", ErrUnknownCategory
+	}
+	for _, c := range matches {
+		if c.Type == txType {
+			return c.Name, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %q is an %s category", ErrCategoryTypeMismatch, matches[0].Name, matches[0].Type)
+}
+
+// CreateTransaction stores a new income, expense or transfer. With an idempotency key, a retry
+// of a request already handled within the TTL returns the transaction it created instead.
 func (s *transactionService) CreateTransaction(ctx context.Context, userID int, req model.CreateTransactionRequest) (*model.Transaction, error) {
-	transactionDate := req.TransactionDate
-	if transactionDate.IsZero() {
-		transactionDate = time.Now()
+	ctx, span := tracing.Start(ctx, "TransactionService.CreateTransaction")
+	defer span.End()
+
+	key := strings.TrimSpace(req.IdempotencyKey)
+	if len(key) > MaxIdempotencyKeyLength {
+		return nil, ErrInvalidIdempotencyKey
+	}
+	// A replay answers with the original before validating again: a retry must not fail just
+	// because something changed since, such as the category being deleted or a stricter minimum
+	if key != "" {
+		existing, err := s.repo.FindByIdempotencyKey(ctx, userID, key, s.idempotencyCutoff())
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up idempotency key: %w", err)
+		}
+		if existing != nil {
+			return existing, nil
+		}
 	}
 
-	transaction := &model.Transaction{
-		UserID:          userID,
-		Amount:          req.Amount,
-		Type:            req.Type,
-		Category:        req.Category,
-		Description:     req.Description,
-		TransactionDate: transactionDate,
-		CreatedAt:       time.Now(),
-		UpdatedAt:       time.Now(),
+	transaction, err := s.buildTransaction(ctx, userID, req)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := s.repo.Create(ctx, transaction); err != nil {
+	if err := s.storeTransaction(ctx, transaction, key); err != nil {
+		if errors.Is(err, repository.ErrDuplicateIdempotencyKey) {
+			// A concurrent retry with the same key got there first; answer with its transaction
+			existing, err := s.repo.FindByIdempotencyKey(ctx, userID, key, s.idempotencyCutoff())
+			if err != nil {
+				return nil, fmt.Errorf("failed to look up idempotency key: %w", err)
+			}
+			if existing == nil {
+				return nil, fmt.Errorf("transaction for idempotency key %q disappeared", key)
+			}
+			return existing, nil
+		}
+		if errors.Is(err, repository.ErrAccountNotFound) {
+			return nil, ErrAccountNotFound
+		}
 		return nil, fmt.Errorf("failed to create transaction in repo: %w", err)
 	}
+	s.AfterCreate(*transaction) // A copy, as the caller may still change transaction
 	return transaction, nil
 }
 
+// AfterCreate counts a new transaction, drops the caches it makes stale and tells the webhook
+func (s *transactionService) AfterCreate(t model.Transaction) {
+	metrics.TransactionsCreated.Inc()
+	s.recent.invalidate(t.UserID)
+	s.stats.clear()
+	if s.notifier != nil {
+		s.notifier.TransactionCreated(t)
+	}
+}
+
+func (s *transactionService) AfterUserDeleted(userID int) {
+	s.recent.invalidate(userID)
+	s.stats.clear()
+}
+
+// storeTransaction inserts t, recording the idempotency key with it when there is one.
+// Transfers also move their amount between the accounts.
+func (s *transactionService) storeTransaction(ctx context.Context, t *model.Transaction, idempotencyKey string) error {
+	switch {
+	case idempotencyKey != "":
+		return s.repo.CreateIdempotent(ctx, t, idempotencyKey, s.idempotencyCutoff())
+	case t.Type == model.TransactionTypeTransfer:
+		return s.repo.CreateTransfer(ctx, t)
+	default:
+		return s.repo.Create(ctx, t)
+	}
+}
+
+// idempotencyCutoff is the creation time before which idempotency keys have expired
+func (s *transactionService) idempotencyCutoff() time.Time {
+	return time.Now().Add(-s.cfg.IdempotencyKeyTTL)
+}
+
+// GetBalance returns the user's running balance in currency: total income minus total expense.
+// An empty currency means the user's own.
+func (s *transactionService) GetBalance(ctx context.Context, userID int, currency string) (int64, error) {
+	ctx, span := tracing.Start(ctx, "TransactionService.GetBalance")
+	defer span.End()
+
+	currency, err := s.resolveCurrency(ctx, userID, currency)
+	if err != nil {
+		return 0, err
+	}
+	return s.repo.GetBalance(ctx, userID, currency)
+}
+
 func (s *transactionService) GetTransactionByID(ctx context.Context, transactionID int64, userID int, userRole string) (*model.Transaction, error) {
+	ctx, span := tracing.Start(ctx, "TransactionService.GetTransactionByID")
+	defer span.End()
+
 	transaction, err := s.repo.FindByID(ctx, transactionID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to find transaction by ID: %w", err)
@@ -91,29 +365,107 @@ func (s *transactionService) GetTransactionByID(ctx context.Context, transaction
 	return transaction, nil
 }
 
-func (s *transactionService) GetUserTransactions(ctx context.Context, userID int, filters model.UserTransactionFilters) ([]model.Transaction, error) {
-	// Adjust date filters to cover the whole day if only date part is provided
-	if filters.StartDate != nil && filters.StartDate.Hour() == 0 && filters.StartDate.Minute() == 0 && filters.StartDate.Second() == 0 {
-		startOfDay := time.Date(filters.StartDate.Year(), filters.StartDate.Month(), filters.StartDate.Day(), 0, 0, 0, 0, filters.StartDate.Location())
-		filters.StartDate = &startOfDay
-		if filters.EndDate == nil { // If only start date (a specific day) is given, set end date to end of that day
-			endOfDay := time.Date(filters.StartDate.Year(), filters.StartDate.Month(), filters.StartDate.Day(), 23, 59, 59, 999999999, filters.StartDate.Location())
-			filters.EndDate = &endOfDay
-		}
+// newTransactionChange snapshots t before it is changed by changedBy, for its history
+func newTransactionChange(t *model.Transaction, action string, changedBy int) (*model.TransactionChange, error) {
+	oldValues, err := json.Marshal(t)
+	if err != nil {
+		return nil, fmt.Errorf("failed to snapshot transaction for history: %w", err)
 	}
-	if filters.EndDate != nil && filters.EndDate.Hour() == 0 && filters.EndDate.Minute() == 0 && filters.EndDate.Second() == 0 {
-		endOfDay := time.Date(filters.EndDate.Year(), filters.EndDate.Month(), filters.EndDate.Day(), 23, 59, 59, 999999999, filters.EndDate.Location())
-		filters.EndDate = &endOfDay
+	return &model.TransactionChange{
+		TransactionID: t.ID,
+		UserID:        t.UserID,
+		Action:        action,
+		OldValues:     oldValues,
+		ChangedBy:     &changedBy,
+	}, nil
+}
+
+// GetTransactionHistory returns a transaction's change log, oldest first, under the same access
+// rules as GetTransactionByID. The log of a deleted transaction stays readable to its owner.
+func (s *transactionService) GetTransactionHistory(ctx context.Context, transactionID int64, userID int, userRole string) ([]model.TransactionChange, error) {
+	ctx, span := tracing.Start(ctx, "TransactionService.GetTransactionHistory")
+	defer span.End()
+
+	transaction, err := s.repo.FindByID(ctx, transactionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find transaction by ID: %w", err)
+	}
+	history, err := s.repo.FindHistory(ctx, transactionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction history from repo: %w", err)
+	}
+
+	ownerID := 0
+	switch {
+	case transaction != nil:
+		ownerID = transaction.UserID
+	case len(history) > 0:
+		ownerID = history[0].UserID
+	default:
+		return nil, ErrTransactionNotFound
+	}
+	if userRole != model.RoleAdmin && ownerID != userID {
+		return nil, ErrForbidden
+	}
+	return history, nil
+}
+
+// applyEndDateMode interprets a caller-supplied EndDate according to the configured mode.
+// Inclusive: the whole UTC calendar day of EndDate is included, whatever its time of day.
+// Exclusive: EndDate is used verbatim as an exclusive upper bound.
+// Filters already marked EndDateExclusive are exact ranges and are left untouched.
+func (s *transactionService) applyEndDateMode(filters *model.UserTransactionFilters) {
+	if filters.EndDate == nil || filters.EndDateExclusive {
+		return
+	}
+	if s.cfg.EndDateMode == config.EndDateExclusive {
+		filters.EndDateExclusive = true
+		return
+	}
+	end := filters.EndDate.UTC()
+	endOfDay := time.Date(end.Year(), end.Month(), end.Day(), 23, 59, 59, 999999999, time.UTC)
+	filters.EndDate = &endOfDay
+}
+
+func (s *transactionService) GetUserTransactions(ctx context.Context, userID int, filters model.UserTransactionFilters) (*model.TransactionPage, error) {
+	ctx, span := tracing.Start(ctx, "TransactionService.GetUserTransactions")
+	defer span.End()
+
+	s.applyEndDateMode(&filters)
+
+	pageSize := filters.Limit
+	if pageSize > 0 {
+		filters.Limit = pageSize + 1 // Fetch one extra row to know whether another page exists
 	}
 
 	transactions, err := s.repo.FindByUser(ctx, userID, filters)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user transactions from repo: %w", err)
 	}
-	return transactions, nil
+
+	page := &model.TransactionPage{Data: transactions}
+	if page.Data == nil {
+		page.Data = []model.Transaction{}
+	}
+	if pageSize > 0 && len(page.Data) > pageSize {
+		page.Data = page.Data[:pageSize]
+		nextCursor := model.CursorAfter(page.Data[pageSize-1]).Encode()
+		page.NextCursor = &nextCursor
+	}
+
+	if filters.WithSummary {
+		page.Summary, err = s.repo.SummarizeByUser(ctx, userID, filters)
+		if err != nil {
+			return nil, fmt.Errorf("failed to summarize user transactions: %w", err)
+		}
+	}
+	return page, nil
 }
 
 func (s *transactionService) UpdateTransaction(ctx context.Context, transactionID int64, userID int, req model.UpdateTransactionRequest) (*model.Transaction, error) {
+	ctx, span := tracing.Start(ctx, "TransactionService.UpdateTransaction")
+	defer span.End()
+
 	existingTx, err := s.repo.FindByID(ctx, transactionID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to find transaction for update: %w", err)
@@ -124,32 +476,86 @@ func (s *transactionService) UpdateTransaction(ctx context.Context, transactionI
 	if existingTx.UserID != userID { // Only author can edit
 		return nil, ErrForbidden
 	}
+	if existingTx.Type == model.TransactionTypeTransfer {
+		return nil, ErrTransferNotEditable
+	}
+	// Without a client version the check still guards the gap between this read and the write
+	if req.Version != nil && *req.Version != existingTx.Version {
+		return nil, ErrVersionConflict
+	}
+
+	change, err := newTransactionChange(existingTx, model.TransactionChangeUpdate, userID)
+	if err != nil {
+		return nil, err
+	}
 
 	// Apply updates
 	if req.Amount != nil {
+		if err := s.validateAmount(*req.Amount); err != nil {
+			return nil, err
+		}
 		existingTx.Amount = *req.Amount
 	}
+	if req.Currency != nil {
+		currency, ok := model.NormalizeCurrency(*req.Currency)
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrUnsupportedCurrency, *req.Currency)
+		}
+		existingTx.Currency = currency
+	}
 	if req.Type != nil {
 		existingTx.Type = *req.Type
 	}
 	if req.Category != nil {
-		existingTx.Category = *req.Category
+		category, err := s.resolveCategory(ctx, userID, *req.Category, existingTx.Type)
+		if err != nil {
+			return nil, err
+		}
+		existingTx.Category = category
+	} else if req.Type != nil {
+		// The kept category must suit the new type; free-text categories from before
+		// categories existed aren't known and are left unchecked
+		if _, err := s.resolveCategory(ctx, userID, existingTx.Category, existingTx.Type); err != nil && !errors.Is(err, ErrUnknownCategory) {
+			return nil, err
+		}
+	}
+	if req.Merchant != nil { // "" clears the merchant
+		existingTx.Merchant = normalizeMerchant(req.Merchant)
 	}
-	if req.Description != nil { // handles setting to "" or null
-		existingTx.Description = req.Description
+	if req.Description != nil { // "" or blank clears the description
+		description, err := s.normalizeDescription(req.Description)
+		if err != nil {
+			return nil, err
+		}
+		existingTx.Description = description
+	}
+	if req.Tags != nil {
+		tags, err := normalizeTags(*req.Tags)
+		if err != nil {
+			return nil, err
+		}
+		existingTx.Tags = tags
 	}
 	if req.TransactionDate != nil {
+		if err := s.validateTransactionDate(*req.TransactionDate); err != nil {
+			return nil, err
+		}
 		existingTx.TransactionDate = *req.TransactionDate
 	}
 	existingTx.UpdatedAt = time.Now()
 
-	if err := s.repo.Update(ctx, existingTx); err != nil {
+	if err := s.repo.Update(ctx, existingTx, change); err != nil {
 		return nil, fmt.Errorf("failed to update transaction in repo: %w", err)
 	}
+	s.recent.invalidate(existingTx.UserID)
+	s.stats.clear()
 	return existingTx, nil
 }
 
 func (s *transactionService) DeleteTransaction(ctx context.Context, transactionID int64, userID int, userRole string) error {
+	ctx, span := tracing.Start(ctx, "TransactionService.DeleteTransaction")
+	defer span.End()
+
 	existingTx, err := s.repo.FindByID(ctx, transactionID)
 	if err != nil {
 		return fmt.Errorf("failed to find transaction for deletion: %w", err)
@@ -161,13 +567,134 @@ func (s *transactionService) DeleteTransaction(ctx context.Context, transactionI
 	if userRole != model.RoleAdmin && existingTx.UserID != userID {
 		return ErrForbidden
 	}
-	if err := s.repo.Delete(ctx, transactionID); err != nil {
+	change, err := newTransactionChange(existingTx, model.TransactionChangeDelete, userID)
+	if err != nil {
+		return err
+	}
+	if existingTx.Type == model.TransactionTypeTransfer {
+		err = s.repo.DeleteTransfer(ctx, existingTx, change) // Also moves the amount back
+	} else {
+		err = s.repo.Delete(ctx, transactionID, change)
+	}
+	if err != nil {
 		return fmt.Errorf("failed to delete transaction in repo: %w", err)
 	}
+	s.recent.invalidate(existingTx.UserID)
+	s.stats.clear()
+
+	// The transaction is already gone, so a leftover file is only logged
+	if existingTx.ReceiptPath != nil && *existingTx.ReceiptPath != "" {
+		removeReceiptFiles(ctx, s.storage, *existingTx.ReceiptPath)
+	}
 	return nil
 }
 
-func (s *transactionService) UploadReceipt(ctx context.Context, transactionID int64, userID int, fileHeader *multipart.FileHeader, baseUploadsDir string) (*model.Transaction, error) {
+// ValidateTransaction runs every check CreateTransaction would, without saving anything.
+// Whether transfer accounts belong to the user is only known when the transfer is stored.
+func (s *transactionService) ValidateTransaction(ctx context.Context, userID int, req model.CreateTransactionRequest) error {
+	ctx, span := tracing.Start(ctx, "TransactionService.ValidateTransaction")
+	defer span.End()
+
+	_, err := s.buildTransaction(ctx, userID, req)
+	return err
+}
+
+// buildTransaction validates a create request and resolves it into the transaction to store.
+// It is shared by CreateTransaction and ValidateTransaction so the two can't disagree.
+func (s *transactionService) buildTransaction(ctx context.Context, userID int, req model.CreateTransactionRequest) (*model.Transaction, error) {
+	if err := s.validateAmount(req.Amount); err != nil {
+		return nil, err
+	}
+	if err := s.validateTransactionDate(req.TransactionDate); err != nil {
+		return nil, err
+	}
+	description, err := s.normalizeDescription(req.Description)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := normalizeTags(req.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	currency, err := s.resolveCurrency(ctx, userID, req.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	var transaction *model.Transaction
+	if req.Type == model.TransactionTypeTransfer {
+		if req.FromAccountID == nil || req.ToAccountID == nil || *req.FromAccountID == *req.ToAccountID {
+			return nil, ErrInvalidTransfer
+		}
+		transaction = &model.Transaction{
+			Category:      model.TransferCategory,
+			FromAccountID: req.FromAccountID,
+			ToAccountID:   req.ToAccountID,
+		}
+	} else {
+		category, err := s.resolveCategory(ctx, userID, req.Category, req.Type)
+		if err != nil {
+			return nil, err
+		}
+		transaction = &model.Transaction{Category: category, Merchant: normalizeMerchant(req.Merchant)}
+	}
+
+	transactionDate := req.TransactionDate
+	if transactionDate.IsZero() {
+		transactionDate = time.Now()
+	}
+	transaction.UserID = userID
+	transaction.Amount = req.Amount
+	transaction.Currency = currency
+	transaction.Type = req.Type
+	transaction.Description = description
+	transaction.Tags = tags
+	transaction.TransactionDate = transactionDate
+	transaction.CreatedAt = time.Now()
+	transaction.UpdatedAt = time.Now()
+	return transaction, nil
+}
+
+// DeleteTransactions deletes the listed transactions at once and returns how many were deleted.
+// Non-admins can only delete their own; ids they don't own, like ids that don't exist, are
+// skipped rather than failing the batch, so a count below len(ids) tells the caller some were.
+func (s *transactionService) DeleteTransactions(ctx context.Context, ids []int64, userID int, userRole string) (int64, error) {
+	ctx, span := tracing.Start(ctx, "TransactionService.DeleteTransactions")
+	defer span.End()
+
+	var ownerID *int
+	if userRole != model.RoleAdmin {
+		ownerID = &userID
+	}
+	deleted, err := s.repo.DeleteMany(ctx, ids, ownerID, func(t *model.Transaction) (*model.TransactionChange, error) {
+		return newTransactionChange(t, model.TransactionChangeDelete, userID)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete transactions in repo: %w", err)
+	}
+	if len(deleted) > 0 {
+		s.stats.clear()
+	}
+
+	invalidated := make(map[int]bool)
+	for _, t := range deleted {
+		if !invalidated[t.UserID] {
+			s.recent.invalidate(t.UserID)
+			invalidated[t.UserID] = true
+		}
+		// The transactions are already gone, so leftover files are only logged
+		if t.ReceiptPath != nil && *t.ReceiptPath != "" {
+			removeReceiptFiles(ctx, s.storage, *t.ReceiptPath)
+		}
+	}
+	return int64(len(deleted)), nil
+}
+
+func (s *transactionService) UploadReceipt(ctx context.Context, transactionID int64, userID int, fileHeader *multipart.FileHeader) (*model.Transaction, error) {
+	ctx, span := tracing.Start(ctx, "TransactionService.UploadReceipt")
+	defer span.End()
+
 	transaction, err := s.repo.FindByID(ctx, transactionID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to find transaction for receipt upload: %w", err)
@@ -180,53 +707,150 @@ func (s *transactionService) UploadReceipt(ctx context.Context, transactionID in
 	}
 
 	// Validate file
-	if fileHeader.Size > MaxFileSize {
-		return nil, ErrFileSizeExceeded
+	if fileHeader.Size > s.cfg.MaxReceiptSize {
+		return nil, fmt.Errorf("%w (max %d MB)", ErrFileSizeExceeded, s.cfg.MaxReceiptSize/(1024*1024))
 	}
-	ext := filepath.Ext(fileHeader.Filename)
-	allowedExts := map[string]bool{".jpg": true, ".jpeg": true, ".png": true, ".pdf": true}
-	if !allowedExts[strings.ToLower(ext)] {
-		return nil, ErrInvalidFileFormat
+	ext := strings.ToLower(filepath.Ext(fileHeader.Filename))
+	if !slices.Contains(s.cfg.AllowedReceiptExtensions, ext) {
+		return nil, fmt.Errorf("%w: allowed extensions are %s", ErrInvalidFileFormat, strings.Join(s.cfg.AllowedReceiptExtensions, ", "))
 	}
 
-	transactionUploadsDir := filepath.Join(baseUploadsDir, "transactions", strconv.FormatInt(transactionID, 10))
-	if err := os.MkdirAll(transactionUploadsDir, os.ModePerm); err != nil {
-		return nil, fmt.Errorf("failed to create upload directory: %w", err)
-	}
-
-	fileName := filepath.Base(fileHeader.Filename) // Basic sanitization
-	filePath := filepath.Join(transactionUploadsDir, fileName)
-	relativeFilePath := filepath.ToSlash(filePath) // Store with forward slashes for consistency
-
-	// Save the file
 	src, err := fileHeader.Open()
 	if err != nil {
 		return nil, fmt.Errorf("failed to open uploaded file: %w", err)
 	}
 	defer src.Close()
 
-	dst, err := os.Create(filePath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create file on server: %w", err)
+	// The extension is client-controlled, so check the actual bytes too
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(src, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to read uploaded file: %w", err)
+	}
+	head = head[:n]
+	if expectedType, ok := receiptContentTypes[ext]; ok && http.DetectContentType(head) != expectedType {
+		return nil, fmt.Errorf("%w: file contents do not match the %s extension", ErrInvalidFileFormat, ext)
+	}
+
+	// The stored name is generated so uploads never collide and a crafted filename cannot pick
+	// the key; the original name is only kept for display
+	receiptName := receiptDisplayName(fileHeader.Filename, ext)
+	key := path.Join("transactions", strconv.FormatInt(transactionID, 10), uuid.NewString()+ext)
+
+	// Keep a copy of images in memory for thumbnailing; uploads are size-limited
+	var body io.Reader = io.MultiReader(bytes.NewReader(head), src)
+	var imageData bytes.Buffer
+	if isImageReceipt(key) {
+		body = io.TeeReader(body, &imageData)
 	}
-	defer dst.Close()
 
-	if _, err := io.Copy(dst, src); err != nil {
-		return nil, fmt.Errorf("failed to save file: %w", err)
+	receiptKey, err := s.storage.Save(ctx, key, body)
+	if err != nil {
+		return nil, err
+	}
+	if isImageReceipt(receiptKey) {
+		s.saveThumbnail(ctx, receiptKey, imageData.Bytes())
 	}
 
+	change, err := newTransactionChange(transaction, model.TransactionChangeReceiptUpload, userID)
+	if err != nil {
+		s.removeReceipt(ctx, receiptKey)
+		return nil, err
+	}
 	// Update transaction with receipt path
-	if err := s.repo.UpdateReceiptPath(ctx, transactionID, relativeFilePath); err != nil {
-		// Potentially roll back file save or log, then return error
-		os.Remove(filePath) // Attempt to clean up
+	if err := s.repo.UpdateReceiptPath(ctx, transactionID, receiptKey, receiptName, change); err != nil {
+		s.removeReceipt(ctx, receiptKey) // Attempt to clean up
 		return nil, fmt.Errorf("failed to update transaction with receipt path: %w", err)
 	}
 
-	transaction.ReceiptPath = &relativeFilePath // Update the model in memory
+	// A replaced receipt lives under a different key now, so it has to be removed explicitly
+	if transaction.ReceiptPath != nil && *transaction.ReceiptPath != "" && *transaction.ReceiptPath != receiptKey {
+		s.removeReceipt(ctx, *transaction.ReceiptPath)
+	}
+
+	transaction.ReceiptPath = &receiptKey // Update the model in memory
+	transaction.ReceiptName = &receiptName
+	return transaction, nil
+}
+
+// DeleteReceipt detaches a transaction's receipt and removes the stored file. Like uploads,
+// only the author may do this.
+func (s *transactionService) DeleteReceipt(ctx context.Context, transactionID int64, userID int) (*model.Transaction, error) {
+	ctx, span := tracing.Start(ctx, "TransactionService.DeleteReceipt")
+	defer span.End()
+
+	transaction, err := s.repo.FindByID(ctx, transactionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find transaction for receipt deletion: %w", err)
+	}
+	if transaction == nil {
+		return nil, ErrTransactionNotFound
+	}
+	if transaction.UserID != userID {
+		return nil, ErrForbidden
+	}
+	if transaction.ReceiptPath == nil || *transaction.ReceiptPath == "" {
+		return nil, ErrReceiptNotFound
+	}
+
+	change, err := newTransactionChange(transaction, model.TransactionChangeReceiptDelete, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.ClearReceiptPath(ctx, transactionID, change); err != nil {
+		return nil, fmt.Errorf("failed to clear receipt path: %w", err)
+	}
+	// The receipt is already detached, so a leftover file is only logged
+	s.removeReceipt(ctx, *transaction.ReceiptPath)
+
+	transaction.ReceiptPath = nil
+	transaction.ReceiptName = nil
 	return transaction, nil
 }
 
-func (s *transactionService) GetReceiptPath(ctx context.Context, transactionID int64, userID int, userRole string) (string, string, error) {
+// receiptDisplayName reduces a client-supplied filename to its last path element, accepting
+// both slash styles, and falls back to a generic name when nothing usable is left
+func receiptDisplayName(fileName, ext string) string {
+	name := strings.TrimSpace(path.Base(strings.ReplaceAll(fileName, "\\", "/")))
+	if name == "" || name == "." || name == "/" || name == ".." {
+		return "receipt" + ext
+	}
+	return name
+}
+
+// removeReceipt deletes a stored receipt and its thumbnail, logging failures
+func (s *transactionService) removeReceipt(ctx context.Context, receiptKey string) {
+	removeReceiptFiles(ctx, s.storage, receiptKey)
+}
+
+// removeReceiptFiles deletes a receipt and, for images, its thumbnail from store, logging failures
+func removeReceiptFiles(ctx context.Context, store storage.ReceiptStorage, receiptKey string) {
+	if err := store.Delete(ctx, receiptKey); err != nil {
+		log.Printf("Error removing receipt %s: %v", receiptKey, err)
+	}
+	if isImageReceipt(receiptKey) {
+		if err := store.Delete(ctx, thumbnailKey(receiptKey)); err != nil {
+			log.Printf("Error removing receipt thumbnail %s: %v", receiptKey, err)
+		}
+	}
+}
+
+// saveThumbnail stores a thumbnail next to an image receipt. A failure only costs the
+// thumbnail, so it is logged rather than failing the upload.
+func (s *transactionService) saveThumbnail(ctx context.Context, receiptKey string, imageData []byte) {
+	thumb, err := makeThumbnail(imageData)
+	if err != nil {
+		log.Printf("Error generating thumbnail for %s: %v", receiptKey, err)
+		return
+	}
+	if _, err := s.storage.Save(ctx, thumbnailKey(receiptKey), bytes.NewReader(thumb)); err != nil {
+		log.Printf("Error saving thumbnail for %s: %v", receiptKey, err)
+	}
+}
+
+// findReceiptKey returns the storage key of a transaction's receipt and the filename to offer
+// for download, after checking access
+func (s *transactionService) findReceiptKey(ctx context.Context, transactionID int64, userID int, userRole string) (string, string, error) {
 	transaction, err := s.repo.FindByID(ctx, transactionID)
 	if err != nil {
 		return "", "", fmt.Errorf("failed to find transaction for receipt retrieval: %w", err)
@@ -240,35 +864,345 @@ func (s *transactionService) GetReceiptPath(ctx context.Context, transactionID i
 	}
 
 	if transaction.ReceiptPath == nil || *transaction.ReceiptPath == "" {
-		return "", "", fmt.Errorf("receipt not found for this transaction")
+		return "", "", ErrReceiptNotFound
+	}
+	receiptName := path.Base(*transaction.ReceiptPath) // Receipts stored before names were recorded used the original name as key
+	if transaction.ReceiptName != nil && *transaction.ReceiptName != "" {
+		receiptName = *transaction.ReceiptName
 	}
+	return *transaction.ReceiptPath, receiptName, nil
+}
 
-	fullPath := filepath.FromSlash(*transaction.ReceiptPath)
-	fileName := filepath.Base(fullPath)
+func (s *transactionService) GetReceipt(ctx context.Context, transactionID int64, userID int, userRole string) (io.ReadCloser, string, error) {
+	ctx, span := tracing.Start(ctx, "TransactionService.GetReceipt")
+	defer span.End()
 
-	return fullPath, fileName, nil
+	receiptKey, receiptName, err := s.findReceiptKey(ctx, transactionID, userID, userRole)
+	if err != nil {
+		return nil, "", err
+	}
+
+	contents, err := s.storage.Open(ctx, receiptKey)
+	if err != nil {
+		return nil, "", err
+	}
+	return contents, receiptName, nil
+}
+
+// GetReceiptThumbnail returns the thumbnail of an image receipt, or the original image
+// when no thumbnail was generated (e.g. for receipts uploaded before thumbnails existed)
+func (s *transactionService) GetReceiptThumbnail(ctx context.Context, transactionID int64, userID int, userRole string) (io.ReadCloser, string, error) {
+	ctx, span := tracing.Start(ctx, "TransactionService.GetReceiptThumbnail")
+	defer span.End()
+
+	receiptKey, receiptName, err := s.findReceiptKey(ctx, transactionID, userID, userRole)
+	if err != nil {
+		return nil, "", err
+	}
+	if !isImageReceipt(receiptKey) {
+		return nil, "", ErrNoThumbnail
+	}
+
+	thumbKey := thumbnailKey(receiptKey)
+	contents, err := s.storage.Open(ctx, thumbKey)
+	if err == nil {
+		return contents, thumbnailKey(receiptName), nil
+	}
+	if !errors.Is(err, storage.ErrNotFound) {
+		return nil, "", err
+	}
+
+	contents, err = s.storage.Open(ctx, receiptKey)
+	if err != nil {
+		return nil, "", err
+	}
+	return contents, receiptName, nil
+}
+
+func (s *transactionService) GetUserStatistics(ctx context.Context, userID int, filters model.UserTransactionFilters) (*model.UserStats, error) {
+	ctx, span := tracing.Start(ctx, "TransactionService.GetUserStatistics")
+	defer span.End()
+
+	s.applyEndDateMode(&filters)
+	var err error
+	if filters.Currency, err = s.resolveCurrency(ctx, userID, filters.Currency); err != nil {
+		return nil, err
+	}
+
+	stats, err := s.repo.GetUserStats(ctx, userID, filters)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user stats from repo: %w", err)
+	}
+	return stats, nil
+}
+
+func (s *transactionService) GetUserOverview(ctx context.Context, userID int, filters model.UserTransactionFilters) (*model.TransactionOverview, error) {
+	ctx, span := tracing.Start(ctx, "TransactionService.GetUserOverview")
+	defer span.End()
+
+	s.applyEndDateMode(&filters)
+	var err error
+	if filters.Currency, err = s.resolveCurrency(ctx, userID, filters.Currency); err != nil {
+		return nil, err
+	}
+
+	overview, err := s.repo.GetUserOverview(ctx, userID, filters)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction overview from repo: %w", err)
+	}
+	return overview, nil
+}
+
+func (s *transactionService) GetCategoryRanking(ctx context.Context, userID int, filters model.UserTransactionFilters, limit int) ([]model.CategoryRank, error) {
+	ctx, span := tracing.Start(ctx, "TransactionService.GetCategoryRanking")
+	defer span.End()
+
+	s.applyEndDateMode(&filters)
+	var err error
+	if filters.Currency, err = s.resolveCurrency(ctx, userID, filters.Currency); err != nil {
+		return nil, err
+	}
+
+	ranking, err := s.repo.GetCategoryRanking(ctx, userID, filters, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get category ranking from repo: %w", err)
+	}
+	return ranking, nil
+}
+
+func (s *transactionService) GetTopMerchants(ctx context.Context, userID int, filters model.UserTransactionFilters, limit int) ([]model.MerchantSpend, error) {
+	ctx, span := tracing.Start(ctx, "TransactionService.GetTopMerchants")
+	defer span.End()
+
+	s.applyEndDateMode(&filters)
+	var err error
+	if filters.Currency, err = s.resolveCurrency(ctx, userID, filters.Currency); err != nil {
+		return nil, err
+	}
+
+	merchants, err := s.repo.GetTopMerchants(ctx, userID, filters, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top merchants from repo: %w", err)
+	}
+	return merchants, nil
+}
+
+// suggestionPatterns turns a description into ILIKE patterns, one per distinct keyword.
+// Keywords are letters and digits only, so they never contain LIKE wildcards.
+func suggestionPatterns(description string) []string {
+	seen := make(map[string]bool)
+	var patterns []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(description), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if utf8.RuneCountInString(word) < minSuggestionKeywordLength || seen[word] {
+			continue
+		}
+		seen[word] = true
+		patterns = append(patterns, "%"+word+"%")
+		if len(patterns) == maxSuggestionKeywords {
+			break
+		}
+	}
+	return patterns
+}
+
+func (s *transactionService) SuggestCategories(ctx context.Context, userID int, description string, limit int) ([]model.CategorySuggestion, error) {
+	ctx, span := tracing.Start(ctx, "TransactionService.SuggestCategories")
+	defer span.End()
+
+	patterns := suggestionPatterns(description)
+	if len(patterns) == 0 {
+		return []model.CategorySuggestion{}, nil
+	}
+	suggestions, err := s.repo.SuggestCategories(ctx, userID, patterns, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get category suggestions from repo: %w", err)
+	}
+	return suggestions, nil
+}
+
+// GetRecentCategories returns the user's most used categories, cached briefly per user
+func (s *transactionService) GetRecentCategories(ctx context.Context, userID int, limit int) ([]model.CategoryUsage, error) {
+	ctx, span := tracing.Start(ctx, "TransactionService.GetRecentCategories")
+	defer span.End()
+
+	now := time.Now()
+	categories, ok := s.recent.get(userID, now)
+	if !ok {
+		var err error
+		categories, err = s.repo.GetRecentCategories(ctx, userID, MaxRecentCategories)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get recent categories from repo: %w", err)
+		}
+		s.recent.set(userID, categories, now)
+	}
+	if limit > 0 && limit < len(categories) {
+		categories = categories[:limit]
+	}
+	return categories, nil
+}
+
+// ExportUserTransactionsPDF renders a printable statement of the user's transactions matching
+// filters, oldest first, titled with the filtered date range
+func (s *transactionService) ExportUserTransactionsPDF(ctx context.Context, userID int, filters model.UserTransactionFilters) (*bytes.Buffer, error) {
+	ctx, span := tracing.Start(ctx, "TransactionService.ExportUserTransactionsPDF")
+	defer span.End()
+
+	s.applyEndDateMode(&filters)
+	filters.Limit, filters.Cursor = 0, nil
+	filters.SortBy, filters.SortOrder = "transaction_date", "asc"
+
+	transactions, err := s.repo.FindByUser(ctx, userID, filters)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch transactions for PDF export: %w", err)
+	}
+	return renderStatementPDF(statementTitle(filters), transactions)
 }
 
 // --- Admin Methods ---
 
-func (s *transactionService) GetAllTransactionsAdmin(ctx context.Context, filters model.AdminTransactionFilters) ([]model.Transaction, error) {
-	transactions, err := s.repo.FindAll(ctx, filters)
+func (s *transactionService) GetAllTransactionsAdmin(ctx context.Context, filters model.AdminTransactionFilters) (*model.AdminTransactionPage, error) {
+	ctx, span := tracing.Start(ctx, "TransactionService.GetAllTransactionsAdmin")
+	defer span.End()
+
+	transactions, total, err := s.repo.FindAll(ctx, filters)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get all transactions for admin: %w", err)
 	}
-	return transactions, nil
+	if transactions == nil {
+		transactions = []model.Transaction{}
+	}
+	return &model.AdminTransactionPage{
+		Data:     transactions,
+		Page:     filters.Page,
+		PageSize: filters.PageSize,
+		Total:    total,
+	}, nil
 }
 
+// GetStatisticsAdmin returns the aggregated statistics, served from the stats cache while fresh.
+// Writes through this service clear the cache; others, like recurring rules materializing
+// transactions, show up once the TTL runs out.
 func (s *transactionService) GetStatisticsAdmin(ctx context.Context, filters model.AdminTransactionFilters) (*model.AggregatedStats, error) {
+	ctx, span := tracing.Start(ctx, "TransactionService.GetStatisticsAdmin")
+	defer span.End()
+
+	now := time.Now()
+	key, cacheable := statsCacheKey(filters)
+	if cacheable {
+		if stats, ok := s.stats.get(key, now); ok {
+			span.SetAttributes(attribute.Bool("cache_hit", true))
+			return stats, nil
+		}
+	}
+
 	stats, err := s.repo.GetAggregatedStats(ctx, filters)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get aggregated stats for admin: %w", err)
 	}
+	if cacheable {
+		s.stats.set(key, stats, now)
+	}
 	return stats, nil
 }
 
+// GetUserStatsAdmin returns one page of per-user totals, ranked by filters.SortBy in currency
+func (s *transactionService) GetUserStatsAdmin(ctx context.Context, filters model.AdminTransactionFilters, currency string) (*model.UserStatPage, error) {
+	ctx, span := tracing.Start(ctx, "TransactionService.GetUserStatsAdmin")
+	defer span.End()
+
+	userStats, total, err := s.repo.GetUserStatsAdmin(ctx, filters, currency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user stats for admin: %w", err)
+	}
+	return &model.UserStatPage{
+		Data:     userStats,
+		Page:     filters.Page,
+		PageSize: filters.PageSize,
+		Total:    total,
+	}, nil
+}
+
+func (s *transactionService) GetTopCategoriesAdmin(ctx context.Context, filters model.AdminTransactionFilters, currency string, limit int) ([]model.CategoryRank, error) {
+	ctx, span := tracing.Start(ctx, "TransactionService.GetTopCategoriesAdmin")
+	defer span.End()
+
+	ranking, err := s.repo.GetTopCategoriesAdmin(ctx, filters, currency, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top categories for admin: %w", err)
+	}
+	return ranking, nil
+}
+
+// GetReimbursableReportAdmin totals each user's expenses tagged model.ReimbursableTag, per currency
+func (s *transactionService) GetReimbursableReportAdmin(ctx context.Context, filters model.AdminTransactionFilters) ([]model.ReimbursableTotal, error) {
+	ctx, span := tracing.Start(ctx, "TransactionService.GetReimbursableReportAdmin")
+	defer span.End()
+
+	totals, err := s.repo.GetReimbursableTotals(ctx, filters)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reimbursable totals for admin: %w", err)
+	}
+	return totals, nil
+}
+
+// ExportReimbursableReportCSVAdmin writes the reimbursable report as CSV, one row per user and currency
+func (s *transactionService) ExportReimbursableReportCSVAdmin(ctx context.Context, filters model.AdminTransactionFilters) (*bytes.Buffer, error) {
+	ctx, span := tracing.Start(ctx, "TransactionService.ExportReimbursableReportCSVAdmin")
+	defer span.End()
+
+	totals, err := s.GetReimbursableReportAdmin(ctx, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	buffer := &bytes.Buffer{}
+	writer := csv.NewWriter(buffer)
+	if err := writer.Write([]string{"UserID", "Phone", "Currency", "TotalReimbursable", "TotalReimbursableMinor", "Count"}); err != nil {
+		return nil, fmt.Errorf("failed to write CSV header: %w", err)
+	}
+	for _, rt := range totals {
+		row := []string{
+			strconv.Itoa(rt.UserID),
+			rt.Phone,
+			rt.Currency,
+			s.csvAmount(rt.TotalReimbursable),
+			strconv.FormatInt(rt.TotalReimbursable, 10), // In the currency's minor unit
+			strconv.FormatInt(rt.Count, 10),
+		}
+		if err := writer.Write(row); err != nil {
+			return nil, fmt.Errorf("failed to write CSV row: %w", err)
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, fmt.Errorf("error flushing CSV writer: %w", err)
+	}
+	return buffer, nil
+}
+
+// csvAmount formats an amount in minor units for a CSV export, as configured by CSV_AMOUNT_DIVISOR
+func (s *transactionService) csvAmount(amount int64) string {
+	return formatAmount(amount, s.cfg.CSVAmountDivisor)
+}
+
+// csvDate formats a date for a CSV export, as configured by CSV_DATE_FORMAT
+func (s *transactionService) csvDate(t time.Time) string {
+	layout := s.cfg.CSVDateLayout
+	if layout == "" {
+		layout = config.DefaultCSVDateLayout
+	}
+	return t.Format(layout)
+}
+
 func (s *transactionService) ExportTransactionsCSVAdmin(ctx context.Context, filters model.AdminTransactionFilters) (*bytes.Buffer, error) {
-	transactions, err := s.repo.FindAll(ctx, filters) // Use FindAll which already supports AdminTransactionFilters
+	ctx, span := tracing.Start(ctx, "TransactionService.ExportTransactionsCSVAdmin")
+	defer span.End()
+
+	filters.PageSize = 0                                 // Export everything that matches
+	transactions, _, err := s.repo.FindAll(ctx, filters) // Use FindAll which already supports AdminTransactionFilters
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch transactions for CSV export: %w", err)
 	}
@@ -277,30 +1211,41 @@ func (s *transactionService) ExportTransactionsCSVAdmin(ctx context.Context, fil
 	writer := csv.NewWriter(buffer)
 
 	// Write header
-	header := []string{"ID", "UserID", "Amount", "Type", "Category", "Description", "TransactionDate", "CreatedAt", "ReceiptPath"}
+	header := []string{"ID", "UserID", "Amount", "AmountMinor", "Currency", "Type", "Category", "Merchant", "Description", "Tags", "TransactionDate", "CreatedAt", "ReceiptPath", "AdminNote"}
 	if err := writer.Write(header); err != nil {
 		return nil, fmt.Errorf("failed to write CSV header: %w", err)
 	}
 
 	// Write rows
 	for _, t := range transactions {
-		var desc, receiptPath string
+		var merchant, desc, receiptPath, adminNote string
+		if t.Merchant != nil {
+			merchant = *t.Merchant
+		}
 		if t.Description != nil {
 			desc = *t.Description
 		}
 		if t.ReceiptPath != nil {
 			receiptPath = *t.ReceiptPath
 		}
+		if t.AdminNote != nil {
+			adminNote = *t.AdminNote
+		}
 		row := []string{
 			strconv.FormatInt(t.ID, 10),
 			strconv.Itoa(t.UserID),
-			strconv.FormatInt(t.Amount, 10), // Amount in cents
+			s.csvAmount(t.Amount),
+			strconv.FormatInt(t.Amount, 10), // Raw amount in the currency's minor unit
+			t.Currency,
 			t.Type,
 			t.Category,
+			merchant,
 			desc,
-			t.TransactionDate.Format(time.RFC3339),
-			t.CreatedAt.Format(time.RFC3339),
+			strings.Join(t.Tags, ","), // Tags never contain commas
+			s.csvDate(t.TransactionDate),
+			s.csvDate(t.CreatedAt),
 			receiptPath,
+			adminNote,
 		}
 		if err := writer.Write(row); err != nil {
 			return nil, fmt.Errorf("failed to write CSV row: %w", err)
@@ -314,3 +1259,24 @@ func (s *transactionService) ExportTransactionsCSVAdmin(ctx context.Context, fil
 
 	return buffer, nil
 }
+
+// ExportTransactionsJSONAdmin returns every transaction matching the filters as a pretty-printed JSON array
+func (s *transactionService) ExportTransactionsJSONAdmin(ctx context.Context, filters model.AdminTransactionFilters) (*bytes.Buffer, error) {
+	ctx, span := tracing.Start(ctx, "TransactionService.ExportTransactionsJSONAdmin")
+	defer span.End()
+
+	filters.PageSize = 0 // Export everything that matches
+	transactions, _, err := s.repo.FindAll(ctx, filters)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch transactions for JSON export: %w", err)
+	}
+	if transactions == nil {
+		transactions = []model.Transaction{} // Export an empty array rather than null
+	}
+
+	data, err := json.MarshalIndent(transactions, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode transactions as JSON: %w", err)
+	}
+	return bytes.NewBuffer(data), nil
+}
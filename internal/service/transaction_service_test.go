@@ -0,0 +1,1379 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"expense_tracker/internal/config"
+	"expense_tracker/internal/model"
+	"expense_tracker/internal/repository"
+	"expense_tracker/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeTransactionRepo keeps transactions in memory; unimplemented methods panic via the nil embedded interface
+type fakeTransactionRepo struct {
+	repository.TransactionRepository
+	transactions map[int64]*model.Transaction
+	nextID       int64
+	lastFilters  model.UserTransactionFilters  // Filters seen by the last FindByUser call
+	balances     map[int]int64                 // Account balances moved by transfers, by account ID
+	idempotency  map[string]fakeIdempotencyKey // By user ID and key
+	recentCalls  int                           // GetRecentCategories calls, to observe caching
+	history      []model.TransactionChange
+}
+
+type fakeIdempotencyKey struct {
+	transactionID int64
+	createdAt     time.Time
+}
+
+func idempotencyMapKey(userID int, key string) string {
+	return fmt.Sprintf("%d:%s", userID, key)
+}
+
+func newFakeTransactionRepo() *fakeTransactionRepo {
+	return &fakeTransactionRepo{transactions: make(map[int64]*model.Transaction)}
+}
+
+func (r *fakeTransactionRepo) Create(ctx context.Context, t *model.Transaction) error {
+	r.nextID++
+	t.ID = r.nextID
+	t.Version = 1
+	stored := *t
+	r.transactions[t.ID] = &stored
+	return nil
+}
+
+func (r *fakeTransactionRepo) CreateBatch(ctx context.Context, transactions []model.Transaction) error {
+	for i := range transactions {
+		if err := r.Create(ctx, &transactions[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *fakeTransactionRepo) CreateTransfer(ctx context.Context, t *model.Transaction) error {
+	_, fromOK := r.balances[*t.FromAccountID]
+	_, toOK := r.balances[*t.ToAccountID]
+	if !fromOK || !toOK {
+		return repository.ErrAccountNotFound
+	}
+	r.balances[*t.FromAccountID] -= t.Amount
+	r.balances[*t.ToAccountID] += t.Amount
+	return r.Create(ctx, t)
+}
+
+func (r *fakeTransactionRepo) DeleteTransfer(ctx context.Context, t *model.Transaction, change *model.TransactionChange) error {
+	r.balances[*t.FromAccountID] += t.Amount
+	r.balances[*t.ToAccountID] -= t.Amount
+	return r.Delete(ctx, t.ID, change)
+}
+
+func (r *fakeTransactionRepo) DeleteMany(ctx context.Context, ids []int64, ownerID *int, newChange func(*model.Transaction) (*model.TransactionChange, error)) ([]model.Transaction, error) {
+	var deleted []model.Transaction
+	for _, id := range ids {
+		t, ok := r.transactions[id]
+		if !ok || (ownerID != nil && t.UserID != *ownerID) {
+			continue
+		}
+		change, err := newChange(t)
+		if err != nil {
+			return nil, err
+		}
+		delete(r.transactions, id)
+		r.recordChange(change)
+		deleted = append(deleted, *t)
+	}
+	return deleted, nil
+}
+
+func (r *fakeTransactionRepo) SetAdminNote(ctx context.Context, id int64, note *string) (bool, error) {
+	t, ok := r.transactions[id]
+	if ok {
+		t.AdminNote = note
+	}
+	return ok, nil
+}
+
+func (r *fakeTransactionRepo) recordChange(change *model.TransactionChange) {
+	if change != nil {
+		change.ID = int64(len(r.history) + 1)
+		change.ChangedAt = time.Now()
+		r.history = append(r.history, *change)
+	}
+}
+
+func (r *fakeTransactionRepo) FindHistory(ctx context.Context, transactionID int64) ([]model.TransactionChange, error) {
+	history := []model.TransactionChange{}
+	for _, change := range r.history {
+		if change.TransactionID == transactionID {
+			history = append(history, change)
+		}
+	}
+	return history, nil
+}
+
+func (r *fakeTransactionRepo) CreateIdempotent(ctx context.Context, t *model.Transaction, key string, expiredBefore time.Time) error {
+	if r.idempotency == nil {
+		r.idempotency = make(map[string]fakeIdempotencyKey)
+	}
+	mapKey := idempotencyMapKey(t.UserID, key)
+	if existing, ok := r.idempotency[mapKey]; ok && !existing.createdAt.Before(expiredBefore) {
+		return repository.ErrDuplicateIdempotencyKey
+	}
+	if err := r.Create(ctx, t); err != nil {
+		return err
+	}
+	r.idempotency[mapKey] = fakeIdempotencyKey{transactionID: t.ID, createdAt: time.Now()}
+	return nil
+}
+
+func (r *fakeTransactionRepo) FindByIdempotencyKey(ctx context.Context, userID int, key string, expiredBefore time.Time) (*model.Transaction, error) {
+	existing, ok := r.idempotency[idempotencyMapKey(userID, key)]
+	if !ok || existing.createdAt.Before(expiredBefore) {
+		return nil, nil
+	}
+	return r.FindByID(ctx, existing.transactionID)
+}
+
+func (r *fakeTransactionRepo) FindByID(ctx context.Context, id int64) (*model.Transaction, error) {
+	t, ok := r.transactions[id]
+	if !ok {
+		return nil, nil
+	}
+	found := *t
+	return &found, nil
+}
+
+func (r *fakeTransactionRepo) FindByUser(ctx context.Context, userID int, filters model.UserTransactionFilters) ([]model.Transaction, error) {
+	r.lastFilters = filters
+	var result []model.Transaction
+	for _, t := range r.transactions {
+		if t.UserID == userID {
+			result = append(result, *t)
+		}
+	}
+	// Newest first by (transaction_date, id), like the paginated query
+	after := func(a, b model.Transaction) bool {
+		if !a.TransactionDate.Equal(b.TransactionDate) {
+			return a.TransactionDate.After(b.TransactionDate)
+		}
+		return a.ID > b.ID
+	}
+	sort.Slice(result, func(i, j int) bool { return after(result[i], result[j]) })
+	if filters.Cursor != nil {
+		cursor := model.Transaction{ID: filters.Cursor.ID, TransactionDate: filters.Cursor.TransactionDate}
+		result = slices.DeleteFunc(result, func(t model.Transaction) bool { return !after(cursor, t) })
+	}
+	if filters.Limit > 0 && len(result) > filters.Limit {
+		result = result[:filters.Limit]
+	}
+	return result, nil
+}
+
+func (r *fakeTransactionRepo) FindAll(ctx context.Context, filters model.AdminTransactionFilters) ([]model.Transaction, int64, error) {
+	var result []model.Transaction
+	for id := int64(1); id <= r.nextID; id++ {
+		t, ok := r.transactions[id]
+		if !ok || (filters.UserID != nil && t.UserID != *filters.UserID) {
+			continue
+		}
+		result = append(result, *t)
+	}
+	return result, int64(len(result)), nil
+}
+
+func (r *fakeTransactionRepo) SummarizeByUser(ctx context.Context, userID int, filters model.UserTransactionFilters) (*model.TransactionSummary, error) {
+	summary := &model.TransactionSummary{TotalIncome: make(map[string]int64), TotalExpense: make(map[string]int64)}
+	for _, t := range r.transactions {
+		if t.UserID != userID || t.Type == model.TransactionTypeTransfer {
+			continue
+		}
+		summary.Count++
+		if t.Type == model.TransactionTypeIncome {
+			summary.TotalIncome[t.Currency] += t.Amount
+		} else {
+			summary.TotalExpense[t.Currency] += t.Amount
+		}
+	}
+	return summary, nil
+}
+
+func (r *fakeTransactionRepo) SumExpenses(ctx context.Context, userID int, currency string, from, to time.Time) (int64, error) {
+	var total int64
+	for _, t := range r.transactions {
+		if t.UserID == userID && t.Type == model.TransactionTypeExpense && t.Currency == currency &&
+			!t.TransactionDate.Before(from) && t.TransactionDate.Before(to) {
+			total += t.Amount
+		}
+	}
+	return total, nil
+}
+
+func (r *fakeTransactionRepo) GetRecentCategories(ctx context.Context, userID int, limit int) ([]model.CategoryUsage, error) {
+	r.recentCalls++
+	counts := make(map[string]int64)
+	for _, t := range r.transactions {
+		if t.UserID == userID && t.Type != model.TransactionTypeTransfer {
+			counts[t.Category]++
+		}
+	}
+	categories := []model.CategoryUsage{}
+	for category, count := range counts {
+		categories = append(categories, model.CategoryUsage{Category: category, Count: count})
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		if categories[i].Count != categories[j].Count {
+			return categories[i].Count > categories[j].Count
+		}
+		return categories[i].Category < categories[j].Category
+	})
+	if len(categories) > limit {
+		categories = categories[:limit]
+	}
+	return categories, nil
+}
+
+func (r *fakeTransactionRepo) Update(ctx context.Context, t *model.Transaction, change *model.TransactionChange) error {
+	if current, ok := r.transactions[t.ID]; !ok || current.Version != t.Version {
+		return repository.ErrVersionConflict
+	}
+	t.Version++
+	stored := *t
+	r.transactions[t.ID] = &stored
+	r.recordChange(change)
+	return nil
+}
+
+func (r *fakeTransactionRepo) ClearReceiptPath(ctx context.Context, transactionID int64, change *model.TransactionChange) error {
+	r.transactions[transactionID].ReceiptPath = nil
+	r.transactions[transactionID].ReceiptName = nil
+	r.recordChange(change)
+	return nil
+}
+
+func (r *fakeTransactionRepo) ListReceiptPaths(ctx context.Context) ([]string, error) {
+	paths := []string{}
+	for _, t := range r.transactions {
+		if t.ReceiptPath != nil {
+			paths = append(paths, *t.ReceiptPath)
+		}
+	}
+	return paths, nil
+}
+
+func (r *fakeTransactionRepo) UpdateReceiptPath(ctx context.Context, transactionID int64, receiptPath, receiptName string, change *model.TransactionChange) error {
+	r.transactions[transactionID].ReceiptPath = &receiptPath
+	r.transactions[transactionID].ReceiptName = &receiptName
+	r.recordChange(change)
+	return nil
+}
+
+func (r *fakeTransactionRepo) Delete(ctx context.Context, id int64, change *model.TransactionChange) error {
+	delete(r.transactions, id)
+	r.recordChange(change)
+	return nil
+}
+
+// newFileHeader builds a multipart file header as gin would hand it to UploadReceipt
+func newFileHeader(t *testing.T, filename string, content []byte) *multipart.FileHeader {
+	t.Helper()
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, err := w.CreateFormFile("receipt", filename)
+	assert.NoError(t, err)
+	_, err = part.Write(content)
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+
+	form, err := multipart.NewReader(&body, w.Boundary()).ReadForm(1 << 20)
+	assert.NoError(t, err)
+	return form.File["receipt"][0]
+}
+
+var (
+	pngBytes = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	pdfBytes = []byte("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+)
+
+func newTestTransactionService(repo repository.TransactionRepository, cfg *config.TransactionConfig) *transactionService {
+	if cfg == nil {
+		cfg = &config.TransactionConfig{
+			MinAmount:                1,
+			EndDateMode:              config.EndDateInclusive,
+			MaxReceiptSize:           config.DefaultMaxReceiptSizeMB * 1024 * 1024,
+			AllowedReceiptExtensions: config.DefaultReceiptExtensions,
+			IdempotencyKeyTTL:        config.DefaultIdempotencyKeyTTLHours * time.Hour,
+			MaxFutureDate:            config.DefaultMaxFutureDateHours * time.Hour,
+			MaxDescriptionLength:     config.MaxDescriptionLength,
+			CSVAmountDivisor:         config.DefaultCSVAmountDivisor,
+			CSVDateLayout:            config.DefaultCSVDateLayout,
+		}
+	}
+	return NewTransactionService(repo, &fakeUserRepo{}, newFakeCategoryRepo(), nil, cfg, nil).(*transactionService)
+}
+
+// withLocalStorage points the service at a LocalStorage in a temp dir and returns that dir
+func withLocalStorage(t *testing.T, svc *transactionService) string {
+	t.Helper()
+	uploadsDir := t.TempDir()
+	local, err := storage.NewLocalStorage(uploadsDir)
+	assert.NoError(t, err)
+	svc.storage = local
+	return uploadsDir
+}
+
+func TestCreateTransaction_BelowMinimumAmount(t *testing.T) {
+	svc := newTestTransactionService(newFakeTransactionRepo(), &config.TransactionConfig{MinAmount: 100})
+
+	_, err := svc.CreateTransaction(context.Background(), 1, model.CreateTransactionRequest{
+		Amount: 99, Type: model.TransactionTypeExpense, Category: "food",
+	})
+	assert.ErrorIs(t, err, ErrAmountBelowMinimum)
+
+	tx, err := svc.CreateTransaction(context.Background(), 1, model.CreateTransactionRequest{
+		Amount: 100, Type: model.TransactionTypeExpense, Category: "food",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100), tx.Amount)
+}
+
+func TestUpdateTransaction_BelowMinimumAmount(t *testing.T) {
+	repo := newFakeTransactionRepo()
+	svc := newTestTransactionService(repo, &config.TransactionConfig{MinAmount: 100})
+	tx, err := svc.CreateTransaction(context.Background(), 1, model.CreateTransactionRequest{
+		Amount: 500, Type: model.TransactionTypeExpense, Category: "food",
+	})
+	assert.NoError(t, err)
+
+	tooSmall := int64(50)
+	_, err = svc.UpdateTransaction(context.Background(), tx.ID, 1, model.UpdateTransactionRequest{Amount: &tooSmall})
+	assert.ErrorIs(t, err, ErrAmountBelowMinimum)
+	assert.Equal(t, int64(500), repo.transactions[tx.ID].Amount)
+}
+
+func TestUpdateTransaction_InvalidAmount(t *testing.T) {
+	repo := newFakeTransactionRepo()
+	svc := newTestTransactionService(repo, nil)
+	tx, err := svc.CreateTransaction(context.Background(), 1, model.CreateTransactionRequest{
+		Amount: 500, Type: model.TransactionTypeExpense, Category: "food",
+	})
+	assert.NoError(t, err)
+
+	for _, amount := range []int64{-100, 0, config.MaxTransactionAmount + 1} {
+		_, err = svc.UpdateTransaction(context.Background(), tx.ID, 1, model.UpdateTransactionRequest{Amount: &amount})
+		assert.ErrorIs(t, err, ErrInvalidAmount, amount)
+	}
+	assert.Equal(t, int64(500), repo.transactions[tx.ID].Amount)
+
+	_, err = svc.CreateTransaction(context.Background(), 1, model.CreateTransactionRequest{
+		Amount: 9e18, Type: model.TransactionTypeExpense, Category: "food",
+	})
+	assert.ErrorIs(t, err, ErrInvalidAmount)
+}
+
+func TestUpdateTransaction_NonPositiveAmountWithoutMinimum(t *testing.T) {
+	// The positivity guard doesn't depend on MIN_TRANSACTION_AMOUNT being set
+	repo := newFakeTransactionRepo()
+	repo.transactions[1] = &model.Transaction{ID: 1, UserID: 1, Amount: 500, Type: model.TransactionTypeExpense}
+	svc := newTestTransactionService(repo, &config.TransactionConfig{})
+
+	for _, amount := range []int64{0, -100} {
+		_, err := svc.UpdateTransaction(context.Background(), 1, 1, model.UpdateTransactionRequest{Amount: &amount})
+		assert.ErrorIs(t, err, ErrInvalidAmount, amount)
+	}
+	assert.Equal(t, int64(500), repo.transactions[1].Amount)
+}
+
+func TestCreateTransaction_CategoryType(t *testing.T) {
+	svc := newTestTransactionService(newFakeTransactionRepo(), nil)
+
+	_, err := svc.CreateTransaction(context.Background(), 1, model.CreateTransactionRequest{
+		Amount: 500, Type: model.TransactionTypeExpense, Category: "Salary",
+	})
+	assert.ErrorIs(t, err, ErrCategoryTypeMismatch)
+
+	tx, err := svc.CreateTransaction(context.Background(), 1, model.CreateTransactionRequest{
+		Amount: 500, Type: model.TransactionTypeIncome, Category: "salary",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "Salary", tx.Category)
+
+	// "Other" exists for both types
+	_, err = svc.CreateTransaction(context.Background(), 1, model.CreateTransactionRequest{
+		Amount: 500, Type: model.TransactionTypeIncome, Category: "Other",
+	})
+	assert.NoError(t, err)
+}
+
+func TestValidateTransaction(t *testing.T) {
+	repo := newFakeTransactionRepo()
+	svc := newTestTransactionService(repo, nil)
+	ctx := context.Background()
+
+	assert.NoError(t, svc.ValidateTransaction(ctx, 1, model.CreateTransactionRequest{
+		Amount: 500, Type: model.TransactionTypeExpense, Category: "food",
+	}))
+	assert.Empty(t, repo.transactions) // Nothing saved
+
+	future := time.Now().Add(72 * time.Hour)
+	for _, tt := range []struct {
+		req model.CreateTransactionRequest
+		err error
+	}{
+		{model.CreateTransactionRequest{Amount: 500, Type: model.TransactionTypeExpense, Category: "Yachts"}, ErrUnknownCategory},
+		{model.CreateTransactionRequest{Amount: 500, Type: model.TransactionTypeExpense, Category: "Salary"}, ErrCategoryTypeMismatch},
+		{model.CreateTransactionRequest{Amount: -1, Type: model.TransactionTypeExpense, Category: "Food"}, ErrInvalidAmount},
+		{model.CreateTransactionRequest{Amount: 500, Type: model.TransactionTypeExpense, Category: "Food", TransactionDate: future}, ErrFutureTransactionDate},
+		{model.CreateTransactionRequest{Amount: 500, Type: model.TransactionTypeTransfer}, ErrInvalidTransfer},
+	} {
+		assert.ErrorIs(t, svc.ValidateTransaction(ctx, 1, tt.req), tt.err)
+	}
+}
+
+func TestUpdateTransaction_CategoryType(t *testing.T) {
+	repo := newFakeTransactionRepo()
+	svc := newTestTransactionService(repo, nil)
+	tx, err := svc.CreateTransaction(context.Background(), 1, model.CreateTransactionRequest{
+		Amount: 500, Type: model.TransactionTypeExpense, Category: "Food",
+	})
+	assert.NoError(t, err)
+
+	// Switching the type alone would leave an income filed under an expense category
+	income := model.TransactionTypeIncome
+	_, err = svc.UpdateTransaction(context.Background(), tx.ID, 1, model.UpdateTransactionRequest{Type: &income})
+	assert.ErrorIs(t, err, ErrCategoryTypeMismatch)
+	assert.Equal(t, model.TransactionTypeExpense, repo.transactions[tx.ID].Type)
+
+	salary := "Salary"
+	updated, err := svc.UpdateTransaction(context.Background(), tx.ID, 1, model.UpdateTransactionRequest{Type: &income, Category: &salary})
+	assert.NoError(t, err)
+	assert.Equal(t, "Salary", updated.Category)
+
+	food := "Food"
+	_, err = svc.UpdateTransaction(context.Background(), tx.ID, 1, model.UpdateTransactionRequest{Category: &food})
+	assert.ErrorIs(t, err, ErrCategoryTypeMismatch)
+}
+
+func TestUpdateTransaction_LegacyFreeTextCategoryUnchecked(t *testing.T) {
+	repo := newFakeTransactionRepo()
+	svc := newTestTransactionService(repo, nil)
+	legacy := &model.Transaction{UserID: 1, Amount: 500, Type: model.TransactionTypeExpense, Category: "groceries and stuff"}
+	assert.NoError(t, repo.Create(context.Background(), legacy))
+
+	income := model.TransactionTypeIncome
+	updated, err := svc.UpdateTransaction(context.Background(), legacy.ID, 1, model.UpdateTransactionRequest{Type: &income})
+	assert.NoError(t, err)
+	assert.Equal(t, "groceries and stuff", updated.Category)
+}
+
+func TestTransfer(t *testing.T) {
+	repo := newFakeTransactionRepo()
+	repo.balances = map[int]int64{1: 100000, 2: 0}
+	svc := newTestTransactionService(repo, nil)
+	ctx := context.Background()
+	cash, bank := 1, 2
+
+	transfer, err := svc.CreateTransaction(ctx, 1, model.CreateTransactionRequest{
+		Amount: 40000, Type: model.TransactionTypeTransfer, FromAccountID: &cash, ToAccountID: &bank,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, model.TransferCategory, transfer.Category)
+	assert.Equal(t, int64(60000), repo.balances[cash])
+	assert.Equal(t, int64(40000), repo.balances[bank])
+
+	amount := int64(1)
+	_, err = svc.UpdateTransaction(ctx, transfer.ID, 1, model.UpdateTransactionRequest{Amount: &amount})
+	assert.ErrorIs(t, err, ErrTransferNotEditable)
+
+	assert.NoError(t, svc.DeleteTransaction(ctx, transfer.ID, 1, model.RoleUser))
+	assert.Equal(t, int64(100000), repo.balances[cash])
+	assert.Equal(t, int64(0), repo.balances[bank])
+}
+
+func TestTransfer_InvalidAccounts(t *testing.T) {
+	repo := newFakeTransactionRepo()
+	repo.balances = map[int]int64{1: 0}
+	svc := newTestTransactionService(repo, nil)
+	ctx := context.Background()
+	cash, unknown := 1, 99
+
+	_, err := svc.CreateTransaction(ctx, 1, model.CreateTransactionRequest{Amount: 500, Type: model.TransactionTypeTransfer, FromAccountID: &cash})
+	assert.ErrorIs(t, err, ErrInvalidTransfer)
+
+	_, err = svc.CreateTransaction(ctx, 1, model.CreateTransactionRequest{
+		Amount: 500, Type: model.TransactionTypeTransfer, FromAccountID: &cash, ToAccountID: &cash,
+	})
+	assert.ErrorIs(t, err, ErrInvalidTransfer)
+
+	_, err = svc.CreateTransaction(ctx, 1, model.CreateTransactionRequest{
+		Amount: 500, Type: model.TransactionTypeTransfer, FromAccountID: &cash, ToAccountID: &unknown,
+	})
+	assert.ErrorIs(t, err, ErrAccountNotFound)
+	assert.Empty(t, repo.transactions)
+}
+
+func TestCreateTransaction_IdempotencyKey(t *testing.T) {
+	repo := newFakeTransactionRepo()
+	svc := newTestTransactionService(repo, nil)
+	ctx := context.Background()
+	req := model.CreateTransactionRequest{
+		Amount: 500, Type: model.TransactionTypeExpense, Category: "Food", IdempotencyKey: "retry-123",
+	}
+
+	first, err := svc.CreateTransaction(ctx, 1, req)
+	assert.NoError(t, err)
+	second, err := svc.CreateTransaction(ctx, 1, req)
+	assert.NoError(t, err)
+	assert.Equal(t, first.ID, second.ID)
+	assert.Len(t, repo.transactions, 1)
+
+	// A replay returns the original even if the request would no longer pass validation
+	svc.cfg.MinAmount = 1000
+	third, err := svc.CreateTransaction(ctx, 1, req)
+	assert.NoError(t, err)
+	assert.Equal(t, first.ID, third.ID)
+	svc.cfg.MinAmount = 1
+
+	// Keys are scoped per user
+	other, err := svc.CreateTransaction(ctx, 2, req)
+	assert.NoError(t, err)
+	assert.NotEqual(t, first.ID, other.ID)
+	assert.Len(t, repo.transactions, 2)
+
+	// Without a key every request creates a transaction
+	req.IdempotencyKey = ""
+	_, err = svc.CreateTransaction(ctx, 1, req)
+	assert.NoError(t, err)
+	assert.Len(t, repo.transactions, 3)
+}
+
+// recordingNotifier collects the transactions it is told about
+type recordingNotifier struct {
+	created []model.Transaction
+}
+
+func (n *recordingNotifier) TransactionCreated(t model.Transaction) {
+	n.created = append(n.created, t)
+}
+
+func TestCreateTransaction_NotifiesOnce(t *testing.T) {
+	notifier := &recordingNotifier{}
+	svc := newTestTransactionService(newFakeTransactionRepo(), nil)
+	svc.notifier = notifier
+	ctx := context.Background()
+	req := model.CreateTransactionRequest{
+		Amount: 500, Type: model.TransactionTypeExpense, Category: "Food", IdempotencyKey: "retry-123",
+	}
+
+	created, err := svc.CreateTransaction(ctx, 1, req)
+	assert.NoError(t, err)
+	_, err = svc.CreateTransaction(ctx, 1, req) // Replayed, nothing new to announce
+	assert.NoError(t, err)
+	_, err = svc.CreateTransaction(ctx, 1, model.CreateTransactionRequest{Amount: 0, Type: model.TransactionTypeExpense, Category: "Food"})
+	assert.Error(t, err)
+
+	if assert.Len(t, notifier.created, 1) {
+		assert.Equal(t, created.ID, notifier.created[0].ID)
+		assert.Equal(t, int64(500), notifier.created[0].Amount)
+	}
+}
+
+func TestCreateTransaction_ExpiredIdempotencyKey(t *testing.T) {
+	repo := newFakeTransactionRepo()
+	svc := newTestTransactionService(repo, nil)
+	ctx := context.Background()
+	req := model.CreateTransactionRequest{
+		Amount: 500, Type: model.TransactionTypeExpense, Category: "Food", IdempotencyKey: "retry-123",
+	}
+
+	first, err := svc.CreateTransaction(ctx, 1, req)
+	assert.NoError(t, err)
+	repo.idempotency[idempotencyMapKey(1, "retry-123")] = fakeIdempotencyKey{
+		transactionID: first.ID,
+		createdAt:     time.Now().Add(-25 * time.Hour),
+	}
+
+	second, err := svc.CreateTransaction(ctx, 1, req)
+	assert.NoError(t, err)
+	assert.NotEqual(t, first.ID, second.ID)
+}
+
+func TestUpdateTransaction_VersionConflict(t *testing.T) {
+	repo := newFakeTransactionRepo()
+	svc := newTestTransactionService(repo, nil)
+	tx, err := svc.CreateTransaction(context.Background(), 1, model.CreateTransactionRequest{
+		Amount: 500, Type: model.TransactionTypeExpense, Category: "food",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, tx.Version)
+
+	// First client saw version 1 and wins
+	seen, first := 1, int64(700)
+	updated, err := svc.UpdateTransaction(context.Background(), tx.ID, 1, model.UpdateTransactionRequest{Amount: &first, Version: &seen})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, updated.Version)
+
+	// Second client still holds version 1 and must not overwrite the first change
+	second := int64(900)
+	_, err = svc.UpdateTransaction(context.Background(), tx.ID, 1, model.UpdateTransactionRequest{Amount: &second, Version: &seen})
+	assert.ErrorIs(t, err, ErrVersionConflict)
+	assert.Equal(t, int64(700), repo.transactions[tx.ID].Amount)
+}
+
+func TestGetUserTransactions_EndDateModes(t *testing.T) {
+	midnight := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+	afternoon := time.Date(2024, 3, 10, 14, 30, 0, 0, time.UTC)
+	endOfDay := time.Date(2024, 3, 10, 23, 59, 59, 999999999, time.UTC)
+
+	tests := []struct {
+		name          string
+		mode          string
+		end           time.Time
+		wantEnd       time.Time
+		wantExclusive bool
+	}{
+		{"inclusive midnight covers the day", config.EndDateInclusive, midnight, endOfDay, false},
+		{"inclusive timestamp covers the day", config.EndDateInclusive, afternoon, endOfDay, false},
+		{"exclusive midnight is exact", config.EndDateExclusive, midnight, midnight, true},
+		{"exclusive timestamp is exact", config.EndDateExclusive, afternoon, afternoon, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeTransactionRepo()
+			svc := newTestTransactionService(repo, &config.TransactionConfig{MinAmount: 1, EndDateMode: tt.mode})
+
+			end := tt.end
+			_, err := svc.GetUserTransactions(context.Background(), 1, model.UserTransactionFilters{EndDate: &end})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantEnd, *repo.lastFilters.EndDate)
+			assert.Equal(t, tt.wantExclusive, repo.lastFilters.EndDateExclusive)
+		})
+	}
+}
+
+func TestGetUserTransactions_InclusiveEndIsUTCDayWhateverServerZone(t *testing.T) {
+	defer func(loc *time.Location) { time.Local = loc }(time.Local)
+	time.Local = time.FixedZone("UTC+5", 5*60*60)
+
+	repo := newFakeTransactionRepo()
+	svc := newTestTransactionService(repo, nil) // inclusive mode
+
+	// 02:00 on Mar 11 in UTC+5 is still Mar 10 in UTC
+	end := time.Date(2024, 3, 11, 2, 0, 0, 0, time.Local)
+	_, err := svc.GetUserTransactions(context.Background(), 1, model.UserTransactionFilters{EndDate: &end})
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 3, 10, 23, 59, 59, 999999999, time.UTC), *repo.lastFilters.EndDate)
+}
+
+func TestGetUserTransactions_StartDateOnlyIsOpenEnded(t *testing.T) {
+	for _, mode := range []string{config.EndDateInclusive, config.EndDateExclusive} {
+		repo := newFakeTransactionRepo()
+		svc := newTestTransactionService(repo, &config.TransactionConfig{MinAmount: 1, EndDateMode: mode})
+
+		start := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+		_, err := svc.GetUserTransactions(context.Background(), 1, model.UserTransactionFilters{StartDate: &start})
+		assert.NoError(t, err)
+		assert.Equal(t, start, *repo.lastFilters.StartDate)
+		assert.Nil(t, repo.lastFilters.EndDate, "a midnight start date must not imply a single-day range")
+	}
+}
+
+func TestGetUserTransactions_ExactRangeUntouched(t *testing.T) {
+	repo := newFakeTransactionRepo()
+	svc := newTestTransactionService(repo, nil) // inclusive mode
+
+	nextMidnight := time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC)
+	_, err := svc.GetUserTransactions(context.Background(), 1, model.UserTransactionFilters{
+		EndDate: &nextMidnight, EndDateExclusive: true,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, nextMidnight, *repo.lastFilters.EndDate)
+	assert.True(t, repo.lastFilters.EndDateExclusive)
+}
+
+func TestSuggestionPatterns(t *testing.T) {
+	assert.Equal(t, []string{"%coffee%", "%starbucks%"}, suggestionPatterns("Coffee at Starbucks, coffee!"))
+	assert.Equal(t, []string{"%100%", "%off%", "%sale%"}, suggestionPatterns("100% off_sale"))
+	assert.Empty(t, suggestionPatterns("a to"))
+}
+
+func TestCreateTransaction_FutureDate(t *testing.T) {
+	svc := newTestTransactionService(newFakeTransactionRepo(), nil)
+	create := func(date time.Time) error {
+		_, err := svc.CreateTransaction(context.Background(), 1, model.CreateTransactionRequest{
+			Amount: 100, Type: model.TransactionTypeExpense, Category: "Food", TransactionDate: date,
+		})
+		return err
+	}
+
+	assert.ErrorIs(t, create(time.Date(2099, 1, 1, 0, 0, 0, 0, time.UTC)), ErrFutureTransactionDate)
+	assert.NoError(t, create(time.Now().Add(3*time.Hour)))  // Within the 24h tolerance
+	assert.NoError(t, create(time.Now().AddDate(-2, 0, 0))) // Backdated entries are fine
+}
+
+func TestUpdateTransaction_FutureDate(t *testing.T) {
+	repo := newFakeTransactionRepo()
+	svc := newTestTransactionService(repo, nil)
+	tx, err := svc.CreateTransaction(context.Background(), 1, model.CreateTransactionRequest{
+		Amount: 100, Type: model.TransactionTypeExpense, Category: "Food",
+	})
+	assert.NoError(t, err)
+
+	farFuture := time.Now().AddDate(5, 0, 0)
+	_, err = svc.UpdateTransaction(context.Background(), tx.ID, 1, model.UpdateTransactionRequest{TransactionDate: &farFuture})
+	assert.ErrorIs(t, err, ErrFutureTransactionDate)
+
+	past := time.Now().AddDate(0, -1, 0)
+	updated, err := svc.UpdateTransaction(context.Background(), tx.ID, 1, model.UpdateTransactionRequest{TransactionDate: &past})
+	assert.NoError(t, err)
+	assert.True(t, past.Equal(updated.TransactionDate))
+}
+
+func TestTransactionDescription_TrimmedAndLimited(t *testing.T) {
+	repo := newFakeTransactionRepo()
+	svc := newTestTransactionService(repo, nil)
+	svc.cfg.MaxDescriptionLength = 10
+	ctx := context.Background()
+	create := func(description string) (*model.Transaction, error) {
+		return svc.CreateTransaction(ctx, 1, model.CreateTransactionRequest{
+			Amount: 100, Type: model.TransactionTypeExpense, Category: "Food", Description: &description,
+		})
+	}
+
+	tx, err := create("  lunch \n")
+	assert.NoError(t, err)
+	assert.Equal(t, "lunch", *tx.Description)
+
+	blank, err := create("   ")
+	assert.NoError(t, err)
+	assert.Nil(t, blank.Description)
+
+	_, err = create("ресторан!!!") // 11 characters, more bytes
+	assert.ErrorIs(t, err, ErrDescriptionTooLong)
+	_, err = create(" ресторан! ")
+	assert.NoError(t, err)
+
+	tooLong := strings.Repeat("x", 11)
+	_, err = svc.UpdateTransaction(ctx, tx.ID, 1, model.UpdateTransactionRequest{Description: &tooLong})
+	assert.ErrorIs(t, err, ErrDescriptionTooLong)
+
+	empty := " "
+	updated, err := svc.UpdateTransaction(ctx, tx.ID, 1, model.UpdateTransactionRequest{Description: &empty})
+	assert.NoError(t, err)
+	assert.Nil(t, updated.Description)
+}
+
+func TestGetRecentCategories(t *testing.T) {
+	repo := newFakeTransactionRepo()
+	svc := newTestTransactionService(repo, nil)
+	ctx := context.Background()
+	create := func(category string) {
+		_, err := svc.CreateTransaction(ctx, 1, model.CreateTransactionRequest{
+			Amount: 100, Type: model.TransactionTypeExpense, Category: category,
+		})
+		assert.NoError(t, err)
+	}
+	create("Food")
+	create("Food")
+	create("Other")
+
+	categories, err := svc.GetRecentCategories(ctx, 1, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []model.CategoryUsage{{Category: "Food", Count: 2}}, categories)
+
+	// Served from the cache, and a smaller limit doesn't shrink what is cached
+	categories, err = svc.GetRecentCategories(ctx, 1, 5)
+	assert.NoError(t, err)
+	assert.Len(t, categories, 2)
+	assert.Equal(t, 1, repo.recentCalls)
+
+	// Other users have their own entries
+	categories, err = svc.GetRecentCategories(ctx, 2, 5)
+	assert.NoError(t, err)
+	assert.Empty(t, categories)
+	assert.Equal(t, 2, repo.recentCalls)
+
+	// A new transaction invalidates the user's entry
+	create("Other")
+	create("Other")
+	categories, err = svc.GetRecentCategories(ctx, 1, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, "Other", categories[0].Category)
+	assert.Equal(t, 3, repo.recentCalls)
+}
+
+func TestRecentCategoriesCache_Expires(t *testing.T) {
+	cache := newRecentCategoriesCache(time.Minute)
+	now := time.Now()
+	cache.set(1, []model.CategoryUsage{{Category: "Food", Count: 1}}, now)
+
+	_, ok := cache.get(1, now.Add(59*time.Second))
+	assert.True(t, ok)
+	_, ok = cache.get(1, now.Add(time.Minute))
+	assert.False(t, ok)
+
+	// Writing another user's entry sweeps the expired one
+	cache.set(2, []model.CategoryUsage{{Category: "Rent", Count: 1}}, now.Add(time.Minute))
+	assert.NotContains(t, cache.entries, 1)
+	assert.Contains(t, cache.entries, 2)
+}
+
+func TestGetUserTransactions_CursorPagination(t *testing.T) {
+	repo := newFakeTransactionRepo()
+	svc := newTestTransactionService(repo, nil)
+	// Created in id order but backdated out of it; pages follow transaction_date, and the id
+	// breaks the tie between the two transactions on the same day
+	base := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	for _, daysAgo := range []int{3, 0, 4, 1, 1} {
+		date := base.AddDate(0, 0, -daysAgo)
+		_, err := svc.CreateTransaction(context.Background(), 1, model.CreateTransactionRequest{
+			Amount: 100, Type: model.TransactionTypeExpense, Category: "food", TransactionDate: date,
+		})
+		assert.NoError(t, err)
+	}
+
+	ids := func(page *model.TransactionPage) []int64 {
+		var result []int64
+		for _, tx := range page.Data {
+			result = append(result, tx.ID)
+		}
+		return result
+	}
+	next := func(page *model.TransactionPage) *model.TransactionCursor {
+		cursor, err := model.ParseTransactionCursor(*page.NextCursor)
+		assert.NoError(t, err)
+		return cursor
+	}
+
+	page, err := svc.GetUserTransactions(context.Background(), 1, model.UserTransactionFilters{Limit: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{2, 5}, ids(page))
+	assert.NotNil(t, page.NextCursor)
+
+	page, err = svc.GetUserTransactions(context.Background(), 1, model.UserTransactionFilters{Limit: 2, Cursor: next(page)})
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{4, 1}, ids(page))
+
+	page, err = svc.GetUserTransactions(context.Background(), 1, model.UserTransactionFilters{Limit: 2, Cursor: next(page)})
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{3}, ids(page))
+	assert.Nil(t, page.NextCursor, "last page has no next cursor")
+}
+
+func TestParseTransactionCursor(t *testing.T) {
+	cursor := model.TransactionCursor{TransactionDate: time.Date(2024, 3, 10, 12, 0, 0, 123456000, time.UTC), ID: 42}
+	parsed, err := model.ParseTransactionCursor(cursor.Encode())
+	assert.NoError(t, err)
+	assert.True(t, cursor.TransactionDate.Equal(parsed.TransactionDate))
+	assert.Equal(t, cursor.ID, parsed.ID)
+
+	for _, token := range []string{"", "42", "not base64!", "e30"} { // "e30" is {}
+		_, err := model.ParseTransactionCursor(token)
+		assert.ErrorIs(t, err, model.ErrInvalidCursor, token)
+	}
+}
+
+func TestUploadReceipt_ContentTypeSniffing(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		content  []byte
+		wantErr  error
+	}{
+		{"real png", "receipt.png", pngBytes, nil},
+		{"real pdf", "receipt.pdf", pdfBytes, nil},
+		{"pdf renamed to png", "receipt.png", pdfBytes, ErrInvalidFileFormat},
+		{"garbage renamed to png", "receipt.png", []byte("MZ\x90\x00 definitely not an image"), ErrInvalidFileFormat},
+		{"png renamed to jpg", "receipt.jpg", pngBytes, ErrInvalidFileFormat},
+		{"unsupported extension", "malware.exe", pngBytes, ErrInvalidFileFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeTransactionRepo()
+			repo.transactions[1] = &model.Transaction{ID: 1, UserID: 1}
+			svc := newTestTransactionService(repo, nil)
+			uploadsDir := withLocalStorage(t, svc)
+
+			tx, err := svc.UploadReceipt(context.Background(), 1, 1, newFileHeader(t, tt.filename, tt.content))
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Nil(t, repo.transactions[1].ReceiptPath)
+				return
+			}
+			assert.NoError(t, err)
+			saved, err := os.ReadFile(filepath.Join(uploadsDir, filepath.FromSlash(*tx.ReceiptPath)))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.content, saved) // Sniffed bytes are written back, not dropped
+		})
+	}
+}
+
+func TestDeleteTransaction_RemovesReceiptFile(t *testing.T) {
+	repo := newFakeTransactionRepo()
+	repo.transactions[1] = &model.Transaction{ID: 1, UserID: 1}
+	svc := newTestTransactionService(repo, nil)
+	uploadsDir := withLocalStorage(t, svc)
+
+	tx, err := svc.UploadReceipt(context.Background(), 1, 1, newFileHeader(t, "receipt.png", pngBytes))
+	assert.NoError(t, err)
+	receiptPath := filepath.Join(uploadsDir, filepath.FromSlash(*tx.ReceiptPath))
+	assert.FileExists(t, receiptPath)
+
+	assert.NoError(t, svc.DeleteTransaction(context.Background(), 1, 1, model.RoleUser))
+	assert.NoFileExists(t, receiptPath)
+	assert.NoDirExists(t, filepath.Dir(receiptPath))
+}
+
+func TestDeleteTransaction_MissingReceiptFileIsNotAnError(t *testing.T) {
+	repo := newFakeTransactionRepo()
+	missing := "transactions/1/gone.png"
+	repo.transactions[1] = &model.Transaction{ID: 1, UserID: 1, ReceiptPath: &missing}
+	svc := newTestTransactionService(repo, nil)
+	withLocalStorage(t, svc)
+
+	assert.NoError(t, svc.DeleteTransaction(context.Background(), 1, 1, model.RoleUser))
+	assert.NotContains(t, repo.transactions, int64(1))
+}
+
+func TestGetTransactionHistory(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeTransactionRepo()
+	svc := newTestTransactionService(repo, nil)
+
+	tx, err := svc.CreateTransaction(ctx, 1, model.CreateTransactionRequest{
+		Amount: 100, Type: model.TransactionTypeExpense, Category: "food",
+	})
+	assert.NoError(t, err)
+	history, err := svc.GetTransactionHistory(ctx, tx.ID, 1, model.RoleUser)
+	assert.NoError(t, err)
+	assert.Empty(t, history)
+
+	amount := int64(250)
+	_, err = svc.UpdateTransaction(ctx, tx.ID, 1, model.UpdateTransactionRequest{Amount: &amount})
+	assert.NoError(t, err)
+	assert.NoError(t, svc.DeleteTransaction(ctx, tx.ID, 1, model.RoleUser))
+
+	// Still readable once the transaction itself is gone
+	history, err = svc.GetTransactionHistory(ctx, tx.ID, 1, model.RoleUser)
+	assert.NoError(t, err)
+	if assert.Len(t, history, 2) {
+		assert.Equal(t, model.TransactionChangeUpdate, history[0].Action)
+		assert.Equal(t, model.TransactionChangeDelete, history[1].Action)
+		assert.Equal(t, 1, *history[0].ChangedBy)
+
+		var old model.Transaction
+		assert.NoError(t, json.Unmarshal(history[0].OldValues, &old))
+		assert.Equal(t, int64(100), old.Amount)
+		assert.NoError(t, json.Unmarshal(history[1].OldValues, &old))
+		assert.Equal(t, int64(250), old.Amount)
+	}
+
+	_, err = svc.GetTransactionHistory(ctx, tx.ID, 2, model.RoleUser)
+	assert.ErrorIs(t, err, ErrForbidden)
+	history, err = svc.GetTransactionHistory(ctx, tx.ID, 2, model.RoleAdmin)
+	assert.NoError(t, err)
+	assert.Len(t, history, 2)
+
+	_, err = svc.GetTransactionHistory(ctx, 999, 1, model.RoleUser)
+	assert.ErrorIs(t, err, ErrTransactionNotFound)
+}
+
+func TestDeleteTransactions(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeTransactionRepo()
+	receipt := "transactions/1/receipt.png"
+	repo.transactions[1] = &model.Transaction{ID: 1, UserID: 1, ReceiptPath: &receipt}
+	repo.transactions[2] = &model.Transaction{ID: 2, UserID: 1}
+	repo.transactions[3] = &model.Transaction{ID: 3, UserID: 2}
+	svc := newTestTransactionService(repo, nil)
+	uploadsDir := withLocalStorage(t, svc)
+	receiptPath := filepath.Join(uploadsDir, filepath.FromSlash(receipt))
+	assert.NoError(t, os.MkdirAll(filepath.Dir(receiptPath), 0o755))
+	assert.NoError(t, os.WriteFile(receiptPath, pngBytes, 0o644))
+
+	// Someone else's and unknown ids are skipped, not fatal
+	deleted, err := svc.DeleteTransactions(ctx, []int64{1, 2, 3, 99}, 1, model.RoleUser)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+	assert.NotContains(t, repo.transactions, int64(1))
+	assert.NotContains(t, repo.transactions, int64(2))
+	assert.Contains(t, repo.transactions, int64(3))
+	assert.NoFileExists(t, receiptPath)
+	if assert.Len(t, repo.history, 2) {
+		assert.Equal(t, model.TransactionChangeDelete, repo.history[0].Action)
+	}
+
+	deleted, err = svc.DeleteTransactions(ctx, []int64{3}, 1, model.RoleAdmin)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+	assert.Empty(t, repo.transactions)
+}
+
+func TestGetReceipt_StreamsFromStorage(t *testing.T) {
+	repo := newFakeTransactionRepo()
+	repo.transactions[1] = &model.Transaction{ID: 1, UserID: 1}
+	svc := newTestTransactionService(repo, nil)
+	withLocalStorage(t, svc)
+
+	_, err := svc.UploadReceipt(context.Background(), 1, 1, newFileHeader(t, "receipt.pdf", pdfBytes))
+	assert.NoError(t, err)
+
+	contents, fileName, err := svc.GetReceipt(context.Background(), 1, 1, model.RoleUser)
+	assert.NoError(t, err)
+	defer contents.Close()
+	data, err := io.ReadAll(contents)
+	assert.NoError(t, err)
+	assert.Equal(t, pdfBytes, data)
+	assert.Equal(t, "receipt.pdf", fileName)
+}
+
+func TestUploadReceipt_ConfiguredLimits(t *testing.T) {
+	repo := newFakeTransactionRepo()
+	repo.transactions[1] = &model.Transaction{ID: 1, UserID: 1}
+	svc := newTestTransactionService(repo, &config.TransactionConfig{
+		MaxReceiptSize:           16,
+		AllowedReceiptExtensions: []string{".pdf", ".heic"},
+	})
+	withLocalStorage(t, svc)
+
+	_, err := svc.UploadReceipt(context.Background(), 1, 1, newFileHeader(t, "receipt.png", pngBytes))
+	assert.ErrorIs(t, err, ErrInvalidFileFormat)
+
+	_, err = svc.UploadReceipt(context.Background(), 1, 1, newFileHeader(t, "receipt.pdf", bytes.Repeat(pdfBytes, 2)))
+	assert.ErrorIs(t, err, ErrFileSizeExceeded)
+
+	// Extensions without a known signature are accepted by extension alone
+	_, err = svc.UploadReceipt(context.Background(), 1, 1, newFileHeader(t, "photo.HEIC", []byte("\x00\x00\x00\x18ftypheic")))
+	assert.NoError(t, err)
+}
+
+func TestGetReceipt_NoReceiptUploaded(t *testing.T) {
+	repo := newFakeTransactionRepo()
+	repo.transactions[1] = &model.Transaction{ID: 1, UserID: 1}
+	svc := newTestTransactionService(repo, nil)
+
+	_, _, err := svc.GetReceipt(context.Background(), 1, 1, model.RoleUser)
+	assert.ErrorIs(t, err, ErrReceiptNotFound)
+
+	_, _, err = svc.GetReceipt(context.Background(), 2, 1, model.RoleUser)
+	assert.ErrorIs(t, err, ErrTransactionNotFound)
+}
+
+func TestGetUserTransactions_SummaryCoversAllPages(t *testing.T) {
+	repo := newFakeTransactionRepo()
+	svc := newTestTransactionService(repo, nil)
+	for _, tx := range []model.Transaction{
+		{UserID: 1, Currency: "UZS", Type: model.TransactionTypeIncome, Amount: 1000},
+		{UserID: 1, Currency: "UZS", Type: model.TransactionTypeExpense, Amount: 300},
+		{UserID: 1, Currency: "USD", Type: model.TransactionTypeExpense, Amount: 500},
+	} {
+		assert.NoError(t, repo.Create(context.Background(), &tx))
+	}
+
+	page, err := svc.GetUserTransactions(context.Background(), 1, model.UserTransactionFilters{Limit: 1})
+	assert.NoError(t, err)
+	assert.Nil(t, page.Summary)
+
+	page, err = svc.GetUserTransactions(context.Background(), 1, model.UserTransactionFilters{Limit: 1, WithSummary: true})
+	assert.NoError(t, err)
+	assert.Len(t, page.Data, 1)
+	assert.Equal(t, &model.TransactionSummary{
+		Count:        3,
+		TotalIncome:  map[string]int64{"UZS": 1000},
+		TotalExpense: map[string]int64{"UZS": 300, "USD": 500}, // Never added across currencies
+	}, page.Summary)
+}
+
+func TestUploadReceipt_GeneratedStorageKey(t *testing.T) {
+	repo := newFakeTransactionRepo()
+	repo.transactions[1] = &model.Transaction{ID: 1, UserID: 1}
+	svc := newTestTransactionService(repo, nil)
+	uploadsDir := withLocalStorage(t, svc)
+
+	first, err := svc.UploadReceipt(context.Background(), 1, 1, newFileHeader(t, "scan.pdf", pdfBytes))
+	assert.NoError(t, err)
+	firstKey := *first.ReceiptPath
+	assert.NotEqual(t, "transactions/1/scan.pdf", firstKey)
+	assert.Equal(t, "scan.pdf", *first.ReceiptName)
+
+	// Same filename again gets its own key, and the replaced file is removed
+	second, err := svc.UploadReceipt(context.Background(), 1, 1, newFileHeader(t, "scan.pdf", pdfBytes))
+	assert.NoError(t, err)
+	assert.NotEqual(t, firstKey, *second.ReceiptPath)
+	assert.NoFileExists(t, filepath.Join(uploadsDir, filepath.FromSlash(firstKey)))
+	assert.FileExists(t, filepath.Join(uploadsDir, filepath.FromSlash(*second.ReceiptPath)))
+
+	// A crafted name cannot steer the key and is reduced to its last element for display
+	crafted, err := svc.UploadReceipt(context.Background(), 1, 1, newFileHeader(t, `..\..\..\etc\evil.pdf`, pdfBytes))
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(*crafted.ReceiptPath, "transactions/1/"))
+	assert.True(t, strings.HasSuffix(*crafted.ReceiptPath, ".pdf"))
+	_, fileName, err := svc.GetReceipt(context.Background(), 1, 1, model.RoleUser)
+	assert.NoError(t, err)
+	assert.Equal(t, "evil.pdf", fileName)
+}
+
+func TestGetReceipt_RejectsPathOutsideUploads(t *testing.T) {
+	repo := newFakeTransactionRepo()
+	escaping := "transactions/1/../../../etc/passwd"
+	repo.transactions[1] = &model.Transaction{ID: 1, UserID: 1, ReceiptPath: &escaping}
+	svc := newTestTransactionService(repo, nil)
+	withLocalStorage(t, svc)
+
+	_, _, err := svc.GetReceipt(context.Background(), 1, 1, model.RoleUser)
+	assert.ErrorIs(t, err, storage.ErrInvalidKey)
+}
+
+func TestDeleteReceipt(t *testing.T) {
+	repo := newFakeTransactionRepo()
+	repo.transactions[1] = &model.Transaction{ID: 1, UserID: 1}
+	svc := newTestTransactionService(repo, nil)
+	uploadsDir := withLocalStorage(t, svc)
+
+	_, err := svc.DeleteReceipt(context.Background(), 1, 1)
+	assert.ErrorIs(t, err, ErrReceiptNotFound)
+
+	tx, err := svc.UploadReceipt(context.Background(), 1, 1, newFileHeader(t, "receipt.pdf", pdfBytes))
+	assert.NoError(t, err)
+	receiptPath := filepath.Join(uploadsDir, filepath.FromSlash(*tx.ReceiptPath))
+
+	_, err = svc.DeleteReceipt(context.Background(), 1, 2)
+	assert.ErrorIs(t, err, ErrForbidden)
+	assert.FileExists(t, receiptPath)
+
+	tx, err = svc.DeleteReceipt(context.Background(), 1, 1)
+	assert.NoError(t, err)
+	assert.Nil(t, tx.ReceiptPath)
+	assert.Nil(t, repo.transactions[1].ReceiptPath)
+	assert.NoFileExists(t, receiptPath)
+
+	// Both receipt changes are in the history, each with the transaction as it was before
+	history, err := svc.GetTransactionHistory(context.Background(), 1, 1, model.RoleUser)
+	assert.NoError(t, err)
+	if assert.Len(t, history, 2) {
+		assert.Equal(t, model.TransactionChangeReceiptUpload, history[0].Action)
+		assert.NotContains(t, string(history[0].OldValues), "receipt_path")
+		assert.Equal(t, model.TransactionChangeReceiptDelete, history[1].Action)
+		assert.Contains(t, string(history[1].OldValues), "receipt_path")
+	}
+}
+
+func TestExportTransactionsJSONAdmin(t *testing.T) {
+	repo := newFakeTransactionRepo()
+	svc := newTestTransactionService(repo, nil)
+
+	buf, err := svc.ExportTransactionsJSONAdmin(context.Background(), model.AdminTransactionFilters{})
+	assert.NoError(t, err)
+	assert.Equal(t, "[]", buf.String())
+
+	for _, userID := range []int{1, 2} {
+		_, err := svc.CreateTransaction(context.Background(), userID, model.CreateTransactionRequest{
+			Amount: 1500, Type: model.TransactionTypeExpense, Category: "food",
+		})
+		assert.NoError(t, err)
+	}
+	userID := 2
+	buf, err = svc.ExportTransactionsJSONAdmin(context.Background(), model.AdminTransactionFilters{UserID: &userID})
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), "\n  {") // Pretty-printed
+
+	var exported []model.Transaction
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &exported))
+	assert.Len(t, exported, 1)
+	assert.Equal(t, 2, exported[0].UserID)
+	assert.Equal(t, int64(1500), exported[0].Amount)
+}
+
+func TestCreateTransaction_Currency(t *testing.T) {
+	repo := newFakeTransactionRepo()
+	svc := newTestTransactionService(repo, nil)
+	svc.userRepo = &fakeUserRepo{users: map[int]*model.User{2: {ID: 2, Currency: "USD"}}}
+	req := model.CreateTransactionRequest{Amount: 100, Type: model.TransactionTypeExpense, Category: "food"}
+
+	tx, err := svc.CreateTransaction(context.Background(), 1, req)
+	assert.NoError(t, err)
+	assert.Equal(t, model.DefaultCurrency, tx.Currency)
+
+	tx, err = svc.CreateTransaction(context.Background(), 2, req)
+	assert.NoError(t, err)
+	assert.Equal(t, "USD", tx.Currency, "defaults to the user's currency")
+
+	req.Currency = " eur "
+	tx, err = svc.CreateTransaction(context.Background(), 2, req)
+	assert.NoError(t, err)
+	assert.Equal(t, "EUR", tx.Currency)
+
+	req.Currency = "XYZ"
+	_, err = svc.CreateTransaction(context.Background(), 2, req)
+	assert.ErrorIs(t, err, ErrUnsupportedCurrency)
+
+	bad := "dollars"
+	_, err = svc.UpdateTransaction(context.Background(), tx.ID, 2, model.UpdateTransactionRequest{Currency: &bad})
+	assert.ErrorIs(t, err, ErrUnsupportedCurrency)
+	usd := "usd"
+	tx, err = svc.UpdateTransaction(context.Background(), tx.ID, 2, model.UpdateTransactionRequest{Currency: &usd})
+	assert.NoError(t, err)
+	assert.Equal(t, "USD", tx.Currency)
+}
+
+func TestTransactionTags(t *testing.T) {
+	svc := newTestTransactionService(newFakeTransactionRepo(), nil)
+	ctx := context.Background()
+	req := model.CreateTransactionRequest{Amount: 100, Type: model.TransactionTypeExpense, Category: "food"}
+
+	tx, err := svc.CreateTransaction(ctx, 1, req)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{}, tx.Tags)
+
+	req.Tags = []string{"work", "reimbursable", "work", "поездка"}
+	tx, err = svc.CreateTransaction(ctx, 1, req)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"work", "reimbursable", "поездка"}, tx.Tags)
+
+	for _, bad := range [][]string{{"Work"}, {"two words"}, {"a,b"}, {""}, {strings.Repeat("x", model.MaxTagLength+1)}} {
+		req.Tags = bad
+		_, err = svc.CreateTransaction(ctx, 1, req)
+		assert.ErrorIs(t, err, ErrInvalidTag, bad)
+	}
+	req.Tags = nil
+	for i := 0; i <= model.MaxTagsPerTransaction; i++ {
+		req.Tags = append(req.Tags, fmt.Sprintf("tag%d", i))
+	}
+	_, err = svc.CreateTransaction(ctx, 1, req)
+	assert.ErrorIs(t, err, ErrInvalidTag)
+
+	// Updates replace the tags only when given
+	tx, err = svc.UpdateTransaction(ctx, tx.ID, 1, model.UpdateTransactionRequest{Tags: &[]string{"home"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"home"}, tx.Tags)
+	amount := int64(200)
+	tx, err = svc.UpdateTransaction(ctx, tx.ID, 1, model.UpdateTransactionRequest{Amount: &amount})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"home"}, tx.Tags)
+	tx, err = svc.UpdateTransaction(ctx, tx.ID, 1, model.UpdateTransactionRequest{Tags: &[]string{}})
+	assert.NoError(t, err)
+	assert.Empty(t, tx.Tags)
+	_, err = svc.UpdateTransaction(ctx, tx.ID, 1, model.UpdateTransactionRequest{Tags: &[]string{"Home"}})
+	assert.ErrorIs(t, err, ErrInvalidTag)
+}
+
+// reimbursableRepo returns canned reimbursable totals
+type reimbursableRepo struct {
+	*fakeTransactionRepo
+	totals []model.ReimbursableTotal
+}
+
+func (r *reimbursableRepo) GetReimbursableTotals(ctx context.Context, filters model.AdminTransactionFilters) ([]model.ReimbursableTotal, error) {
+	return r.totals, nil
+}
+
+func TestExportReimbursableReportCSVAdmin(t *testing.T) {
+	repo := &reimbursableRepo{
+		fakeTransactionRepo: newFakeTransactionRepo(),
+		totals: []model.ReimbursableTotal{
+			{UserID: 1, Phone: "+998901234567", Currency: "UZS", TotalReimbursable: 150000, Count: 3},
+			{UserID: 1, Phone: "+998901234567", Currency: "USD", TotalReimbursable: 2500, Count: 1},
+		},
+	}
+	svc := newTestTransactionService(repo.fakeTransactionRepo, nil)
+	svc.repo = repo
+
+	buf, err := svc.ExportReimbursableReportCSVAdmin(context.Background(), model.AdminTransactionFilters{})
+	assert.NoError(t, err)
+	assert.Equal(t, "UserID,Phone,Currency,TotalReimbursable,TotalReimbursableMinor,Count\n"+
+		"1,+998901234567,UZS,1500.00,150000,3\n"+
+		"1,+998901234567,USD,25.00,2500,1\n", buf.String())
+}
+
+func TestExportTransactionsCSVAdmin_FormatsAmountsAndDates(t *testing.T) {
+	repo := newFakeTransactionRepo()
+	date := time.Date(2024, 3, 5, 14, 30, 0, 0, time.UTC)
+	repo.transactions[1] = &model.Transaction{ID: 1, UserID: 1, Amount: 1234, Currency: "UZS", Type: model.TransactionTypeExpense,
+		Category: "Food", TransactionDate: date, CreatedAt: date}
+	repo.nextID = 1
+
+	svc := newTestTransactionService(repo, nil)
+	buf, err := svc.ExportTransactionsCSVAdmin(context.Background(), model.AdminTransactionFilters{})
+	assert.NoError(t, err)
+	rows, err := csv.NewReader(buf).ReadAll()
+	assert.NoError(t, err)
+	if assert.Len(t, rows, 2) {
+		assert.Equal(t, []string{"Amount", "AmountMinor"}, rows[0][2:4])
+		assert.Equal(t, []string{"12.34", "1234"}, rows[1][2:4])
+		assert.Equal(t, "2024-03-05T14:30:00Z", rows[1][10])
+	}
+
+	svc.cfg.CSVAmountDivisor, svc.cfg.CSVDateLayout = 1, "02.01.2006 15:04"
+	buf, err = svc.ExportTransactionsCSVAdmin(context.Background(), model.AdminTransactionFilters{})
+	assert.NoError(t, err)
+	rows, err = csv.NewReader(buf).ReadAll()
+	assert.NoError(t, err)
+	if assert.Len(t, rows, 2) {
+		assert.Equal(t, []string{"1234", "1234"}, rows[1][2:4])
+		assert.Equal(t, "05.03.2024 14:30", rows[1][10])
+	}
+}
+
+// countingStatsRepo counts the aggregations actually run
+type countingStatsRepo struct {
+	*fakeTransactionRepo
+	calls int
+}
+
+func (r *countingStatsRepo) GetAggregatedStats(ctx context.Context, filters model.AdminTransactionFilters) (*model.AggregatedStats, error) {
+	r.calls++
+	return &model.AggregatedStats{TotalIncome: map[string]int64{model.DefaultCurrency: int64(r.calls)}}, nil
+}
+
+func TestGetStatisticsAdmin_Cache(t *testing.T) {
+	repo := &countingStatsRepo{fakeTransactionRepo: newFakeTransactionRepo()}
+	svc := newTestTransactionService(repo.fakeTransactionRepo, nil)
+	svc.repo, svc.stats = repo, newStatsCache(time.Minute)
+	ctx := context.Background()
+
+	food, transport := "Food", "Transport"
+	_, err := svc.GetStatisticsAdmin(ctx, model.AdminTransactionFilters{Merchant: &food})
+	assert.NoError(t, err)
+	stats, err := svc.GetStatisticsAdmin(ctx, model.AdminTransactionFilters{Merchant: &food})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, repo.calls)
+	assert.Equal(t, int64(1), stats.TotalIncome[model.DefaultCurrency])
+
+	// Different filters never share an entry
+	_, err = svc.GetStatisticsAdmin(ctx, model.AdminTransactionFilters{Merchant: &transport})
+	assert.NoError(t, err)
+	_, err = svc.GetStatisticsAdmin(ctx, model.AdminTransactionFilters{Merchant: &food, Types: []string{model.TransactionTypeIncome}})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, repo.calls)
+
+	// Any write clears the cache
+	_, err = svc.CreateTransaction(ctx, 1, model.CreateTransactionRequest{Amount: 100, Type: model.TransactionTypeExpense, Category: "Food"})
+	assert.NoError(t, err)
+	stats, err = svc.GetStatisticsAdmin(ctx, model.AdminTransactionFilters{Merchant: &food})
+	assert.NoError(t, err)
+	assert.Equal(t, 4, repo.calls)
+	assert.Equal(t, int64(4), stats.TotalIncome[model.DefaultCurrency])
+
+	// Disabled by a zero TTL
+	svc.stats = newStatsCache(0)
+	_, _ = svc.GetStatisticsAdmin(ctx, model.AdminTransactionFilters{})
+	_, _ = svc.GetStatisticsAdmin(ctx, model.AdminTransactionFilters{})
+	assert.Equal(t, 6, repo.calls)
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"expense_tracker/internal/repository"
+)
+
+// UserStatusInvalidator drops what is cached about a user once their account changes
+type UserStatusInvalidator interface {
+	Invalidate(userID int)
+}
+
+// UserStatusCache answers whether a user still exists and what their role is, caching lookups
+// for a short TTL so the JWT middleware can verify tokens without a DB query on every request.
+type UserStatusCache struct {
+	userRepo repository.UserRepository
+	ttl      time.Duration
+
+	mu      sync.Mutex
+	entries map[int]userStatusEntry
+}
+
+type userStatusEntry struct {
+	role      string // Empty once the user is deleted
+	expiresAt time.Time
+}
+
+// NewUserStatusCache creates a new UserStatusCache
+func NewUserStatusCache(userRepo repository.UserRepository, ttl time.Duration) *UserStatusCache {
+	return &UserStatusCache{
+		userRepo: userRepo,
+		ttl:      ttl,
+		entries:  make(map[int]userStatusEntry),
+	}
+}
+
+// UserRole returns the user's current role, or "" if the user no longer exists
+func (c *UserStatusCache) UserRole(ctx context.Context, userID int) (string, error) {
+	now := time.Now()
+
+	c.mu.Lock()
+	entry, ok := c.entries[userID]
+	c.mu.Unlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.role, nil
+	}
+
+	user, err := c.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return "", fmt.Errorf("failed to look up user status: %w", err)
+	}
+	var role string
+	if user != nil {
+		role = user.Role
+	}
+
+	c.mu.Lock()
+	c.entries[userID] = userStatusEntry{role: role, expiresAt: now.Add(c.ttl)}
+	c.mu.Unlock()
+
+	return role, nil
+}
+
+// Invalidate drops the cached status so the next check hits the DB. It does nothing on a nil
+// cache, so callers can hold one even when VERIFY_TOKEN_USER is off.
+func (c *UserStatusCache) Invalidate(userID int) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	delete(c.entries, userID)
+	c.mu.Unlock()
+}
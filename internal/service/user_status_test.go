@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"expense_tracker/internal/model"
+	"expense_tracker/internal/repository"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeUserRepo keeps users in memory and counts lookups
+type fakeUserRepo struct {
+	repository.UserRepository
+	users    map[int]*model.User
+	lookups  int
+	receipts map[int][]string // Receipt keys of each user's transactions, returned by Delete
+}
+
+func (r *fakeUserRepo) FindByID(ctx context.Context, id int) (*model.User, error) {
+	r.lookups++
+	return r.users[id], nil
+}
+
+func (r *fakeUserRepo) FindByPhone(ctx context.Context, phone string) (*model.User, error) {
+	for _, u := range r.users {
+		if u.Phone == phone {
+			return u, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeUserRepo) Create(ctx context.Context, user *model.User) error {
+	user.ID = len(r.users) + 1
+	r.users[user.ID] = user
+	return nil
+}
+
+func (r *fakeUserRepo) CreateFirstAdmin(ctx context.Context, user *model.User) error {
+	user.Role = model.RoleAdmin
+	if r.countRole(model.RoleAdmin) > 0 {
+		user.Role = model.RoleUser
+	}
+	return r.Create(ctx, user)
+}
+
+func (r *fakeUserRepo) UpdateRole(ctx context.Context, id int, role string) error {
+	if r.users[id].Role == model.RoleAdmin && role != model.RoleAdmin && r.countRole(model.RoleAdmin) == 1 {
+		return repository.ErrLastAdmin
+	}
+	r.users[id].Role = role
+	return nil
+}
+
+func (r *fakeUserRepo) countRole(role string) int {
+	count := 0
+	for _, u := range r.users {
+		if u.Role == role {
+			count++
+		}
+	}
+	return count
+}
+
+func (r *fakeUserRepo) Delete(ctx context.Context, id int) ([]string, error) {
+	if _, ok := r.users[id]; !ok {
+		return nil, pgx.ErrNoRows
+	}
+	if r.users[id].Role == model.RoleAdmin && r.countRole(model.RoleAdmin) == 1 {
+		return nil, repository.ErrLastAdmin
+	}
+	delete(r.users, id)
+	return r.receipts[id], nil
+}
+
+func (r *fakeUserRepo) CountByRole(ctx context.Context, role string) (int, error) {
+	return r.countRole(role), nil
+}
+
+// GetCurrency returns the user's currency, model.DefaultCurrency for unknown users or none set
+func (r *fakeUserRepo) GetCurrency(ctx context.Context, id int) (string, error) {
+	if u, ok := r.users[id]; ok && u.Currency != "" {
+		return u.Currency, nil
+	}
+	return model.DefaultCurrency, nil
+}
+
+func (r *fakeUserRepo) UpdateCurrency(ctx context.Context, id int, currency string) error {
+	r.users[id].Currency = currency
+	return nil
+}
+
+func (r *fakeUserRepo) UpdateAlertThreshold(ctx context.Context, id int, threshold *int64) error {
+	r.users[id].MonthlyAlertThreshold = threshold
+	return nil
+}
+
+func (r *fakeUserRepo) UpdatePassword(ctx context.Context, id int, passwordHash string) error {
+	r.users[id].PasswordHash = passwordHash
+	return nil
+}
+
+func TestUserStatusCache_CachesLookups(t *testing.T) {
+	repo := &fakeUserRepo{users: map[int]*model.User{1: {ID: 1, Role: model.RoleUser}}}
+	cache := NewUserStatusCache(repo, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		role, err := cache.UserRole(context.Background(), 1)
+		assert.NoError(t, err)
+		assert.Equal(t, model.RoleUser, role)
+	}
+	assert.Equal(t, 1, repo.lookups)
+}
+
+func TestUserStatusCache_DeletedUser(t *testing.T) {
+	repo := &fakeUserRepo{users: map[int]*model.User{1: {ID: 1, Role: model.RoleUser}}}
+	cache := NewUserStatusCache(repo, time.Minute)
+
+	role, _ := cache.UserRole(context.Background(), 1)
+	assert.Equal(t, model.RoleUser, role)
+
+	delete(repo.users, 1)
+	cache.Invalidate(1)
+
+	role, err := cache.UserRole(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Empty(t, role)
+}
+
+func TestUserStatusCache_RoleChange(t *testing.T) {
+	repo := &fakeUserRepo{users: map[int]*model.User{1: {ID: 1, Role: model.RoleAdmin}}}
+	cache := NewUserStatusCache(repo, time.Minute)
+
+	role, _ := cache.UserRole(context.Background(), 1)
+	assert.Equal(t, model.RoleAdmin, role)
+
+	repo.users[1].Role = model.RoleUser
+	cache.Invalidate(1)
+
+	role, err := cache.UserRole(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, model.RoleUser, role)
+}
@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// LocalStorage keeps receipts on the local filesystem under a base directory
+type LocalStorage struct {
+	baseDir string
+}
+
+// NewLocalStorage creates a LocalStorage rooted at baseDir, creating the directory if needed
+func NewLocalStorage(baseDir string) (*LocalStorage, error) {
+	if err := os.MkdirAll(baseDir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed to create uploads directory %s: %w", baseDir, err)
+	}
+	return &LocalStorage{baseDir: baseDir}, nil
+}
+
+// fullPath maps a key to a path under baseDir, returning ErrInvalidKey for keys that would resolve outside it.
+// Receipts saved before storage keys were introduced recorded the path including baseDir; those are accepted as-is.
+func (s *LocalStorage) fullPath(key string) (string, error) {
+	key = s.NormalizeKey(key)
+	if err := checkKey(key); err != nil {
+		return "", err
+	}
+
+	// Belt and braces: the resolved path itself must sit below the base directory
+	base, err := filepath.Abs(s.baseDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve uploads directory: %w", err)
+	}
+	fullPath := filepath.Join(base, filepath.FromSlash(key))
+	if !strings.HasPrefix(fullPath, base+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	return fullPath, nil
+}
+
+// NormalizeKey strips the base directory that receipts saved before storage keys were introduced
+// recorded in front of the key
+func (s *LocalStorage) NormalizeKey(key string) string {
+	legacyPrefix := filepath.ToSlash(filepath.Clean(s.baseDir)) + "/"
+	return strings.TrimPrefix(key, legacyPrefix)
+}
+
+// Walk calls fn for every file under baseDir with its slash-separated key and modification time.
+// Files removed while the walk is in progress are skipped.
+func (s *LocalStorage) Walk(ctx context.Context, fn func(key string, modTime time.Time) error) error {
+	err := filepath.WalkDir(s.baseDir, func(fullPath string, d os.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		rel, err := filepath.Rel(s.baseDir, fullPath)
+		if err != nil {
+			return err
+		}
+		return fn(filepath.ToSlash(rel), info.ModTime())
+	})
+	if err != nil {
+		return fmt.Errorf("failed to walk uploads directory: %w", err)
+	}
+	return nil
+}
+
+// Save writes r to the file for key
+func (s *LocalStorage) Save(ctx context.Context, key string, r io.Reader) (string, error) {
+	fullPath, err := s.fullPath(key)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(fullPath), os.ModePerm); err != nil {
+		return "", fmt.Errorf("failed to create upload directory: %w", err)
+	}
+
+	dst, err := os.Create(fullPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to create file on server: %w", err)
+	}
+	if _, err := io.Copy(dst, r); err != nil {
+		dst.Close()
+		os.Remove(fullPath) // Don't leave a truncated file behind
+		return "", fmt.Errorf("failed to save file: %w", err)
+	}
+	if err := dst.Close(); err != nil {
+		return "", fmt.Errorf("failed to save file: %w", err)
+	}
+	return key, nil
+}
+
+// Open opens the file for key
+func (s *LocalStorage) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	fullPath, err := s.fullPath(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(fullPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to open stored file: %w", err)
+	}
+	return f, nil
+}
+
+// Delete removes the file for key and its directory once empty
+func (s *LocalStorage) Delete(ctx context.Context, key string) error {
+	fullPath, err := s.fullPath(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(fullPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove stored file: %w", err)
+	}
+	// Drop the per-transaction directory once its last file is gone
+	dir := filepath.Dir(fullPath)
+	if base, err := filepath.Abs(s.baseDir); err != nil || dir == base {
+		return nil
+	}
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) == 0 {
+		if err := os.Remove(dir); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove stored file directory: %w", err)
+		}
+	}
+	return nil
+}
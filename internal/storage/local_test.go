@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalStorage_SaveOpenDelete(t *testing.T) {
+	baseDir := t.TempDir()
+	s, err := NewLocalStorage(baseDir)
+	assert.NoError(t, err)
+	ctx := context.Background()
+
+	key, err := s.Save(ctx, "transactions/1/receipt.png", strings.NewReader("data"))
+	assert.NoError(t, err)
+	assert.Equal(t, "transactions/1/receipt.png", key)
+	assert.FileExists(t, filepath.Join(baseDir, "transactions", "1", "receipt.png"))
+
+	r, err := s.Open(ctx, key)
+	assert.NoError(t, err)
+	data, _ := io.ReadAll(r)
+	r.Close()
+	assert.Equal(t, "data", string(data))
+
+	assert.NoError(t, s.Delete(ctx, key))
+	assert.NoDirExists(t, filepath.Join(baseDir, "transactions", "1"))
+	assert.NoError(t, s.Delete(ctx, key)) // Already gone
+
+	_, err = s.Open(ctx, key)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestLocalStorage_LegacyPathsIncludingBaseDir(t *testing.T) {
+	baseDir := filepath.Join(t.TempDir(), "uploads")
+	s, err := NewLocalStorage(baseDir)
+	assert.NoError(t, err)
+	_, err = s.Save(context.Background(), "transactions/1/receipt.png", strings.NewReader("data"))
+	assert.NoError(t, err)
+
+	r, err := s.Open(context.Background(), filepath.ToSlash(baseDir)+"/transactions/1/receipt.png")
+	assert.NoError(t, err)
+	r.Close()
+}
+
+func TestLocalStorage_KeysStayInsideBaseDir(t *testing.T) {
+	root := t.TempDir()
+	baseDir := filepath.Join(root, "uploads")
+	s, err := NewLocalStorage(baseDir)
+	assert.NoError(t, err)
+
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "secret.txt"), []byte("secret"), 0o600))
+
+	for _, key := range []string{"../escaped.txt", "transactions/1/../../../escaped.txt", "..%2f..%2fescaped.txt", `..\escaped.txt`, "/escaped.txt"} {
+		_, err = s.Save(context.Background(), key, strings.NewReader("data"))
+		assert.ErrorIs(t, err, ErrInvalidKey, key)
+	}
+	_, statErr := os.Stat(filepath.Join(root, "escaped.txt"))
+	assert.True(t, os.IsNotExist(statErr))
+
+	_, err = s.Open(context.Background(), "../secret.txt")
+	assert.ErrorIs(t, err, ErrInvalidKey)
+	_, err = s.Open(context.Background(), "transactions%2f..%2f..%2fsecret.txt")
+	assert.ErrorIs(t, err, ErrInvalidKey)
+	assert.ErrorIs(t, s.Delete(context.Background(), "../secret.txt"), ErrInvalidKey)
+	assert.FileExists(t, filepath.Join(root, "secret.txt"))
+}
+
+func TestLocalStorage_Walk(t *testing.T) {
+	baseDir := filepath.Join(t.TempDir(), "uploads")
+	s, err := NewLocalStorage(baseDir)
+	assert.NoError(t, err)
+	ctx := context.Background()
+	for _, key := range []string{"transactions/1/a.png", "transactions/2/b.pdf"} {
+		_, err := s.Save(ctx, key, strings.NewReader("data"))
+		assert.NoError(t, err)
+	}
+
+	var keys []string
+	err = s.Walk(ctx, func(key string, modTime time.Time) error {
+		keys = append(keys, key)
+		assert.False(t, modTime.IsZero())
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"transactions/1/a.png", "transactions/2/b.pdf"}, keys)
+
+	assert.Equal(t, "transactions/1/a.png", s.NormalizeKey(filepath.ToSlash(baseDir)+"/transactions/1/a.png"))
+	assert.Equal(t, "transactions/1/a.png", s.NormalizeKey("transactions/1/a.png"))
+}
@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// S3Config holds the settings for an S3-compatible bucket
+type S3Config struct {
+	Bucket   string
+	Region   string
+	Endpoint string // Optional; set for MinIO and other S3-compatible services
+}
+
+// LoadS3Config reads S3_BUCKET, S3_REGION and S3_ENDPOINT. It returns nil when S3_BUCKET is unset.
+func LoadS3Config() (*S3Config, error) {
+	bucket := os.Getenv("S3_BUCKET")
+	if bucket == "" {
+		return nil, nil
+	}
+	region := os.Getenv("S3_REGION")
+	if region == "" {
+		return nil, fmt.Errorf("S3_REGION must be set when S3_BUCKET is set")
+	}
+	return &S3Config{Bucket: bucket, Region: region, Endpoint: os.Getenv("S3_ENDPOINT")}, nil
+}
+
+// S3Storage keeps receipts in an S3-compatible bucket. Credentials come from the standard AWS environment.
+type S3Storage struct {
+	client *s3.Client
+	bucket string
+}
+
+// NewS3Storage creates an S3Storage for the configured bucket
+func NewS3Storage(ctx context.Context, cfg *S3Config) (*S3Storage, error) {
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, awsconfig.WithRegion(cfg.Region))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	client := s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		if cfg.Endpoint != "" {
+			o.BaseEndpoint = aws.String(cfg.Endpoint)
+			o.UsePathStyle = true // Most S3-compatible services don't support virtual-hosted buckets
+		}
+	})
+	return &S3Storage{client: client, bucket: cfg.Bucket}, nil
+}
+
+// Save uploads r as the object for key
+func (s *S3Storage) Save(ctx context.Context, key string, r io.Reader) (string, error) {
+	if err := checkKey(key); err != nil {
+		return "", err
+	}
+	// Receipts are small; buffering gives the SDK the seekable body it needs for signing
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+	_, err = s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(s.bucket),
+		Key:           aws.String(key),
+		Body:          bytes.NewReader(data),
+		ContentLength: aws.Int64(int64(len(data))),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload file to S3: %w", err)
+	}
+	return key, nil
+}
+
+// Open streams the object for key
+func (s *S3Storage) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	if err := checkKey(key); err != nil {
+		return nil, err
+	}
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to download file from S3: %w", err)
+	}
+	return out.Body, nil
+}
+
+// Delete removes the object for key
+func (s *S3Storage) Delete(ctx context.Context, key string) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete file from S3: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned when no object is stored under a key
+	ErrNotFound = errors.New("stored object not found")
+	// ErrInvalidKey is returned for keys that are absolute or would resolve outside the storage root
+	ErrInvalidKey = errors.New("invalid storage key")
+)
+
+// ReceiptStorage stores receipt files under slash-separated keys such as "transactions/42/receipt.png"
+type ReceiptStorage interface {
+	// Save stores the contents of r under key and returns the key to persist
+	Save(ctx context.Context, key string, r io.Reader) (string, error)
+	// Open returns a reader for the object stored under key; the caller must close it
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the object stored under key; deleting a missing object is not an error
+	Delete(ctx context.Context, key string) error
+}
+
+// WalkableStorage is a ReceiptStorage whose objects can be enumerated, which purging orphaned
+// receipts needs. LocalStorage implements it; buckets are better served by lifecycle rules.
+type WalkableStorage interface {
+	ReceiptStorage
+	// Walk calls fn with the key and last modification time of every stored object
+	Walk(ctx context.Context, fn func(key string, modTime time.Time) error) error
+	// NormalizeKey returns a persisted key in the form Walk reports it
+	NormalizeKey(key string) string
+}
+
+// checkKey rejects keys that are absolute, use backslashes or climb out of the storage root
+// with "..", both as given and once percent-decoded
+func checkKey(key string) error {
+	candidates := []string{key}
+	if decoded, err := url.PathUnescape(key); err != nil {
+		return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	} else if decoded != key {
+		candidates = append(candidates, decoded)
+	}
+	for _, k := range candidates {
+		if k == "" || strings.HasPrefix(k, "/") || strings.Contains(k, "\\") {
+			return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+		}
+		for _, segment := range strings.Split(k, "/") {
+			if segment == ".." {
+				return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+			}
+		}
+	}
+	return nil
+}
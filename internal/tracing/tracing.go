@@ -0,0 +1,135 @@
+// Package tracing sets up OpenTelemetry tracing and the spans shared by the layers: one per
+// HTTP request (see middleware.TracingMiddleware), one per service call and one per SQL query.
+// Until Setup runs the global tracer provider is a no-op, so the spans cost next to nothing.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies this application's spans to the tracer provider
+const instrumentationName = "expense_tracker"
+
+// DefaultServiceName is reported as service.name unless OTEL_SERVICE_NAME sets another
+const DefaultServiceName = "expense-tracker"
+
+// Config holds where traces are exported to
+type Config struct {
+	Endpoint    string // OTLP/HTTP collector, e.g. http://localhost:4318
+	ServiceName string
+}
+
+// LoadConfig reads OTEL_EXPORTER_OTLP_ENDPOINT (or OTEL_EXPORTER_OTLP_TRACES_ENDPOINT) and
+// OTEL_SERVICE_NAME. It returns nil when no endpoint is set, leaving tracing off.
+func LoadConfig() *Config {
+	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+	if endpoint == "" {
+		endpoint = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	}
+	if endpoint == "" {
+		return nil
+	}
+	serviceName := os.Getenv("OTEL_SERVICE_NAME")
+	if serviceName == "" {
+		serviceName = DefaultServiceName
+	}
+	return &Config{Endpoint: endpoint, ServiceName: serviceName}
+}
+
+// Setup installs a tracer provider exporting to cfg's collector in batches, and W3C trace
+// context propagation. The returned function flushes pending spans and must be called on exit.
+func Setup(ctx context.Context, cfg *Config) (shutdown func(context.Context) error, err error) {
+	// The exporter reads the endpoint, headers and TLS settings from the standard OTEL_* variables
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", cfg.ServiceName)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the application's tracer from the current global provider
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Start begins a span named name, a child of any span already in ctx. Callers must End it.
+func Start(ctx context.Context, name string) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name)
+}
+
+// QueryTracer is a pgx tracer giving every query and COPY its own client span, named after the
+// SQL operation and carrying the statement text. Arguments are never recorded.
+type QueryTracer struct{}
+
+var (
+	_ pgx.QueryTracer    = QueryTracer{}
+	_ pgx.CopyFromTracer = QueryTracer{}
+)
+
+// TraceQueryStart implements pgx.QueryTracer
+func (QueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	ctx, _ = Tracer().Start(ctx, queryOperation(data.SQL), trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("db.system.name", "postgresql"), attribute.String("db.query.text", data.SQL)))
+	return ctx
+}
+
+// TraceQueryEnd implements pgx.QueryTracer
+func (QueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	endQuerySpan(trace.SpanFromContext(ctx), data.CommandTag, data.Err)
+}
+
+// TraceCopyFromStart implements pgx.CopyFromTracer
+func (QueryTracer) TraceCopyFromStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceCopyFromStartData) context.Context {
+	ctx, _ = Tracer().Start(ctx, "COPY "+data.TableName.Sanitize(), trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("db.system.name", "postgresql")))
+	return ctx
+}
+
+// TraceCopyFromEnd implements pgx.CopyFromTracer
+func (QueryTracer) TraceCopyFromEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceCopyFromEndData) {
+	endQuerySpan(trace.SpanFromContext(ctx), data.CommandTag, data.Err)
+}
+
+// endQuerySpan records a query's outcome; no rows is how repositories learn about a missing
+// record, so it isn't an error
+func endQuerySpan(span trace.Span, tag pgconn.CommandTag, err error) {
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else {
+		span.SetAttributes(attribute.Int64("db.response.returned_rows", tag.RowsAffected()))
+	}
+	span.End()
+}
+
+// queryOperation names a query's span after its first keyword, e.g. "SELECT" or "WITH"
+func queryOperation(sql string) string {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return "query"
+	}
+	return strings.ToUpper(fields[0])
+}
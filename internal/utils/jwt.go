@@ -1,6 +1,10 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"strconv"
 	"time"
@@ -15,26 +19,56 @@ type JWTClaims struct {
 	jwt.RegisteredClaims
 }
 
+// DefaultRefreshExpirationHours is the refresh token lifetime unless configured otherwise (30 days)
+const DefaultRefreshExpirationHours = 30 * 24
+
 // JWTUtil provides JWT generation and validation
 type JWTUtil struct {
-	secretKey      string
-	expirationHours int64
+	secretKey              string
+	expirationHours        int64
+	refreshExpirationHours int64
+}
+
+// RefreshToken is a freshly generated opaque refresh token; only Hash should be stored
+type RefreshToken struct {
+	Token     string
+	Hash      string
+	UserID    int
+	ExpiresAt time.Time
 }
 
 // NewJWTUtil creates a new JWTUtil
 func NewJWTUtil(secretKey string, expirationHours int64) *JWTUtil {
-	return &JWTUtil{secretKey: secretKey, expirationHours: expirationHours}
+	return &JWTUtil{secretKey: secretKey, expirationHours: expirationHours, refreshExpirationHours: DefaultRefreshExpirationHours}
+}
+
+// WithRefreshExpiration sets the refresh token lifetime in hours
+func (ju *JWTUtil) WithRefreshExpiration(hours int64) *JWTUtil {
+	ju.refreshExpirationHours = hours
+	return ju
+}
+
+// ExpiresAt returns when an access token issued at issuedAt expires
+func (ju *JWTUtil) ExpiresAt(issuedAt time.Time) time.Time {
+	return issuedAt.Add(time.Hour * time.Duration(ju.expirationHours))
 }
 
 // GenerateToken generates a new JWT token
 func (ju *JWTUtil) GenerateToken(userID int, role string) (string, error) {
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", fmt.Errorf("failed to generate token ID: %w", err)
+	}
+
+	now := time.Now()
 	claims := &JWTClaims{
 		UserID: userID,
 		Role:   role,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * time.Duration(ju.expirationHours))),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(ju.ExpiresAt(now)),
+			IssuedAt:  jwt.NewNumericDate(now),
 			Subject:   strconv.Itoa(userID),
+			ID:        hex.EncodeToString(jti), // Lets the token be revoked on logout
 		},
 	}
 
@@ -49,7 +83,10 @@ func (ju *JWTUtil) GenerateToken(userID int, role string) (string, error) {
 // ValidateToken validates the JWT token
 func (ju *JWTUtil) ValidateToken(tokenString string) (*JWTClaims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		// Accept only HS256, which GenerateToken signs with. Any HMAC variant would verify
+		// against the same secret, so checking the family alone lets HS384/HS512 tokens
+		// that this service never issues through.
+		if token.Method != jwt.SigningMethodHS256 {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
 		return []byte(ju.secretKey), nil
@@ -64,4 +101,25 @@ func (ju *JWTUtil) ValidateToken(tokenString string) (*JWTClaims, error) {
 	}
 
 	return nil, fmt.Errorf("invalid token")
-}
\ No newline at end of file
+}
+
+// GenerateRefreshToken generates a long-lived opaque refresh token for the user
+func (ju *JWTUtil) GenerateRefreshToken(userID int) (*RefreshToken, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	return &RefreshToken{
+		Token:     token,
+		Hash:      HashRefreshToken(token),
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(time.Hour * time.Duration(ju.refreshExpirationHours)),
+	}, nil
+}
+
+// HashRefreshToken returns the value stored in place of a refresh token
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
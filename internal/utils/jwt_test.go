@@ -25,6 +25,11 @@ func TestJWTUtil_GenerateToken(t *testing.T) {
 	assert.Equal(t, userID, claims.UserID)
 	assert.Equal(t, role, claims.Role)
 	assert.WithinDuration(t, time.Now().Add(time.Hour), claims.ExpiresAt.Time, 5*time.Second)
+	assert.NotEmpty(t, claims.ID)
+
+	other, _ := jwtUtil.GenerateToken(userID, role)
+	otherClaims, _ := jwtUtil.ValidateToken(other)
+	assert.NotEqual(t, claims.ID, otherClaims.ID)
 }
 
 func TestJWTUtil_ValidateToken(t *testing.T) {
@@ -95,4 +100,25 @@ func TestJWTUtil_ValidateToken_InvalidSigningMethod(t *testing.T) {
 	_, err := jwtUtil.ValidateToken(tokenString)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "unexpected signing method")
-} 
\ No newline at end of file
+} 
+
+func TestJWTUtil_GenerateRefreshToken(t *testing.T) {
+	jwtUtil := NewJWTUtil("secret", 1).WithRefreshExpiration(48)
+
+	first, err := jwtUtil.GenerateRefreshToken(1)
+	assert.NoError(t, err)
+	second, err := jwtUtil.GenerateRefreshToken(1)
+	assert.NoError(t, err)
+
+	assert.NotEqual(t, first.Token, second.Token)
+	assert.Equal(t, HashRefreshToken(first.Token), first.Hash)
+	assert.NotEqual(t, first.Token, first.Hash)
+	assert.Equal(t, 1, first.UserID)
+	assert.WithinDuration(t, time.Now().Add(48*time.Hour), first.ExpiresAt, 5*time.Second)
+}
+
+func TestJWTUtil_ExpiresAt(t *testing.T) {
+	jwtUtil := NewJWTUtil("secret", 24)
+	issuedAt := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2024, 3, 2, 10, 0, 0, 0, time.UTC), jwtUtil.ExpiresAt(issuedAt))
+}
@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrInvalidPhone is returned for strings that can't be a valid phone number
+var ErrInvalidPhone = errors.New("invalid phone number")
+
+// DefaultCountryCode is assumed for bare national numbers (9 digits in Uzbekistan)
+const DefaultCountryCode = "998"
+
+const nationalNumberLength = 9
+
+// NormalizePhone converts a phone number to its E.164 form (+998901234567), so the same
+// number written with spaces, dashes, parentheses or a 00 prefix maps to one account.
+// A bare 9-digit number is taken as national and gets DefaultCountryCode.
+func NormalizePhone(raw string) (string, error) {
+	s := strings.TrimSpace(raw)
+	international := false
+	switch {
+	case strings.HasPrefix(s, "+"):
+		international, s = true, s[1:]
+	case strings.HasPrefix(s, "00"):
+		international, s = true, s[2:]
+	}
+
+	var digits strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == ' ' || r == '-' || r == '(' || r == ')' || r == '.':
+			// Formatting
+		default:
+			return "", ErrInvalidPhone
+		}
+	}
+
+	number := digits.String()
+	if !international && len(number) == nationalNumberLength {
+		number = DefaultCountryCode + number
+	}
+	// E.164: a country code that doesn't start with 0, at most 15 digits in total
+	if len(number) < 8 || len(number) > 15 || number[0] == '0' {
+		return "", ErrInvalidPhone
+	}
+	return "+" + number, nil
+}
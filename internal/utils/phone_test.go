@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizePhone_MessyInputsShareCanonicalForm(t *testing.T) {
+	inputs := []string{
+		"+998901234567",
+		"+998 90 123 45 67",
+		"+998 (90) 123-45-67",
+		"00998901234567",
+		"998901234567",
+		"90 123 45 67",
+		" 901234567 ",
+	}
+	for _, input := range inputs {
+		got, err := NormalizePhone(input)
+		assert.NoError(t, err, input)
+		assert.Equal(t, "+998901234567", got, input)
+	}
+
+	got, err := NormalizePhone("+1 (555) 123-4567")
+	assert.NoError(t, err)
+	assert.Equal(t, "+15551234567", got)
+}
+
+func TestNormalizePhone_RejectsInvalid(t *testing.T) {
+	for _, input := range []string{"", "abc", "+12", "12345", "+998 90 123 45 67 89 01", "+0998901234567", "90-123-45-67 ext 5"} {
+		_, err := NormalizePhone(input)
+		assert.ErrorIs(t, err, ErrInvalidPhone, input)
+	}
+}
@@ -0,0 +1,160 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"expense_tracker/internal/model"
+)
+
+// SignatureHeader carries the hex HMAC-SHA256 of the request body, keyed with the shared secret,
+// as "sha256=<hex>"
+const SignatureHeader = "X-Webhook-Signature"
+
+// EventHeader names the event a delivery is for
+const EventHeader = "X-Webhook-Event"
+
+// EventTransactionCreated is sent after a transaction is created
+const EventTransactionCreated = "transaction.created"
+
+// Delivery attempts per event and the delay before the first retry, doubled for each later one
+const (
+	maxAttempts    = 3
+	initialBackoff = time.Second
+	requestTimeout = 10 * time.Second
+)
+
+// Config holds the webhook endpoint and the secret deliveries are signed with
+type Config struct {
+	URL    string
+	Secret string
+}
+
+// LoadConfig reads WEBHOOK_URL and WEBHOOK_SECRET. It returns nil when WEBHOOK_URL is unset.
+func LoadConfig() (*Config, error) {
+	url := os.Getenv("WEBHOOK_URL")
+	if url == "" {
+		return nil, nil
+	}
+	secret := os.Getenv("WEBHOOK_SECRET")
+	if secret == "" {
+		return nil, fmt.Errorf("WEBHOOK_SECRET must be set when WEBHOOK_URL is set")
+	}
+	return &Config{URL: url, Secret: secret}, nil
+}
+
+// Payload is the JSON body of a delivery
+type Payload struct {
+	Event       string            `json:"event"`
+	Transaction model.Transaction `json:"transaction"`
+}
+
+// Notifier POSTs signed events to the configured URL in the background. A nil *Notifier does nothing.
+type Notifier struct {
+	cfg     Config
+	client  *http.Client
+	backoff time.Duration
+
+	inFlight sync.WaitGroup // Deliveries not yet finished, for Drain
+}
+
+// NewNotifier creates a Notifier for cfg, or returns nil when cfg is nil so notifications are skipped
+func NewNotifier(cfg *Config) *Notifier {
+	if cfg == nil {
+		return nil
+	}
+	return &Notifier{cfg: *cfg, client: &http.Client{Timeout: requestTimeout}, backoff: initialBackoff}
+}
+
+// TransactionCreated sends a transaction.created event without waiting for the delivery
+func (n *Notifier) TransactionCreated(t model.Transaction) {
+	if n == nil {
+		return
+	}
+	body, err := json.Marshal(Payload{Event: EventTransactionCreated, Transaction: t})
+	if err != nil {
+		log.Printf("Error encoding webhook payload for transaction %d: %v", t.ID, err)
+		return
+	}
+	n.inFlight.Add(1)
+	go func() {
+		defer n.inFlight.Done()
+		n.deliver(EventTransactionCreated, body)
+	}()
+}
+
+// Drain waits for deliveries in progress, retries included, until they finish or ctx is done.
+// It reports whether they all finished. Call it on shutdown, once nothing creates transactions.
+func (n *Notifier) Drain(ctx context.Context) bool {
+	if n == nil {
+		return true
+	}
+	done := make(chan struct{})
+	go func() {
+		n.inFlight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// deliver POSTs body, retrying with exponential backoff, and logs when every attempt fails
+func (n *Notifier) deliver(event string, body []byte) {
+	backoff := n.backoff
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if err = n.post(event, body); err == nil {
+			return
+		}
+		if attempt < maxAttempts {
+			log.Printf("Webhook %s delivery attempt %d failed, retrying in %v: %v", event, attempt, backoff, err)
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+	log.Printf("Error delivering webhook %s after %d attempts: %v", event, maxAttempts, err)
+}
+
+// post makes a single delivery attempt; any non-2xx response is an error
+func (n *Notifier) post(event string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, n.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, event)
+	req.Header.Set(SignatureHeader, "sha256="+Sign(n.cfg.Secret, body))
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body) // Drain so the connection can be reused
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook endpoint responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Sign returns the hex HMAC-SHA256 of body keyed with secret, as receivers should compute it
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
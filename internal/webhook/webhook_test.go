@@ -0,0 +1,78 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"expense_tracker/internal/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotifier_SignsAndRetries(t *testing.T) {
+	var attempts atomic.Int32
+	received := make(chan Payload, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, "sha256="+Sign("secret", body), r.Header.Get(SignatureHeader))
+		assert.Equal(t, EventTransactionCreated, r.Header.Get(EventHeader))
+		var p Payload
+		assert.NoError(t, json.Unmarshal(body, &p))
+		received <- p
+	}))
+	defer srv.Close()
+
+	n := NewNotifier(&Config{URL: srv.URL, Secret: "secret"})
+	n.backoff = time.Millisecond
+	n.TransactionCreated(model.Transaction{ID: 7, Amount: 1500})
+
+	select {
+	case p := <-received:
+		assert.Equal(t, int64(7), p.Transaction.ID)
+		assert.Equal(t, int64(1500), p.Transaction.Amount)
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not delivered")
+	}
+	assert.Equal(t, int32(2), attempts.Load())
+}
+
+func TestNotifier_NilIsNoop(t *testing.T) {
+	n := NewNotifier(nil)
+	assert.Nil(t, n)
+	n.TransactionCreated(model.Transaction{ID: 1}) // Must not panic
+}
+
+func TestNotifier_DrainWaitsForDeliveries(t *testing.T) {
+	release := make(chan struct{})
+	var delivered atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		delivered.Store(true)
+	}))
+	defer srv.Close()
+
+	n := NewNotifier(&Config{URL: srv.URL, Secret: "secret"})
+	n.TransactionCreated(model.Transaction{ID: 1})
+
+	// Gives up when ctx ends first
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.False(t, n.Drain(ctx))
+
+	close(release)
+	assert.True(t, n.Drain(context.Background()))
+	assert.True(t, delivered.Load())
+
+	var nilNotifier *Notifier
+	assert.True(t, nilNotifier.Drain(context.Background()))
+}